@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"LearningCampusKabre/models"
+
+	"gorm.io/gorm"
+)
+
+// bootstrapAdminFromEnv crée le premier administrateur à partir de BOOTSTRAP_ADMIN_EMAIL et
+// BOOTSTRAP_ADMIN_PASSWORD. Sans effet dès qu'un administrateur existe : les variables peuvent
+// rester définies sans risque.
+func bootstrapAdminFromEnv(db *gorm.DB) {
+	email := os.Getenv("BOOTSTRAP_ADMIN_EMAIL")
+	password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+	if email == "" || password == "" {
+		return
+	}
+
+	created, err := models.BootstrapAdmin(db, email, password)
+	if err != nil {
+		log.Fatal("❌ Erreur lors de la création de l'administrateur initial :", err)
+	}
+	if created {
+		log.Println("👤 Administrateur initial créé :", email)
+	}
+}
+
+// runCreateAdmin traite la commande "create-admin -email ... -password ..." et retourne le code de sortie.
+// Le mot de passe peut aussi venir de BOOTSTRAP_ADMIN_PASSWORD pour ne pas apparaître dans l'historique.
+func runCreateAdmin(db *gorm.DB, args []string) int {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email de l'administrateur")
+	password := flags.String("password", os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"), "mot de passe de l'administrateur")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" || *password == "" {
+		fmt.Fprintln(os.Stderr, "usage : create-admin -email <email> -password <mot de passe>")
+		return 2
+	}
+
+	created, err := models.BootstrapAdmin(db, *email, *password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		return 1
+	}
+	if !created {
+		fmt.Fprintln(os.Stderr, "Un administrateur existe déjà : invitez les autres comptes depuis l'API")
+		return 1
+	}
+	fmt.Println("👤 Administrateur créé :", *email)
+	return 0
+}
@@ -1,32 +1,53 @@
 package controllers
 
 import (
+	"errors"
+
+	"LearningCampusKabre/jwtkeys"
+	"LearningCampusKabre/mail"
+	"LearningCampusKabre/middlewares"
 	"LearningCampusKabre/models"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type AuthController struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Mailer mail.Mailer
 }
 
 func RefAuthController(db *gorm.DB) *AuthController {
-	return &AuthController{DB: db}
+	return &AuthController{DB: db, Mailer: mail.Default()}
+}
+
+// ForgotPasswordInput représente une demande de réinitialisation du mot de passe
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required" example:"john@example.com"`
+}
+
+// ResetPasswordInput représente le jeton reçu par email et le nouveau mot de passe
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required" example:"nouveau456"`
 }
 
 // Register godoc
-// @Summary Enregistrer un utilisateur
+// @Summary Créer son compte à partir d'une invitation
+// @Description L'email et le rôle sont ceux de l'invitation ; le jeton ne sert qu'une fois.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param user body models.RegisterInput true "Données utilisateur"
-// @Success 200 {object} map[string]string
+// @Param user body models.RegisterInput true "Jeton d'invitation et mot de passe"
+// @Success 201 {object} map[string]string
+// @Failure 400 {object} map[string]string
 // @Router /auth/register [post]
 func (ac *AuthController) Register(c *gin.Context) {
 	var input models.RegisterInput
@@ -35,28 +56,188 @@ func (ac *AuthController) Register(c *gin.Context) {
 		return
 	}
 
-	user := models.User{
-		Email:    input.Email,
-		Password: input.Password,
-		Role:     models.UserRole(input.Role),
+	user, err := models.RedeemInvitation(ac.DB, input.Token, input.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Utilisateur enregistré avec succès", "email": user.Email, "role": string(user.Role)})
+}
+
+// CreateInvitation godoc
+// @Summary Inviter un membre du staff
+// @Description Le jeton est retourné une seule fois et doit être transmis à la personne invitée.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param invitation body models.InvitationInput true "Invitation"
+// @Success 201 {object} map[string]interface{}
+// @Router /invitations [post]
+// @Security BearerAuth
+func (ac *AuthController) CreateInvitation(c *gin.Context) {
+	var input models.InvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+	if !checkCanGrantRoles(c, ac.DB, models.UserRole(input.Role)) {
+		return
+	}
+
+	ttl := models.DefaultInvitationTTL
+	if input.ExpiresInHours > 0 {
+		ttl = time.Duration(input.ExpiresInHours) * time.Hour
+	}
+
+	invitation := models.Invitation{Email: input.Email, Role: models.UserRole(input.Role)}
+	if id := middlewares.CurrentUserID(c); id != 0 {
+		invitation.CreatedByID = &id
+	}
+
+	token, err := models.CreateInvitation(ac.DB, &invitation, ttl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	var count int64
-	ac.DB.Model(&models.User{}).Where("email = ?", user.Email).Count(&count)
-	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "L'email existe déjà"})
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"token":      token,
+	})
+}
+
+// GetAllInvitations godoc
+// @Summary Liste des invitations
+// @Tags auth
+// @Produce json
+// @Success 200 {array} models.Invitation
+// @Router /invitations [get]
+// @Security BearerAuth
+func (ac *AuthController) GetAllInvitations(c *gin.Context) {
+	invitations, err := models.GetAllInvitations(ac.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des invitations"})
 		return
 	}
+	c.JSON(http.StatusOK, invitations)
+}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	user.Password = string(hashedPassword)
+// RevokeInvitation godoc
+// @Summary Révoquer une invitation
+// @Tags auth
+// @Param id path int true "ID invitation"
+// @Success 200 {object} map[string]string
+// @Router /invitations/{id} [delete]
+// @Security BearerAuth
+func (ac *AuthController) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
 
-	if err := models.CreateUser(ac.DB, &user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'utilisateur"})
+	if err := models.RevokeInvitation(ac.DB, uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Utilisateur enregistré avec succès"})
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation révoquée"})
+}
+
+// TokenResponse est retourné à la connexion et à chaque rafraîchissement
+type TokenResponse struct {
+	// JWT d'accès, à envoyer dans l'en-tête Authorization
+	Token string `json:"token"`
+	// Jeton de rafraîchissement à usage unique, à échanger sur /auth/refresh
+	RefreshToken string `json:"refresh_token"`
+	// Durée de vie du JWT d'accès en secondes
+	ExpiresIn int `json:"expires_in" example:"900"`
+	// Le compte doit activer la double authentification avant d'accéder aux routes protégées par permission
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+}
+
+// MFAChallengeResponse est retourné à la connexion quand la double authentification est activée :
+// le challenge s'échange contre les jetons sur /auth/2fa/verify
+type MFAChallengeResponse struct {
+	MFARequired    bool   `json:"mfa_required" example:"true"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in" example:"300"`
+}
+
+// RefreshInput représente le jeton de rafraîchissement envoyé par le client
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutInput précise la session à fermer
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+	// Ferme toutes les sessions de l'utilisateur (tous les appareils)
+	All bool `json:"all"`
+}
+
+// signAccessToken signe un JWT d'accès court rattaché à une session ; le claim mfa reprend
+// celui de la session, il est donc conservé à chaque rafraîchissement
+func signAccessToken(user *models.User, session *models.AuthSession) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"role":    string(user.Role),
+		"sid":     session.ID,
+		"mfa":     session.MFA,
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(models.AccessTokenTTL()).Unix(),
+	}
+
+	return jwtkeys.Sign(claims)
+}
+
+// respondTokens signe le JWT d'accès et répond avec la paire de jetons
+func respondTokens(c *gin.Context, user *models.User, session *models.AuthSession, refreshToken string) {
+	tokenString, err := signAccessToken(user, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenResponse{
+		Token:                 tokenString,
+		RefreshToken:          refreshToken,
+		ExpiresIn:             int(models.AccessTokenTTL().Seconds()),
+		MFAEnrollmentRequired: user.NeedsMFA() && !user.MFAEnabled(),
+	})
+}
+
+// startSession ouvre une session pour l'utilisateur authentifié et répond avec ses jetons.
+// session précise le second facteur, la tablette ou la limite d'inactivité.
+func startSession(c *gin.Context, db *gorm.DB, user *models.User, session models.AuthSession) {
+	session.UserID, session.UserAgent, session.IP = user.ID, c.Request.UserAgent(), c.ClientIP()
+	refreshToken, err := models.StartSession(db, &session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'ouverture de la session"})
+		return
+	}
+	respondTokens(c, user, &session, refreshToken)
+}
+
+// completeLogin ouvre la session d'un utilisateur authentifié, ou retourne un challenge si sa
+// double authentification est activée
+func completeLogin(c *gin.Context, db *gorm.DB, user *models.User) {
+	if user.MFAEnabled() {
+		challenge, err := models.CreateMFAChallenge(db, user.ID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion"})
+			return
+		}
+		c.JSON(http.StatusAccepted, MFAChallengeResponse{
+			MFARequired:    true,
+			ChallengeToken: challenge,
+			ExpiresIn:      int(models.MFAChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	startSession(c, db, user, models.AuthSession{})
 }
 
 // Login godoc
@@ -65,7 +246,11 @@ func (ac *AuthController) Register(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param credentials body models.LoginInput true "Email et mot de passe"
-// @Success 200 {object} map[string]string
+// @Description Si la double authentification est activée, retourne un challenge à compléter sur /auth/2fa/verify au lieu des jetons.
+// @Success 200 {object} TokenResponse
+// @Success 202 {object} MFAChallengeResponse
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string "Trop d'échecs : compte verrouillé ou tentative trop rapprochée"
 // @Router /auth/login [post]
 func (ac *AuthController) Login(c *gin.Context) {
 	var input models.LoginInput
@@ -74,25 +259,324 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := ac.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	user, err := models.AuthenticateLogin(ac.DB, input.Email, input.Password, c.ClientIP(), time.Now())
+	var blocked *models.LoginBlockedError
+	if errors.As(err, &blocked) {
+		c.Header("Retry-After", strconv.Itoa(int(blocked.RetryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": blocked.Error()})
+		return
+	}
+	if err == models.ErrInvalidCredentials {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou mot de passe incorrect"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion"})
+		return
+	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou mot de passe incorrect"})
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Compte désactivé"})
 		return
 	}
 
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    string(user.Role),
-		"exp":     time.Now().Add(2 * time.Hour).Unix(),
+	completeLogin(c, ac.DB, user)
+}
+
+// Refresh godoc
+// @Summary Rafraîchir le JWT d'accès
+// @Description Échange le jeton de rafraîchissement contre une nouvelle paire de jetons. Un jeton déjà échangé ferme la session. Une session ouverte par code PIN exige les en-têtes X-Device-ID et X-Device-Key de sa tablette.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body RefreshInput true "Jeton de rafraîchissement"
+// @Success 200 {object} TokenResponse
+// @Failure 401 {object} map[string]string
+// @Router /auth/refresh [post]
+func (ac *AuthController) Refresh(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	// Une session de tablette ne se rafraîchit que depuis sa tablette ; vérifié avant l'échange
+	// pour qu'un jeton volé ne consomme pas celui de la tablette
+	bound, err := models.SessionForRefreshToken(ac.DB, input.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err := middlewares.VerifySessionDevice(c, ac.DB, bound); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, session, refreshToken, err := models.RotateRefreshToken(ac.DB, input.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondTokens(c, user, session, refreshToken)
+}
+
+// Logout godoc
+// @Summary Déconnexion
+// @Description Ferme la session du JWT, ou celle du jeton de rafraîchissement fourni, ou toutes les sessions avec "all". Les JWT d'accès de la session cessent d'être acceptés.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param logout body LogoutInput false "Session à fermer"
+// @Success 200 {object} map[string]string
+// @Router /auth/logout [post]
+// @Security BearerAuth
+func (ac *AuthController) Logout(c *gin.Context) {
+	var input LogoutInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+			return
+		}
+	}
+
+	userID := middlewares.CurrentUserID(c)
+	if input.All {
+		if err := models.RevokeUserSessions(ac.DB, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la déconnexion"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Toutes les sessions ont été fermées"})
+		return
+	}
+
+	sessionID := c.GetUint("session_id")
+	if input.RefreshToken != "" {
+		session, err := models.SessionForRefreshToken(ac.DB, input.RefreshToken)
+		if err != nil || session.UserID != userID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Jeton de rafraîchissement invalide"})
+			return
+		}
+		sessionID = session.ID
+	}
+
+	if err := models.RevokeSession(ac.DB, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la déconnexion"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Déconnexion réussie"})
+}
+
+// ChangePassword godoc
+// @Summary Changer son mot de passe
+// @Description Vérifie l'ancien mot de passe ; les autres sessions de l'utilisateur sont fermées
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param password body models.ChangePasswordInput true "Ancien et nouveau mot de passe"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/change-password [post]
+// @Security BearerAuth
+func (ac *AuthController) ChangePassword(c *gin.Context) {
+	var input models.ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	err := models.ChangePassword(ac.DB, middlewares.CurrentUserID(c), input.OldPassword, input.NewPassword, c.GetUint("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mot de passe modifié"})
+}
+
+// resetLink construit le lien envoyé par email à partir de PASSWORD_RESET_URL ("{token}" remplacé)
+func resetLink(token string) string {
+	base := os.Getenv("PASSWORD_RESET_URL")
+	if base == "" {
+		return token
+	}
+	return strings.ReplaceAll(base, "{token}", token)
+}
+
+// ForgotPassword godoc
+// @Summary Demander la réinitialisation de son mot de passe
+// @Description Envoie un lien à usage unique par email. La réponse est la même que l'email ait un compte ou non.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordInput true "Email du compte"
+// @Success 200 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/forgot [post]
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	token, user, err := models.RequestPasswordReset(ac.DB, input.Email, c.ClientIP(), time.Now())
+	if err == models.ErrTooManyResetRequests {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la demande de réinitialisation"})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, _ := token.SignedString([]byte(os.Getenv("JWT_SIGNATURE_KEY")))
+	if user != nil {
+		msg := mail.Message{
+			To:      user.Email,
+			Subject: "Réinitialisation de votre mot de passe",
+			Body: "Bonjour,\n\nPour choisir un nouveau mot de passe, utilisez ce lien dans les " +
+				strconv.Itoa(int(models.PasswordResetTTL().Minutes())) + " minutes :\n\n" + resetLink(token) +
+				"\n\nSi vous n'êtes pas à l'origine de cette demande, ignorez cet email.\n",
+		}
+		// Envoi en tâche de fond : la durée de la réponse ne doit pas révéler si le compte existe
+		mailer := ac.Mailer
+		go func() {
+			if err := mailer.Send(msg); err != nil {
+				log.Println("❌ Erreur lors de l'envoi de l'email de réinitialisation :", err)
+			}
+		}()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Si un compte correspond à cet email, un lien de réinitialisation a été envoyé"})
+}
+
+// ResetPassword godoc
+// @Summary Choisir un nouveau mot de passe
+// @Description Le lien ne sert qu'une fois ; toutes les sessions du compte sont fermées
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordInput true "Jeton et nouveau mot de passe"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/reset [post]
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	if err := models.ResetPassword(ac.DB, input.Token, input.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mot de passe réinitialisé, vous pouvez vous connecter"})
+}
+
+// SetPIN godoc
+// @Summary Choisir son code PIN
+// @Description Code de 4 à 6 chiffres pour se connecter rapidement sur les tablettes partagées. Non disponible pour les comptes soumis à la double authentification.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param pin body models.SetPINInput true "Mot de passe et code PIN"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/pin/set [post]
+// @Security BearerAuth
+func (ac *AuthController) SetPIN(c *gin.Context) {
+	var input models.SetPINInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	if err := models.SetUserPIN(ac.DB, middlewares.CurrentUserID(c), input.Password, input.PIN); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Code PIN enregistré"})
+}
+
+// GetPINUsers godoc
+// @Summary Membres du staff pouvant se connecter sur la tablette
+// @Description Appelé par une tablette enregistrée (en-têtes X-Device-ID et X-Device-Key)
+// @Tags auth
+// @Produce json
+// @Success 200 {array} models.PINUserResponse
+// @Router /auth/pin/users [get]
+func (ac *AuthController) GetPINUsers(c *gin.Context) {
+	users, err := models.GetPINUsers(ac.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des utilisateurs"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// PINLogin godoc
+// @Summary Connexion rapide par code PIN sur une tablette
+// @Description Réservé aux tablettes enregistrées (en-têtes X-Device-ID et X-Device-Key). Ferme la session de l'utilisateur précédent sur la tablette : c'est aussi le changement rapide d'utilisateur. La session se ferme après PIN_IDLE_TIMEOUT_MINUTES d'inactivité.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.PINLoginInput true "Utilisateur et code PIN"
+// @Success 200 {object} TokenResponse
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/pin [post]
+func (ac *AuthController) PINLogin(c *gin.Context) {
+	var input models.PINLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	user, err := models.AuthenticatePIN(ac.DB, input.UserID, input.PIN, c.ClientIP(), time.Now())
+	var blocked *models.LoginBlockedError
+	if errors.As(err, &blocked) {
+		c.Header("Retry-After", strconv.Itoa(int(blocked.RetryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": blocked.Error()})
+		return
+	}
+	if err == models.ErrInvalidPIN || err == models.ErrPINNotAllowed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion"})
+		return
+	}
+
+	deviceID := c.GetUint("device_id")
+	if err := models.RevokeDeviceSessions(ac.DB, deviceID, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la fermeture de la session précédente"})
+		return
+	}
+	startSession(c, ac.DB, user, models.AuthSession{
+		DeviceID:           &deviceID,
+		IdleTimeoutSeconds: int(models.PINIdleTimeout().Seconds()),
+	})
+}
+
+// JWKS godoc
+// @Summary Clés publiques de vérification des JWT
+// @Description Document JWKS (RFC 7517) servi sur /.well-known/jwks.json, hors préfixe /api. Les autres services y trouvent la clé correspondant au kid de l'en-tête d'un jeton ; une clé programmée pour une rotation y figure avant de signer.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKSet
+// @Failure 503 {object} map[string]string
+// @Router /.well-known/jwks.json [get]
+func (ac *AuthController) JWKS(c *gin.Context) {
+	keys := jwtkeys.Default()
+	if keys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": jwtkeys.ErrNotConfigured.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keys.JWKS())
 }
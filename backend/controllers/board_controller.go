@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Intervalle par défaut entre deux lectures de l'état des commandes pour le flux
+const boardPollInterval = 2 * time.Second
+
+type BoardController struct {
+	DB *gorm.DB
+	// Intervalle entre deux lectures de l'état des commandes pour le flux
+	PollInterval time.Duration
+}
+
+func RefBoardController(db *gorm.DB) *BoardController {
+	return &BoardController{DB: db, PollInterval: boardPollInterval}
+}
+
+// GetBoard godoc
+// @Summary Écran de suivi des commandes
+// @Description Numéros de ticket du jour en préparation, prêts et récemment livrés
+// @Tags board
+// @Produce json
+// @Success 200 {object} models.StatusBoard
+// @Router /board [get]
+func (bc *BoardController) GetBoard(c *gin.Context) {
+	board, err := models.GetStatusBoard(bc.DB, time.Now(), models.BoardDeliveredDisplay())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des commandes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// StreamBoard godoc
+// @Summary Flux de l'écran de suivi des commandes
+// @Description Server-Sent Events : un événement "board" est envoyé à chaque changement
+// @Tags board
+// @Produce text/event-stream
+// @Success 200 {object} models.StatusBoard
+// @Router /board/stream [get]
+func (bc *BoardController) StreamBoard(c *gin.Context) {
+	ticker := time.NewTicker(bc.PollInterval)
+	defer ticker.Stop()
+
+	var last *models.StatusBoard
+
+	c.Stream(func(w io.Writer) bool {
+		board, err := models.GetStatusBoard(bc.DB, time.Now(), models.BoardDeliveredDisplay())
+		if err == nil && (last == nil || !last.Equal(board)) {
+			c.SSEvent("board", board)
+			last = &board
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	})
+}
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type CartController struct {
+	DB *gorm.DB
+}
+
+func RefCartController(db *gorm.DB) *CartController {
+	return &CartController{DB: db}
+}
+
+// CartLineInput représente une ligne à ajouter ou modifier dans un panier
+type CartLineInput struct {
+	MenuID    uint     `json:"menu_id,omitempty" example:"1"`
+	ProductID uint     `json:"product_id,omitempty" example:"0"`
+	Quantity  int      `json:"quantity" example:"1"`
+	Modifiers []string `json:"modifiers,omitempty" example:"sans oignons"`
+}
+
+// CheckoutInput contient les informations facultatives données à l'encaissement
+type CheckoutInput struct {
+	ServiceMode models.ServiceMode `json:"service_mode" example:"takeaway" enums:"on_site,takeaway"`
+	CouponCodes []string           `json:"coupon_codes,omitempty" example:"BIENVENUE"`
+	PriceListID *uint              `json:"price_list_id,omitempty" example:"2"`
+	// Client à qui rattacher la commande (téléphone, e-mail ou carte de fidélité), facultatif
+	Customer *models.CustomerRef `json:"customer,omitempty"`
+	// Heure de retrait souhaitée pour une commande programmée, facultative
+	PickupAt *time.Time `json:"pickup_at,omitempty" example:"2026-10-19T12:30:00+02:00"`
+}
+
+// CartLineView est une ligne de panier avec son prix et ses erreurs éventuelles
+type CartLineView struct {
+	ID uint `json:"id"`
+	models.PricedLine
+}
+
+// CartView est la représentation d'un panier renvoyée au client, avec les prix à jour
+// et les promotions automatiques déjà déduites
+type CartView struct {
+	ID         uint                      `json:"id"`
+	ExpiresAt  time.Time                 `json:"expires_at"`
+	Lines      []CartLineView            `json:"lines"`
+	Subtotal   decimal.Decimal           `json:"subtotal"`
+	Discount   decimal.Decimal           `json:"discount"`
+	Promotions []models.AppliedPromotion `json:"promotions,omitempty"`
+	Total      decimal.Decimal           `json:"total"`
+	Valid      bool                      `json:"valid"`
+}
+
+// loadCart récupère le panier de la route, s'il appartient à l'utilisateur connecté, et gère son expiration
+func (cc *CartController) loadCart(c *gin.Context) (*models.Cart, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+
+	// Le panier d'un autre utilisateur est traité comme inexistant
+	cart, err := models.GetCartByID(cc.DB, uint(id))
+	if err == nil && cart.UserID != middlewares.CurrentUserID(c) {
+		err = fmt.Errorf("Le panier avec l'Id %d n'a pas été trouvé", id)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if cart.IsExpired(time.Now()) {
+		models.DeleteCart(cc.DB, cart.ID)
+		c.JSON(http.StatusGone, gin.H{"error": "Le panier a expiré"})
+		return nil, false
+	}
+
+	return cart, true
+}
+
+// renderCart recalcule les prix du panier et l'envoie au client
+func (cc *CartController) renderCart(c *gin.Context, status int, cart *models.Cart) {
+	order, err := models.PriceOrder(cc.DB, cart.OrderLines(), models.PricingContext{Channel: models.ChannelCounter})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul du panier"})
+		return
+	}
+
+	view := CartView{
+		ID:         cart.ID,
+		ExpiresAt:  cart.ExpiresAt,
+		Lines:      []CartLineView{},
+		Subtotal:   order.Subtotal,
+		Discount:   order.Discount,
+		Promotions: order.Promotions,
+		Total:      order.Total,
+		Valid:      order.Valid,
+	}
+	for i, line := range order.Lines {
+		view.Lines = append(view.Lines, CartLineView{ID: cart.Lines[i].ID, PricedLine: line})
+	}
+
+	c.JSON(status, view)
+}
+
+// CreateCart godoc
+// @Summary Créer un panier
+// @Tags carts
+// @Produce json
+// @Success 201 {object} CartView
+// @Router /carts [post]
+// @Security BearerAuth
+func (cc *CartController) CreateCart(c *gin.Context) {
+	// On profite de la création pour nettoyer les paniers abandonnés
+	models.PurgeExpiredCarts(cc.DB, time.Now())
+
+	cart := models.Cart{UserID: middlewares.CurrentUserID(c)}
+	if err := models.CreateCart(cc.DB, &cart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du panier"})
+		return
+	}
+
+	cc.renderCart(c, http.StatusCreated, &cart)
+}
+
+// GetCart godoc
+// @Summary Récupérer un panier
+// @Tags carts
+// @Produce json
+// @Param id path int true "ID panier"
+// @Success 200 {object} CartView
+// @Failure 410 {object} map[string]string
+// @Router /carts/{id} [get]
+// @Security BearerAuth
+func (cc *CartController) GetCart(c *gin.Context) {
+	cart, ok := cc.loadCart(c)
+	if !ok {
+		return
+	}
+
+	cc.renderCart(c, http.StatusOK, cart)
+}
+
+// AddCartLine godoc
+// @Summary Ajouter une ligne au panier
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param id path int true "ID panier"
+// @Param line body CartLineInput true "Ligne"
+// @Success 200 {object} CartView
+// @Router /carts/{id}/lines [post]
+// @Security BearerAuth
+func (cc *CartController) AddCartLine(c *gin.Context) {
+	cart, ok := cc.loadCart(c)
+	if !ok {
+		return
+	}
+
+	var request CartLineInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La quantité doit être supérieure à 0"})
+		return
+	}
+	if (request.MenuID == 0) == (request.ProductID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Une ligne doit référencer soit un menu, soit un produit"})
+		return
+	}
+
+	line := models.CartLine{
+		CartID:    cart.ID,
+		MenuID:    request.MenuID,
+		ProductID: request.ProductID,
+		Quantity:  request.Quantity,
+		Modifiers: request.Modifiers,
+	}
+	if err := cc.DB.Create(&line).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'ajout de la ligne"})
+		return
+	}
+
+	models.TouchCart(cc.DB, cart)
+	cart.Lines = append(cart.Lines, line)
+
+	cc.renderCart(c, http.StatusOK, cart)
+}
+
+// UpdateCartLine godoc
+// @Summary Modifier la quantité ou les modificateurs d'une ligne
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param id path int true "ID panier"
+// @Param lineId path int true "ID ligne"
+// @Param line body CartLineInput true "Ligne"
+// @Success 200 {object} CartView
+// @Router /carts/{id}/lines/{lineId} [put]
+// @Security BearerAuth
+func (cc *CartController) UpdateCartLine(c *gin.Context) {
+	cart, ok := cc.loadCart(c)
+	if !ok {
+		return
+	}
+
+	var request CartLineInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La quantité doit être supérieure à 0"})
+		return
+	}
+
+	lineID, _ := strconv.ParseUint(c.Param("lineId"), 10, 32)
+	for i := range cart.Lines {
+		line := &cart.Lines[i]
+		if line.ID != uint(lineID) {
+			continue
+		}
+
+		line.Quantity = request.Quantity
+		line.Modifiers = request.Modifiers
+		if err := cc.DB.Save(line).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la ligne"})
+			return
+		}
+
+		models.TouchCart(cc.DB, cart)
+		cc.renderCart(c, http.StatusOK, cart)
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Ligne introuvable dans ce panier"})
+}
+
+// RemoveCartLine godoc
+// @Summary Retirer une ligne du panier
+// @Tags carts
+// @Produce json
+// @Param id path int true "ID panier"
+// @Param lineId path int true "ID ligne"
+// @Success 200 {object} CartView
+// @Router /carts/{id}/lines/{lineId} [delete]
+// @Security BearerAuth
+func (cc *CartController) RemoveCartLine(c *gin.Context) {
+	cart, ok := cc.loadCart(c)
+	if !ok {
+		return
+	}
+
+	lineID, _ := strconv.ParseUint(c.Param("lineId"), 10, 32)
+	for i, line := range cart.Lines {
+		if line.ID != uint(lineID) {
+			continue
+		}
+
+		if err := cc.DB.Delete(&line).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de la ligne"})
+			return
+		}
+
+		cart.Lines = append(cart.Lines[:i], cart.Lines[i+1:]...)
+		models.TouchCart(cc.DB, cart)
+		cc.renderCart(c, http.StatusOK, cart)
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Ligne introuvable dans ce panier"})
+}
+
+// DeleteCart godoc
+// @Summary Abandonner un panier
+// @Tags carts
+// @Param id path int true "ID panier"
+// @Success 200 {object} map[string]string
+// @Router /carts/{id} [delete]
+// @Security BearerAuth
+func (cc *CartController) DeleteCart(c *gin.Context) {
+	cart, ok := cc.loadCart(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteCart(cc.DB, cart.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression du panier"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Panier supprimé"})
+}
+
+// CheckoutCart godoc
+// @Summary Convertir un panier en commande
+// @Description Recalcule les prix, applique les promotions et les codes promo, puis crée la commande ; le panier est supprimé dans la même transaction
+// @Tags carts
+// @Produce json
+// @Param id path int true "ID panier"
+// @Param checkout body CheckoutInput false "Mode de consommation, codes promo et grille de prix manuelle"
+// @Success 201 {object} models.Commande
+// @Failure 422 {object} models.PricedOrder
+// @Router /carts/{id}/checkout [post]
+// @Security BearerAuth
+func (cc *CartController) CheckoutCart(c *gin.Context) {
+	cart, ok := cc.loadCart(c)
+	if !ok {
+		return
+	}
+
+	if len(cart.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La commande doit contenir au moins un menu ou un produit"})
+		return
+	}
+
+	// Le corps est facultatif : sans précision, la commande est consommée sur place
+	request := CheckoutInput{ServiceMode: models.ServiceOnSite}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !request.ServiceMode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode de consommation invalide"})
+		return
+	}
+	if !validatePickupAt(c, request.PickupAt) {
+		return
+	}
+	customerID, ok := resolveCustomer(c, cc.DB, request.Customer)
+	if !ok {
+		return
+	}
+
+	commande := models.Commande{
+		Status:        models.StatusPending,
+		Channel:       models.ChannelCounter,
+		PaymentStatus: models.PaymentPaid,
+		ServiceMode:   request.ServiceMode,
+		CustomerID:    customerID,
+		PickupAt:      request.PickupAt,
+	}
+
+	order, err := models.CheckoutCart(cc.DB, cart, &commande, models.PricingContext{
+		Channel:     models.ChannelCounter,
+		PriceListID: request.PriceListID,
+		CouponCodes: request.CouponCodes,
+	})
+	if err == models.ErrPriceListUnavailable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La grille de prix demandée n'est pas disponible"})
+		return
+	}
+	if err == models.ErrPromotionExhausted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le code promo a atteint sa limite d'utilisation"})
+		return
+	}
+	if err == models.ErrPickupSlotFull {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le créneau de retrait demandé est complet"})
+		return
+	}
+	if err == models.ErrCartCheckedOut {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la commande"})
+		return
+	}
+	if !order.Valid {
+		c.JSON(http.StatusUnprocessableEntity, order)
+		return
+	}
+
+	enqueueKitchenTickets(cc.DB, &commande)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Commande créée avec succès",
+		"commande": commande,
+	})
+}
@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"LearningCampusKabre/middlewares"
 	"LearningCampusKabre/models"
+	"LearningCampusKabre/printing"
+	"LearningCampusKabre/receipts"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -16,26 +20,76 @@ type CommandeController struct {
 }
 
 type CommandeInput struct {
-	Menus    []int           `json:"menus"`
-	Products []int           `json:"products"`
-	Price    decimal.Decimal `json:"price"`
+	Menus       []int              `json:"menus"`
+	Products    []int              `json:"products"`
+	ServiceMode models.ServiceMode `json:"service_mode" example:"on_site" enums:"on_site,takeaway"`
+	// Codes promo saisis au comptoir et grille de prix choisie (ex. tarif étudiant), facultatifs
+	CouponCodes []string `json:"coupon_codes,omitempty" example:"BIENVENUE"`
+	PriceListID *uint    `json:"price_list_id,omitempty" example:"2"`
+	// Client à qui rattacher la commande (téléphone, e-mail ou carte de fidélité), facultatif
+	Customer *models.CustomerRef `json:"customer,omitempty"`
+	// Heure de retrait souhaitée pour une commande programmée, facultative
+	PickupAt *time.Time `json:"pickup_at,omitempty" example:"2026-10-19T12:30:00+02:00"`
 }
 
-// Struct utilisée pour la mise à jour d'une commande existante
+// Struct utilisée pour la mise à jour d'une commande existante. Sans lines, menus ni products, seul le
+// statut change ; sinon les lignes sont remplacées et la commande chiffrée à nouveau.
 type CommandeUpdateInput struct {
-	Menus    []int             `json:"menus"`
-	Products []int             `json:"products"`
-	Price    decimal.Decimal   `json:"price"`
-	Status   models.StatusType `json:"status"`
+	// Lignes avec quantités et options ; menus et products ajoutent chacun une ligne de quantité 1
+	Lines    []models.OrderLine `json:"lines,omitempty"`
+	Menus    []int              `json:"menus"`
+	Products []int              `json:"products"`
+	Status   models.StatusType  `json:"status"`
+}
+
+// orderLines retourne les lignes demandées, ou nil si la requête ne change que le statut
+func (input CommandeUpdateInput) orderLines() []models.OrderLine {
+	if input.Lines == nil && input.Menus == nil && input.Products == nil {
+		return nil
+	}
+	lines := make([]models.OrderLine, 0, len(input.Lines)+len(input.Menus)+len(input.Products))
+	lines = append(lines, input.Lines...)
+	for _, id := range input.Menus {
+		lines = append(lines, models.OrderLine{MenuID: uint(id), Quantity: 1})
+	}
+	for _, id := range input.Products {
+		lines = append(lines, models.OrderLine{ProductID: uint(id), Quantity: 1})
+	}
+	return lines
+}
+
+// enqueueKitchenTickets envoie les tickets en cuisine ; une commande programmée attend sa
+// libération par le scheduler. Un problème d'impression ne doit pas empêcher la prise de commande.
+func enqueueKitchenTickets(db *gorm.DB, commande *models.Commande) {
+	if commande.Status == models.StatusScheduled {
+		return
+	}
+	if _, err := printing.EnqueueCommande(db, commande.ID); err != nil {
+		log.Println("❌ Erreur lors de la mise en file d'impression :", err)
+	}
+}
+
+// validatePickupAt contrôle l'heure de retrait demandée, facultative
+func validatePickupAt(c *gin.Context, pickupAt *time.Time) bool {
+	if pickupAt == nil {
+		return true
+	}
+	if err := models.ValidatePickupAt(*pickupAt, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
 }
 
 // CreateCommande crée une nouvelle commande
 // @Summary Create a new commande
-// @Description Create a new commande with associated menus and products
+// @Description Create a new commande with associated menus and products, priced like every other channel: catalogue prices, price lists of the moment and promotions
 // @Tags commandes
 // @Accept json
 // @Produce json
+// @Param commande body CommandeInput true "Commande"
 // @Success 201 {object} models.Commande
+// @Failure 422 {object} models.PricedOrder
 // @Router /commandes [post]
 // @Security BearerAuth
 func (cc *CommandeController) CreateCommande(c *gin.Context) {
@@ -53,73 +107,75 @@ func (cc *CommandeController) CreateCommande(c *gin.Context) {
 		return
 	}
 
-	// Vérifier que les menus existent
-	var menus []models.Menu
-	if len(request.Menus) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Menus).Find(&menus).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des menus"})
-			return
-		}
-		if len(menus) != len(request.Menus) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs menus sont introuvables"})
-			return
-		}
+	if request.ServiceMode == "" {
+		request.ServiceMode = models.ServiceOnSite
+	}
+	if !request.ServiceMode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode de consommation invalide"})
+		return
 	}
 
-	// Vérifier que les produits existent
-	var products []models.Product
-	if len(request.Products) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Products).Find(&products).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des produits"})
-			return
-		}
-		if len(products) != len(request.Products) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs produits sont introuvables"})
-			return
-		}
+	if !validatePickupAt(c, request.PickupAt) {
+		return
+	}
+	customerID, ok := resolveCustomer(c, cc.DB, request.Customer)
+	if !ok {
+		return
+	}
+
+	// Le prix est calculé par le serveur, comme pour la borne et le panier
+	lines := make([]models.OrderLine, 0, len(request.Menus)+len(request.Products))
+	for _, id := range request.Menus {
+		lines = append(lines, models.OrderLine{MenuID: uint(id), Quantity: 1})
+	}
+	for _, id := range request.Products {
+		lines = append(lines, models.OrderLine{ProductID: uint(id), Quantity: 1})
+	}
+	order, err := models.PriceOrder(cc.DB, lines, models.PricingContext{
+		Channel:     models.ChannelCounter,
+		PriceListID: request.PriceListID,
+		CouponCodes: request.CouponCodes,
+	})
+	if err == models.ErrPriceListUnavailable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La grille de prix demandée n'est pas disponible"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul de la commande"})
+		return
+	}
+	if !order.Valid {
+		c.JSON(http.StatusUnprocessableEntity, order)
+		return
 	}
 
 	// Création de la commande et on la passe à l'état "pending"
+	// Au comptoir, le paiement est encaissé par le receiver au moment de la prise de commande.
+	// Une commande programmée reste hors de la file de préparation jusqu'à sa libération.
 	commande := models.Commande{
-		Status: models.StatusPending,
-		Price:  request.Price,
+		Status:        models.StatusPending,
+		Channel:       models.ChannelCounter,
+		PaymentStatus: models.PaymentPaid,
+		ServiceMode:   request.ServiceMode,
+		CustomerID:    customerID,
+		PickupAt:      request.PickupAt,
 	}
 
-	if err := cc.DB.Create(&commande).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	err = models.CreateCommandeFromLines(cc.DB, order, &commande)
+	if err == models.ErrPromotionExhausted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le code promo a atteint sa limite d'utilisation"})
 		return
 	}
-
-	// Création des CommandeMenu (snapshots)
-	for _, m := range menus {
-		cc.DB.Create(&models.CommandeMenu{
-			CommandeID:  commande.ID,
-			MenuID:      m.ID,
-			Name:        m.Name,
-			Price:       m.Price,
-			Description: m.Description,
-			ImageURL:    m.ImageURL,
-		})
+	if err == models.ErrPickupSlotFull {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le créneau de retrait demandé est complet"})
+		return
 	}
-
-	// Création des CommandeProduct (snapshots)
-	for _, p := range products {
-		cc.DB.Create(&models.CommandeProduct{
-			CommandeID:  commande.ID,
-			ProductID:   p.ID,
-			Name:        p.Name,
-			Price:       p.Price,
-			ImageURL:    p.ImageURL,
-			Description: p.Description,
-			Type:        p.Type,
-		})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la commande"})
+		return
 	}
 
-	// Recharger la commande complète
-	cc.DB.
-		Preload("Menus").
-		Preload("Products").
-		First(&commande)
+	enqueueKitchenTickets(cc.DB, &commande)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Commande créée avec succès",
@@ -127,12 +183,72 @@ func (cc *CommandeController) CreateCommande(c *gin.Context) {
 	})
 }
 
+// validStatus répond 400 pour un statut inconnu
+func validStatus(c *gin.Context, status models.StatusType) bool {
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Statut de commande invalide"})
+		return false
+	}
+	return true
+}
+
+// updateCommande change le statut d'une commande et, si la requête en contient, remplace ses lignes :
+// elles sont chiffrées comme à la création (PriceOrder, avec le canal, la grille et les codes promo
+// de la commande) et ses promotions recalculées. Les lignes offertes par une récompense sont gardées.
+func (cc *CommandeController) updateCommande(c *gin.Context, commande *models.Commande, request CommandeUpdateInput) {
+	// Une commande programmée entre en file à l'heure prévue, par le scheduler : l'avancer
+	// ou la reprogrammer demande la permission de modifier les commandes
+	scheduling := commande.Status == models.StatusScheduled || request.Status == models.StatusScheduled
+	if scheduling && request.Status != commande.Status && !middlewares.HasPermission(c, models.PermOrderEdit) {
+		c.JSON(http.StatusConflict, gin.H{"error": "La commande est programmée : elle entrera en préparation à l'heure de retrait prévue"})
+		return
+	}
+
+	order, err := models.EditCommande(cc.DB, commande, request.Status, request.orderLines())
+	switch {
+	case err == models.ErrPriceListUnavailable:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La grille de prix de la commande n'est plus disponible"})
+		return
+	case err == models.ErrPromotionExhausted:
+		c.JSON(http.StatusConflict, gin.H{"error": "Le code promo a atteint sa limite d'utilisation"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la commande"})
+		return
+	case order != nil && !order.Valid:
+		c.JSON(http.StatusUnprocessableEntity, order)
+		return
+	}
+
+	if order == nil {
+		if err := cc.DB.Preload("Menus").Preload("Products").Preload("Promotions").First(commande, commande.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la commande"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Commande mise à jour avec succès",
+		"commande": commande,
+	})
+}
+
+// canEditLines répond 403 si la requête remplace les lignes sans la permission de modifier les commandes
+func canEditLines(c *gin.Context, request CommandeUpdateInput) bool {
+	if request.orderLines() != nil && !middlewares.HasPermission(c, models.PermOrderEdit) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Modifier les lignes d'une commande demande la permission " + string(models.PermOrderEdit)})
+		return false
+	}
+	return true
+}
+
 // GetAllCommandes récupère toutes les commandes
 // @Summary Get all commandes
-// @Description Get all commandes with their associated menus and products
+// @Description Get all commandes with their associated menus and products, timings and SLA flag
 // @Tags commandes
 // @Accept json
 // @Produce json
+// @Param sla_breached query bool false "Only commandes exceeding the SLA"
 // @Success 201 {object} models.Commande
 // @Router /commandes [get]
 // @Security BearerAuth
@@ -144,6 +260,17 @@ func (cc *CommandeController) GetAllCommandes(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des commandes"})
 		return
 	}
+
+	if c.Query("sla_breached") == "true" {
+		breached := []models.Commande{}
+		for _, commande := range commandes {
+			if commande.SLABreached {
+				breached = append(breached, commande)
+			}
+		}
+		commandes = breached
+	}
+
 	c.JSON(http.StatusOK, commandes)
 }
 
@@ -173,11 +300,13 @@ func (cc *CommandeController) GetCommandeByID(c *gin.Context) {
 
 // UpdateCommande met à jour une commande existante
 // @Summary Update an existing commande
-// @Description Update an existing commande with new data
+// @Description Update the status of a commande. When lines, menus or products are sent, its lines are replaced and priced like at creation (price lists and coupon codes of the commande, promotions recomputed); otherwise they are left unchanged.
 // @Tags commandes
 // @Accept json
 // @Produce json
+// @Param commande body CommandeUpdateInput true "Commande"
 // @Success 201 {object} models.Commande
+// @Failure 422 {object} models.PricedOrder
 // @Router /commandes/{id} [put]
 // @Security BearerAuth
 func (cc *CommandeController) AdminUpdateCommande(c *gin.Context) {
@@ -195,82 +324,27 @@ func (cc *CommandeController) AdminUpdateCommande(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	var menus []models.Menu
-	if len(request.Menus) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Menus).Find(&menus).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des menus"})
-			return
-		}
-		if len(menus) != len(request.Menus) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs menus sont introuvables"})
-			return
-		}
-	}
-
-	var products []models.Product
-	if len(request.Products) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Products).Find(&products).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des produits"})
-			return
-		}
-		if len(products) != len(request.Products) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs produits sont introuvables"})
-			return
-		}
-	}
-
-	commande.Price = request.Price
-	commande.Status = request.Status
-	cc.DB.Save(&commande)
-
-	cc.DB.Where("commande_id = ?", commande.ID).Delete(&models.CommandeMenu{})
-	cc.DB.Where("commande_id = ?", commande.ID).Delete(&models.CommandeProduct{})
-
-	for _, m := range menus {
-		cc.DB.Create(&models.CommandeMenu{
-			CommandeID:  commande.ID,
-			MenuID:      m.ID,
-			Name:        m.Name,
-			Price:       m.Price,
-			Description: m.Description,
-			ImageURL:    m.ImageURL,
-		})
-	}
-
-	for _, p := range products {
-		cc.DB.Create(&models.CommandeProduct{
-			CommandeID:  commande.ID,
-			ProductID:   p.ID,
-			Name:        p.Name,
-			Price:       p.Price,
-			ImageURL:    p.ImageURL,
-			Description: p.Description,
-			Type:        p.Type,
-		})
+	if !validStatus(c, request.Status) {
+		return
 	}
 
-	cc.DB.
-		Preload("Menus").
-		Preload("Products").
-		First(&commande)
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Commande mise à jour avec succès",
-		"commande": commande,
-	})
+	cc.updateCommande(c, &commande, request)
 }
 
-// PreparerUpdateCommande met à jour une commande existante
-// @Summary Update an existing commande to ready status
-// @Description Update an existing commande with new data
+// StaffUpdateCommande fait avancer une commande en cuisine ou au comptoir
+// @Summary Update the status of a commande from the kitchen or the counter
+// @Description Each status requires its own permission (order:prepare to prepare, order:deliver to deliver, see models.StatusPermission). Lines can only be replaced with order:edit.
 // @Tags commandes
 // @Accept json
 // @Produce json
+// @Param id path int true "ID commande"
+// @Param commande body CommandeUpdateInput true "Commande"
 // @Success 201 {object} models.Commande
-// @Router /commandes/{id} [put]
+// @Failure 403 {object} map[string]string
+// @Router /commandes/preparer/{id} [put]
+// @Router /commandes/receiver/{id} [put]
 // @Security BearerAuth
-func (cc *CommandeController) PreparerUpdateCommande(c *gin.Context) {
+func (cc *CommandeController) StaffUpdateCommande(c *gin.Context) {
 
 	id := c.Param("id")
 
@@ -285,88 +359,28 @@ func (cc *CommandeController) PreparerUpdateCommande(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// On s'assure que le preparer passe le status à Ready et pas autre chose.
-	if request.Status != "ready" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status non autorisé pour l'utilisateur preparer"})
+	if !validStatus(c, request.Status) || !canEditLines(c, request) {
 		return
 	}
-
-	var menus []models.Menu
-	if len(request.Menus) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Menus).Find(&menus).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des menus"})
-			return
-		}
-		if len(menus) != len(request.Menus) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs menus sont introuvables"})
-			return
-		}
-	}
-
-	var products []models.Product
-	if len(request.Products) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Products).Find(&products).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des produits"})
-			return
-		}
-		if len(products) != len(request.Products) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs produits sont introuvables"})
-			return
-		}
-	}
-
-	commande.Price = request.Price
-	commande.Status = request.Status
-	cc.DB.Save(&commande)
-
-	cc.DB.Where("commande_id = ?", commande.ID).Delete(&models.CommandeMenu{})
-	cc.DB.Where("commande_id = ?", commande.ID).Delete(&models.CommandeProduct{})
-
-	for _, m := range menus {
-		cc.DB.Create(&models.CommandeMenu{
-			CommandeID:  commande.ID,
-			MenuID:      m.ID,
-			Name:        m.Name,
-			Price:       m.Price,
-			Description: m.Description,
-			ImageURL:    m.ImageURL,
-		})
-	}
-
-	for _, p := range products {
-		cc.DB.Create(&models.CommandeProduct{
-			CommandeID:  commande.ID,
-			ProductID:   p.ID,
-			Name:        p.Name,
-			Price:       p.Price,
-			ImageURL:    p.ImageURL,
-			Description: p.Description,
-			Type:        p.Type,
-		})
+	// Chaque statut demande sa permission (préparation, remise...) : voir models.StatusPermission
+	if !middlewares.HasPermission(c, models.StatusPermission(request.Status)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Statut non autorisé : permission " + string(models.StatusPermission(request.Status)) + " requise"})
+		return
 	}
 
-	cc.DB.
-		Preload("Menus").
-		Preload("Products").
-		First(&commande)
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Commande mise à jour avec succès",
-		"commande": commande,
-	})
+	cc.updateCommande(c, &commande, request)
 }
 
-// ReceiverUpdateCommande met à jour une commande existante to pending or delivered
-// @Summary Update an existing commande to ready status
-// @Description Update an existing commande with new data
+// MarkCommandePaid enregistre le paiement d'une commande passée sur une borne
+// @Summary Mark a commande as paid
+// @Description Record the payment of a commande waiting for payment (kiosk orders)
 // @Tags commandes
-// @Accept json
 // @Produce json
-// @Success 201 {object} models.Commande
-// @Router /commandes/{id} [put]
+// @Param id path int true "Commande ID"
+// @Success 200 {object} models.Commande
+// @Router /commandes/{id}/payment [put]
 // @Security BearerAuth
-func (cc *CommandeController) ReceiverUpdateCommande(c *gin.Context) {
-
+func (cc *CommandeController) MarkCommandePaid(c *gin.Context) {
 	id := c.Param("id")
 
 	var commande models.Commande
@@ -375,79 +389,73 @@ func (cc *CommandeController) ReceiverUpdateCommande(c *gin.Context) {
 		return
 	}
 
-	var request CommandeUpdateInput
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if commande.PaymentStatus == models.PaymentPaid {
+		c.JSON(http.StatusConflict, gin.H{"error": "La commande est déjà payée"})
 		return
 	}
-	// On s'assure que le preparer passe le status à Ready et pas autre chose.
-	if request.Status != "pending" && request.Status != "delivered" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status non autorisé pour l'utilisateur receiver"})
-		return
-	}
-
-	var menus []models.Menu
-	if len(request.Menus) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Menus).Find(&menus).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des menus"})
-			return
-		}
-		if len(menus) != len(request.Menus) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs menus sont introuvables"})
-			return
-		}
-	}
 
-	var products []models.Product
-	if len(request.Products) > 0 {
-		if err := cc.DB.Where("id IN ?", request.Products).Find(&products).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Erreur lors de la vérification des produits"})
-			return
-		}
-		if len(products) != len(request.Products) {
-			c.JSON(400, gin.H{"error": "Un ou plusieurs produits sont introuvables"})
-			return
-		}
+	commande.PaymentStatus = models.PaymentPaid
+	if err := cc.DB.Save(&commande).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du paiement"})
+		return
 	}
 
-	commande.Price = request.Price
-	commande.Status = request.Status
-	cc.DB.Save(&commande)
+	c.JSON(http.StatusOK, commande)
+}
 
-	cc.DB.Where("commande_id = ?", commande.ID).Delete(&models.CommandeMenu{})
-	cc.DB.Where("commande_id = ?", commande.ID).Delete(&models.CommandeProduct{})
+// GetCommandeReceipt génère la facture PDF ou le ticket cuisine d'une commande
+// @Summary Get the receipt of a commande
+// @Description PDF invoice (format=pdf, default) or kitchen ticket as an ESC/POS byte stream (format=escpos)
+// @Tags commandes
+// @Produce application/pdf
+// @Produce application/octet-stream
+// @Param id path int true "Commande ID"
+// @Param format query string false "pdf or escpos"
+// @Success 200 {file} file
+// @Router /commandes/{id}/receipt [get]
+// @Security BearerAuth
+func (cc *CommandeController) GetCommandeReceipt(c *gin.Context) {
+	id := c.Param("id")
 
-	for _, m := range menus {
-		cc.DB.Create(&models.CommandeMenu{
-			CommandeID:  commande.ID,
-			MenuID:      m.ID,
-			Name:        m.Name,
-			Price:       m.Price,
-			Description: m.Description,
-			ImageURL:    m.ImageURL,
-		})
+	var commande models.Commande
+	if err := cc.DB.Preload("Menus").Preload("Products").Preload("Promotions").First(&commande, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commande introuvable"})
+		return
 	}
 
-	for _, p := range products {
-		cc.DB.Create(&models.CommandeProduct{
-			CommandeID:  commande.ID,
-			ProductID:   p.ID,
-			Name:        p.Name,
-			Price:       p.Price,
-			ImageURL:    p.ImageURL,
-			Description: p.Description,
-			Type:        p.Type,
-		})
+	switch c.DefaultQuery("format", "pdf") {
+	case "pdf":
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=facture-%d.pdf", commande.ID))
+		c.Data(http.StatusOK, "application/pdf", receipts.RenderInvoicePDF(&commande, receipts.CompanyFromEnv()))
+	case "escpos":
+		c.Data(http.StatusOK, "application/octet-stream", receipts.RenderKitchenTicket(receipts.KitchenTicketFromCommande(&commande)))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format de reçu inconnu"})
 	}
+}
+
+// GetCommandeVAT retourne le détail de TVA d'une commande
+// @Summary Get the VAT summary of a commande
+// @Description Net, tax and gross amounts of the commande grouped by VAT rate
+// @Tags commandes
+// @Produce json
+// @Param id path int true "Commande ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /commandes/{id}/vat [get]
+// @Security BearerAuth
+func (cc *CommandeController) GetCommandeVAT(c *gin.Context) {
+	id := c.Param("id")
 
-	cc.DB.
-		Preload("Menus").
-		Preload("Products").
-		First(&commande)
+	var commande models.Commande
+	if err := cc.DB.Preload("Menus").Preload("Products").First(&commande, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commande introuvable"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Commande mise à jour avec succès",
-		"commande": commande,
+		"commande_id":  commande.ID,
+		"service_mode": commande.ServiceMode,
+		"vat":          models.SummarizeVAT([]models.Commande{commande}),
 	})
 }
 
@@ -0,0 +1,524 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CustomerController struct {
+	DB *gorm.DB
+}
+
+func RefCustomerController(db *gorm.DB) *CustomerController {
+	return &CustomerController{DB: db}
+}
+
+// CustomerInput représente les données attendues pour inscrire ou modifier un client
+type CustomerInput struct {
+	FirstName        string `json:"first_name" example:"Awa"`
+	Phone            string `json:"phone" example:"06 12 34 56 78"`
+	Email            string `json:"email" example:"awa@example.com"`
+	LoyaltyConsent   bool   `json:"loyalty_consent" example:"true"`
+	MarketingConsent bool   `json:"marketing_consent" example:"false"`
+}
+
+// PointsAdjustmentInput représente une correction manuelle du solde de points
+type PointsAdjustmentInput struct {
+	Points      int    `json:"points" example:"-20"`
+	Description string `json:"description" example:"Geste commercial"`
+}
+
+// RedeemRewardInput indique la commande à laquelle ajouter le produit offert
+type RedeemRewardInput struct {
+	CommandeID uint `json:"commande_id" example:"42"`
+}
+
+// AttachCustomerInput indique le client à rattacher à une commande
+type AttachCustomerInput struct {
+	CustomerID uint `json:"customer_id" example:"7"`
+}
+
+// ReorderInput contient les informations facultatives d'une nouvelle commande à l'identique
+type ReorderInput struct {
+	ServiceMode models.ServiceMode `json:"service_mode" example:"takeaway" enums:"on_site,takeaway"`
+	CouponCodes []string           `json:"coupon_codes,omitempty" example:"BIENVENUE"`
+	// Heure de retrait souhaitée pour une commande programmée, facultative
+	PickupAt *time.Time `json:"pickup_at,omitempty" example:"2026-10-19T12:30:00+02:00"`
+}
+
+// resolveCustomer retrouve le client désigné à la commande ; sans identifiant, la commande reste anonyme
+func resolveCustomer(c *gin.Context, db *gorm.DB, ref *models.CustomerRef) (*uint, bool) {
+	customer, err := models.ResolveCustomer(db, ref)
+	if err == models.ErrCustomerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la recherche du client"})
+		return nil, false
+	}
+	if customer == nil {
+		return nil, true
+	}
+	return &customer.ID, true
+}
+
+// loadCustomer récupère le client de la route
+func (cc *CustomerController) loadCustomer(c *gin.Context) (*models.Customer, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+
+	customer, err := models.GetCustomerByID(cc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return customer, true
+}
+
+// apply valide l'entrée et l'applique au client
+func (input CustomerInput) apply(db *gorm.DB, customer *models.Customer) (int, string) {
+	if err := customer.SetContact(input.Phone, input.Email); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	if err := models.CheckContactAvailable(db, customer); err != nil {
+		return http.StatusConflict, err.Error()
+	}
+	customer.FirstName = input.FirstName
+	customer.SetConsents(input.LoyaltyConsent, input.MarketingConsent, time.Now())
+	return 0, ""
+}
+
+// CreateCustomer godoc
+// @Summary Inscrire un client
+// @Description Crée un client avec son code de carte de fidélité ; les points ne sont acquis qu'avec le consentement au programme
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer body CustomerInput true "Client"
+// @Success 201 {object} models.Customer
+// @Failure 409 {object} map[string]string
+// @Router /customers [post]
+// @Security BearerAuth
+func (cc *CustomerController) CreateCustomer(c *gin.Context) {
+	var request CustomerInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var customer models.Customer
+	if status, msg := request.apply(cc.DB, &customer); msg != "" {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.CreateCustomer(cc.DB, &customer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'inscription du client"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, customer)
+}
+
+// LookupCustomer godoc
+// @Summary Retrouver un client
+// @Description Recherche par numéro de téléphone, e-mail ou code lu sur le QR code de la carte
+// @Tags customers
+// @Produce json
+// @Param phone query string false "Téléphone"
+// @Param email query string false "E-mail"
+// @Param code query string false "Code de la carte"
+// @Success 200 {object} models.Customer
+// @Router /customers/lookup [get]
+// @Security BearerAuth
+func (cc *CustomerController) LookupCustomer(c *gin.Context) {
+	ref := models.CustomerRef{Phone: c.Query("phone"), Email: c.Query("email"), LoyaltyCode: c.Query("code")}
+	if ref.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Un numéro de téléphone, un e-mail ou un code de carte est obligatoire"})
+		return
+	}
+
+	customer, err := models.FindCustomer(cc.DB, ref)
+	if err == models.ErrCustomerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la recherche du client"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// GetCustomerByID godoc
+// @Summary Récupérer un client
+// @Tags customers
+// @Produce json
+// @Param id path int true "ID client"
+// @Success 200 {object} models.Customer
+// @Router /customers/{id} [get]
+// @Security BearerAuth
+func (cc *CustomerController) GetCustomerByID(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+// UpdateCustomer godoc
+// @Summary Modifier un client
+// @Description Met à jour les coordonnées et les consentements
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID client"
+// @Param customer body CustomerInput true "Client"
+// @Success 200 {object} models.Customer
+// @Router /customers/{id} [put]
+// @Security BearerAuth
+func (cc *CustomerController) UpdateCustomer(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	var request CustomerInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, msg := request.apply(cc.DB, customer); msg != "" {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.UpdateCustomer(cc.DB, customer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du client"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// DeleteCustomer godoc
+// @Summary Supprimer un client
+// @Description Efface les coordonnées du client (droit à l'effacement) ; ses commandes sont conservées
+// @Tags customers
+// @Param id path int true "ID client"
+// @Success 200 {object} map[string]string
+// @Router /customers/{id} [delete]
+// @Security BearerAuth
+func (cc *CustomerController) DeleteCustomer(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteCustomer(cc.DB, customer.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression du client"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client supprimé"})
+}
+
+// GetLoyaltyLedger godoc
+// @Summary Historique des points d'un client
+// @Tags customers
+// @Produce json
+// @Param id path int true "ID client"
+// @Success 200 {array} models.LoyaltyLedgerEntry
+// @Router /customers/{id}/ledger [get]
+// @Security BearerAuth
+func (cc *CustomerController) GetLoyaltyLedger(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	entries, err := models.GetLoyaltyLedger(cc.DB, customer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'historique des points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// AdjustPoints godoc
+// @Summary Corriger le solde de points
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID client"
+// @Param adjustment body PointsAdjustmentInput true "Correction"
+// @Success 201 {object} models.LoyaltyLedgerEntry
+// @Router /customers/{id}/points [post]
+// @Security BearerAuth
+func (cc *CustomerController) AdjustPoints(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	var request PointsAdjustmentInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Points == 0 || request.Description == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Un nombre de points non nul et un motif sont obligatoires"})
+		return
+	}
+
+	entry, err := models.AdjustPoints(cc.DB, customer, request.Points, request.Description)
+	if err == models.ErrNotEnoughPoints {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le solde de points ne peut pas devenir négatif"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la correction du solde"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// RedeemReward godoc
+// @Summary Échanger des points contre une récompense
+// @Description Débite les points et ajoute le produit offert à une commande en attente
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID client"
+// @Param rewardId path int true "ID récompense"
+// @Param redeem body RedeemRewardInput true "Commande"
+// @Success 201 {object} models.LoyaltyLedgerEntry
+// @Failure 409 {object} map[string]string
+// @Router /customers/{id}/rewards/{rewardId}/redeem [post]
+// @Security BearerAuth
+func (cc *CustomerController) RedeemReward(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	rewardID, err := strconv.ParseUint(c.Param("rewardId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	reward, err := models.GetRewardByID(cc.DB, uint(rewardID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request RedeemRewardInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var commande models.Commande
+	if err := cc.DB.First(&commande, request.CommandeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commande introuvable"})
+		return
+	}
+	if commande.CustomerID != nil && *commande.CustomerID != customer.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "La commande est rattachée à un autre client"})
+		return
+	}
+
+	entry, err := models.RedeemReward(cc.DB, customer, reward, &commande)
+	if err == models.ErrNotEnoughPoints {
+		c.JSON(http.StatusConflict, gin.H{"error": "Solde de points insuffisant"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"entry":          entry,
+		"points_balance": customer.PointsBalance,
+	})
+}
+
+// AttachCustomer godoc
+// @Summary Rattacher un client à une commande
+// @Description Les points sont crédités lors de la remise de la commande, ou immédiatement si elle a déjà été remise
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID commande"
+// @Param customer body AttachCustomerInput true "Client"
+// @Success 200 {object} models.Commande
+// @Router /commandes/{id}/customer [put]
+// @Security BearerAuth
+func (cc *CustomerController) AttachCustomer(c *gin.Context) {
+	var commande models.Commande
+	if err := cc.DB.First(&commande, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commande introuvable"})
+		return
+	}
+
+	var request AttachCustomerInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	customer, err := models.GetCustomerByID(cc.DB, request.CustomerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if commande.CustomerID != nil && *commande.CustomerID != customer.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "La commande est rattachée à un autre client"})
+		return
+	}
+
+	err = cc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&commande).UpdateColumn("customer_id", customer.ID).Error; err != nil {
+			return err
+		}
+		// Carte présentée après la remise : les points sont crédités tout de suite
+		if commande.Status == models.StatusDelivered {
+			return models.EarnLoyaltyPoints(tx, commande.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du rattachement du client"})
+		return
+	}
+	commande.CustomerID = &customer.ID
+
+	c.JSON(http.StatusOK, commande)
+}
+
+// GetCustomerCommandes godoc
+// @Summary Historique des commandes d'un client
+// @Tags customers
+// @Produce json
+// @Param id path int true "ID client"
+// @Param limit query int false "Nombre maximum de commandes"
+// @Success 200 {array} models.Commande
+// @Router /customers/{id}/commandes [get]
+// @Security BearerAuth
+func (cc *CustomerController) GetCustomerCommandes(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limite invalide"})
+			return
+		}
+		limit = parsed
+	}
+
+	commandes, err := models.GetCustomerCommandes(cc.DB, customer.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des commandes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, commandes)
+}
+
+// Reorder godoc
+// @Summary Recommander une commande passée
+// @Description Recrée la commande aux prix et disponibilités du moment ; les lignes qui ne peuvent plus être servies sont écartées et listées dans "unavailable"
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID client"
+// @Param commandeId path int true "ID de la commande à recommander"
+// @Param reorder body ReorderInput false "Mode de consommation et codes promo"
+// @Success 201 {object} models.ReorderResult
+// @Failure 422 {object} models.ReorderResult
+// @Router /customers/{id}/commandes/{commandeId}/reorder [post]
+// @Security BearerAuth
+func (cc *CustomerController) Reorder(c *gin.Context) {
+	customer, ok := cc.loadCustomer(c)
+	if !ok {
+		return
+	}
+
+	var source models.Commande
+	err := cc.DB.Preload("Menus").Preload("Products").
+		Where("customer_id = ?", customer.ID).
+		First(&source, c.Param("commandeId")).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commande introuvable pour ce client"})
+		return
+	}
+
+	// Le corps est facultatif : sans précision, le mode de consommation d'origine est repris
+	var request ReorderInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if request.ServiceMode != "" && !request.ServiceMode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode de consommation invalide"})
+		return
+	}
+	if !validatePickupAt(c, request.PickupAt) {
+		return
+	}
+
+	commande := models.Commande{
+		Status:        models.StatusPending,
+		Channel:       models.ChannelCounter,
+		PaymentStatus: models.PaymentPaid,
+		ServiceMode:   request.ServiceMode,
+		CustomerID:    &customer.ID,
+		PickupAt:      request.PickupAt,
+	}
+
+	result, err := models.Reorder(cc.DB, &source, &commande, models.PricingContext{
+		Channel:     models.ChannelCounter,
+		CouponCodes: request.CouponCodes,
+	})
+	if err == models.ErrPromotionExhausted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le code promo a atteint sa limite d'utilisation"})
+		return
+	}
+	if err == models.ErrPickupSlotFull {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le créneau de retrait demandé est complet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la commande"})
+		return
+	}
+	if result.Commande == nil {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	enqueueKitchenTickets(cc.DB, &commande)
+
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"LearningCampusKabre/delivery"
+	"LearningCampusKabre/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Taille maximale acceptée pour le corps d'un webhook
+const maxWebhookBody = 1 << 20
+
+type DeliveryController struct {
+	DB *gorm.DB
+}
+
+func RefDeliveryController(db *gorm.DB) *DeliveryController {
+	return &DeliveryController{DB: db}
+}
+
+// DeliveryPlatformInput représente les données attendues pour enregistrer une plateforme de livraison
+type DeliveryPlatformInput struct {
+	Name    string `json:"name" example:"Uber Eats"`
+	Adapter string `json:"adapter" example:"ubereats" enums:"ubereats,deliveroo"`
+	// Secret et clé d'API : laissés vides lors d'une modification, les valeurs actuelles sont conservées
+	WebhookSecret string `json:"webhook_secret" example:"s3cr3t"`
+	StatusURL     string `json:"status_url" example:"https://api.example.com/orders/{id}/status"`
+	APIKey        string `json:"api_key" example:"key_123"`
+	IsActive      *bool  `json:"is_active" example:"true"`
+}
+
+// DeliveryMappingInput associe un article de la plateforme à un produit ou un menu
+type DeliveryMappingInput struct {
+	ExternalID string `json:"external_id" example:"burger-classic"`
+	ProductID  *uint  `json:"product_id,omitempty" example:"1"`
+	MenuID     *uint  `json:"menu_id,omitempty"`
+}
+
+// apply valide l'entrée et l'applique à la plateforme
+func (input DeliveryPlatformInput) apply(platform *models.DeliveryPlatform) string {
+	if _, ok := delivery.GetAdapter(input.Adapter); !ok {
+		return "Format de plateforme inconnu, attendu : " + strings.Join(delivery.AdapterNames(), ", ")
+	}
+
+	platform.Name = input.Name
+	platform.Adapter = input.Adapter
+	platform.StatusURL = input.StatusURL
+	if input.WebhookSecret != "" {
+		platform.WebhookSecret = input.WebhookSecret
+	}
+	if input.APIKey != "" {
+		platform.APIKey = input.APIKey
+	}
+	if input.IsActive != nil {
+		platform.IsActive = *input.IsActive
+	}
+	if err := platform.Validate(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// loadPlatform récupère la plateforme de la route
+func (dc *DeliveryController) loadPlatform(c *gin.Context) (*models.DeliveryPlatform, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+
+	platform, err := models.GetDeliveryPlatformByID(dc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return platform, true
+}
+
+// ReceiveWebhook godoc
+// @Summary Recevoir une commande d'une plateforme de livraison
+// @Description Vérifie la signature HMAC, traduit les articles de la plateforme dans le catalogue et crée une commande "delivery". Un webhook rejoué retourne la commande existante.
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Param id path int true "ID plateforme"
+// @Success 201 {object} models.Commande
+// @Success 200 {object} models.Commande
+// @Failure 401 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /delivery/webhooks/{id} [post]
+func (dc *DeliveryController) ReceiveWebhook(c *gin.Context) {
+	platform, ok := dc.loadPlatform(c)
+	if !ok {
+		return
+	}
+	if !platform.IsActive {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plateforme désactivée"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Corps de la requête illisible"})
+		return
+	}
+
+	result, err := delivery.ReceiveOrder(dc.DB, platform, body, c.Request.Header)
+	if err == delivery.ErrInvalidSignature {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Signature invalide"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case len(result.Unmapped) > 0:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Articles sans correspondance dans le catalogue",
+			"unmapped": result.Unmapped,
+		})
+	case result.Order != nil:
+		c.JSON(http.StatusUnprocessableEntity, result.Order)
+	case result.Duplicate:
+		c.JSON(http.StatusOK, result.Commande)
+	default:
+		// La plateforme est prévenue que la commande est acceptée par la file des statuts,
+		// alimentée à la création de la commande
+		enqueueKitchenTickets(dc.DB, result.Commande)
+		c.JSON(http.StatusCreated, result.Commande)
+	}
+}
+
+// CreateDeliveryPlatform godoc
+// @Summary Enregistrer une plateforme de livraison
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Param platform body DeliveryPlatformInput true "Plateforme"
+// @Success 201 {object} models.DeliveryPlatform
+// @Router /delivery/platforms [post]
+// @Security BearerAuth
+func (dc *DeliveryController) CreateDeliveryPlatform(c *gin.Context) {
+	var request DeliveryPlatformInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	platform := models.DeliveryPlatform{IsActive: true}
+	if msg := request.apply(&platform); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.CreateDeliveryPlatform(dc.DB, &platform); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement de la plateforme"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, platform)
+}
+
+// GetAllDeliveryPlatforms godoc
+// @Summary Liste des plateformes de livraison
+// @Tags delivery
+// @Produce json
+// @Success 200 {array} models.DeliveryPlatform
+// @Router /delivery/platforms [get]
+// @Security BearerAuth
+func (dc *DeliveryController) GetAllDeliveryPlatforms(c *gin.Context) {
+	platforms, err := models.GetAllDeliveryPlatforms(dc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des plateformes"})
+		return
+	}
+	c.JSON(http.StatusOK, platforms)
+}
+
+// UpdateDeliveryPlatform godoc
+// @Summary Modifier une plateforme de livraison
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Param id path int true "ID plateforme"
+// @Param platform body DeliveryPlatformInput true "Plateforme"
+// @Success 200 {object} models.DeliveryPlatform
+// @Router /delivery/platforms/{id} [put]
+// @Security BearerAuth
+func (dc *DeliveryController) UpdateDeliveryPlatform(c *gin.Context) {
+	platform, ok := dc.loadPlatform(c)
+	if !ok {
+		return
+	}
+
+	var request DeliveryPlatformInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := request.apply(platform); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.UpdateDeliveryPlatform(dc.DB, platform); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la plateforme"})
+		return
+	}
+
+	c.JSON(http.StatusOK, platform)
+}
+
+// DeleteDeliveryPlatform godoc
+// @Summary Supprimer une plateforme de livraison
+// @Tags delivery
+// @Param id path int true "ID plateforme"
+// @Success 200 {object} map[string]string
+// @Router /delivery/platforms/{id} [delete]
+// @Security BearerAuth
+func (dc *DeliveryController) DeleteDeliveryPlatform(c *gin.Context) {
+	platform, ok := dc.loadPlatform(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteDeliveryPlatform(dc.DB, platform.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de la plateforme"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Plateforme supprimée"})
+}
+
+// GetDeliveryMappings godoc
+// @Summary Correspondances d'articles d'une plateforme
+// @Tags delivery
+// @Produce json
+// @Param id path int true "ID plateforme"
+// @Success 200 {array} models.DeliveryProductMapping
+// @Router /delivery/platforms/{id}/mappings [get]
+// @Security BearerAuth
+func (dc *DeliveryController) GetDeliveryMappings(c *gin.Context) {
+	platform, ok := dc.loadPlatform(c)
+	if !ok {
+		return
+	}
+
+	mappings, err := models.GetDeliveryMappings(dc.DB, platform.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des correspondances"})
+		return
+	}
+	c.JSON(http.StatusOK, mappings)
+}
+
+// SaveDeliveryMapping godoc
+// @Summary Associer un article de la plateforme au catalogue
+// @Description Crée la correspondance ou remplace celle qui existe pour cet article
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Param id path int true "ID plateforme"
+// @Param mapping body DeliveryMappingInput true "Correspondance"
+// @Success 200 {object} models.DeliveryProductMapping
+// @Router /delivery/platforms/{id}/mappings [put]
+// @Security BearerAuth
+func (dc *DeliveryController) SaveDeliveryMapping(c *gin.Context) {
+	platform, ok := dc.loadPlatform(c)
+	if !ok {
+		return
+	}
+
+	var request DeliveryMappingInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mapping := models.DeliveryProductMapping{
+		PlatformID: platform.ID,
+		ExternalID: request.ExternalID,
+		ProductID:  request.ProductID,
+		MenuID:     request.MenuID,
+	}
+	if err := mapping.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if mapping.ProductID != nil {
+		if err := dc.DB.First(&models.Product{}, *mapping.ProductID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Produit introuvable"})
+			return
+		}
+	} else if err := dc.DB.First(&models.Menu{}, *mapping.MenuID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Menu introuvable"})
+		return
+	}
+
+	if err := models.SaveDeliveryMapping(dc.DB, &mapping); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement de la correspondance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// DeleteDeliveryMapping godoc
+// @Summary Supprimer une correspondance d'article
+// @Tags delivery
+// @Param id path int true "ID plateforme"
+// @Param mappingId path int true "ID correspondance"
+// @Success 200 {object} map[string]string
+// @Router /delivery/platforms/{id}/mappings/{mappingId} [delete]
+// @Security BearerAuth
+func (dc *DeliveryController) DeleteDeliveryMapping(c *gin.Context) {
+	platform, ok := dc.loadPlatform(c)
+	if !ok {
+		return
+	}
+
+	mappingID, err := strconv.ParseUint(c.Param("mappingId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if err := models.DeleteDeliveryMapping(dc.DB, platform.ID, uint(mappingID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de la correspondance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Correspondance supprimée"})
+}
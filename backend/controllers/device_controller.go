@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DeviceController struct {
+	DB *gorm.DB
+}
+
+func RefDeviceController(db *gorm.DB) *DeviceController {
+	return &DeviceController{DB: db}
+}
+
+// DeviceInput représente les données attendues pour enregistrer un appareil
+type DeviceInput struct {
+	Name string            `json:"name" example:"Borne entrée"`
+	Kind models.DeviceKind `json:"kind" example:"kiosk"`
+}
+
+// CreateDevice godoc
+// @Summary Enregistrer un appareil
+// @Description Crée un appareil et retourne sa clé. La clé n'est affichée qu'une seule fois.
+// @Tags devices
+// @Accept json
+// @Produce json
+// @Param device body DeviceInput true "Appareil"
+// @Success 201 {object} map[string]interface{}
+// @Router /devices [post]
+// @Security BearerAuth
+func (dc *DeviceController) CreateDevice(c *gin.Context) {
+	var request DeviceInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Name == "" || !request.Kind.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nom ou type d'appareil invalide"})
+		return
+	}
+
+	device := models.Device{Name: request.Name, Kind: request.Kind, IsActive: true}
+	key, err := models.CreateDevice(dc.DB, &device)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'appareil"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"device": device,
+		"key":    key,
+	})
+}
+
+// GetAllDevices godoc
+// @Summary Liste des appareils
+// @Tags devices
+// @Produce json
+// @Success 200 {array} models.Device
+// @Router /devices [get]
+// @Security BearerAuth
+func (dc *DeviceController) GetAllDevices(c *gin.Context) {
+	devices, err := models.GetAllDevices(dc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des appareils"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// DeleteDevice godoc
+// @Summary Révoquer un appareil
+// @Tags devices
+// @Param id path int true "ID appareil"
+// @Success 200 {object} map[string]string
+// @Router /devices/{id} [delete]
+// @Security BearerAuth
+func (dc *DeviceController) DeleteDevice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if err := models.DeleteDevice(dc.DB, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la révocation de l'appareil"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Appareil révoqué"})
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type KioskController struct {
+	DB *gorm.DB
+}
+
+func RefKioskController(db *gorm.DB) *KioskController {
+	return &KioskController{DB: db}
+}
+
+// KioskCartInput représente le panier construit sur la borne
+type KioskCartInput struct {
+	Lines       []models.OrderLine `json:"lines"`
+	ServiceMode models.ServiceMode `json:"service_mode" example:"takeaway" enums:"on_site,takeaway"`
+	CouponCodes []string           `json:"coupon_codes,omitempty" example:"BIENVENUE"`
+	// Client identifié sur la borne (téléphone, e-mail ou carte de fidélité), facultatif
+	Customer *models.CustomerRef `json:"customer,omitempty"`
+	// Heure de retrait souhaitée pour une commande programmée, facultative
+	PickupAt *time.Time `json:"pickup_at,omitempty" example:"2026-10-19T12:30:00+02:00"`
+}
+
+// pricing retourne le contexte de calcul des prix d'une commande passée sur la borne
+func (input KioskCartInput) pricing() models.PricingContext {
+	return models.PricingContext{Channel: models.ChannelKiosk, CouponCodes: input.CouponCodes}
+}
+
+// GetCatalog godoc
+// @Summary Catalogue de la borne
+// @Description Liste les produits disponibles et les menus dont tous les produits sont disponibles, avec leurs items, aux prix des grilles en vigueur sur la borne
+// @Tags kiosk
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /kiosk/catalog [get]
+func (kc *KioskController) GetCatalog(c *gin.Context) {
+	var products []models.Product
+	if err := kc.DB.Where("is_available = ?", true).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des produits"})
+		return
+	}
+
+	// Un menu dont un produit est en rupture serait refusé à la commande
+	menus, err := models.GetOrderableMenus(kc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des menus"})
+		return
+	}
+
+	// Les prix affichés doivent être ceux qui seront facturés à cet instant
+	resolver, err := models.NewPriceResolver(kc.DB, models.ChannelKiosk, time.Now(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des grilles de prix"})
+		return
+	}
+	for i := range products {
+		if resolved, ok := resolver.ProductPrice(products[i].ID); ok {
+			products[i].Price = resolved.Price
+		}
+	}
+	for i := range menus {
+		if resolved, ok := resolver.MenuPrice(menus[i].ID); ok {
+			menus[i].Price = resolved.Price
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+		"menus":    menus,
+	})
+}
+
+// ValidateCart godoc
+// @Summary Valider un panier
+// @Description Vérifie chaque ligne du panier, applique les promotions et calcule le total côté serveur
+// @Tags kiosk
+// @Accept json
+// @Produce json
+// @Param cart body KioskCartInput true "Panier"
+// @Success 200 {object} models.PricedOrder
+// @Router /kiosk/cart/validate [post]
+func (kc *KioskController) ValidateCart(c *gin.Context) {
+	var request KioskCartInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := models.PriceOrder(kc.DB, request.Lines, request.pricing())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du panier"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// CreateKioskCommande godoc
+// @Summary Passer commande depuis la borne
+// @Description Crée une commande "pending" en attente de paiement et retourne son numéro de ticket
+// @Tags kiosk
+// @Accept json
+// @Produce json
+// @Param cart body KioskCartInput true "Panier"
+// @Success 201 {object} map[string]interface{}
+// @Failure 422 {object} models.PricedOrder
+// @Router /kiosk/commandes [post]
+func (kc *KioskController) CreateKioskCommande(c *gin.Context) {
+	var request KioskCartInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(request.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La commande doit contenir au moins un menu ou un produit"})
+		return
+	}
+
+	if request.ServiceMode == "" {
+		request.ServiceMode = models.ServiceOnSite
+	}
+	if !request.ServiceMode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode de consommation invalide"})
+		return
+	}
+
+	if !validatePickupAt(c, request.PickupAt) {
+		return
+	}
+	customerID, ok := resolveCustomer(c, kc.DB, request.Customer)
+	if !ok {
+		return
+	}
+
+	order, err := models.PriceOrder(kc.DB, request.Lines, request.pricing())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du panier"})
+		return
+	}
+
+	if !order.Valid {
+		c.JSON(http.StatusUnprocessableEntity, order)
+		return
+	}
+
+	deviceID := c.GetUint("device_id")
+	commande := models.Commande{
+		Status:        models.StatusPending,
+		Channel:       models.ChannelKiosk,
+		PaymentStatus: models.PaymentRequired,
+		ServiceMode:   request.ServiceMode,
+		DeviceID:      &deviceID,
+		CustomerID:    customerID,
+		PickupAt:      request.PickupAt,
+	}
+
+	err = models.CreateCommandeFromLines(kc.DB, order, &commande)
+	if err == models.ErrPromotionExhausted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le code promo a atteint sa limite d'utilisation"})
+		return
+	}
+	if err == models.ErrPickupSlotFull {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le créneau de retrait demandé est complet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la commande"})
+		return
+	}
+
+	enqueueKitchenTickets(kc.DB, &commande)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Commande enregistrée, merci de régler au comptoir",
+		"ticket_number":  commande.TicketNumber,
+		"payment_status": commande.PaymentStatus,
+		"commande":       commande,
+	})
+}
+
+// GetPickupSlots godoc
+// @Summary Créneaux de retrait disponibles
+// @Description Liste les créneaux de 15 minutes à venir d'une journée avec leurs places restantes
+// @Tags kiosk
+// @Produce json
+// @Param date query string false "Jour au format AAAA-MM-JJ (aujourd'hui par défaut)"
+// @Success 200 {array} models.PickupSlot
+// @Router /kiosk/pickup-slots [get]
+func (kc *KioskController) GetPickupSlots(c *gin.Context) {
+	now := time.Now()
+	day := now
+	if param := c.Query("date"); param != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", param, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Date invalide, format attendu : AAAA-MM-JJ"})
+			return
+		}
+		day = parsed
+	}
+
+	slots, err := models.GetPickupSlots(kc.DB, day, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des créneaux"})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
@@ -19,11 +19,12 @@ func RefMenuController(db *gorm.DB) *MenuController {
 
 // MenuInput représente les données attendues pour créer un menu
 type MenuInput struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Items       []int           `json:"items"`
-	Price       decimal.Decimal `json:"price"`
-	ImageURL    string          `json:"image_url"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Items       []int              `json:"items"`
+	Price       decimal.Decimal    `json:"price"`
+	ImageURL    string             `json:"image_url"`
+	TaxCategory models.TaxCategory `json:"tax_category" example:"food" enums:"food,soft_drink,alcohol"`
 }
 
 // ContainsID vérifie si un slice de produits contient un produit avec l'ID donné
@@ -61,6 +62,14 @@ func (mc *MenuController) CreateMenu(c *gin.Context) {
 		return
 	}
 
+	if request.TaxCategory == "" {
+		request.TaxCategory = models.TaxFood
+	}
+	if !request.TaxCategory.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Catégorie fiscale invalide"})
+		return
+	}
+
 	// Vérifier que tous les produits existent et sont disponibles
 	var products []models.Product
 
@@ -80,6 +89,7 @@ func (mc *MenuController) CreateMenu(c *gin.Context) {
 		Description: request.Description,
 		Price:       request.Price,
 		ImageURL:    request.ImageURL,
+		TaxCategory: request.TaxCategory,
 	}
 
 	if err := mc.DB.Create(&menu).Error; err != nil {
@@ -92,6 +102,7 @@ func (mc *MenuController) CreateMenu(c *gin.Context) {
 		println(p.Price.String())
 		mc.DB.Create(&models.MenuItem{
 			MenuID:      menu.ID,
+			ProductID:   p.ID,
 			Name:        p.Name,
 			Price:       p.Price,
 			ImageURL:    p.ImageURL,
@@ -186,6 +197,14 @@ func (mc *MenuController) UpdateMenu(c *gin.Context) {
 		return
 	}
 
+	if request.TaxCategory == "" {
+		request.TaxCategory = models.TaxFood
+	}
+	if !request.TaxCategory.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Catégorie fiscale invalide"})
+		return
+	}
+
 	var products []models.Product
 	if err := mc.DB.Where("id IN ? AND is_available = ?", request.Items, true).Find(&products).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des produits"})
@@ -202,6 +221,7 @@ func (mc *MenuController) UpdateMenu(c *gin.Context) {
 	menu.Description = request.Description
 	menu.Price = request.Price
 	menu.ImageURL = request.ImageURL
+	menu.TaxCategory = request.TaxCategory
 
 	if err := mc.DB.Save(&menu).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du menu"})
@@ -213,6 +233,7 @@ func (mc *MenuController) UpdateMenu(c *gin.Context) {
 	for _, p := range products {
 		mc.DB.Create(&models.MenuItem{
 			MenuID:      menu.ID,
+			ProductID:   p.ID,
 			Name:        p.Name,
 			Price:       p.Price,
 			ImageURL:    p.ImageURL,
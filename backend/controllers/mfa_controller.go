@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MFAController struct {
+	DB *gorm.DB
+}
+
+func RefMFAController(db *gorm.DB) *MFAController {
+	return &MFAController{DB: db}
+}
+
+// MFASetupResponse contient le secret à saisir ou à scanner dans l'application d'authentification
+type MFASetupResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// URI otpauth:// à afficher sous forme de QR code
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Wacdo:john@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Wacdo"`
+}
+
+// MFACodeInput contient un code à 6 chiffres de l'application d'authentification
+type MFACodeInput struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// MFADisableInput demande le mot de passe et un code (ou un code de secours)
+type MFADisableInput struct {
+	Password string `json:"password" binding:"required" example:"motdepasse123"`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+// MFAVerifyInput complète une connexion avec un code TOTP ou un code de secours
+type MFAVerifyInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required" example:"123456"`
+}
+
+// RecoveryCodesResponse liste les codes de secours, affichés une seule fois
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFARequiredInput impose ou lève l'obligation de double authentification
+type MFARequiredInput struct {
+	Required bool `json:"required" example:"true"`
+}
+
+func (mc *MFAController) currentUser(c *gin.Context) (*models.User, bool) {
+	user, err := models.GetUserByID(mc.DB, middlewares.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return user, true
+}
+
+// Setup godoc
+// @Summary Commencer l'activation de la double authentification
+// @Description Génère un nouveau secret TOTP, à confirmer par un premier code sur /auth/2fa/enable
+// @Tags auth
+// @Produce json
+// @Success 200 {object} MFASetupResponse
+// @Failure 409 {object} map[string]string
+// @Router /auth/2fa/setup [post]
+// @Security BearerAuth
+func (mc *MFAController) Setup(c *gin.Context) {
+	user, ok := mc.currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := models.BeginTOTPEnrollment(mc.DB, user)
+	if err == models.ErrMFAAlreadyEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MFASetupResponse{Secret: secret, ProvisioningURI: uri})
+}
+
+// Enable godoc
+// @Summary Activer la double authentification
+// @Description Vérifie un premier code et retourne les codes de secours. Les sessions ouvertes avant restent sans second facteur : reconnectez-vous pour accéder aux routes qui l'exigent.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body MFACodeInput true "Code de l'application"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Router /auth/2fa/enable [post]
+// @Security BearerAuth
+func (mc *MFAController) Enable(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+	user, ok := mc.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := models.ConfirmTOTPEnrollment(mc.DB, user, input.Code, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary Désactiver la double authentification
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body MFADisableInput true "Mot de passe et code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/2fa/disable [post]
+// @Security BearerAuth
+func (mc *MFAController) Disable(c *gin.Context) {
+	var input MFADisableInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+	user, ok := mc.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DisableTOTP(mc.DB, user, input.Password, input.Code, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Double authentification désactivée"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Générer de nouveaux codes de secours
+// @Description Les anciens codes ne sont plus acceptés
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body MFACodeInput true "Code de l'application"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Router /auth/2fa/recovery-codes [post]
+// @Security BearerAuth
+func (mc *MFAController) RegenerateRecoveryCodes(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+	user, ok := mc.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := models.RegenerateRecoveryCodes(mc.DB, user, input.Code, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Verify godoc
+// @Summary Terminer une connexion avec double authentification
+// @Description Échange le challenge retourné par /auth/login et un code (TOTP ou de secours) contre les jetons
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verify body MFAVerifyInput true "Challenge et code"
+// @Success 200 {object} TokenResponse
+// @Failure 401 {object} map[string]string
+// @Router /auth/2fa/verify [post]
+func (mc *MFAController) Verify(c *gin.Context) {
+	var input MFAVerifyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	user, err := models.CompleteMFAChallenge(mc.DB, input.ChallengeToken, input.Code, time.Now())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	startSession(c, mc.DB, user, models.AuthSession{MFA: true})
+}
+
+// SetRequired godoc
+// @Summary Imposer la double authentification à un utilisateur
+// @Description Sans second facteur, ses jetons sont refusés sur les routes protégées par permission. Toujours imposée aux administrateurs.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID utilisateur"
+// @Param required body MFARequiredInput true "Obligation"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/require-2fa [post]
+// @Security BearerAuth
+func (mc *MFAController) SetRequired(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var input MFARequiredInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	user, err := models.SetMFARequired(mc.DB, userID, input.Required)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Response())
+}
+
+// Reset godoc
+// @Summary Réinitialiser la double authentification d'un utilisateur
+// @Description Pour un utilisateur qui a perdu son téléphone et ses codes de secours : le secret et les codes sont supprimés, ses sessions fermées
+// @Tags users
+// @Produce json
+// @Param id path int true "ID utilisateur"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/2fa [delete]
+// @Security BearerAuth
+func (mc *MFAController) Reset(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := models.ResetTOTP(mc.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Response())
+}
+
+func parseUserID(c *gin.Context) (uint, bool) {
+	var userID uint
+	if _, err := fmt.Sscan(c.Param("id"), &userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return 0, false
+	}
+	return userID, true
+}
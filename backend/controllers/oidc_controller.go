@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/oidc"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OIDCController struct {
+	DB *gorm.DB
+	// Fournisseur d'identité ; nil si le SSO n'est pas configuré
+	Provider *oidc.Provider
+}
+
+// oidcBindingCookie lie une connexion SSO au navigateur qui l'a démarrée (voir ConsumeOIDCLogin)
+const oidcBindingCookie = "oidc_binding"
+
+// RefOIDCController configure le SSO à partir des variables OIDC_* ; une configuration
+// incomplète est une erreur plutôt que de désactiver le SSO sans prévenir
+func RefOIDCController(db *gorm.DB) (*OIDCController, error) {
+	provider, err := oidc.NewFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("configuration OIDC : %w", err)
+	}
+	return &OIDCController{DB: db, Provider: provider}, nil
+}
+
+// OIDCCallbackInput contient les paramètres reçus par le front-end au retour du fournisseur
+type OIDCCallbackInput struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// enabled répond 404 si le SSO n'est pas configuré
+func (oc *OIDCController) enabled(c *gin.Context) bool {
+	if oc.Provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Connexion SSO non configurée"})
+		return false
+	}
+	return true
+}
+
+// Login godoc
+// @Summary Démarrer une connexion SSO
+// @Description Redirige vers le fournisseur d'identité (authorization code + PKCE) et dépose le cookie oidc_binding qui lie la connexion au navigateur. Le fournisseur renvoie ensuite le navigateur sur OIDC_REDIRECT_URL avec code et state, à transmettre à /auth/oidc/callback depuis le même navigateur.
+// @Tags auth
+// @Success 302
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /auth/oidc/login [get]
+func (oc *OIDCController) Login(c *gin.Context) {
+	if !oc.enabled(c) {
+		return
+	}
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion SSO"})
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion SSO"})
+		return
+	}
+	state, binding, err := models.CreateOIDCLogin(oc.DB, nonce, verifier, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion SSO"})
+		return
+	}
+
+	authURL, err := oc.Provider.AuthCodeURL(c.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Println("❌ OIDC :", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Fournisseur d'identité indisponible"})
+		return
+	}
+
+	// Le cookie n'est envoyé qu'à /callback (même chemin que /login), et pas depuis un autre site
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, binding, int(models.OIDCLoginTTL.Seconds()), path.Dir(c.Request.URL.Path), "", true, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback godoc
+// @Summary Terminer une connexion SSO
+// @Description Vérifie le cookie oidc_binding déposé par /auth/oidc/login, échange le code d'autorisation, vérifie l'ID token et crée ou rattache le compte. Les comptes créés par le SSO reçoivent le rôle de leurs groupes (OIDC_GROUP_ROLES) ; un compte local rattaché garde son rôle. Si la double authentification locale est activée, retourne un challenge, sauf si le fournisseur atteste d'une authentification multifacteur (amr "mfa") et que OIDC_TRUST_AMR est activé.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param callback body OIDCCallbackInput true "Code et state reçus du fournisseur"
+// @Success 200 {object} TokenResponse
+// @Success 202 {object} MFAChallengeResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/oidc/callback [post]
+func (oc *OIDCController) Callback(c *gin.Context) {
+	if !oc.enabled(c) {
+		return
+	}
+	var input OIDCCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	binding, _ := c.Cookie(oidcBindingCookie)
+	login, err := models.ConsumeOIDCLogin(oc.DB, input.State, binding, time.Now())
+	if err == models.ErrOIDCStateInvalid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la connexion SSO"})
+		return
+	}
+
+	identity, err := oc.Provider.Exchange(c.Request.Context(), input.Code, login.CodeVerifier, login.Nonce)
+	if errors.Is(err, oidc.ErrExchangeFailed) || errors.Is(err, oidc.ErrInvalidIDToken) {
+		log.Println("❌ OIDC :", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Connexion SSO refusée"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ OIDC :", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Fournisseur d'identité indisponible"})
+		return
+	}
+
+	role, _ := oc.Provider.Config.RoleFor(identity.Groups)
+	user, err := models.ProvisionOIDCUser(oc.DB, identity, models.UserRole(role), oc.Provider.Config.LinkPrivileged, c.ClientIP(), time.Now())
+	switch err {
+	case nil:
+	case models.ErrOIDCNoRole, models.ErrOIDCPrivilegedLink:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case models.ErrOIDCEmailConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Compte désactivé"})
+		return
+	}
+
+	// Second facteur déjà vérifié par le fournisseur, si l'on fait confiance à son claim amr :
+	// la session vaut une connexion avec 2FA
+	if oc.Provider.Config.TrustAMR && identity.MFA() {
+		startSession(c, oc.DB, user, models.AuthSession{MFA: true})
+		return
+	}
+	completeLogin(c, oc.DB, user)
+}
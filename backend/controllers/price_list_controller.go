@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type PriceListController struct {
+	DB *gorm.DB
+}
+
+func RefPriceListController(db *gorm.DB) *PriceListController {
+	return &PriceListController{DB: db}
+}
+
+// PriceListItemInput est le prix d'un produit ou d'un menu dans une grille
+type PriceListItemInput struct {
+	ProductID *uint           `json:"product_id,omitempty" example:"1"`
+	MenuID    *uint           `json:"menu_id,omitempty"`
+	Price     decimal.Decimal `json:"price" example:"6.50"`
+}
+
+// PriceListInput représente les données attendues pour créer ou modifier une grille de prix
+type PriceListInput struct {
+	Name      string               `json:"name" example:"Late night"`
+	Priority  int                  `json:"priority" example:"10"`
+	Channels  []models.ChannelType `json:"channels" example:"kiosk"`
+	Days      []int                `json:"days" example:"5,6"`
+	StartTime string               `json:"start_time" example:"22:00"`
+	EndTime   string               `json:"end_time" example:"02:00"`
+	Manual    bool                 `json:"manual" example:"false"`
+	IsActive  *bool                `json:"is_active" example:"true"`
+	Items     []PriceListItemInput `json:"items"`
+}
+
+// apply valide l'entrée et l'applique à la grille
+func (input PriceListInput) apply(db *gorm.DB, priceList *models.PriceList) error {
+	priceList.Name = input.Name
+	priceList.Priority = input.Priority
+	priceList.Channels = input.Channels
+	priceList.Days = input.Days
+	priceList.StartTime = input.StartTime
+	priceList.EndTime = input.EndTime
+	priceList.Manual = input.Manual
+	if input.IsActive != nil {
+		priceList.IsActive = *input.IsActive
+	}
+
+	priceList.Items = nil
+	for _, item := range input.Items {
+		priceList.Items = append(priceList.Items, models.PriceListItem{ProductID: item.ProductID, MenuID: item.MenuID, Price: item.Price})
+	}
+	if err := priceList.Validate(); err != nil {
+		return err
+	}
+
+	// Les articles référencés doivent exister
+	for _, item := range priceList.Items {
+		if item.ProductID != nil {
+			if _, err := models.GetProductByID(db, *item.ProductID); err != nil {
+				return err
+			}
+		} else if _, err := models.GetMenuByID(db, *item.MenuID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreatePriceList godoc
+// @Summary Créer une grille de prix
+// @Description Les prix de la grille remplacent les prix de base sur son créneau et ses canaux, ou uniquement sur demande si elle est manuelle
+// @Tags price-lists
+// @Accept json
+// @Produce json
+// @Param priceList body PriceListInput true "Grille de prix"
+// @Success 201 {object} models.PriceList
+// @Router /price-lists [post]
+// @Security BearerAuth
+func (pc *PriceListController) CreatePriceList(c *gin.Context) {
+	var request PriceListInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	priceList := models.PriceList{IsActive: true}
+	if err := request.apply(pc.DB, &priceList); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.CreatePriceList(pc.DB, &priceList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la grille de prix"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, priceList)
+}
+
+// GetAllPriceLists godoc
+// @Summary Liste des grilles de prix
+// @Tags price-lists
+// @Produce json
+// @Success 200 {array} models.PriceList
+// @Router /price-lists [get]
+// @Security BearerAuth
+func (pc *PriceListController) GetAllPriceLists(c *gin.Context) {
+	priceLists, err := models.GetAllPriceLists(pc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des grilles de prix"})
+		return
+	}
+	c.JSON(http.StatusOK, priceLists)
+}
+
+// GetPriceListByID godoc
+// @Summary Récupérer une grille de prix
+// @Tags price-lists
+// @Produce json
+// @Param id path int true "ID grille"
+// @Success 200 {object} models.PriceList
+// @Router /price-lists/{id} [get]
+// @Security BearerAuth
+func (pc *PriceListController) GetPriceListByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	priceList, err := models.GetPriceListByID(pc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, priceList)
+}
+
+// UpdatePriceList godoc
+// @Summary Modifier une grille de prix
+// @Description Les prix de la grille sont entièrement remplacés par ceux envoyés
+// @Tags price-lists
+// @Accept json
+// @Produce json
+// @Param id path int true "ID grille"
+// @Param priceList body PriceListInput true "Grille de prix"
+// @Success 200 {object} models.PriceList
+// @Router /price-lists/{id} [put]
+// @Security BearerAuth
+func (pc *PriceListController) UpdatePriceList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	priceList, err := models.GetPriceListByID(pc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request PriceListInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := request.apply(pc.DB, priceList); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.UpdatePriceList(pc.DB, priceList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la grille de prix"})
+		return
+	}
+
+	c.JSON(http.StatusOK, priceList)
+}
+
+// DeletePriceList godoc
+// @Summary Supprimer une grille de prix
+// @Description Les commandes passées gardent le nom de la grille dans leurs lignes
+// @Tags price-lists
+// @Param id path int true "ID grille"
+// @Success 200 {object} map[string]string
+// @Router /price-lists/{id} [delete]
+// @Security BearerAuth
+func (pc *PriceListController) DeletePriceList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if err := models.DeletePriceList(pc.DB, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de la grille de prix"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Grille de prix supprimée"})
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/printing"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PrinterController struct {
+	DB *gorm.DB
+}
+
+func RefPrinterController(db *gorm.DB) *PrinterController {
+	return &PrinterController{DB: db}
+}
+
+// PrinterInput représente les données attendues pour enregistrer une imprimante
+type PrinterInput struct {
+	Name      string `json:"name" example:"Imprimante grill"`
+	Address   string `json:"address" example:"192.168.1.50:9100"`
+	StationID *uint  `json:"station_id" example:"2"`
+	IsActive  *bool  `json:"is_active" example:"true"`
+}
+
+// apply valide l'entrée et l'applique à l'imprimante
+func (input PrinterInput) apply(db *gorm.DB, printer *models.Printer) string {
+	if input.Name == "" {
+		return "Le nom de l'imprimante est obligatoire"
+	}
+	address, err := models.NormalizePrinterAddress(input.Address)
+	if err != nil {
+		return err.Error()
+	}
+	if input.StationID != nil {
+		if _, err := models.GetStationByID(db, *input.StationID); err != nil {
+			return err.Error()
+		}
+	}
+
+	printer.Name = input.Name
+	printer.Address = address
+	printer.StationID = input.StationID
+	if input.IsActive != nil {
+		printer.IsActive = *input.IsActive
+	}
+	return ""
+}
+
+// CreatePrinter godoc
+// @Summary Enregistrer une imprimante
+// @Tags printers
+// @Accept json
+// @Produce json
+// @Param printer body PrinterInput true "Imprimante"
+// @Success 201 {object} models.Printer
+// @Router /printers [post]
+// @Security BearerAuth
+func (pc *PrinterController) CreatePrinter(c *gin.Context) {
+	var request PrinterInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	printer := models.Printer{IsActive: true}
+	if msg := request.apply(pc.DB, &printer); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.CreatePrinter(pc.DB, &printer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'imprimante"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, printer)
+}
+
+// GetAllPrinters godoc
+// @Summary Liste des imprimantes
+// @Tags printers
+// @Produce json
+// @Success 200 {array} models.Printer
+// @Router /printers [get]
+// @Security BearerAuth
+func (pc *PrinterController) GetAllPrinters(c *gin.Context) {
+	printers, err := models.GetAllPrinters(pc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des imprimantes"})
+		return
+	}
+	c.JSON(http.StatusOK, printers)
+}
+
+// UpdatePrinter godoc
+// @Summary Modifier une imprimante
+// @Tags printers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID imprimante"
+// @Param printer body PrinterInput true "Imprimante"
+// @Success 200 {object} models.Printer
+// @Router /printers/{id} [put]
+// @Security BearerAuth
+func (pc *PrinterController) UpdatePrinter(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	printer, err := models.GetPrinterByID(pc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request PrinterInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := request.apply(pc.DB, printer); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.UpdatePrinter(pc.DB, printer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de l'imprimante"})
+		return
+	}
+
+	c.JSON(http.StatusOK, printer)
+}
+
+// DeletePrinter godoc
+// @Summary Supprimer une imprimante
+// @Tags printers
+// @Param id path int true "ID imprimante"
+// @Success 200 {object} map[string]string
+// @Router /printers/{id} [delete]
+// @Security BearerAuth
+func (pc *PrinterController) DeletePrinter(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if err := models.DeletePrinter(pc.DB, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de l'imprimante"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Imprimante supprimée"})
+}
+
+// GetPrintJobs godoc
+// @Summary Liste des travaux d'impression
+// @Description Le filtre status=dead donne la liste des tickets abandonnés après trop d'échecs
+// @Tags printers
+// @Produce json
+// @Param status query string false "pending, printed ou dead"
+// @Success 200 {array} models.PrintJob
+// @Router /print-jobs [get]
+// @Security BearerAuth
+func (pc *PrinterController) GetPrintJobs(c *gin.Context) {
+	jobs, err := models.GetPrintJobs(pc.DB, models.PrintJobStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des travaux d'impression"})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// RetryPrintJob godoc
+// @Summary Relancer un travail d'impression
+// @Tags printers
+// @Produce json
+// @Param id path int true "ID travail"
+// @Success 200 {object} models.PrintJob
+// @Router /print-jobs/{id}/retry [post]
+// @Security BearerAuth
+func (pc *PrinterController) RetryPrintJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	job, err := models.RetryPrintJob(pc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ReprintCommande godoc
+// @Summary Réimprimer les tickets d'une commande
+// @Tags printers
+// @Produce json
+// @Param id path int true "ID commande"
+// @Success 201 {array} models.PrintJob
+// @Router /print-jobs/commandes/{id} [post]
+// @Security BearerAuth
+func (pc *PrinterController) ReprintCommande(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	jobs, err := printing.EnqueueCommande(pc.DB, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commande introuvable"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise en file d'impression"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, jobs)
+}
@@ -36,6 +36,11 @@ func (pc *ProductController) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	if product.TaxCategory != "" && !product.TaxCategory.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Catégorie fiscale invalide"})
+		return
+	}
+
 	if err := models.CreateProduct(pc.DB, &product); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du produit"})
 		return
@@ -121,6 +126,11 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if product.TaxCategory != "" && !product.TaxCategory.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Catégorie fiscale invalide"})
+		return
+	}
+
 	if err := models.UpdateProduct(pc.DB, product); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du produit"})
 		return
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type PromotionController struct {
+	DB *gorm.DB
+}
+
+func RefPromotionController(db *gorm.DB) *PromotionController {
+	return &PromotionController{DB: db}
+}
+
+// PromotionInput représente les données attendues pour créer ou modifier une promotion
+type PromotionInput struct {
+	Name           string               `json:"name" example:"Happy Tiramisu"`
+	Kind           models.PromotionKind `json:"kind" example:"buy_x_get_y" enums:"percentage,fixed,buy_x_get_y,menu_upgrade"`
+	Value          decimal.Decimal      `json:"value" example:"0"`
+	Code           string               `json:"code,omitempty" example:"BIENVENUE"`
+	ProductID      *uint                `json:"product_id,omitempty" example:"3"`
+	MenuID         *uint                `json:"menu_id,omitempty"`
+	BaseMenuID     *uint                `json:"base_menu_id,omitempty"`
+	BuyQuantity    int                  `json:"buy_quantity,omitempty" example:"2"`
+	FreeQuantity   int                  `json:"free_quantity,omitempty" example:"1"`
+	MinOrderAmount decimal.Decimal      `json:"min_order_amount" example:"0"`
+	MaxUses        int                  `json:"max_uses" example:"0"`
+	StartsAt       *time.Time           `json:"starts_at,omitempty"`
+	EndsAt         *time.Time           `json:"ends_at,omitempty"`
+	Stackable      bool                 `json:"stackable" example:"true"`
+	IsActive       *bool                `json:"is_active" example:"true"`
+}
+
+// apply valide l'entrée et l'applique à la promotion
+func (input PromotionInput) apply(promotion *models.Promotion) error {
+	promotion.Name = input.Name
+	promotion.Kind = input.Kind
+	promotion.Value = input.Value
+	promotion.Code = nil
+	if code := strings.TrimSpace(input.Code); code != "" {
+		promotion.Code = &code
+	}
+	promotion.ProductID = input.ProductID
+	promotion.MenuID = input.MenuID
+	promotion.BaseMenuID = input.BaseMenuID
+	promotion.BuyQuantity = input.BuyQuantity
+	promotion.FreeQuantity = input.FreeQuantity
+	promotion.MinOrderAmount = input.MinOrderAmount
+	promotion.MaxUses = input.MaxUses
+	promotion.StartsAt = input.StartsAt
+	promotion.EndsAt = input.EndsAt
+	promotion.Stackable = input.Stackable
+	if input.IsActive != nil {
+		promotion.IsActive = *input.IsActive
+	}
+	return promotion.Validate()
+}
+
+// CreatePromotion godoc
+// @Summary Créer une promotion
+// @Description Sans code, la promotion s'applique automatiquement ; avec un code, le client doit le saisir
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param promotion body PromotionInput true "Promotion"
+// @Success 201 {object} models.Promotion
+// @Router /promotions [post]
+// @Security BearerAuth
+func (pc *PromotionController) CreatePromotion(c *gin.Context) {
+	var request PromotionInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion := models.Promotion{IsActive: true}
+	if err := request.apply(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.CreatePromotion(pc.DB, &promotion); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la promotion"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// GetAllPromotions godoc
+// @Summary Liste des promotions
+// @Tags promotions
+// @Produce json
+// @Success 200 {array} models.Promotion
+// @Router /promotions [get]
+// @Security BearerAuth
+func (pc *PromotionController) GetAllPromotions(c *gin.Context) {
+	promotions, err := models.GetAllPromotions(pc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des promotions"})
+		return
+	}
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetPromotionByID godoc
+// @Summary Récupérer une promotion
+// @Tags promotions
+// @Produce json
+// @Param id path int true "ID promotion"
+// @Success 200 {object} models.Promotion
+// @Router /promotions/{id} [get]
+// @Security BearerAuth
+func (pc *PromotionController) GetPromotionByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	promotion, err := models.GetPromotionByID(pc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// UpdatePromotion godoc
+// @Summary Modifier une promotion
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID promotion"
+// @Param promotion body PromotionInput true "Promotion"
+// @Success 200 {object} models.Promotion
+// @Router /promotions/{id} [put]
+// @Security BearerAuth
+func (pc *PromotionController) UpdatePromotion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	promotion, err := models.GetPromotionByID(pc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request PromotionInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := request.apply(promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.UpdatePromotion(pc.DB, promotion); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// DeletePromotion godoc
+// @Summary Supprimer une promotion
+// @Tags promotions
+// @Param id path int true "ID promotion"
+// @Success 200 {object} map[string]string
+// @Router /promotions/{id} [delete]
+// @Security BearerAuth
+func (pc *PromotionController) DeletePromotion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if err := models.DeletePromotion(pc.DB, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de la promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion supprimée"})
+}
+
+// GetDiscountReport godoc
+// @Summary Coût des promotions
+// @Description Nombre d'utilisations et montant total remisé par promotion sur une période
+// @Tags promotions
+// @Produce json
+// @Param from query string false "Premier jour inclus, AAAA-MM-JJ (30 derniers jours par défaut)"
+// @Param to query string false "Dernier jour inclus, AAAA-MM-JJ (aujourd'hui par défaut)"
+// @Success 200 {object} map[string]interface{}
+// @Router /reports/discounts [get]
+// @Security BearerAuth
+func (pc *PromotionController) GetDiscountReport(c *gin.Context) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from, to := today.AddDate(0, 0, -29), today
+
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Date invalide, format attendu : AAAA-MM-JJ"})
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	report, err := models.GetDiscountReport(pc.DB, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul du coût des promotions"})
+		return
+	}
+
+	total := decimal.Zero
+	for _, line := range report {
+		total = total.Add(line.Discount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":           from.Format("2006-01-02"),
+		"to":             to.Format("2006-01-02"),
+		"promotions":     report,
+		"total_discount": total,
+	})
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RewardController struct {
+	DB *gorm.DB
+}
+
+func RefRewardController(db *gorm.DB) *RewardController {
+	return &RewardController{DB: db}
+}
+
+// RewardInput représente les données attendues pour créer ou modifier une récompense
+type RewardInput struct {
+	Name       string `json:"name" example:"Dessert offert"`
+	ProductID  uint   `json:"product_id" example:"4"`
+	PointsCost int    `json:"points_cost" example:"50"`
+	IsActive   *bool  `json:"is_active" example:"true"`
+}
+
+// apply valide l'entrée et l'applique à la récompense
+func (input RewardInput) apply(db *gorm.DB, reward *models.Reward) string {
+	if input.Name == "" {
+		return "Le nom de la récompense est obligatoire"
+	}
+	if input.PointsCost <= 0 {
+		return "Le coût en points doit être supérieur à 0"
+	}
+	if _, err := models.GetProductByID(db, input.ProductID); err != nil {
+		return err.Error()
+	}
+
+	reward.Name = input.Name
+	reward.ProductID = input.ProductID
+	reward.PointsCost = input.PointsCost
+	if input.IsActive != nil {
+		reward.IsActive = *input.IsActive
+	}
+	return ""
+}
+
+// CreateReward godoc
+// @Summary Créer une récompense
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param reward body RewardInput true "Récompense"
+// @Success 201 {object} models.Reward
+// @Router /rewards [post]
+// @Security BearerAuth
+func (rc *RewardController) CreateReward(c *gin.Context) {
+	var request RewardInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward := models.Reward{IsActive: true}
+	if msg := request.apply(rc.DB, &reward); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.CreateReward(rc.DB, &reward); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la récompense"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reward)
+}
+
+// GetAllRewards godoc
+// @Summary Liste des récompenses
+// @Tags rewards
+// @Produce json
+// @Success 200 {array} models.Reward
+// @Router /rewards [get]
+// @Security BearerAuth
+func (rc *RewardController) GetAllRewards(c *gin.Context) {
+	rewards, err := models.GetAllRewards(rc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des récompenses"})
+		return
+	}
+	c.JSON(http.StatusOK, rewards)
+}
+
+// UpdateReward godoc
+// @Summary Modifier une récompense
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param id path int true "ID récompense"
+// @Param reward body RewardInput true "Récompense"
+// @Success 200 {object} models.Reward
+// @Router /rewards/{id} [put]
+// @Security BearerAuth
+func (rc *RewardController) UpdateReward(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	reward, err := models.GetRewardByID(rc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request RewardInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := request.apply(rc.DB, reward); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := models.UpdateReward(rc.DB, reward); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la récompense"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reward)
+}
+
+// DeleteReward godoc
+// @Summary Supprimer une récompense
+// @Tags rewards
+// @Param id path int true "ID récompense"
+// @Success 200 {object} map[string]string
+// @Router /rewards/{id} [delete]
+// @Security BearerAuth
+func (rc *RewardController) DeleteReward(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	if err := models.DeleteReward(rc.DB, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de la récompense"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Récompense supprimée"})
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoleController struct {
+	DB *gorm.DB
+}
+
+func RefRoleController(db *gorm.DB) *RoleController {
+	return &RoleController{DB: db}
+}
+
+// roleID lit l'ID du rôle de la route
+func roleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// roleErrorStatus choisit le code HTTP d'une erreur de rôle
+func roleErrorStatus(err error) int {
+	switch err {
+	case models.ErrRoleNotFound:
+		return http.StatusNotFound
+	case models.ErrRoleProtected, models.ErrRoleInUse:
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// checkCanGrantPermissions refuse les permissions que l'utilisateur authentifié n'a pas : sans cela,
+// role:manage suffirait à se donner tous les droits
+func checkCanGrantPermissions(c *gin.Context, permissions []models.Permission) bool {
+	for _, permission := range permissions {
+		if !middlewares.HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Accès interdit : vous n'avez pas la permission " + string(permission)})
+			return false
+		}
+	}
+	return true
+}
+
+// GetPermissions godoc
+// @Summary Catalogue des permissions
+// @Tags roles
+// @Produce json
+// @Success 200 {array} models.PermissionInfo
+// @Router /permissions [get]
+// @Security BearerAuth
+func (rc *RoleController) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.AllPermissions)
+}
+
+// GetAllRoles godoc
+// @Summary Liste des rôles et de leurs permissions
+// @Tags roles
+// @Produce json
+// @Success 200 {array} models.Role
+// @Router /roles [get]
+// @Security BearerAuth
+func (rc *RoleController) GetAllRoles(c *gin.Context) {
+	roles, err := models.GetAllRoles(rc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des rôles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateRole godoc
+// @Summary Créer un rôle
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param role body models.RoleInput true "Rôle"
+// @Success 201 {object} models.Role
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /roles [post]
+// @Security BearerAuth
+func (rc *RoleController) CreateRole(c *gin.Context) {
+	var input models.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+	if !checkCanGrantPermissions(c, input.Permissions) {
+		return
+	}
+
+	role := models.Role{Name: models.UserRole(input.Name), Description: input.Description, Permissions: input.Permissions}
+	if err := models.CreateRole(rc.DB, &role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole godoc
+// @Summary Modifier les permissions d'un rôle
+// @Description Le nom ne change pas. Le rôle admin a toujours toutes les permissions et n'est pas modifiable. Les changements s'appliquent dès la requête suivante des utilisateurs concernés. On ne peut ni modifier son propre rôle (sauf admin), ni un rôle qui a des permissions qu'on n'a pas, ni donner une permission qu'on n'a pas.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "ID rôle"
+// @Param role body models.RoleInput true "Description et permissions"
+// @Success 200 {object} models.Role
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /roles/{id} [put]
+// @Security BearerAuth
+func (rc *RoleController) UpdateRole(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+	var input models.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	current, err := models.GetRoleByID(rc.DB, id)
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if callerRole := models.UserRole(c.GetString("role")); callerRole != models.RoleAdmin && current.Name == callerRole {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accès interdit : vous ne pouvez pas modifier votre propre rôle"})
+		return
+	}
+	if !checkCanGrantRoles(c, rc.DB, current.Name) || !checkCanGrantPermissions(c, input.Permissions) {
+		return
+	}
+
+	role, err := models.UpdateRole(rc.DB, id, input.Description, input.Permissions)
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Supprimer un rôle
+// @Description Seuls les rôles personnalisés qui ne sont plus attribués peuvent être supprimés
+// @Tags roles
+// @Param id path int true "ID rôle"
+// @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /roles/{id} [delete]
+// @Security BearerAuth
+func (rc *RoleController) DeleteRole(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteRole(rc.DB, id); err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle supprimé"})
+}
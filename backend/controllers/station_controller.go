@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StationController struct {
+	DB *gorm.DB
+}
+
+func RefStationController(db *gorm.DB) *StationController {
+	return &StationController{DB: db}
+}
+
+// StationInput représente les données attendues pour créer ou modifier un poste
+type StationInput struct {
+	Name         string               `json:"name" example:"Boissons"`
+	ProductTypes []models.TypeProduct `json:"product_types" example:"boisson"`
+	IsDefault    bool                 `json:"is_default" example:"false"`
+}
+
+// validate vérifie le nom et les types de produits d'un poste
+func (input StationInput) validate() string {
+	if input.Name == "" {
+		return "Le nom du poste est obligatoire"
+	}
+	for _, t := range input.ProductTypes {
+		if !t.IsValid() {
+			return "Type de produit invalide : " + string(t)
+		}
+	}
+	return ""
+}
+
+// loadStation récupère le poste de la route
+func (sc *StationController) loadStation(c *gin.Context) (*models.Station, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+
+	station, err := models.GetStationByID(sc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return station, true
+}
+
+// checkCoverage vérifie que la configuration des postes obtenue après la modification (poste créé
+// ou modifié, ou supprimé si removed) laisse chaque type de produit à un poste
+func (sc *StationController) checkCoverage(c *gin.Context, changed models.Station, removed bool) bool {
+	stations, err := models.GetAllStations(sc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des postes"})
+		return false
+	}
+
+	next := []models.Station{}
+	for _, station := range stations {
+		if station.ID != changed.ID {
+			next = append(next, station)
+		}
+	}
+	if !removed {
+		next = append(next, changed)
+	}
+
+	if err := models.CheckStationsCoverage(next); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// CreateStation godoc
+// @Summary Créer un poste de préparation
+// @Description Refusé si un type de produit n'est plus préparé par aucun poste et qu'aucun poste n'est par défaut
+// @Tags stations
+// @Accept json
+// @Produce json
+// @Param station body StationInput true "Poste"
+// @Success 201 {object} models.Station
+// @Router /stations [post]
+// @Security BearerAuth
+func (sc *StationController) CreateStation(c *gin.Context) {
+	var request StationInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := request.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	station := models.Station{Name: request.Name, ProductTypes: request.ProductTypes, IsDefault: request.IsDefault}
+	if !sc.checkCoverage(c, station, false) {
+		return
+	}
+	if err := models.CreateStation(sc.DB, &station); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du poste"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, station)
+}
+
+// GetAllStations godoc
+// @Summary Liste des postes de préparation
+// @Tags stations
+// @Produce json
+// @Success 200 {array} models.Station
+// @Router /stations [get]
+// @Security BearerAuth
+func (sc *StationController) GetAllStations(c *gin.Context) {
+	stations, err := models.GetAllStations(sc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des postes"})
+		return
+	}
+	c.JSON(http.StatusOK, stations)
+}
+
+// UpdateStation godoc
+// @Summary Modifier un poste de préparation
+// @Tags stations
+// @Accept json
+// @Produce json
+// @Param id path int true "ID poste"
+// @Param station body StationInput true "Poste"
+// @Success 200 {object} models.Station
+// @Router /stations/{id} [put]
+// @Security BearerAuth
+func (sc *StationController) UpdateStation(c *gin.Context) {
+	station, ok := sc.loadStation(c)
+	if !ok {
+		return
+	}
+
+	var request StationInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := request.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	station.Name = request.Name
+	station.ProductTypes = request.ProductTypes
+	station.IsDefault = request.IsDefault
+	if !sc.checkCoverage(c, *station, false) {
+		return
+	}
+
+	if err := models.UpdateStation(sc.DB, station); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du poste"})
+		return
+	}
+
+	c.JSON(http.StatusOK, station)
+}
+
+// DeleteStation godoc
+// @Summary Supprimer un poste de préparation
+// @Tags stations
+// @Param id path int true "ID poste"
+// @Success 200 {object} map[string]string
+// @Router /stations/{id} [delete]
+// @Security BearerAuth
+func (sc *StationController) DeleteStation(c *gin.Context) {
+	station, ok := sc.loadStation(c)
+	if !ok {
+		return
+	}
+
+	if !sc.checkCoverage(c, *station, true) {
+		return
+	}
+
+	if err := models.DeleteStation(sc.DB, station.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression du poste"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Poste supprimé"})
+}
+
+// GetStationTickets godoc
+// @Summary Tickets d'un poste
+// @Description Commandes en cours avec uniquement les lignes préparées par ce poste. Une commande affichée pour la première fois est prise en charge par le poste et passe à "preparing".
+// @Tags stations
+// @Produce json
+// @Param id path int true "ID poste"
+// @Success 200 {array} models.StationTicket
+// @Router /stations/{id}/tickets [get]
+// @Security BearerAuth
+func (sc *StationController) GetStationTickets(c *gin.Context) {
+	station, ok := sc.loadStation(c)
+	if !ok {
+		return
+	}
+
+	tickets, err := models.GetStationTickets(sc.DB, station)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des tickets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tickets)
+}
+
+// MarkStationDone godoc
+// @Summary Terminer les lignes d'un poste
+// @Description Marque les lignes du poste comme prêtes ; la commande passe à "ready" quand tous les postes ont fini
+// @Tags stations
+// @Produce json
+// @Param id path int true "ID poste"
+// @Param commandeId path int true "ID commande"
+// @Success 200 {object} models.Commande
+// @Router /stations/{id}/commandes/{commandeId}/done [put]
+// @Security BearerAuth
+func (sc *StationController) MarkStationDone(c *gin.Context) {
+	station, ok := sc.loadStation(c)
+	if !ok {
+		return
+	}
+
+	commandeID, err := strconv.ParseUint(c.Param("commandeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	commande, err := models.MarkStationDone(sc.DB, uint(commandeID), station, middlewares.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, commande)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type TaxController struct {
+	DB *gorm.DB
+}
+
+func RefTaxController(db *gorm.DB) *TaxController {
+	return &TaxController{DB: db}
+}
+
+// TaxRateInput représente le taux à appliquer pour une catégorie et un mode de consommation
+type TaxRateInput struct {
+	Category    models.TaxCategory `json:"category" example:"alcohol" enums:"food,soft_drink,alcohol"`
+	ServiceMode models.ServiceMode `json:"service_mode" example:"on_site" enums:"on_site,takeaway"`
+	Rate        decimal.Decimal    `json:"rate" example:"20"`
+}
+
+// GetTaxRates godoc
+// @Summary Liste des taux de TVA
+// @Tags taxes
+// @Produce json
+// @Success 200 {array} models.TaxRate
+// @Router /tax-rates [get]
+// @Security BearerAuth
+func (tc *TaxController) GetTaxRates(c *gin.Context) {
+	rates, err := models.GetAllTaxRates(tc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des taux de TVA"})
+		return
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+// UpdateTaxRate godoc
+// @Summary Définir un taux de TVA
+// @Description Crée ou remplace le taux d'une catégorie pour un mode de consommation. Les commandes déjà passées ne sont pas modifiées.
+// @Tags taxes
+// @Accept json
+// @Produce json
+// @Param rate body TaxRateInput true "Taux"
+// @Success 200 {object} models.TaxRate
+// @Router /tax-rates [put]
+// @Security BearerAuth
+func (tc *TaxController) UpdateTaxRate(c *gin.Context) {
+	var request TaxRateInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !request.Category.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Catégorie fiscale invalide"})
+		return
+	}
+	if !request.ServiceMode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode de consommation invalide"})
+		return
+	}
+	if request.Rate.IsNegative() || request.Rate.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le taux doit être compris entre 0 et 100"})
+		return
+	}
+
+	rate := models.TaxRate{Category: request.Category, ServiceMode: request.ServiceMode, Rate: request.Rate}
+	if err := models.UpsertTaxRate(tc.DB, &rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du taux de TVA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// GetDailyVAT godoc
+// @Summary Récapitulatif de TVA d'une journée
+// @Description Montants HT, TVA et TTC des commandes de la journée, par taux
+// @Tags taxes
+// @Produce json
+// @Param date query string false "Jour au format AAAA-MM-JJ (aujourd'hui par défaut)"
+// @Success 200 {object} map[string]interface{}
+// @Router /reports/vat [get]
+// @Security BearerAuth
+func (tc *TaxController) GetDailyVAT(c *gin.Context) {
+	day := time.Now()
+	if param := c.Query("date"); param != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", param, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Date invalide, format attendu : AAAA-MM-JJ"})
+			return
+		}
+		day = parsed
+	}
+
+	summaries, err := models.GetDailyVATSummary(tc.DB, day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul de la TVA"})
+		return
+	}
+
+	net, tax, gross := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, s := range summaries {
+		net, tax, gross = net.Add(s.Net), tax.Add(s.Tax), gross.Add(s.Gross)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":        day.Format("2006-01-02"),
+		"vat":         summaries,
+		"total_net":   net,
+		"total_tax":   tax,
+		"total_gross": gross,
+	})
+}
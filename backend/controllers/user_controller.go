@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"LearningCampusKabre/middlewares"
 	"LearningCampusKabre/models"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,40 +19,60 @@ func RefUserController(db *gorm.DB) *UserController {
 	return &UserController{DB: db}
 }
 
+// checkCanGrantRoles refuse l'opération si l'un des rôles a des permissions que l'utilisateur
+// authentifié n'a pas : sans cela, user:write ou invitation:manage suffiraient à devenir admin
+func checkCanGrantRoles(c *gin.Context, db *gorm.DB, roles ...models.UserRole) bool {
+	for _, role := range roles {
+		allowed, err := middlewares.CanGrantRole(c, db, role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des permissions"})
+			return false
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Accès interdit : le rôle " + string(role) + " a des permissions que vous n'avez pas"})
+			return false
+		}
+	}
+	return true
+}
+
 // CreateUser godoc
 // @Summary Créer un utilisateur
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param user body models.User true "Utilisateur à créer"
-// @Success 201 {object} models.User
+// @Param user body models.UserInput true "Utilisateur à créer"
+// @Success 201 {object} models.UserResponse
 // @Router /users [post]
 // @Security BearerAuth
 func (uc *UserController) CreateUser(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var input models.UserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	if !user.Role.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Rôle utilisateur invalide"})
+	if !checkCanGrantRoles(c, uc.DB, models.UserRole(input.Role)) {
 		return
 	}
 
-	if err := models.CreateUser(uc.DB, &user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'utilisateur"})
+	user := models.User{
+		Email:       input.Email,
+		Role:        models.UserRole(input.Role),
+		Description: input.Description,
+	}
+	if err := models.CreateUser(uc.DB, &user, input.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, user)
+	c.JSON(http.StatusCreated, user.Response())
 }
 
 // GetAllUsers godoc
 // @Summary Liste des utilisateurs
 // @Tags users
 // @Produce json
-// @Success 200 {array} models.User
+// @Success 200 {array} models.UserResponse
 // @Router /users [get]
 // @Security BearerAuth
 func (uc *UserController) GetAllUsers(c *gin.Context) {
@@ -59,7 +81,7 @@ func (uc *UserController) GetAllUsers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des utilisateurs"})
 		return
 	}
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, models.UserResponses(users))
 }
 
 // GetUserByID godoc
@@ -67,7 +89,7 @@ func (uc *UserController) GetAllUsers(c *gin.Context) {
 // @Tags users
 // @Produce json
 // @Param id path int true "ID utilisateur"
-// @Success 200 {object} models.User
+// @Success 200 {object} models.UserResponse
 // @Router /users/{id} [get]
 // @Security BearerAuth
 func (uc *UserController) GetUserByID(c *gin.Context) {
@@ -85,17 +107,18 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, user.Response())
 }
 
 // UpdateUser godoc
 // @Summary Mettre à jour un utilisateur
+// @Description Le mot de passe ne se modifie pas ici : voir /auth/change-password
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path int true "ID utilisateur"
-// @Param user body models.User true "Données utilisateur"
-// @Success 200 {object} models.User
+// @Param user body models.UserUpdateInput true "Données utilisateur"
+// @Success 200 {object} models.UserResponse
 // @Router /users/{id} [put]
 // @Security BearerAuth
 func (uc *UserController) UpdateUser(c *gin.Context) {
@@ -107,31 +130,33 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	existingUser, err := models.GetUserByID(uc.DB, userID)
+	user, err := models.GetUserByID(uc.DB, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
 		return
 	}
 
-	var updatedUser models.User
-	if err := c.ShouldBindJSON(&updatedUser); err != nil {
+	var input models.UserUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	if !updatedUser.Role.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Rôle utilisateur invalide"})
+	// Le compte modifié ne doit pas avoir plus de droits que l'appelant, sinon changer son
+	// e-mail suffirait à en prendre le contrôle
+	if !checkCanGrantRoles(c, uc.DB, user.Role, models.UserRole(input.Role)) {
 		return
 	}
 
-	updatedUser.ID = existingUser.ID
+	user.Email = input.Email
+	user.Role = models.UserRole(input.Role)
+	user.Description = input.Description
 
-	if err := models.UpdateUser(uc.DB, &updatedUser); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de l'utilisateur"})
+	if err := models.UpdateUser(uc.DB, user); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedUser)
+	c.JSON(http.StatusOK, user.Response())
 }
 
 // DeleteUser godoc
@@ -150,10 +175,135 @@ func (ctrl *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.DB.Delete(&user).Error; err != nil {
+	// Les sessions du compte sont fermées avec lui
+	if err := models.DeleteUser(ctrl.DB, user.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du soft delete"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Utilisateur soft supprimé"})
 }
+
+// DisableUser godoc
+// @Summary Désactiver un utilisateur
+// @Description Ferme ses sessions : ses jetons ne sont plus acceptés, il ne peut plus se connecter
+// @Tags users
+// @Produce json
+// @Param id path int true "ID utilisateur"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/disable [post]
+// @Security BearerAuth
+func (uc *UserController) DisableUser(c *gin.Context) {
+	uc.setDisabled(c, true)
+}
+
+// EnableUser godoc
+// @Summary Réactiver un utilisateur
+// @Tags users
+// @Produce json
+// @Param id path int true "ID utilisateur"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/enable [post]
+// @Security BearerAuth
+func (uc *UserController) EnableUser(c *gin.Context) {
+	uc.setDisabled(c, false)
+}
+
+func (uc *UserController) setDisabled(c *gin.Context, disabled bool) {
+	var userID uint
+	if _, err := fmt.Sscan(c.Param("id"), &userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	user, err := models.SetUserDisabled(uc.DB, userID, disabled)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Response())
+}
+
+// UnlockUser godoc
+// @Summary Déverrouiller un utilisateur
+// @Description Lève le verrouillage après trop d'échecs de connexion et remet les compteurs à zéro
+// @Tags users
+// @Produce json
+// @Param id path int true "ID utilisateur"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/unlock [post]
+// @Security BearerAuth
+func (uc *UserController) UnlockUser(c *gin.Context) {
+	var userID uint
+	if _, err := fmt.Sscan(c.Param("id"), &userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	user, err := models.UnlockUser(uc.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Response())
+}
+
+// ClearPIN godoc
+// @Summary Effacer le code PIN d'un utilisateur
+// @Description Ferme aussi ses sessions ouvertes sur les tablettes
+// @Tags users
+// @Produce json
+// @Param id path int true "ID utilisateur"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/pin [delete]
+// @Security BearerAuth
+func (uc *UserController) ClearPIN(c *gin.Context) {
+	var userID uint
+	if _, err := fmt.Sscan(c.Param("id"), &userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	user, err := models.ClearUserPIN(uc.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Response())
+}
+
+// GetLoginAttempts godoc
+// @Summary Journal des tentatives de connexion
+// @Tags users
+// @Produce json
+// @Param email query string false "Email saisi"
+// @Param ip query string false "Adresse IP"
+// @Param user_id query int false "ID utilisateur"
+// @Param failed query bool false "Uniquement les échecs"
+// @Param limit query int false "Nombre maximum (100 par défaut, 500 au plus)"
+// @Success 200 {array} models.LoginAttempt
+// @Router /login-attempts [get]
+// @Security BearerAuth
+func (uc *UserController) GetLoginAttempts(c *gin.Context) {
+	filter := models.LoginAttemptFilter{
+		Email:      c.Query("email"),
+		IP:         c.Query("ip"),
+		FailedOnly: c.Query("failed") == "true",
+	}
+	if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		filter.UserID = uint(userID)
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		filter.Limit = limit
+	}
+
+	attempts, err := models.GetLoginAttempts(uc.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du journal"})
+		return
+	}
+	c.JSON(http.StatusOK, attempts)
+}
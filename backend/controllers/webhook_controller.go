@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"LearningCampusKabre/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookController struct {
+	DB *gorm.DB
+}
+
+func RefWebhookController(db *gorm.DB) *WebhookController {
+	return &WebhookController{DB: db}
+}
+
+// WebhookSubscriptionInput représente les données attendues pour un abonnement aux évènements
+type WebhookSubscriptionInput struct {
+	URL         string `json:"url" example:"https://compta.example.com/hooks/kabre"`
+	Description string `json:"description" example:"Export comptable"`
+	// Évènements transmis ; vide pour tous les évènements
+	Events   []models.EventType `json:"events" example:"commande.created,commande.status_changed"`
+	IsActive *bool              `json:"is_active" example:"true"`
+}
+
+// apply valide l'entrée et l'applique à l'abonnement
+func (input WebhookSubscriptionInput) apply(subscription *models.WebhookSubscription) error {
+	subscription.URL = input.URL
+	subscription.Description = input.Description
+	subscription.Events = input.Events
+	if input.IsActive != nil {
+		subscription.IsActive = *input.IsActive
+	}
+	return subscription.Validate()
+}
+
+// loadSubscription récupère l'abonnement de la route
+func (wc *WebhookController) loadSubscription(c *gin.Context) (*models.WebhookSubscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil, false
+	}
+
+	subscription, err := models.GetWebhookSubscriptionByID(wc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return subscription, true
+}
+
+// CreateWebhookSubscription godoc
+// @Summary Abonner un système externe aux évènements
+// @Description Le secret de signature est retourné une seule fois : l'abonné vérifie l'en-tête X-Webhook-Signature (sha256=HMAC du "timestamp.corps").
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param subscription body WebhookSubscriptionInput true "Abonnement"
+// @Success 201 {object} map[string]interface{}
+// @Router /webhooks [post]
+// @Security BearerAuth
+func (wc *WebhookController) CreateWebhookSubscription(c *gin.Context) {
+	var request WebhookSubscriptionInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := models.WebhookSubscription{IsActive: true}
+	if err := request.apply(&subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.CreateWebhookSubscription(wc.DB, &subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'abonnement"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription": subscription,
+		"secret":       subscription.Secret,
+	})
+}
+
+// GetAllWebhookSubscriptions godoc
+// @Summary Liste des abonnements aux évènements
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.WebhookSubscription
+// @Router /webhooks [get]
+// @Security BearerAuth
+func (wc *WebhookController) GetAllWebhookSubscriptions(c *gin.Context) {
+	subscriptions, err := models.GetAllWebhookSubscriptions(wc.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des abonnements"})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// UpdateWebhookSubscription godoc
+// @Summary Modifier un abonnement aux évènements
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID abonnement"
+// @Param subscription body WebhookSubscriptionInput true "Abonnement"
+// @Success 200 {object} models.WebhookSubscription
+// @Router /webhooks/{id} [put]
+// @Security BearerAuth
+func (wc *WebhookController) UpdateWebhookSubscription(c *gin.Context) {
+	subscription, ok := wc.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	var request WebhookSubscriptionInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := request.apply(subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.UpdateWebhookSubscription(wc.DB, subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de l'abonnement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhookSubscription godoc
+// @Summary Supprimer un abonnement aux évènements
+// @Description Les envois encore en attente sont abandonnés
+// @Tags webhooks
+// @Param id path int true "ID abonnement"
+// @Success 200 {object} map[string]string
+// @Router /webhooks/{id} [delete]
+// @Security BearerAuth
+func (wc *WebhookController) DeleteWebhookSubscription(c *gin.Context) {
+	subscription, ok := wc.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteWebhookSubscription(wc.DB, subscription.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de l'abonnement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Abonnement supprimé"})
+}
+
+// GetWebhookDeliveries godoc
+// @Summary Journal des envois d'un abonnement
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID abonnement"
+// @Param status query string false "Filtrer par état" Enums(pending, delivered, dead)
+// @Success 200 {array} models.WebhookDelivery
+// @Router /webhooks/{id}/deliveries [get]
+// @Security BearerAuth
+func (wc *WebhookController) GetWebhookDeliveries(c *gin.Context) {
+	subscription, ok := wc.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := models.GetWebhookDeliveries(wc.DB, subscription.ID, models.WebhookDeliveryStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des envois"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery godoc
+// @Summary Détail d'un envoi et de ses tentatives
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID envoi"
+// @Success 200 {object} models.WebhookDelivery
+// @Router /webhook-deliveries/{id} [get]
+// @Security BearerAuth
+func (wc *WebhookController) GetWebhookDelivery(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	delivery, err := models.GetWebhookDeliveryByID(wc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook godoc
+// @Summary Renvoyer un webhook
+// @Description Remet l'envoi en file d'attente, qu'il ait été reçu ou abandonné
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID envoi"
+// @Success 200 {object} models.WebhookDelivery
+// @Router /webhook-deliveries/{id}/redeliver [post]
+// @Security BearerAuth
+func (wc *WebhookController) RedeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	delivery, err := models.RedeliverWebhook(wc.DB, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
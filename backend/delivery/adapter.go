@@ -0,0 +1,68 @@
+package delivery
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"net/http"
+	"sort"
+
+	"LearningCampusKabre/models"
+)
+
+// ExternalOrder est une commande de plateforme, convertie dans un format commun par l'adaptateur
+type ExternalOrder struct {
+	ID           string
+	CustomerName string
+	Lines        []ExternalLine
+}
+
+// ExternalLine est un article commandé sur la plateforme
+type ExternalLine struct {
+	ExternalID string
+	Quantity   int
+	Modifiers  []string
+}
+
+// Adapter traduit le format d'une plateforme : webhooks entrants et mises à jour de statut sortantes
+type Adapter interface {
+	// VerifySignature vérifie la signature HMAC du corps brut reçu
+	VerifySignature(body []byte, header http.Header, secret string) bool
+	// ParseOrder convertit le corps du webhook en commande externe
+	ParseOrder(body []byte) (*ExternalOrder, error)
+	// StatusUpdate construit le corps envoyé à la plateforme ; false si ce statut ne lui est pas communiqué
+	StatusUpdate(externalID string, status models.StatusType) ([]byte, bool)
+}
+
+// adapters recense les formats de plateformes pris en charge
+var adapters = map[string]Adapter{
+	"ubereats":  uberEatsAdapter{},
+	"deliveroo": deliverooAdapter{},
+}
+
+// GetAdapter retourne l'adaptateur d'un format de plateforme
+func GetAdapter(name string) (Adapter, bool) {
+	adapter, ok := adapters[name]
+	return adapter, ok
+}
+
+// AdapterNames liste les formats pris en charge
+func AdapterNames() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sign calcule le HMAC-SHA256 d'un corps avec le secret de la plateforme
+func Sign(body []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// validSignature compare en temps constant la signature reçue à celle attendue
+func validSignature(body []byte, secret string, received []byte) bool {
+	return len(received) > 0 && hmac.Equal(Sign(body, secret), received)
+}
@@ -0,0 +1,90 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"LearningCampusKabre/models"
+
+	"gorm.io/gorm"
+)
+
+// Client envoie les changements de statut aux plateformes
+type Client struct {
+	HTTP *http.Client
+}
+
+// NewClient crée un client avec les réglages par défaut
+func NewClient() *Client {
+	return &Client{HTTP: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// PushStatus envoie le statut d'une commande à sa plateforme. Le corps est signé avec le secret
+// partagé (en-tête X-Signature) ; une plateforme sans URL de statut est ignorée.
+func (c *Client) PushStatus(platform *models.DeliveryPlatform, externalID string, status models.StatusType) error {
+	if platform.StatusURL == "" {
+		return nil
+	}
+	adapter, ok := GetAdapter(platform.Adapter)
+	if !ok {
+		return fmt.Errorf("format de plateforme inconnu %q", platform.Adapter)
+	}
+	body, ok := adapter.StatusUpdate(externalID, status)
+	if !ok {
+		return nil
+	}
+
+	url := strings.ReplaceAll(platform.StatusURL, "{id}", externalID)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature", hex.EncodeToString(Sign(body, platform.WebhookSecret)))
+	if platform.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+platform.APIKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("la plateforme a répondu %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Listen est le listener à enregistrer avec models.OnEvent : la création et les changements de
+// statut des commandes venant d'une plateforme sont mis en file dans la transaction en cours,
+// puis envoyés par le Worker une fois l'opération validée
+func Listen(tx *gorm.DB, event models.Event) error {
+	if event.Type != models.EventCommandeCreated && event.Type != models.EventCommandeStatusChanged {
+		return nil
+	}
+	commande, ok := event.Data.(*models.Commande)
+	if !ok || commande.DeliveryPlatformID == nil || commande.ExternalOrderID == nil {
+		return nil
+	}
+	platform, err := models.GetDeliveryPlatformByID(tx, *commande.DeliveryPlatformID)
+	if err != nil {
+		log.Println("❌ Plateforme de livraison introuvable :", err)
+		return nil
+	}
+	if platform.StatusURL == "" {
+		return nil
+	}
+	// Les statuts que la plateforme ne connaît pas ne sont pas mis en file
+	if adapter, ok := GetAdapter(platform.Adapter); ok {
+		if _, ok := adapter.StatusUpdate(*commande.ExternalOrderID, commande.Status); !ok {
+			return nil
+		}
+	}
+	return models.EnqueueDeliveryStatusPush(tx, platform.ID, *commande.ExternalOrderID, commande.Status)
+}
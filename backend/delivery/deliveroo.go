@@ -0,0 +1,73 @@
+package delivery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"LearningCampusKabre/models"
+)
+
+// deliverooAdapter lit les webhooks au format Deliveroo : signature HMAC-SHA256 en base64
+// dans l'en-tête X-Deliveroo-Hmac-Sha256, articles identifiés par leur pos_item_id.
+type deliverooAdapter struct{}
+
+type deliverooOrder struct {
+	Order struct {
+		ID       string `json:"id"`
+		Customer struct {
+			FirstName string `json:"first_name"`
+		} `json:"customer"`
+		Items []struct {
+			PosItemID string `json:"pos_item_id"`
+			Quantity  int    `json:"quantity"`
+			Modifiers []struct {
+				Name string `json:"name"`
+			} `json:"modifiers"`
+		} `json:"items"`
+	} `json:"order"`
+}
+
+func (deliverooAdapter) VerifySignature(body []byte, header http.Header, secret string) bool {
+	received, err := base64.StdEncoding.DecodeString(header.Get("X-Deliveroo-Hmac-Sha256"))
+	return err == nil && validSignature(body, secret, received)
+}
+
+func (deliverooAdapter) ParseOrder(body []byte) (*ExternalOrder, error) {
+	var payload deliverooOrder
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("Commande Deliveroo illisible : %w", err)
+	}
+	if payload.Order.ID == "" {
+		return nil, fmt.Errorf("Commande Deliveroo sans identifiant")
+	}
+
+	order := &ExternalOrder{ID: payload.Order.ID, CustomerName: payload.Order.Customer.FirstName}
+	for _, item := range payload.Order.Items {
+		line := ExternalLine{ExternalID: item.PosItemID, Quantity: item.Quantity}
+		for _, modifier := range item.Modifiers {
+			line.Modifiers = append(line.Modifiers, modifier.Name)
+		}
+		order.Lines = append(order.Lines, line)
+	}
+	return order, nil
+}
+
+func (deliverooAdapter) StatusUpdate(externalID string, status models.StatusType) ([]byte, bool) {
+	var state string
+	switch status {
+	case models.StatusPending, models.StatusScheduled:
+		state = "ACCEPTED"
+	case models.StatusPreparing:
+		state = "IN_KITCHEN"
+	case models.StatusReady:
+		state = "READY_FOR_COLLECTION"
+	case models.StatusDelivered:
+		state = "COLLECTED"
+	default:
+		return nil, false
+	}
+	body, _ := json.Marshal(map[string]string{"order_id": externalID, "status": state})
+	return body, true
+}
@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"LearningCampusKabre/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSignature est retournée quand la signature d'un webhook ne correspond pas au secret de la plateforme
+var ErrInvalidSignature = errors.New("signature du webhook invalide")
+
+// Result est l'issue de la réception d'une commande de plateforme
+type Result struct {
+	Commande *models.Commande
+	// La commande avait déjà été reçue : la plateforme rejoue son webhook
+	Duplicate bool
+	// Articles de la plateforme sans correspondance dans le catalogue
+	Unmapped []string
+	// Lignes refusées (article supprimé ou indisponible), renseigné si la commande n'a pas été créée
+	Order *models.PricedOrder
+}
+
+// ReceiveOrder vérifie la signature d'un webhook, traduit la commande de la plateforme dans notre
+// catalogue et crée la commande. Les prix appliqués sont ceux du canal "delivery".
+func ReceiveOrder(db *gorm.DB, platform *models.DeliveryPlatform, body []byte, header http.Header) (*Result, error) {
+	adapter, ok := GetAdapter(platform.Adapter)
+	if !ok {
+		return nil, fmt.Errorf("format de plateforme inconnu %q", platform.Adapter)
+	}
+	if !adapter.VerifySignature(body, header, platform.WebhookSecret) {
+		return nil, ErrInvalidSignature
+	}
+
+	external, err := adapter.ParseOrder(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := models.FindExternalCommande(db, platform.ID, external.ID); err == nil {
+		return &Result{Commande: existing, Duplicate: true}, nil
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	externalIDs := make([]string, 0, len(external.Lines))
+	for _, line := range external.Lines {
+		externalIDs = append(externalIDs, line.ExternalID)
+	}
+	mappings, err := models.FindDeliveryMappings(db, platform.ID, externalIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	var lines []models.OrderLine
+	for _, line := range external.Lines {
+		mapping, ok := mappings[line.ExternalID]
+		if !ok {
+			result.Unmapped = append(result.Unmapped, line.ExternalID)
+			continue
+		}
+		lines = append(lines, mapping.OrderLine(line.Quantity, line.Modifiers))
+	}
+	if len(result.Unmapped) > 0 {
+		return result, nil
+	}
+
+	order, err := models.PriceOrder(db, lines, models.PricingContext{Channel: models.ChannelDelivery})
+	if err != nil {
+		return nil, err
+	}
+	if !order.Valid {
+		result.Order = order
+		return result, nil
+	}
+
+	// La plateforme encaisse le client : la commande est déjà payée et emportée
+	externalID := external.ID
+	commande := models.Commande{
+		Status:             models.StatusPending,
+		Channel:            models.ChannelDelivery,
+		PaymentStatus:      models.PaymentPaid,
+		ServiceMode:        models.ServiceTakeaway,
+		DeliveryPlatformID: &platform.ID,
+		ExternalOrderID:    &externalID,
+	}
+	if err := models.CreateCommandeFromLines(db, order, &commande); err != nil {
+		// Deux livraisons simultanées du même webhook : l'index unique garde la première
+		if existing, findErr := models.FindExternalCommande(db, platform.ID, external.ID); findErr == nil {
+			return &Result{Commande: existing, Duplicate: true}, nil
+		}
+		return nil, err
+	}
+
+	result.Commande = &commande
+	return result, nil
+}
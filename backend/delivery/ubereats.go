@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"LearningCampusKabre/models"
+)
+
+// uberEatsAdapter lit les webhooks au format Uber Eats : signature HMAC-SHA256 en hexadécimal
+// dans l'en-tête X-Uber-Signature, articles identifiés par leur external_data.
+type uberEatsAdapter struct{}
+
+type uberEatsOrder struct {
+	ID    string `json:"id"`
+	Eater struct {
+		FirstName string `json:"first_name"`
+	} `json:"eater"`
+	Cart struct {
+		Items []struct {
+			ExternalData        string `json:"external_data"`
+			Quantity            int    `json:"quantity"`
+			SpecialInstructions string `json:"special_instructions"`
+		} `json:"items"`
+	} `json:"cart"`
+}
+
+func (uberEatsAdapter) VerifySignature(body []byte, header http.Header, secret string) bool {
+	received, err := hex.DecodeString(header.Get("X-Uber-Signature"))
+	return err == nil && validSignature(body, secret, received)
+}
+
+func (uberEatsAdapter) ParseOrder(body []byte) (*ExternalOrder, error) {
+	var payload uberEatsOrder
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("Commande Uber Eats illisible : %w", err)
+	}
+	if payload.ID == "" {
+		return nil, fmt.Errorf("Commande Uber Eats sans identifiant")
+	}
+
+	order := &ExternalOrder{ID: payload.ID, CustomerName: payload.Eater.FirstName}
+	for _, item := range payload.Cart.Items {
+		line := ExternalLine{ExternalID: item.ExternalData, Quantity: item.Quantity}
+		if item.SpecialInstructions != "" {
+			line.Modifiers = []string{item.SpecialInstructions}
+		}
+		order.Lines = append(order.Lines, line)
+	}
+	return order, nil
+}
+
+func (uberEatsAdapter) StatusUpdate(externalID string, status models.StatusType) ([]byte, bool) {
+	var state string
+	switch status {
+	case models.StatusPending, models.StatusScheduled:
+		state = "accepted"
+	case models.StatusPreparing:
+		state = "preparing"
+	case models.StatusReady:
+		state = "ready_for_pickup"
+	case models.StatusDelivered:
+		state = "picked_up"
+	default:
+		return nil, false
+	}
+	body, _ := json.Marshal(map[string]string{"order_id": externalID, "status": state})
+	return body, true
+}
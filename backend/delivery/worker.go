@@ -0,0 +1,106 @@
+package delivery
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"LearningCampusKabre/models"
+
+	"gorm.io/gorm"
+)
+
+// Worker envoie aux plateformes les mises à jour de statut en attente, avec nouvelles tentatives.
+type Worker struct {
+	DB          *gorm.DB
+	Client      *Client
+	Interval    time.Duration
+	MaxAttempts int
+	// Durée pendant laquelle une mise à jour réservée n'est pas reprise par un autre worker
+	Lease time.Duration
+	// Backoff retourne le délai avant la tentative suivante
+	Backoff func(attempts int) time.Duration
+}
+
+// NewWorker crée un worker avec les réglages par défaut : 6 tentatives espacées de 5 s, 10 s,
+// 20 s... jusqu'à 5 min entre deux tentatives
+func NewWorker(db *gorm.DB) *Worker {
+	return &Worker{
+		DB:          db,
+		Client:      NewClient(),
+		Interval:    time.Second,
+		MaxAttempts: 6,
+		Lease:       time.Minute,
+		Backoff: func(attempts int) time.Duration {
+			delay := 5 * time.Second << (attempts - 1)
+			return min(delay, 5*time.Minute)
+		},
+	}
+}
+
+// Run traite la file jusqu'à l'annulation du contexte
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.ProcessDue(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue envoie les mises à jour arrivées à échéance et retourne le nombre de celles traitées
+func (w *Worker) ProcessDue(now time.Time) int {
+	pushes, err := models.GetDueDeliveryStatusPushes(w.DB, now, 50)
+	if err != nil {
+		log.Println("❌ Erreur lors de la lecture de la file des statuts de livraison :", err)
+		return 0
+	}
+
+	processed := 0
+	for i := range pushes {
+		claimed, err := models.ClaimDeliveryStatusPush(w.DB, &pushes[i], now, w.Lease)
+		if err != nil {
+			log.Println("❌ Erreur lors de la réservation d'un statut de livraison :", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		w.process(&pushes[i], now)
+		processed++
+	}
+	return processed
+}
+
+func (w *Worker) process(push *models.DeliveryStatusPush, now time.Time) {
+	push.Attempts++
+
+	platform, err := models.GetDeliveryPlatformByID(w.DB, push.PlatformID)
+	if err == nil {
+		err = w.Client.PushStatus(platform, push.ExternalOrderID, push.OrderStatus)
+	}
+
+	switch {
+	case err == nil:
+		sentAt := now
+		push.Status = models.DeliveryPushSent
+		push.SentAt = &sentAt
+		push.LastError = ""
+	case push.Attempts >= w.MaxAttempts:
+		push.Status = models.DeliveryPushDead
+		push.LastError = err.Error()
+		log.Printf("❌ Statut %s de la commande %s abandonné : %v", push.OrderStatus, push.ExternalOrderID, err)
+	default:
+		push.LastError = err.Error()
+		push.NextAttemptAt = now.Add(w.Backoff(push.Attempts))
+	}
+
+	if err := w.DB.Save(push).Error; err != nil {
+		log.Println("❌ Erreur lors de la mise à jour du statut de livraison :", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"LearningCampusKabre/jwtkeys"
+)
+
+// loadSigningKeys charge les clés JWT et arrête le serveur si aucune n'est utilisable :
+// sans clé, aucun jeton ne pourrait être signé ni vérifié
+func loadSigningKeys() {
+	keys, err := jwtkeys.FromEnv()
+	if err != nil {
+		log.Fatal("❌ Clés de signature JWT : ", err)
+	}
+	jwtkeys.SetDefault(keys)
+
+	signing, _ := keys.SigningKey(time.Now())
+	log.Printf("🔑 %d clé(s) JWT chargée(s), signature avec %s (%s)", len(keys.Keys()), signing.ID, signing.Algorithm)
+
+	// Les clés ajoutées à JWT_KEYS_DIR sont publiées sans redémarrage
+	go keys.Watch(context.Background(), jwtkeys.DefaultReloadInterval)
+}
+
+// runGenerateJWTKey traite la commande "generate-jwt-key" et retourne le code de sortie.
+// Pour une rotation, la clé est créée à l'avance avec -not-before : elle est publiée dans le JWKS
+// dès la relecture du répertoire et signe à partir de la date indiquée. L'ancienne clé peut être
+// supprimée une fois la durée de vie d'un jeton d'accès écoulée après la bascule.
+func runGenerateJWTKey(args []string) int {
+	flags := flag.NewFlagSet("generate-jwt-key", flag.ContinueOnError)
+	alg := flags.String("alg", jwtkeys.AlgEdDSA, "algorithme : EdDSA ou RS256")
+	kid := flags.String("kid", "", "identifiant de la clé (par défaut dérivé de la clé publique)")
+	notBefore := flags.String("not-before", "", "date RFC 3339 à partir de laquelle la clé signe (par défaut immédiatement)")
+	dir := flags.String("dir", os.Getenv("JWT_KEYS_DIR"), "répertoire des clés (JWT_KEYS_DIR)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "usage : generate-jwt-key -dir <répertoire> [-alg EdDSA|RS256] [-kid <id>] [-not-before <date>]")
+		return 2
+	}
+
+	var start time.Time
+	if *notBefore != "" {
+		var err error
+		if start, err = time.Parse(time.RFC3339, *notBefore); err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Date -not-before invalide :", err)
+			return 2
+		}
+	}
+
+	key, err := jwtkeys.GenerateKey(*kid, *alg, start)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		return 1
+	}
+	data, err := jwtkeys.EncodePEM(key)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		return 1
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		return 1
+	}
+	path := filepath.Join(*dir, key.ID+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		return 1
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		return 1
+	}
+
+	fmt.Printf("🔑 Clé %s (%s) écrite dans %s\n", key.ID, key.Algorithm, path)
+	return 0
+}
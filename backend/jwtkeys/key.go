@@ -0,0 +1,236 @@
+// Package jwtkeys gère les clés asymétriques (RS256, EdDSA) qui signent les JWT d'accès :
+// plusieurs clés actives identifiées par leur kid, rotation programmée et publication des clés
+// publiques au format JWKS pour que d'autres services puissent vérifier nos jetons.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithmes de signature acceptés ; HS256 et "none" sont toujours refusés
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// MinRSABits est la taille minimale d'une clé RSA
+const MinRSABits = 2048
+
+// En-têtes PEM optionnels d'une clé : son identifiant et la date à partir de laquelle elle signe
+const (
+	headerKid       = "Kid"
+	headerNotBefore = "Not-Before"
+)
+
+var kidPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Key est une clé privée de signature
+type Key struct {
+	ID        string
+	Algorithm string
+	// Date à partir de laquelle la clé signe les nouveaux jetons ; elle est publiée dans le JWKS
+	// dès son chargement pour que les autres services la connaissent avant son premier jeton
+	NotBefore time.Time
+	Private   crypto.Signer
+}
+
+// Public retourne la clé publique de vérification
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// method retourne la méthode jwt de l'algorithme de la clé
+func (k *Key) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// newKey déduit l'algorithme du type de clé et vérifie sa robustesse
+func newKey(kid string, private any, notBefore time.Time) (*Key, error) {
+	key := &Key{ID: kid, NotBefore: notBefore}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < MinRSABits {
+			return nil, fmt.Errorf("clé RSA trop courte : %d bits, %d minimum", k.N.BitLen(), MinRSABits)
+		}
+		key.Algorithm, key.Private = AlgRS256, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Private = AlgEdDSA, k
+	default:
+		return nil, errors.New("type de clé non supporté : RSA ou Ed25519 attendu")
+	}
+
+	if key.ID == "" {
+		key.ID = thumbprint(key.Public())
+	}
+	if !kidPattern.MatchString(key.ID) {
+		return nil, fmt.Errorf("kid invalide : %q", key.ID)
+	}
+	return key, nil
+}
+
+// thumbprint dérive un kid stable de la clé publique, pour les clés sans en-tête Kid
+func thumbprint(public crypto.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey crée une clé de l'algorithme demandé ; un kid vide est dérivé de la clé publique
+func GenerateKey(kid, alg string, notBefore time.Time) (*Key, error) {
+	switch alg {
+	case AlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, MinRSABits)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(kid, private, notBefore)
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(kid, private, notBefore)
+	}
+	return nil, fmt.Errorf("algorithme non supporté : %q (%s ou %s)", alg, AlgRS256, AlgEdDSA)
+}
+
+// ParsePEM lit toutes les clés privées d'un contenu PEM (PKCS#8, ou PKCS#1 pour RSA).
+// Chaque bloc peut porter les en-têtes "Kid" et "Not-Before" (RFC 3339).
+func ParsePEM(data []byte) ([]*Key, error) {
+	var keys []*Key
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+
+		var private any
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("clé privée illisible : %w", err)
+		}
+
+		var notBefore time.Time
+		if value := block.Headers[headerNotBefore]; value != "" {
+			if notBefore, err = time.Parse(time.RFC3339, value); err != nil {
+				return nil, fmt.Errorf("en-tête %s invalide : %q", headerNotBefore, value)
+			}
+		}
+		key, err := newKey(block.Headers[headerKid], private, notBefore)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// EncodePEM écrit la clé en PKCS#8 avec ses en-têtes Kid et Not-Before
+func EncodePEM(k *Key) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{headerKid: k.ID}
+	if !k.NotBefore.IsZero() {
+		headers[headerNotBefore] = k.NotBefore.UTC().Format(time.RFC3339)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: headers, Bytes: der}), nil
+}
+
+// JWK est la clé publique au format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty" example:"OKP"`
+	Kid string `json:"kid"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"EdDSA"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty" example:"Ed25519"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet est le document publié sur /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK retourne la clé publique au format JWK
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// PublicKey décode une clé publique JWK RSA ou Ed25519, par exemple celle d'un fournisseur d'identité
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(j.N)
+		e, errE := base64.RawURLEncoding.DecodeString(j.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("clé RSA %q mal formée", j.Kid)
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if public.N.BitLen() < MinRSABits {
+			return nil, fmt.Errorf("clé RSA %q trop courte", j.Kid)
+		}
+		return public, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if j.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("clé Ed25519 %q mal formée", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("type de clé non supporté : %q", j.Kty)
+}
+
+// Algorithm retourne l'algorithme de la clé : celui annoncé, sinon celui déduit de son type
+func (j JWK) Algorithm() string {
+	if j.Alg != "" {
+		return j.Alg
+	}
+	if j.Kty == "OKP" {
+		return AlgEdDSA
+	}
+	return AlgRS256
+}
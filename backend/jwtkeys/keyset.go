@@ -0,0 +1,252 @@
+package jwtkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoKey         = errors.New("aucune clé de signature JWT configurée (JWT_KEYS_DIR ou JWT_PRIVATE_KEYS)")
+	ErrNoActiveKey   = errors.New("aucune clé de signature JWT active à cette date")
+	ErrUnknownKey    = errors.New("kid inconnu")
+	ErrAlgorithm     = errors.New("algorithme de signature refusé")
+	ErrNotConfigured = errors.New("clés de signature JWT non chargées")
+)
+
+// validMethods sont les seuls algorithmes acceptés à la vérification
+var validMethods = []string{AlgRS256, AlgEdDSA}
+
+// DefaultReloadInterval est la période de relecture de JWT_KEYS_DIR
+const DefaultReloadInterval = time.Minute
+
+// KeySet regroupe les clés de signature. Toutes vérifient les jetons ; la plus récente dont la
+// date Not-Before est passée signe les nouveaux. Une rotation se programme donc en ajoutant une
+// clé datée dans le futur, et l'ancienne clé se retire une fois ses derniers jetons expirés.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*Key
+	// Répertoire relu par Watch, vide pour des clés fixes
+	dir string
+}
+
+// NewKeySet crée un jeu de clés ; il en faut au moins une et les kid doivent être uniques
+func NewKeySet(keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{}
+	if err := ks.Replace(keys); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Replace remplace les clés du jeu
+func (ks *KeySet) Replace(keys []*Key) error {
+	if len(keys) == 0 {
+		return ErrNoKey
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key.ID] {
+			return fmt.Errorf("kid en double : %q", key.ID)
+		}
+		seen[key.ID] = true
+	}
+
+	sorted := append([]*Key(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].NotBefore.Before(sorted[j].NotBefore) })
+
+	ks.mu.Lock()
+	ks.keys = sorted
+	ks.mu.Unlock()
+	return nil
+}
+
+// Keys retourne les clés, de la plus ancienne à la plus récente
+func (ks *KeySet) Keys() []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return append([]*Key(nil), ks.keys...)
+}
+
+// SigningKey retourne la clé qui signe à l'instant now
+func (ks *KeySet) SigningKey(now time.Time) (*Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		if !ks.keys[i].NotBefore.After(now) {
+			return ks.keys[i], nil
+		}
+	}
+	return nil, ErrNoActiveKey
+}
+
+// key retourne la clé d'identifiant kid
+func (ks *KeySet) key(kid string) (*Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, key := range ks.keys {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Sign signe les claims avec la clé active et indique son kid dans l'en-tête du jeton
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key, err := ks.SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Parse vérifie un jeton : kid connu, algorithme identique à celui de la clé (un jeton HS256
+// signé avec une clé publique est refusé) et expiration obligatoire
+func (ks *KeySet) Parse(tokenString string) (*jwt.Token, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(validMethods), jwt.WithExpirationRequired())
+	return parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.key(kid)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, ErrAlgorithm
+		}
+		return key.Public(), nil
+	})
+}
+
+// JWKS retourne les clés publiques, y compris celles programmées pour une rotation à venir
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range ks.Keys() {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	return set
+}
+
+// loadDir lit les clés de tous les fichiers .pem du répertoire
+func loadDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	var keys []*Key
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", filepath.Base(path), err)
+		}
+		keys = append(keys, parsed...)
+	}
+	return keys, nil
+}
+
+// FromEnv charge les clés de JWT_KEYS_DIR (fichiers .pem, relus par Watch) et de
+// JWT_PRIVATE_KEYS (blocs PEM dans la variable, pour les hébergeurs sans fichiers).
+// Une erreur est retournée si aucune clé n'est configurée ou si aucune n'est active.
+func FromEnv() (*KeySet, error) {
+	ks := &KeySet{dir: os.Getenv("JWT_KEYS_DIR")}
+	keys, err := ks.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Replace(keys); err != nil {
+		return nil, err
+	}
+	if _, err := ks.SigningKey(time.Now()); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// read lit les clés du répertoire et de la variable d'environnement
+func (ks *KeySet) read() ([]*Key, error) {
+	var keys []*Key
+	if ks.dir != "" {
+		fromDir, err := loadDir(ks.dir)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fromDir...)
+	}
+	if inline := os.Getenv("JWT_PRIVATE_KEYS"); inline != "" {
+		fromEnv, err := ParsePEM([]byte(inline))
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEYS : %w", err)
+		}
+		keys = append(keys, fromEnv...)
+	}
+	return keys, nil
+}
+
+// Watch relit les clés à chaque intervalle jusqu'à l'annulation du contexte, pour prendre en
+// compte une clé ajoutée ou retirée sans redémarrer. En cas d'erreur, les clés en place restent.
+func (ks *KeySet) Watch(ctx context.Context, interval time.Duration) {
+	if ks.dir == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			keys, err := ks.read()
+			if err == nil {
+				err = ks.Replace(keys)
+			}
+			if err != nil {
+				log.Println("❌ Relecture des clés JWT :", err)
+			}
+		}
+	}
+}
+
+var current atomic.Pointer[KeySet]
+
+// SetDefault installe le jeu de clés utilisé par Sign et Parse
+func SetDefault(ks *KeySet) {
+	current.Store(ks)
+}
+
+// Default retourne le jeu de clés installé, ou nil
+func Default() *KeySet {
+	return current.Load()
+}
+
+// Sign signe les claims avec le jeu de clés installé
+func Sign(claims jwt.Claims) (string, error) {
+	ks := Default()
+	if ks == nil {
+		return "", ErrNotConfigured
+	}
+	return ks.Sign(claims)
+}
+
+// Parse vérifie un jeton avec le jeu de clés installé
+func Parse(tokenString string) (*jwt.Token, error) {
+	ks := Default()
+	if ks == nil {
+		return nil, ErrNotConfigured
+	}
+	return ks.Parse(tokenString)
+}
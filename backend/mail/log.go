@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+var logSequence atomic.Int64
+
+// LogMailer n'envoie rien : chaque message est écrit dans un fichier .eml de Dir, ou dans les
+// logs si Dir est vide. Pour le développement local et les tests.
+type LogMailer struct {
+	Dir string
+}
+
+// Send écrit le message
+func (m *LogMailer) Send(msg Message) error {
+	if err := checkHeaders(msg.To, msg.Subject); err != nil {
+		return err
+	}
+	if m.Dir == "" {
+		log.Printf("📧 Email pour %s : %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().Format("20060102-150405"), logSequence.Add(1))
+	return os.WriteFile(filepath.Join(m.Dir, name), format("", msg), 0o644)
+}
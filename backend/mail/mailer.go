@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Message est un email texte
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envoie des emails ; l'implémentation dépend de l'environnement
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// ErrNotConfigured est retournée par le mailer par défaut tant que SetDefault n'a pas été appelé
+var ErrNotConfigured = errors.New("envoi des emails non configuré")
+
+// NewFromEnv retourne le mailer choisi par MAIL_DRIVER : "smtp" (valeur par défaut) envoie via
+// SMTP_HOST, "log" écrit les messages dans MAIL_DIR (ou dans les logs) pour le développement
+// local. Sans SMTP_HOST, il faut choisir explicitement "log" : une configuration oubliée ne doit
+// pas faire disparaître les emails sans prévenir.
+func NewFromEnv() (Mailer, error) {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "log":
+		return &LogMailer{Dir: os.Getenv("MAIL_DIR")}, nil
+	case "", "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST manquant (MAIL_DRIVER=log pour écrire les emails dans les logs)")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Addr:     host + ":" + port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER inconnu %q (smtp ou log)", driver)
+	}
+}
+
+type mailerHolder struct {
+	mailer Mailer
+}
+
+var current atomic.Pointer[mailerHolder]
+
+// SetDefault installe le mailer retourné par Default
+func SetDefault(m Mailer) {
+	current.Store(&mailerHolder{mailer: m})
+}
+
+// Default retourne le mailer installé ; sans SetDefault, tout envoi échoue avec ErrNotConfigured
+func Default() Mailer {
+	if holder := current.Load(); holder != nil {
+		return holder.mailer
+	}
+	return notConfigured{}
+}
+
+type notConfigured struct{}
+
+func (notConfigured) Send(Message) error {
+	return ErrNotConfigured
+}
+
+// format construit le message au format RFC 5322
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	// Les accents du sujet sont encodés selon la RFC 2047
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// checkHeaders refuse les retours à la ligne dans les en-têtes (injection d'en-têtes)
+func checkHeaders(values ...string) error {
+	for _, v := range values {
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("en-tête d'email invalide")
+		}
+	}
+	return nil
+}
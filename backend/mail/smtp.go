@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer envoie les emails via un serveur SMTP (STARTTLS si le serveur le propose)
+type SMTPMailer struct {
+	// Adresse du serveur, "hôte:port"
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send envoie le message
+func (m *SMTPMailer) Send(msg Message) error {
+	if err := checkHeaders(m.From, msg.To, msg.Subject); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 
+	"LearningCampusKabre/delivery"
+	"LearningCampusKabre/mail"
 	"LearningCampusKabre/models"
+	"LearningCampusKabre/printing"
 	"LearningCampusKabre/routes"
+	"LearningCampusKabre/scheduling"
+	"LearningCampusKabre/webhooks"
 
 	_ "LearningCampusKabre/docs"
 
@@ -33,6 +39,11 @@ func main() {
 	// Charger .env uniquement en local
 	_ = godotenv.Load()
 
+	// Commande en ligne : création d'une clé de signature JWT, puis arrêt
+	if len(os.Args) > 1 && os.Args[1] == "generate-jwt-key" {
+		os.Exit(runGenerateJWTKey(os.Args[2:]))
+	}
+
 	// Vérifier si on est en production Railway
 	dsn := os.Getenv("DATABASE_URL")
 
@@ -77,14 +88,107 @@ func main() {
 	db.AutoMigrate(
 		&models.Product{},
 		&models.User{},
+		&models.Invitation{},
+		&models.AuthSession{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.MFAChallenge{},
+		&models.OIDCLogin{},
+		&models.Role{},
 		&models.Menu{},
 		&models.MenuItem{},
 		&models.Commande{},
+		&models.TicketCounter{},
+		&models.PickupSlotLock{},
+		&models.CommandeMenu{},
+		&models.CommandeProduct{},
+		&models.CommandeStatusChange{},
+		&models.Device{},
+		&models.Cart{},
+		&models.CartLine{},
+		&models.Station{},
+		&models.CommandeStationTask{},
+		&models.Printer{},
+		&models.PrintJob{},
+		&models.TaxRate{},
+		&models.Promotion{},
+		&models.CommandePromotion{},
+		&models.PriceList{},
+		&models.PriceListItem{},
+		&models.Customer{},
+		&models.LoyaltyLedgerEntry{},
+		&models.Reward{},
+		&models.DeliveryPlatform{},
+		&models.DeliveryProductMapping{},
+		&models.DeliveryStatusPush{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
 	)
 
+	if err := models.DropLegacyPromotionCodeIndex(db); err != nil {
+		log.Fatal("❌ Erreur lors de la migration des codes promo :", err)
+	}
+
+	// Comptes créés avant le hachage systématique : mots de passe en clair hachés au démarrage
+	if count, err := models.RehashPlaintextPasswords(db); err != nil {
+		log.Fatal("❌ Erreur lors du hachage des mots de passe :", err)
+	} else if count > 0 {
+		log.Printf("🔒 %d mot(s) de passe en clair haché(s)", count)
+	}
+
+	// Rôles prédéfinis et leurs permissions, modifiables ensuite par l'administrateur
+	if err := models.SeedRoles(db); err != nil {
+		log.Fatal("❌ Erreur lors de l'initialisation des rôles :", err)
+	}
+
+	// Commande en ligne : création du premier administrateur, puis arrêt
+	if len(os.Args) > 1 && os.Args[1] == "create-admin" {
+		os.Exit(runCreateAdmin(db, os.Args[2:]))
+	}
+	bootstrapAdminFromEnv(db)
+
+	// Clés de signature des JWT : le serveur ne démarre pas sans clé active
+	loadSigningKeys()
+
+	// Envoi des emails : SMTP, ou MAIL_DRIVER=log explicitement en développement
+	mailer, err := mail.NewFromEnv()
+	if err != nil {
+		log.Fatal("❌ Configuration des emails : ", err)
+	}
+	mail.SetDefault(mailer)
+
+	// Taux de TVA par défaut, modifiables ensuite par l'administrateur
+	if err := models.SeedTaxRates(db); err != nil {
+		log.Fatal("❌ Erreur lors de l'initialisation des taux de TVA :", err)
+	}
+
+	// Envoi des tickets aux imprimantes en tâche de fond
+	go printing.NewWorker(db).Run(context.Background())
+
+	// Libération des commandes programmées dans la file de préparation
+	go scheduling.NewScheduler(db).Run(context.Background())
+
+	// Statut des commandes mis en file pour les plateformes de livraison, puis envoyé en tâche de fond
+	models.OnEvent(delivery.Listen)
+	go delivery.NewWorker(db).Run(context.Background())
+
+	// Évènements mis en file pour les abonnés (comptabilité, BI, affichage), puis envoyés en tâche de fond
+	models.OnEvent(models.EnqueueWebhooks)
+	go webhooks.NewWorker(db).Run(context.Background())
+
 	// Gin
 	router := gin.Default()
 
+	// Seuls les reverse proxies listés dans TRUSTED_PROXIES (adresses ou CIDR séparés par des
+	// virgules) peuvent fournir l'adresse du client : sinon un en-tête X-Forwarded-For forgé
+	// contournerait les limites par adresse IP de la connexion
+	if err := router.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+		log.Fatal("❌ TRUSTED_PROXIES invalide : ", err)
+	}
+
 	// Swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -95,10 +199,9 @@ func main() {
 	})
 
 	// Routes
-	routes.SetupProductRoutes(router, db)
-	routes.SetupUserRoutes(router, db)
-	routes.SetupMenuRoutes(router, db)
-	routes.SetupCommandesRoutes(router, db)
+	if err := routes.SetupRoutes(router, db); err != nil {
+		log.Fatal("❌ ", err)
+	}
 
 	// Port Railway ou 8080 en local
 	port := os.Getenv("PORT")
@@ -109,3 +212,14 @@ func main() {
 	log.Println("🚀 Serveur démarré sur http://localhost:" + port)
 	router.Run(":" + port)
 }
+
+// trustedProxiesFromEnv lit TRUSTED_PROXIES ; nil si la variable est vide (aucun proxy de confiance)
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
@@ -2,14 +2,23 @@ package middlewares
 
 import (
 	"net/http"
-	"os"
 	"strings"
+	"time"
+
+	"LearningCampusKabre/jwtkeys"
+	"LearningCampusKabre/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware vérifie le JWT d'accès, puis que le compte existe encore, n'est pas désactivé
+// et que la session du jeton n'a pas été fermée (déconnexion, réutilisation détectée, inactivité).
+// Une session ouverte sur une tablette exige en plus les identifiants de cette tablette.
+// Le rôle, ses permissions et l'obligation de double authentification viennent de la base :
+// un changement de rôle ou de permissions s'applique sans attendre l'expiration du jeton.
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -25,10 +34,8 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SIGNATURE_KEY")), nil
-		})
-
+		// Signature, kid et algorithme vérifiés strictement par le jeu de clés
+		token, err := jwtkeys.Parse(tokenString)
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide"})
 			c.Abort()
@@ -36,9 +43,70 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
+		userID, ok := claims["user_id"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide"})
+			c.Abort()
+			return
+		}
+
+		user, err := models.ActiveUser(db, uint(userID))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Compte supprimé ou désactivé"})
+			c.Abort()
+			return
+		}
+		sessionID, ok := claims["sid"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide"})
+			c.Abort()
+			return
+		}
+		session, err := models.TouchSession(db, uint(sessionID), user.ID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrSessionExpired.Error()})
+			c.Abort()
+			return
+		}
+		if err := VerifySessionDevice(c, db, session); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims["user_id"])
-		c.Set("role", claims["role"])
+		c.Set("session_id", uint(sessionID))
+		permissions, err := models.RolePermissions(db, user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Set("role", string(user.Role))
+		c.Set("permissions", permissions)
+		// Le claim mfa est signé : il atteste que la session a été ouverte avec un second facteur
+		mfa, _ := claims["mfa"].(bool)
+		c.Set("mfa", mfa)
+		c.Set("mfa_required", user.NeedsMFA())
 
 		c.Next()
 	}
 }
+
+// CurrentUserID retourne l'ID de l'utilisateur authentifié, ou 0 si aucun
+func CurrentUserID(c *gin.Context) uint {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0
+	}
+
+	// Les claims JWT sont décodées en float64
+	switch id := value.(type) {
+	case float64:
+		return uint(id)
+	case uint:
+		return id
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DeviceAuthMiddleware authentifie un appareil via les en-têtes X-Device-ID et X-Device-Key.
+// Un appareil n'a pas de JWT : il ne peut donc jamais atteindre les routes protégées par AuthMiddleware.
+func DeviceAuthMiddleware(db *gorm.DB, allowedKinds ...models.DeviceKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.GetHeader("X-Device-ID"), 10, 32)
+		key := c.GetHeader("X-Device-Key")
+		if err != nil || key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Identifiants de l'appareil manquants"})
+			return
+		}
+
+		device, err := models.AuthenticateDevice(db, uint(id), key)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, kind := range allowedKinds {
+			if device.Kind == kind {
+				c.Set("device_id", device.ID)
+				c.Set("device_kind", string(device.Kind))
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Appareil non autorisé"})
+	}
+}
+
+// VerifySessionDevice vérifie qu'une session ouverte sur une tablette est utilisée depuis cette
+// tablette : X-Device-ID et X-Device-Key doivent désigner l'appareil de la session. Un jeton
+// copié hors de la tablette est ainsi inutilisable.
+func VerifySessionDevice(c *gin.Context, db *gorm.DB, session *models.AuthSession) error {
+	if session.DeviceID == nil {
+		return nil
+	}
+	id, err := strconv.ParseUint(c.GetHeader("X-Device-ID"), 10, 32)
+	key := c.GetHeader("X-Device-Key")
+	if err != nil || key == "" || uint(id) != *session.DeviceID {
+		return models.ErrSessionDevice
+	}
+	if _, err := models.AuthenticateDevice(db, uint(id), key); err != nil {
+		return models.ErrSessionDevice
+	}
+	return nil
+}
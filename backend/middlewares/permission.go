@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequirePermission n'autorise que les utilisateurs dont le rôle a toutes les permissions
+// demandées. À placer après AuthMiddleware, qui charge les permissions du rôle.
+func RequirePermission(required ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("permissions"); !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Accès non autorisé"})
+			return
+		}
+
+		for _, permission := range required {
+			if !HasPermission(c, permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Accès interdit : permission " + string(permission) + " requise"})
+				return
+			}
+		}
+
+		if !checkMFA(c) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAnyPermission n'autorise que les utilisateurs dont le rôle a au moins une des permissions
+// demandées, pour les routes dont le handler vérifie ensuite la permission précise
+func RequireAnyPermission(allowed ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("permissions"); !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Accès non autorisé"})
+			return
+		}
+
+		granted := false
+		for _, permission := range allowed {
+			granted = granted || HasPermission(c, permission)
+		}
+		if !granted {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Accès interdit : permission requise"})
+			return
+		}
+
+		if !checkMFA(c) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// checkMFA bloque les comptes soumis à la double authentification (dont tous les administrateurs)
+// tant qu'ils ne présentent pas un jeton émis après vérification du second facteur
+func checkMFA(c *gin.Context) bool {
+	if c.GetBool("mfa_required") && !c.GetBool("mfa") {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Double authentification requise : activez-la puis reconnectez-vous", "mfa_required": true})
+		return false
+	}
+	return true
+}
+
+// HasPermission indique si l'utilisateur authentifié a la permission, pour les contrôles
+// qui dépendent du contenu de la requête
+func HasPermission(c *gin.Context, permission models.Permission) bool {
+	value, _ := c.Get("permissions")
+	permissions, _ := value.([]models.Permission)
+	return models.HasPermission(permissions, permission)
+}
+
+// CanGrantRole indique si l'utilisateur authentifié a toutes les permissions du rôle : on ne peut
+// pas attribuer plus de droits que les siens
+func CanGrantRole(c *gin.Context, db *gorm.DB, role models.UserRole) (bool, error) {
+	permissions, err := models.RolePermissions(db, role)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !HasPermission(c, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
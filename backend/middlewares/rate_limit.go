@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter compte les requêtes par clé sur une fenêtre fixe. Les compteurs sont en mémoire :
+// chaque instance du serveur applique sa propre limite.
+type RateLimiter struct {
+	Limit  int
+	Window time.Duration
+	// Now est remplaçable dans les tests
+	Now func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter autorise limit requêtes par clé et par fenêtre
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{Limit: limit, Window: window, Now: time.Now, windows: map[string]*rateWindow{}}
+}
+
+// Allow compte une requête pour la clé ; si la limite est dépassée, retourne false et le délai
+// avant la fenêtre suivante
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.Window {
+		// Purge des fenêtres expirées pour ne pas accumuler les clés
+		if len(l.windows) > 10000 {
+			for k, old := range l.windows {
+				if now.Sub(old.start) >= l.Window {
+					delete(l.windows, k)
+				}
+			}
+		}
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.Limit {
+		return false, w.start.Add(l.Window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// ByIP identifie le client par son adresse IP
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// RateLimit refuse avec 429 et Retry-After les requêtes au-delà de la limite. key regroupe les
+// requêtes (ByIP le plus souvent) ; chaque route sensible utilise son propre RateLimiter.
+func RateLimit(limiter *RateLimiter, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(key(c))
+		if !allowed {
+			seconds := int(retryAfter.Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Trop de requêtes, réessayez dans " + strconv.Itoa(seconds) + " secondes"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,10 +1,17 @@
 package models
 
-// @Description Données nécessaires pour créer un utilisateur
+// @Description Données nécessaires pour créer son compte à partir d'une invitation
 type RegisterInput struct {
-	Email    string `json:"email" example:"john@example.com"`
-	Password string `json:"password" example:"motdepasse123"`
-	Role     string `json:"role" example:"admin" enums:"admin,preparer,receiver"`
+	Token    string `json:"token" binding:"required" example:"3f2a9c..."`
+	Password string `json:"password" binding:"required" example:"motdepasse123"`
+}
+
+// @Description Données nécessaires pour inviter un membre du staff
+type InvitationInput struct {
+	Email string `json:"email" binding:"required" example:"john@example.com"`
+	Role  string `json:"role" binding:"required" example:"preparer"`
+	// Durée de validité en heures, 72 par défaut
+	ExpiresInHours int `json:"expires_in_hours" example:"72"`
 }
 
 // @Description Données nécessaires pour se connecter
@@ -12,3 +19,15 @@ type LoginInput struct {
 	Email    string `json:"email" example:"john@example.com"`
 	Password string `json:"password" example:"motdepasse123"`
 }
+
+// @Description Choix de son code PIN, confirmé par le mot de passe
+type SetPINInput struct {
+	Password string `json:"password" binding:"required" example:"motdepasse123"`
+	PIN      string `json:"pin" binding:"required" example:"4821"`
+}
+
+// @Description Connexion rapide sur une tablette
+type PINLoginInput struct {
+	UserID uint   `json:"user_id" binding:"required" example:"3"`
+	PIN    string `json:"pin" binding:"required" example:"4821"`
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCartTTL est la durée d'inactivité après laquelle un panier expire
+const DefaultCartTTL = 30 * time.Minute
+
+// CartTTL retourne la durée d'expiration des paniers, configurable via CART_TTL_MINUTES
+func CartTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("CART_TTL_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultCartTTL
+}
+
+// ErrCartCheckedOut est retournée quand le panier a déjà été converti en commande (ou supprimé)
+// par une requête concurrente
+var ErrCartCheckedOut = errors.New("le panier a déjà été converti en commande")
+
+// Cart représente une commande en cours de saisie (brouillon) sur la tablette du receiver
+type Cart struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Lines     []CartLine `json:"lines" gorm:"foreignKey:CartID;constraint:OnDelete:CASCADE"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CartLine représente une ligne (menu ou produit) d'un panier
+type CartLine struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CartID    uint      `json:"cart_id" gorm:"index"`
+	MenuID    uint      `json:"menu_id,omitempty"`
+	ProductID uint      `json:"product_id,omitempty"`
+	Quantity  int       `json:"quantity"`
+	Modifiers []string  `json:"modifiers,omitempty" gorm:"serializer:json"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsExpired indique si le panier a dépassé sa durée d'inactivité
+func (cart *Cart) IsExpired(now time.Time) bool {
+	return now.After(cart.ExpiresAt)
+}
+
+// OrderLines convertit les lignes du panier pour la validation et la création de commande
+func (cart *Cart) OrderLines() []OrderLine {
+	lines := make([]OrderLine, 0, len(cart.Lines))
+	for _, l := range cart.Lines {
+		lines = append(lines, OrderLine{
+			MenuID:    l.MenuID,
+			ProductID: l.ProductID,
+			Quantity:  l.Quantity,
+			Modifiers: l.Modifiers,
+		})
+	}
+	return lines
+}
+
+// CreateCart crée un panier vide pour un utilisateur
+func CreateCart(db *gorm.DB, cart *Cart) error {
+	cart.ExpiresAt = time.Now().Add(CartTTL())
+	return db.Create(cart).Error
+}
+
+// GetCartByID récupère un panier et ses lignes
+func GetCartByID(db *gorm.DB, id uint) (*Cart, error) {
+	var cart Cart
+	err := db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&cart, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("Le panier avec l'Id %d n'a pas été trouvé", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &cart, nil
+}
+
+// TouchCart repousse l'expiration du panier après une modification
+func TouchCart(db *gorm.DB, cart *Cart) error {
+	cart.ExpiresAt = time.Now().Add(CartTTL())
+	return db.Model(cart).Update("expires_at", cart.ExpiresAt).Error
+}
+
+// DeleteCart supprime un panier et ses lignes
+func DeleteCart(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id = ?", id).Delete(&CartLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Cart{}, id).Error
+	})
+}
+
+// PurgeExpiredCarts supprime les paniers expirés et retourne leur nombre
+func PurgeExpiredCarts(db *gorm.DB, now time.Time) (int64, error) {
+	var ids []uint
+	if err := db.Model(&Cart{}).Where("expires_at < ?", now).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id IN ?", ids).Delete(&CartLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&Cart{}).Error
+	})
+	return int64(len(ids)), err
+}
+
+// errCartInvalid annule la transaction d'encaissement d'un panier aux lignes invalides
+var errCartInvalid = errors.New("panier invalide")
+
+// CheckoutCart convertit le panier en commande : la commande est créée et le panier supprimé
+// dans la même transaction, les prix et les promotions étant recalculés à ce moment-là.
+// Le panier est supprimé en premier : la ligne reste verrouillée jusqu'à la fin de la transaction
+// et un encaissement concurrent du même panier ne supprime rien, il est donc refusé.
+func CheckoutCart(db *gorm.DB, cart *Cart, commande *Commande, ctx PricingContext) (*PricedOrder, error) {
+	var order *PricedOrder
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Cart{}, cart.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCartCheckedOut
+		}
+		if err := tx.Where("cart_id = ?", cart.ID).Delete(&CartLine{}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		order, err = PriceOrder(tx, cart.OrderLines(), ctx)
+		if err != nil {
+			return err
+		}
+		if !order.Valid {
+			// Le panier est conservé pour que le client corrige ses lignes
+			return errCartInvalid
+		}
+		return CreateCommandeFromLines(tx, order, commande)
+	})
+	if err == errCartInvalid {
+		err = nil
+	}
+
+	return order, err
+}
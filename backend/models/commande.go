@@ -6,6 +6,7 @@ import (
 
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/plugin/soft_delete"
 )
 
@@ -14,22 +15,83 @@ type StatusType string
 
 // Constantes pour les valeurs possibles
 const (
+	// Commande programmée, hors de la file de préparation jusqu'à sa libération
+	StatusScheduled StatusType = "scheduled"
 	StatusPending   StatusType = "pending"
 	StatusPreparing StatusType = "preparing"
 	StatusReady     StatusType = "ready"
 	StatusDelivered StatusType = "delivered"
 )
 
+// Méthode pour valider si un statut est valide
+func (s StatusType) IsValid() bool {
+	switch s {
+	case StatusScheduled, StatusPending, StatusPreparing, StatusReady, StatusDelivered:
+		return true
+	}
+	return false
+}
+
+// Définition du canal par lequel la commande a été passée
+type ChannelType string
+
+const (
+	ChannelCounter ChannelType = "counter"
+	ChannelKiosk   ChannelType = "kiosk"
+	// Commande reçue d'une plateforme de livraison
+	ChannelDelivery ChannelType = "delivery"
+)
+
+// Méthode pour valider si un canal est valide
+func (c ChannelType) IsValid() bool {
+	switch c {
+	case ChannelCounter, ChannelKiosk, ChannelDelivery:
+		return true
+	}
+	return false
+}
+
+// Définition de l'état du paiement d'une commande
+type PaymentStatusType string
+
+const (
+	PaymentRequired PaymentStatusType = "required"
+	PaymentPaid     PaymentStatusType = "paid"
+)
+
 // Une commande peut être composée de plusieurs menus et produits
 type Commande struct {
-	ID        uint                  `json:"id" gorm:"primaryKey"`
-	Menus     []CommandeMenu        `json:"menus" gorm:"foreignKey:CommandeID"`
-	Products  []CommandeProduct     `json:"products" gorm:"foreignKey:CommandeID"`
-	Status    StatusType            `json:"status" gorm:"not null"`
-	Price     decimal.Decimal       `json:"price" gorm:"type:decimal(10,2);not null"`
-	CreatedAt time.Time             `json:"created_at"`
-	UpdatedAt time.Time             `json:"updated_at"`
-	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	TicketNumber  uint              `json:"ticket_number" gorm:"index"`
+	Menus         []CommandeMenu    `json:"menus" gorm:"foreignKey:CommandeID"`
+	Products      []CommandeProduct `json:"products" gorm:"foreignKey:CommandeID"`
+	Status        StatusType        `json:"status" gorm:"not null"`
+	Channel       ChannelType       `json:"channel" gorm:"type:varchar(20);default:counter" enums:"counter,kiosk,delivery"`
+	PaymentStatus PaymentStatusType `json:"payment_status" gorm:"type:varchar(20);default:paid" enums:"required,paid"`
+	DeviceID      *uint             `json:"device_id,omitempty"`
+	CustomerID    *uint             `json:"customer_id,omitempty" gorm:"index"`
+	// Plateforme de livraison et identifiant de la commande chez elle
+	DeliveryPlatformID *uint               `json:"delivery_platform_id,omitempty" gorm:"uniqueIndex:idx_external_order"`
+	ExternalOrderID    *string             `json:"external_order_id,omitempty" gorm:"type:varchar(100);uniqueIndex:idx_external_order"`
+	ServiceMode        ServiceMode         `json:"service_mode" gorm:"type:varchar(20);default:on_site" enums:"on_site,takeaway"`
+	Price              decimal.Decimal     `json:"price" gorm:"type:decimal(10,2);not null"`
+	DiscountTotal      decimal.Decimal     `json:"discount_total" gorm:"type:decimal(10,2)"`
+	Promotions         []CommandePromotion `json:"promotions,omitempty" gorm:"foreignKey:CommandeID"`
+	NetTotal           decimal.Decimal     `json:"net_total" gorm:"type:decimal(10,2)"`
+	TaxTotal           decimal.Decimal     `json:"tax_total" gorm:"type:decimal(10,2)"`
+	EstimatedReadyAt   *time.Time          `json:"estimated_ready_at"`
+	// Temps de préparation en secondes, enregistré à la première mise à disposition pour les estimations
+	PrepSeconds   *float64               `json:"-"`
+	PickupAt      *time.Time             `json:"pickup_at,omitempty" gorm:"index"`
+	StatusChanges []CommandeStatusChange `json:"status_changes,omitempty" gorm:"foreignKey:CommandeID"`
+	Timings       *CommandeTimings       `json:"timings,omitempty" gorm:"-"`
+	SLABreached   bool                   `json:"sla_breached" gorm:"-"`
+	CreatedAt     time.Time              `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	DeletedAt     soft_delete.DeletedAt  `gorm:"softDelete:milli" swaggerignore:"true"`
+
+	// Statut en base avant une mise à jour, utilisé par les hooks d'historisation
+	previousStatus StatusType
 }
 
 // CreateCommande crée une nouvelle commande
@@ -37,23 +99,85 @@ func CreateCommande(db *gorm.DB, commande *Commande) error {
 	return db.Create(commande).Error
 }
 
+// TicketCounter garde le dernier numéro de ticket attribué pour une journée
+type TicketCounter struct {
+	Day  string `json:"day" gorm:"primaryKey;type:varchar(10)"`
+	Last uint   `json:"last" gorm:"not null;default:0"`
+}
+
+// QueueDay renvoie le jour où la commande passe en file : celui du retrait pour une
+// précommande, celui de sa création sinon
+func QueueDay(commande *Commande, now time.Time) time.Time {
+	if commande.PickupAt != nil {
+		return commande.PickupAt.In(now.Location())
+	}
+	return now
+}
+
+// onQueueDay limite une requête aux commandes dont le jour de file est celui de day
+func onQueueDay(day time.Time) func(*gorm.DB) *gorm.DB {
+	startOfDay := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("COALESCE(pickup_at, created_at) >= ? AND COALESCE(pickup_at, created_at) < ?",
+			startOfDay, startOfDay.AddDate(0, 0, 1))
+	}
+}
+
+// NextTicketNumber attribue le prochain numéro de ticket du jour de file donné (voir QueueDay).
+// Le compteur du jour est incrémenté par un UPDATE atomique : la ligne reste verrouillée jusqu'à
+// la fin de la transaction, deux commandes simultanées ne peuvent donc pas recevoir le même numéro.
+func NextTicketNumber(db *gorm.DB, queueDay time.Time) (uint, error) {
+	day := queueDay.Format("2006-01-02")
+
+	var ticket uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Premier ticket du jour : le compteur part du plus grand numéro déjà attribué, commandes
+		// supprimées comprises, pour ne jamais réattribuer un numéro
+		var last uint
+		if err := tx.Unscoped().Model(&Commande{}).Scopes(onQueueDay(queueDay)).
+			Select("COALESCE(MAX(ticket_number), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&TicketCounter{Day: day, Last: last}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&TicketCounter{}).Where("day = ?", day).Update("last", gorm.Expr("last + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&TicketCounter{}).Where("day = ?", day).Select("last").Scan(&ticket).Error
+	})
+	return ticket, err
+}
+
 // GetAllComm récupère toutes les commandes
 func GetAllComm(db *gorm.DB) ([]Commande, error) {
 	var commandes []Commande
-	err := db.Preload("Menus").Preload("Products").Find(&commandes).Error
+	err := db.Preload("Menus").Preload("Products").Preload("Promotions").Preload("StatusChanges", orderStatusChanges).Find(&commandes).Error
+
+	now, sla := time.Now(), OrderSLA()
+	for i := range commandes {
+		commandes[i].ComputeTimings(now, sla)
+	}
 
 	return commandes, err
 }
 
+// orderStatusChanges trie l'historique des statuts par date lors du préchargement
+func orderStatusChanges(db *gorm.DB) *gorm.DB {
+	return db.Order("changed_at, id")
+}
+
 func GetCommandeById(db *gorm.DB, id uint) (*Commande, error) {
 	var commande Commande
-	err := db.First(&commande, id).Error
+	err := db.Preload("Menus").Preload("Products").Preload("Promotions").Preload("StatusChanges", orderStatusChanges).First(&commande, id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("La commande avec l'Id %d n'a pas été trouvée", id)
 		}
 		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
 	}
+	commande.ComputeTimings(time.Now(), OrderSLA())
 	return &commande, nil
 }
 
@@ -8,14 +8,28 @@ import (
 
 // CommandeProduct représente un produit dans une commande
 type CommandeProduct struct {
-	ID          uint            `json:"id" gorm:"primaryKey"`
-	CommandeID  uint            `json:"commande_id"`
-	ProductID   uint            `json:"product_id"`
-	Name        string          `json:"name"`
-	Price       decimal.Decimal `json:"price"`
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	CommandeID uint            `json:"commande_id"`
+	ProductID  uint            `json:"product_id"`
+	Name       string          `json:"name"`
+	Price      decimal.Decimal `json:"price"`
+	// Prix catalogue au moment de la commande et grille de prix ayant fixé Price
+	BasePrice     decimal.Decimal `json:"base_price" gorm:"type:decimal(10,2)"`
+	PriceListID   *uint           `json:"price_list_id,omitempty"`
+	PriceListName string          `json:"price_list_name,omitempty"`
+	Quantity      int             `json:"quantity" gorm:"default:1"`
+	Modifiers     []string        `json:"modifiers,omitempty" gorm:"serializer:json"`
+	// Récompense fidélité ayant offert ce produit, le cas échéant
+	RewardID    *uint           `json:"reward_id,omitempty"`
+	Discount    decimal.Decimal `json:"discount" gorm:"type:decimal(10,2)"`
 	ImageURL    string          `json:"image_url"`
 	Description string          `json:"description"`
 	Type        TypeProduct     `json:"type"`
+	TaxCategory TaxCategory     `json:"tax_category" gorm:"type:varchar(20);default:food"`
+	TaxRate     decimal.Decimal `json:"tax_rate" gorm:"type:decimal(5,2)"`
+	NetAmount   decimal.Decimal `json:"net_amount" gorm:"type:decimal(10,2)"`
+	TaxAmount   decimal.Decimal `json:"tax_amount" gorm:"type:decimal(10,2)"`
+	GrossAmount decimal.Decimal `json:"gross_amount" gorm:"type:decimal(10,2)"`
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 }
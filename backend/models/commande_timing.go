@@ -0,0 +1,300 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Valeurs par défaut pour le suivi des délais
+const (
+	DefaultOrderSLA           = 10 * time.Minute
+	DefaultPrepTime           = 5 * time.Minute
+	DefaultQueueDelayPerOrder = 1 * time.Minute
+	// Fenêtre d'historique utilisée pour estimer les temps de préparation par produit ;
+	// les moyennes sont calculées en base sur l'index created_at des commandes
+	prepHistoryWindow = 30 * 24 * time.Hour
+)
+
+// durationFromEnv lit une durée en secondes dans une variable d'environnement
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv(name)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return fallback
+}
+
+// OrderSLA retourne le délai maximal entre la création et la mise à disposition, configurable via ORDER_SLA_SECONDS
+func OrderSLA() time.Duration {
+	return durationFromEnv("ORDER_SLA_SECONDS", DefaultOrderSLA)
+}
+
+// CommandeStatusChange enregistre chaque changement de statut d'une commande
+type CommandeStatusChange struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CommandeID uint       `json:"commande_id" gorm:"index"`
+	FromStatus StatusType `json:"from_status"`
+	ToStatus   StatusType `json:"to_status"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+// CommandeTimings regroupe les durées calculées pour une commande (en secondes).
+// Une durée vaut nil tant que l'étape correspondante n'est pas atteinte.
+type CommandeTimings struct {
+	WaitingSeconds     *float64 `json:"waiting_seconds"`
+	PreparationSeconds *float64 `json:"preparation_seconds"`
+	HandoverSeconds    *float64 `json:"handover_seconds"`
+	ElapsedSeconds     float64  `json:"elapsed_seconds"`
+}
+
+// AfterCreate enregistre le statut initial de la commande
+func (c *Commande) AfterCreate(tx *gorm.DB) error {
+	return tx.Create(&CommandeStatusChange{
+		CommandeID: c.ID,
+		ToStatus:   c.Status,
+		ChangedAt:  c.CreatedAt,
+	}).Error
+}
+
+// BeforeUpdate mémorise le statut en base avant la sauvegarde
+func (c *Commande) BeforeUpdate(tx *gorm.DB) error {
+	var previous Commande
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Select("status").
+		First(&previous, c.ID).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	c.previousStatus = previous.Status
+	return nil
+}
+
+// AfterUpdate historise le changement de statut s'il y en a un
+func (c *Commande) AfterUpdate(tx *gorm.DB) error {
+	if c.Status == "" || c.previousStatus == "" || c.Status == c.previousStatus {
+		return nil
+	}
+
+	changedAt := time.Now()
+	err := tx.Create(&CommandeStatusChange{
+		CommandeID: c.ID,
+		FromStatus: c.previousStatus,
+		ToStatus:   c.Status,
+		ChangedAt:  changedAt,
+	}).Error
+	from := c.previousStatus
+	c.previousStatus = c.Status
+	if err != nil {
+		return err
+	}
+	if c.Status == StatusReady {
+		if err := c.recordPrepTime(tx, changedAt); err != nil {
+			return err
+		}
+	}
+	if err := PublishEvent(tx, Event{Type: EventCommandeStatusChanged, Data: c, FromStatus: from}); err != nil {
+		return err
+	}
+
+	// Les points de fidélité sont acquis à la remise de la commande
+	if c.Status == StatusDelivered {
+		return EarnLoyaltyPoints(tx, c.ID)
+	}
+	return nil
+}
+
+// firstChangeTo retourne la date du premier passage au statut donné
+func (c *Commande) firstChangeTo(status StatusType) *time.Time {
+	for _, change := range c.StatusChanges {
+		if change.ToStatus == status {
+			at := change.ChangedAt
+			return &at
+		}
+	}
+	return nil
+}
+
+func secondsBetween(from, to time.Time) *float64 {
+	s := to.Sub(from).Seconds()
+	return &s
+}
+
+// ComputeTimings calcule les durées d'attente, de préparation et de remise ainsi que le dépassement du SLA.
+// Les StatusChanges doivent être préchargés et triés par date.
+func (c *Commande) ComputeTimings(now time.Time, sla time.Duration) {
+	startedAt := c.firstChangeTo(StatusPreparing)
+	readyAt := c.firstChangeTo(StatusReady)
+	deliveredAt := c.firstChangeTo(StatusDelivered)
+
+	timings := &CommandeTimings{}
+
+	// Une commande programmée n'entre dans la file qu'à sa libération
+	if c.Status == StatusScheduled {
+		c.Timings = timings
+		c.SLABreached = false
+		return
+	}
+	queuedAt := c.CreatedAt
+	if released := c.firstChangeTo(StatusPending); released != nil {
+		queuedAt = *released
+	}
+
+	prepStart := queuedAt
+	if startedAt != nil {
+		timings.WaitingSeconds = secondsBetween(queuedAt, *startedAt)
+		prepStart = *startedAt
+	}
+	if readyAt != nil {
+		timings.PreparationSeconds = secondsBetween(prepStart, *readyAt)
+		if deliveredAt != nil {
+			timings.HandoverSeconds = secondsBetween(*readyAt, *deliveredAt)
+		}
+	}
+
+	// Le SLA porte sur le délai entre l'entrée en file et la mise à disposition
+	end := now
+	if readyAt != nil {
+		end = *readyAt
+	} else if deliveredAt != nil {
+		end = *deliveredAt
+	}
+	timings.ElapsedSeconds = end.Sub(queuedAt).Seconds()
+
+	c.Timings = timings
+	c.SLABreached = end.Sub(queuedAt) > sla
+}
+
+// recordPrepTime enregistre le temps de préparation de la commande à sa première mise à disposition :
+// depuis le passage en préparation ou, si cette étape a été sautée, depuis l'entrée en file comme
+// dans ComputeTimings (l'attente d'une commande programmée n'est pas du temps de préparation)
+func (c *Commande) recordPrepTime(tx *gorm.DB, readyAt time.Time) error {
+	var stored Commande
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Select("id", "created_at", "prep_seconds").
+		Preload("StatusChanges", orderStatusChanges).
+		First(&stored, c.ID).Error
+	if err != nil || stored.PrepSeconds != nil {
+		return err
+	}
+
+	start := stored.CreatedAt
+	if released := stored.firstChangeTo(StatusPending); released != nil {
+		start = *released
+	}
+	if started := stored.firstChangeTo(StatusPreparing); started != nil {
+		start = *started
+	}
+	seconds := readyAt.Sub(start).Seconds()
+	err = tx.Session(&gorm.Session{NewDB: true}).Model(&Commande{}).
+		Where("id = ? AND prep_seconds IS NULL", c.ID).
+		UpdateColumn("prep_seconds", seconds).Error
+	if err != nil {
+		return err
+	}
+	// Une sauvegarde ultérieure de la même instance ne doit pas effacer la valeur
+	c.PrepSeconds = &seconds
+	return nil
+}
+
+// averagePrepTimes calcule en base le temps moyen de préparation des commandes récentes contenant
+// chacune des lignes demandées ; table est commande_products ou commande_menus
+func averagePrepTimes(db *gorm.DB, table, column string, ids []uint, since time.Time) (map[uint]time.Duration, error) {
+	averages := map[uint]time.Duration{}
+	if len(ids) == 0 {
+		return averages, nil
+	}
+
+	var rows []struct {
+		ID      uint
+		Seconds float64
+	}
+	err := db.Table(table).
+		Select(table+"."+column+" AS id, AVG(commandes.prep_seconds) AS seconds").
+		Joins("JOIN commandes ON commandes.id = "+table+".commande_id").
+		Where(table+"."+column+" IN ?", ids).
+		Where("commandes.created_at >= ? AND commandes.prep_seconds IS NOT NULL AND commandes.deleted_at = 0", since).
+		Group(table + "." + column).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		averages[row.ID] = time.Duration(row.Seconds * float64(time.Second))
+	}
+	return averages, nil
+}
+
+// EstimateReadyAt estime l'heure à laquelle la commande sera prête :
+// file d'attente actuelle + temps de préparation historique le plus long parmi ses lignes.
+func EstimateReadyAt(db *gorm.DB, commande *Commande, now time.Time) (time.Time, error) {
+	var queue int64
+	err := db.Model(&Commande{}).
+		Where("id <> ? AND status IN ?", commande.ID, []StatusType{StatusPending, StatusPreparing}).
+		Count(&queue).Error
+	if err != nil {
+		return now, err
+	}
+
+	var productIDs, menuIDs []uint
+	for _, p := range commande.Products {
+		productIDs = append(productIDs, p.ProductID)
+	}
+	for _, m := range commande.Menus {
+		menuIDs = append(menuIDs, m.MenuID)
+	}
+	since := now.Add(-prepHistoryWindow)
+	productTimes, err := averagePrepTimes(db, "commande_products", "product_id", productIDs, since)
+	if err != nil {
+		return now, err
+	}
+	menuTimes, err := averagePrepTimes(db, "commande_menus", "menu_id", menuIDs, since)
+	if err != nil {
+		return now, err
+	}
+
+	defaultPrep := durationFromEnv("DEFAULT_PREP_SECONDS", DefaultPrepTime)
+
+	// Les lignes sont préparées en parallèle : c'est la plus longue qui compte
+	var prep time.Duration
+	for _, p := range commande.Products {
+		d, ok := productTimes[p.ProductID]
+		if !ok {
+			d = defaultPrep
+		}
+		if d > prep {
+			prep = d
+		}
+	}
+	for _, m := range commande.Menus {
+		d, ok := menuTimes[m.MenuID]
+		if !ok {
+			d = defaultPrep
+		}
+		if d > prep {
+			prep = d
+		}
+	}
+	if prep == 0 {
+		prep = defaultPrep
+	}
+
+	queueDelay := durationFromEnv("QUEUE_DELAY_PER_ORDER_SECONDS", DefaultQueueDelayPerOrder)
+	return now.Add(time.Duration(queue)*queueDelay + prep), nil
+}
+
+// SetEstimatedReadyAt calcule et enregistre l'heure estimée de mise à disposition ;
+// une commande programmée n'est pas annoncée avant son heure de retrait.
+// Les lignes (Menus, Products) de la commande doivent être chargées.
+func SetEstimatedReadyAt(db *gorm.DB, commande *Commande) error {
+	estimate, err := EstimateReadyAt(db, commande, time.Now())
+	if err != nil {
+		return err
+	}
+	if commande.PickupAt != nil && commande.PickupAt.After(estimate) {
+		estimate = *commande.PickupAt
+	}
+	commande.EstimatedReadyAt = &estimate
+	return db.Model(commande).UpdateColumn("estimated_ready_at", estimate).Error
+}
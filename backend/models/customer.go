@@ -0,0 +1,207 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// Customer est un client final inscrit au programme de fidélité.
+// À ne pas confondre avec User, réservé au personnel.
+type Customer struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	FirstName string  `json:"first_name" example:"Awa"`
+	Phone     *string `json:"phone,omitempty" gorm:"type:varchar(20);uniqueIndex" example:"+33612345678"`
+	Email     *string `json:"email,omitempty" gorm:"type:varchar(255);uniqueIndex" example:"awa@example.com"`
+	// Code encodé dans le QR code de la carte de fidélité
+	LoyaltyCode string `json:"loyalty_code" gorm:"type:varchar(32);uniqueIndex;not null"`
+	// Consentements RGPD : programme de fidélité (obligatoire) et communications commerciales
+	LoyaltyConsent   bool                  `json:"loyalty_consent"`
+	MarketingConsent bool                  `json:"marketing_consent"`
+	ConsentUpdatedAt time.Time             `json:"consent_updated_at"`
+	PointsBalance    int                   `json:"points_balance"`
+	CreatedAt        time.Time             `json:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+	DeletedAt        soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+}
+
+// NormalizePhone ne garde que les chiffres et convertit les numéros français au format international
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if unicode.IsDigit(r) || (i == 0 && r == '+') {
+			b.WriteRune(r)
+		}
+	}
+	normalized := b.String()
+	if len(normalized) == 10 && strings.HasPrefix(normalized, "0") {
+		return "+33" + normalized[1:]
+	}
+	return normalized
+}
+
+// NormalizeEmail met une adresse e-mail en minuscules
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// SetContact valide et enregistre le téléphone et l'e-mail du client ; au moins l'un des deux est requis
+func (c *Customer) SetContact(phone, email string) error {
+	c.Phone, c.Email = nil, nil
+
+	if phone = NormalizePhone(phone); phone != "" {
+		if len(strings.TrimPrefix(phone, "+")) < 8 {
+			return fmt.Errorf("Numéro de téléphone invalide")
+		}
+		c.Phone = &phone
+	}
+	if email = NormalizeEmail(email); email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("Adresse e-mail invalide")
+		}
+		c.Email = &email
+	}
+
+	if c.Phone == nil && c.Email == nil {
+		return fmt.Errorf("Un numéro de téléphone ou une adresse e-mail est obligatoire")
+	}
+	return nil
+}
+
+// SetConsents met à jour les consentements et leur date
+func (c *Customer) SetConsents(loyalty, marketing bool, now time.Time) {
+	if c.ConsentUpdatedAt.IsZero() || c.LoyaltyConsent != loyalty || c.MarketingConsent != marketing {
+		c.ConsentUpdatedAt = now
+	}
+	c.LoyaltyConsent = loyalty
+	c.MarketingConsent = marketing
+}
+
+// generateLoyaltyCode crée un code de carte aléatoire
+func generateLoyaltyCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(raw)), nil
+}
+
+// CreateCustomer inscrit un client et lui attribue un code de carte
+func CreateCustomer(db *gorm.DB, customer *Customer) error {
+	code, err := generateLoyaltyCode()
+	if err != nil {
+		return err
+	}
+	customer.LoyaltyCode = code
+	customer.PointsBalance = 0
+	return db.Create(customer).Error
+}
+
+// GetCustomerByID récupère un client par son ID
+func GetCustomerByID(db *gorm.DB, id uint) (*Customer, error) {
+	var customer Customer
+	err := db.First(&customer, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("Le client avec l'Id %d n'a pas été trouvé", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &customer, nil
+}
+
+// ErrCustomerNotFound est retournée quand aucun client ne correspond à l'identifiant donné
+var ErrCustomerNotFound = errors.New("Aucun client ne correspond")
+
+// CustomerRef identifie un client par l'un de ses identifiants : ID, téléphone, e-mail ou carte
+type CustomerRef struct {
+	ID          uint   `json:"id,omitempty" example:"7"`
+	Phone       string `json:"phone,omitempty" example:"06 12 34 56 78"`
+	Email       string `json:"email,omitempty" example:"awa@example.com"`
+	LoyaltyCode string `json:"loyalty_code,omitempty" example:"3F2A9C0B11D4E5F6"`
+}
+
+// IsEmpty indique qu'aucun identifiant n'a été fourni
+func (ref CustomerRef) IsEmpty() bool {
+	return ref.ID == 0 && strings.TrimSpace(ref.Phone) == "" && strings.TrimSpace(ref.Email) == "" && strings.TrimSpace(ref.LoyaltyCode) == ""
+}
+
+// ResolveCustomer retrouve le client d'une commande ; une référence vide donne nil sans erreur
+func ResolveCustomer(db *gorm.DB, ref *CustomerRef) (*Customer, error) {
+	if ref == nil || ref.IsEmpty() {
+		return nil, nil
+	}
+	return FindCustomer(db, *ref)
+}
+
+// FindCustomer retrouve un client par ID, code de carte (QR code), téléphone ou e-mail
+func FindCustomer(db *gorm.DB, ref CustomerRef) (*Customer, error) {
+	query := db.Model(&Customer{})
+	switch {
+	case ref.ID != 0:
+		query = query.Where("id = ?", ref.ID)
+	case strings.TrimSpace(ref.LoyaltyCode) != "":
+		query = query.Where("loyalty_code = ?", strings.ToUpper(strings.TrimSpace(ref.LoyaltyCode)))
+	case strings.TrimSpace(ref.Phone) != "":
+		query = query.Where("phone = ?", NormalizePhone(ref.Phone))
+	case strings.TrimSpace(ref.Email) != "":
+		query = query.Where("email = ?", NormalizeEmail(ref.Email))
+	default:
+		return nil, fmt.Errorf("Un identifiant client est obligatoire")
+	}
+
+	var customer Customer
+	if err := query.First(&customer).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &customer, nil
+}
+
+// UpdateCustomer met à jour un client existant
+func UpdateCustomer(db *gorm.DB, customer *Customer) error {
+	return db.Omit("points_balance", "loyalty_code").Save(customer).Error
+}
+
+// DeleteCustomer supprime un client ; ses coordonnées sont effacées pour libérer téléphone et e-mail
+func DeleteCustomer(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Customer{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"phone": nil, "email": nil, "first_name": "", "marketing_consent": false}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&Customer{}, id).Error
+	})
+}
+
+// CheckContactAvailable vérifie que le téléphone et l'e-mail ne sont pas déjà utilisés par un autre client
+func CheckContactAvailable(db *gorm.DB, customer *Customer) error {
+	check := func(column string, value *string, label string) error {
+		if value == nil {
+			return nil
+		}
+		var count int64
+		if err := db.Model(&Customer{}).Where(column+" = ? AND id <> ?", *value, customer.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%s est déjà utilisé par un autre client", label)
+		}
+		return nil
+	}
+	if err := check("phone", customer.Phone, "Ce numéro de téléphone"); err != nil {
+		return err
+	}
+	return check("email", customer.Email, "Cette adresse e-mail")
+}
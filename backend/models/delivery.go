@@ -0,0 +1,235 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// DeliveryPlatform est une plateforme de livraison qui nous envoie ses commandes par webhook
+type DeliveryPlatform struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"not null" example:"Uber Eats"`
+	// Format des webhooks et des mises à jour de statut (voir le package delivery)
+	Adapter string `json:"adapter" gorm:"type:varchar(30);not null" example:"ubereats"`
+	// Secret partagé servant à vérifier la signature HMAC des webhooks
+	WebhookSecret string `json:"-" gorm:"not null"`
+	// URL de mise à jour du statut ; {id} est remplacé par l'identifiant de la commande chez la plateforme
+	StatusURL string                `json:"status_url" example:"https://api.example.com/orders/{id}/status"`
+	APIKey    string                `json:"-"`
+	IsActive  bool                  `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+}
+
+// DeliveryProductMapping associe un article de la plateforme à un produit ou un menu du catalogue
+type DeliveryProductMapping struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	PlatformID uint      `json:"platform_id" gorm:"uniqueIndex:idx_delivery_mapping"`
+	ExternalID string    `json:"external_id" gorm:"type:varchar(100);uniqueIndex:idx_delivery_mapping" example:"burger-classic"`
+	ProductID  *uint     `json:"product_id,omitempty" example:"1"`
+	MenuID     *uint     `json:"menu_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Définition de l'état d'une mise à jour de statut à envoyer à une plateforme
+type DeliveryPushStatus string
+
+const (
+	DeliveryPushPending DeliveryPushStatus = "pending"
+	DeliveryPushSent    DeliveryPushStatus = "sent"
+	// Abandonnée après trop d'échecs
+	DeliveryPushDead DeliveryPushStatus = "dead"
+)
+
+// DeliveryStatusPush est un statut de commande à transmettre à sa plateforme. Il est mis en file
+// dans la transaction qui change le statut, puis envoyé par le worker du package delivery.
+type DeliveryStatusPush struct {
+	ID              uint               `json:"id" gorm:"primaryKey"`
+	PlatformID      uint               `json:"platform_id" gorm:"index"`
+	ExternalOrderID string             `json:"external_order_id" gorm:"type:varchar(100)"`
+	OrderStatus     StatusType         `json:"order_status" gorm:"type:varchar(20)"`
+	Status          DeliveryPushStatus `json:"status" gorm:"type:varchar(20);index"`
+	Attempts        int                `json:"attempts"`
+	LastError       string             `json:"last_error"`
+	NextAttemptAt   time.Time          `json:"next_attempt_at" gorm:"index"`
+	SentAt          *time.Time         `json:"sent_at"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// Validate vérifie la cohérence de la plateforme
+func (p *DeliveryPlatform) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("Le nom de la plateforme est obligatoire")
+	}
+	if p.WebhookSecret == "" {
+		return fmt.Errorf("Le secret de signature des webhooks est obligatoire")
+	}
+	if p.StatusURL != "" {
+		if u, err := url.Parse(p.StatusURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("URL de mise à jour du statut invalide")
+		}
+	}
+	return nil
+}
+
+// Validate vérifie qu'une correspondance référence soit un produit, soit un menu
+func (m *DeliveryProductMapping) Validate() error {
+	if m.ExternalID == "" {
+		return fmt.Errorf("L'identifiant de l'article chez la plateforme est obligatoire")
+	}
+	if (m.ProductID == nil) == (m.MenuID == nil) {
+		return fmt.Errorf("Une correspondance doit référencer soit un produit, soit un menu")
+	}
+	return nil
+}
+
+// OrderLine retourne la ligne de commande correspondant à l'article externe
+func (m *DeliveryProductMapping) OrderLine(quantity int, modifiers []string) OrderLine {
+	line := OrderLine{Quantity: quantity, Modifiers: modifiers}
+	if m.MenuID != nil {
+		line.MenuID = *m.MenuID
+	} else {
+		line.ProductID = *m.ProductID
+	}
+	return line
+}
+
+// CreateDeliveryPlatform enregistre une plateforme
+func CreateDeliveryPlatform(db *gorm.DB, platform *DeliveryPlatform) error {
+	return db.Create(platform).Error
+}
+
+// GetAllDeliveryPlatforms récupère toutes les plateformes
+func GetAllDeliveryPlatforms(db *gorm.DB) ([]DeliveryPlatform, error) {
+	var platforms []DeliveryPlatform
+	err := db.Order("id").Find(&platforms).Error
+	return platforms, err
+}
+
+// GetDeliveryPlatformByID récupère une plateforme par son ID
+func GetDeliveryPlatformByID(db *gorm.DB, id uint) (*DeliveryPlatform, error) {
+	var platform DeliveryPlatform
+	err := db.First(&platform, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("La plateforme avec l'Id %d n'a pas été trouvée", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &platform, nil
+}
+
+// UpdateDeliveryPlatform met à jour une plateforme
+func UpdateDeliveryPlatform(db *gorm.DB, platform *DeliveryPlatform) error {
+	return db.Save(platform).Error
+}
+
+// DeleteDeliveryPlatform supprime une plateforme et ses correspondances
+func DeleteDeliveryPlatform(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("platform_id = ?", id).Delete(&DeliveryProductMapping{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&DeliveryPlatform{}, id).Error
+	})
+}
+
+// GetDeliveryMappings récupère les correspondances d'une plateforme
+func GetDeliveryMappings(db *gorm.DB, platformID uint) ([]DeliveryProductMapping, error) {
+	var mappings []DeliveryProductMapping
+	err := db.Where("platform_id = ?", platformID).Order("external_id").Find(&mappings).Error
+	return mappings, err
+}
+
+// FindDeliveryMappings retourne les correspondances des articles externes donnés, indexées par identifiant externe
+func FindDeliveryMappings(db *gorm.DB, platformID uint, externalIDs []string) (map[string]DeliveryProductMapping, error) {
+	var mappings []DeliveryProductMapping
+	err := db.Where("platform_id = ? AND external_id IN ?", platformID, externalIDs).Find(&mappings).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]DeliveryProductMapping, len(mappings))
+	for _, m := range mappings {
+		byID[m.ExternalID] = m
+	}
+	return byID, nil
+}
+
+// SaveDeliveryMapping crée ou remplace la correspondance d'un article externe
+func SaveDeliveryMapping(db *gorm.DB, mapping *DeliveryProductMapping) error {
+	var existing DeliveryProductMapping
+	err := db.Where("platform_id = ? AND external_id = ?", mapping.PlatformID, mapping.ExternalID).First(&existing).Error
+	if err == nil {
+		mapping.ID = existing.ID
+		mapping.CreatedAt = existing.CreatedAt
+		return db.Save(mapping).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return db.Create(mapping).Error
+}
+
+// DeleteDeliveryMapping supprime une correspondance
+func DeleteDeliveryMapping(db *gorm.DB, platformID, id uint) error {
+	return db.Where("platform_id = ?", platformID).Delete(&DeliveryProductMapping{}, id).Error
+}
+
+// FindExternalCommande retrouve une commande déjà reçue d'une plateforme
+func FindExternalCommande(db *gorm.DB, platformID uint, externalID string) (*Commande, error) {
+	var commande Commande
+	err := db.Preload("Menus").Preload("Products").
+		Where("delivery_platform_id = ? AND external_order_id = ?", platformID, externalID).
+		First(&commande).Error
+	if err != nil {
+		return nil, err
+	}
+	return &commande, nil
+}
+
+// EnqueueDeliveryStatusPush met en file le statut d'une commande pour sa plateforme
+func EnqueueDeliveryStatusPush(tx *gorm.DB, platformID uint, externalID string, status StatusType) error {
+	return tx.Create(&DeliveryStatusPush{
+		PlatformID:      platformID,
+		ExternalOrderID: externalID,
+		OrderStatus:     status,
+		Status:          DeliveryPushPending,
+		NextAttemptAt:   time.Now(),
+	}).Error
+}
+
+// GetDueDeliveryStatusPushes récupère les mises à jour de statut arrivées à échéance, dans l'ordre
+// où elles ont été produites
+func GetDueDeliveryStatusPushes(db *gorm.DB, now time.Time, limit int) ([]DeliveryStatusPush, error) {
+	var pushes []DeliveryStatusPush
+	err := db.Where("status = ? AND next_attempt_at <= ?", DeliveryPushPending, now).
+		Order("id").
+		Limit(limit).
+		Find(&pushes).Error
+	return pushes, err
+}
+
+// ClaimDeliveryStatusPush réserve une mise à jour pour un worker jusqu'à now+lease. La mise à jour
+// conditionnelle garantit qu'un seul worker l'obtient ; si ce worker s'arrête avant d'enregistrer
+// le résultat, elle redevient disponible à la fin du bail.
+func ClaimDeliveryStatusPush(db *gorm.DB, push *DeliveryStatusPush, now time.Time, lease time.Duration) (bool, error) {
+	leaseUntil := now.Add(lease)
+	result := db.Model(&DeliveryStatusPush{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", push.ID, DeliveryPushPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	push.NextAttemptAt = leaseUntil
+	return true, nil
+}
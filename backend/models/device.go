@@ -0,0 +1,102 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// Définition du type d'appareil
+type DeviceKind string
+
+// Constantes pour les valeurs possibles
+const (
+	DeviceKiosk DeviceKind = "kiosk"
+	DeviceBoard DeviceKind = "board"
+	// Tablette partagée par le staff, qui s'y connecte avec son code PIN
+	DeviceTablet DeviceKind = "tablet"
+)
+
+// Méthode pour valider si un type d'appareil est valide
+func (k DeviceKind) IsValid() bool {
+	switch k {
+	case DeviceKiosk, DeviceBoard, DeviceTablet:
+		return true
+	}
+	return false
+}
+
+// Device représente un appareil (borne, écran...) authentifié par une clé et non par un compte staff
+type Device struct {
+	ID         uint                  `json:"id" gorm:"primaryKey" example:"1"`
+	Name       string                `json:"name" gorm:"not null" example:"Borne entrée"`
+	Kind       DeviceKind            `json:"kind" gorm:"type:varchar(20);not null" example:"kiosk" enums:"kiosk,board,tablet"`
+	KeyHash    string                `json:"-" gorm:"not null"`
+	IsActive   bool                  `json:"is_active" gorm:"default:true" example:"true"`
+	LastSeenAt *time.Time            `json:"last_seen_at"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	DeletedAt  soft_delete.DeletedAt `gorm:"softDelete:milli" json:"-"`
+}
+
+// hashDeviceKey hache la clé d'un appareil. La clé étant aléatoire et longue, un SHA-256 suffit.
+func hashDeviceKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateDevice crée un appareil et retourne sa clé en clair (elle n'est plus récupérable ensuite)
+func CreateDevice(db *gorm.DB, device *Device) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(raw)
+
+	device.KeyHash = hashDeviceKey(key)
+	if err := db.Create(device).Error; err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// GetAllDevices récupère tous les appareils
+func GetAllDevices(db *gorm.DB) ([]Device, error) {
+	var devices []Device
+	err := db.Find(&devices).Error
+	return devices, err
+}
+
+// AuthenticateDevice vérifie l'identifiant et la clé d'un appareil actif
+func AuthenticateDevice(db *gorm.DB, id uint, key string) (*Device, error) {
+	var device Device
+	if err := db.Where("id = ? AND is_active = ?", id, true).First(&device).Error; err != nil {
+		return nil, fmt.Errorf("Appareil inconnu ou désactivé")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(device.KeyHash), []byte(hashDeviceKey(key))) != 1 {
+		return nil, fmt.Errorf("Clé d'appareil invalide")
+	}
+
+	now := time.Now()
+	db.Model(&device).UpdateColumn("last_seen_at", now)
+	device.LastSeenAt = &now
+
+	return &device, nil
+}
+
+// DeleteDevice révoque un appareil et ferme les sessions ouvertes dessus
+func DeleteDevice(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&Device{}, id).Error; err != nil {
+			return err
+		}
+		return RevokeDeviceSessions(tx, id, 0)
+	})
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Définition du type d'évènement publié par les modèles
+type EventType string
+
+const (
+	EventCommandeCreated       EventType = "commande.created"
+	EventCommandeStatusChanged EventType = "commande.status_changed"
+	EventProductCreated        EventType = "product.created"
+	EventProductUpdated        EventType = "product.updated"
+	EventProductDeleted        EventType = "product.deleted"
+	EventMenuCreated           EventType = "menu.created"
+	EventMenuUpdated           EventType = "menu.updated"
+	EventMenuDeleted           EventType = "menu.deleted"
+)
+
+// EventTypes liste tous les évènements publiés
+var EventTypes = []EventType{
+	EventCommandeCreated, EventCommandeStatusChanged,
+	EventProductCreated, EventProductUpdated, EventProductDeleted,
+	EventMenuCreated, EventMenuUpdated, EventMenuDeleted,
+}
+
+// Méthode pour valider si un type d'évènement est valide
+func (e EventType) IsValid() bool {
+	for _, t := range EventTypes {
+		if t == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Event est un évènement métier : création ou changement de statut d'une commande, modification du catalogue
+type Event struct {
+	Type EventType
+	// Objet concerné : *Commande, *Product ou *Menu
+	Data interface{}
+	// Statut précédent, pour commande.status_changed
+	FromStatus StatusType
+}
+
+// EventListener est appelé dans la transaction qui a produit l'évènement ; une erreur l'annule.
+// Il ne doit pas bloquer : les appels réseau se font en tâche de fond.
+type EventListener func(tx *gorm.DB, event Event) error
+
+type registeredListener struct {
+	id       int
+	listener EventListener
+}
+
+var (
+	eventListenersMu sync.RWMutex
+	eventListeners   []registeredListener
+	nextListenerID   int
+)
+
+// OnEvent enregistre un listener, généralement au démarrage de l'application, et retourne
+// la fonction qui le retire
+func OnEvent(listener EventListener) func() {
+	eventListenersMu.Lock()
+	defer eventListenersMu.Unlock()
+	nextListenerID++
+	id := nextListenerID
+	eventListeners = append(eventListeners, registeredListener{id: id, listener: listener})
+
+	return func() {
+		eventListenersMu.Lock()
+		defer eventListenersMu.Unlock()
+		for i, l := range eventListeners {
+			if l.id == id {
+				eventListeners = append(eventListeners[:i:i], eventListeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// PublishEvent prévient les listeners enregistrés
+func PublishEvent(tx *gorm.DB, event Event) error {
+	eventListenersMu.RLock()
+	listeners := make([]registeredListener, len(eventListeners))
+	copy(listeners, eventListeners)
+	eventListenersMu.RUnlock()
+
+	// Les listeners travaillent sur une session neuve, sans le modèle de l'opération en cours
+	session := tx.Session(&gorm.Session{NewDB: true})
+	for _, l := range listeners {
+		if err := l.listener(session, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Durée de validité par défaut d'une invitation
+const DefaultInvitationTTL = 72 * time.Hour
+
+// ErrInvitationInvalid est retournée pour une invitation inconnue, expirée, révoquée ou déjà utilisée
+var ErrInvitationInvalid = errors.New("invitation invalide ou expirée")
+
+// ErrEmailTaken est retournée quand un compte existe déjà pour cet email
+var ErrEmailTaken = errors.New("L'email existe déjà")
+
+// Invitation permet à un nouveau membre du staff de créer son compte, avec le rôle choisi par l'administrateur.
+// Le jeton n'est conservé que haché et ne sert qu'une fois.
+type Invitation struct {
+	ID          uint       `json:"id" gorm:"primaryKey" example:"1"`
+	Email       string     `json:"email" gorm:"not null;index" example:"john@example.com"`
+	Role        UserRole   `json:"role" gorm:"type:varchar(20);not null" example:"preparer"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID *uint      `json:"created_by_id"`
+	// Compte créé avec l'invitation
+	UserID    *uint     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// newSecretToken génère un jeton aléatoire et son empreinte, seule conservée en base
+func newSecretToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(raw)
+	return token, hashSecretToken(token), nil
+}
+
+// hashSecretToken hache un jeton aléatoire. Le jeton étant long, un SHA-256 suffit.
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// emailTaken indique si un compte existe déjà pour cet email
+func emailTaken(db *gorm.DB, email string) (bool, error) {
+	var count int64
+	err := db.Model(&User{}).Where("LOWER(email) = ?", strings.ToLower(email)).Count(&count).Error
+	return count > 0, err
+}
+
+// Validate vérifie la cohérence de l'invitation
+func (i *Invitation) Validate() error {
+	if !strings.Contains(i.Email, "@") {
+		return fmt.Errorf("Email invalide")
+	}
+	if !roleNamePattern.MatchString(string(i.Role)) {
+		return fmt.Errorf("Rôle utilisateur invalide")
+	}
+	return nil
+}
+
+// CreateInvitation enregistre une invitation valable ttl et retourne le jeton en clair
+// (il n'est plus récupérable ensuite)
+func CreateInvitation(db *gorm.DB, invitation *Invitation, ttl time.Duration) (string, error) {
+	if err := invitation.Validate(); err != nil {
+		return "", err
+	}
+	if err := checkRoleExists(db, invitation.Role); err != nil {
+		return "", err
+	}
+	taken, err := emailTaken(db, invitation.Email)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrEmailTaken
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	invitation.TokenHash = hash
+	invitation.ExpiresAt = time.Now().Add(ttl)
+	if err := db.Create(invitation).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetAllInvitations récupère les invitations, les plus récentes d'abord
+func GetAllInvitations(db *gorm.DB) ([]Invitation, error) {
+	var invitations []Invitation
+	err := db.Order("created_at DESC, id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// RevokeInvitation rend une invitation non utilisée inutilisable
+func RevokeInvitation(db *gorm.DB, id uint) error {
+	result := db.Model(&Invitation{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("L'invitation avec l'Id %d n'existe pas ou a déjà été utilisée", id)
+	}
+	return nil
+}
+
+// RedeemInvitation crée le compte associé à l'invitation avec le mot de passe choisi.
+// Le rôle et l'email sont ceux fixés par l'administrateur.
+func RedeemInvitation(db *gorm.DB, token string, password string) (*User, error) {
+	var user *User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var invitation Invitation
+		if err := tx.Where("token_hash = ?", hashSecretToken(token)).First(&invitation).Error; err != nil {
+			return ErrInvitationInvalid
+		}
+		now := time.Now()
+		if invitation.UsedAt != nil || invitation.RevokedAt != nil || now.After(invitation.ExpiresAt) {
+			return ErrInvitationInvalid
+		}
+
+		user = &User{Email: invitation.Email, Role: invitation.Role}
+		if err := CreateUser(tx, user, password); err != nil {
+			return err
+		}
+
+		// La condition sur used_at garantit l'usage unique face à deux requêtes simultanées
+		result := tx.Model(&Invitation{}).
+			Where("id = ? AND used_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"used_at": now, "user_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// BootstrapAdmin crée le premier administrateur si aucun n'existe encore. Retourne false sans
+// rien modifier quand un administrateur est déjà présent.
+func BootstrapAdmin(db *gorm.DB, email string, password string) (bool, error) {
+	var count int64
+	if err := db.Model(&User{}).Where("role = ?", RoleAdmin).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	if err := CreateUser(db, &User{Email: email, Role: RoleAdmin, Description: "Administrateur initial"}, password); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Politique de protection contre les attaques par force brute sur la connexion
+const (
+	// Échecs consécutifs avant verrouillage du compte
+	MaxFailedLogins = 5
+	LockoutDuration = 15 * time.Minute
+	// À partir de ce nombre d'échecs, chaque tentative doit attendre un délai qui double (1 s, 2 s, 4 s...)
+	FailedLoginsBeforeDelay = 2
+	MaxLoginDelay           = 30 * time.Second
+	// Échecs acceptés depuis une même adresse IP, tous comptes confondus, sur la fenêtre
+	MaxFailedLoginsPerIP = 20
+	FailedLoginIPWindow  = 15 * time.Minute
+)
+
+// LoginBlockedError est retournée quand une tentative est refusée sans vérifier le mot de passe
+type LoginBlockedError struct {
+	// Le compte est verrouillé (et non simplement ralenti)
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	seconds := int(e.RetryAfter.Round(time.Second).Seconds())
+	if e.Locked {
+		return fmt.Sprintf("Compte temporairement verrouillé, réessayez dans %d secondes", seconds)
+	}
+	return fmt.Sprintf("Trop de tentatives, réessayez dans %d secondes", seconds)
+}
+
+// LoginAttempt trace une tentative de connexion, réussie ou non
+type LoginAttempt struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Email   string `json:"email" gorm:"index"`
+	UserID  *uint  `json:"user_id" gorm:"index"`
+	IP      string `json:"ip" gorm:"index"`
+	Success bool   `json:"success"`
+	// Motif du refus : invalid_credentials, locked, throttled, ip_blocked, invalid_pin, pin_disabled,
+	// disabled, oidc_no_role, oidc_email_conflict
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// loginDelay retourne le délai imposé après failures échecs consécutifs
+func loginDelay(failures int) time.Duration {
+	if failures < FailedLoginsBeforeDelay {
+		return 0
+	}
+	delay := time.Second << (failures - FailedLoginsBeforeDelay)
+	return min(delay, MaxLoginDelay)
+}
+
+func recordLoginAttempt(db *gorm.DB, attempt LoginAttempt) error {
+	return db.Create(&attempt).Error
+}
+
+// AuthenticateLogin vérifie les identifiants en appliquant les limites par compte et par adresse IP.
+// Chaque tentative est journalisée. Un refus avant vérification retourne un *LoginBlockedError.
+func AuthenticateLogin(db *gorm.DB, email string, password string, ip string, now time.Time) (*User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	attempt := LoginAttempt{Email: email, IP: ip, CreatedAt: now}
+
+	var ipFailures int64
+	since := now.Add(-FailedLoginIPWindow)
+	if err := db.Model(&LoginAttempt{}).Where("ip = ? AND success = ? AND created_at > ?", ip, false, since).Count(&ipFailures).Error; err != nil {
+		return nil, err
+	}
+	if ipFailures >= MaxFailedLoginsPerIP {
+		attempt.Reason = "ip_blocked"
+		recordLoginAttempt(db, attempt)
+		return nil, &LoginBlockedError{RetryAfter: FailedLoginIPWindow}
+	}
+
+	var user User
+	err := db.Where("LOWER(email) = ?", email).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		// Même coût qu'une vraie vérification : la durée de la réponse ne révèle pas si le compte existe
+		compareDummyPassword(password)
+		attempt.Reason = "invalid_credentials"
+		if err := recordLoginAttempt(db, attempt); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	attempt.UserID = &user.ID
+
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		attempt.Reason = "locked"
+		recordLoginAttempt(db, attempt)
+		return nil, &LoginBlockedError{Locked: true, RetryAfter: user.LockedUntil.Sub(now)}
+	}
+	if user.LastFailedLoginAt != nil {
+		if next := user.LastFailedLoginAt.Add(loginDelay(user.FailedLogins)); now.Before(next) {
+			attempt.Reason = "throttled"
+			recordLoginAttempt(db, attempt)
+			return nil, &LoginBlockedError{RetryAfter: next.Sub(now)}
+		}
+	}
+
+	if !user.CheckPassword(password) {
+		attempt.Reason = "invalid_credentials"
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Incrément en base : des tentatives simultanées comptent toutes, et la décision de
+			// verrouiller se prend sur la nouvelle valeur, pas sur celle lue avant la vérification
+			err := tx.Model(&User{}).Where("id = ?", user.ID).
+				UpdateColumns(map[string]interface{}{"failed_logins": gorm.Expr("failed_logins + 1"), "last_failed_login_at": now}).Error
+			if err != nil {
+				return err
+			}
+			var failures int
+			if err := tx.Model(&User{}).Where("id = ?", user.ID).Select("failed_logins").Scan(&failures).Error; err != nil {
+				return err
+			}
+			if failures >= MaxFailedLogins {
+				// Le compteur repart de zéro : après le verrouillage, le compte a de nouveau droit à quelques essais
+				err := tx.Model(&User{}).Where("id = ?", user.ID).
+					UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": now.Add(LockoutDuration)}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return recordLoginAttempt(tx, attempt)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	attempt.Success = true
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if user.FailedLogins > 0 || user.LockedUntil != nil || user.LastFailedLoginAt != nil {
+			err := tx.Model(&User{}).Where("id = ?", user.ID).
+				UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": nil, "last_failed_login_at": nil}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return recordLoginAttempt(tx, attempt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.FailedLogins, user.LockedUntil, user.LastFailedLoginAt = 0, nil, nil
+	return &user, nil
+}
+
+// UnlockUser lève le verrouillage d'un compte et remet ses compteurs à zéro
+func UnlockUser(db *gorm.DB, id uint) (*User, error) {
+	user, err := GetUserByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(user).UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": nil, "last_failed_login_at": nil}).Error
+	if err != nil {
+		return nil, err
+	}
+	user.FailedLogins, user.LockedUntil, user.LastFailedLoginAt = 0, nil, nil
+	return user, nil
+}
+
+// LoginAttemptFilter restreint le journal des tentatives
+type LoginAttemptFilter struct {
+	Email  string
+	IP     string
+	UserID uint
+	// Uniquement les échecs
+	FailedOnly bool
+	Limit      int
+}
+
+// GetLoginAttempts récupère le journal des tentatives, les plus récentes d'abord
+func GetLoginAttempts(db *gorm.DB, filter LoginAttemptFilter) ([]LoginAttempt, error) {
+	query := db.Order("created_at DESC, id DESC")
+	if filter.Email != "" {
+		query = query.Where("email = ?", strings.ToLower(filter.Email))
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.FailedOnly {
+		query = query.Where("success = ?", false)
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	var attempts []LoginAttempt
+	err := query.Limit(limit).Find(&attempts).Error
+	return attempts, err
+}
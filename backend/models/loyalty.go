@@ -0,0 +1,246 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// DefaultPointsPerEuro est le nombre de points gagnés par euro payé
+const DefaultPointsPerEuro = 1
+
+// PointsPerEuro retourne le taux de gain, configurable via LOYALTY_POINTS_PER_EURO
+func PointsPerEuro() decimal.Decimal {
+	if rate, err := decimal.NewFromString(os.Getenv("LOYALTY_POINTS_PER_EURO")); err == nil && rate.IsPositive() {
+		return rate
+	}
+	return decimal.NewFromInt(DefaultPointsPerEuro)
+}
+
+// ErrNotEnoughPoints est retournée quand le solde ne couvre pas la récompense
+var ErrNotEnoughPoints = errors.New("solde de points insuffisant")
+
+// Définition du type de mouvement de points
+type LedgerKind string
+
+const (
+	LedgerEarn       LedgerKind = "earn"
+	LedgerRedeem     LedgerKind = "redeem"
+	LedgerAdjustment LedgerKind = "adjustment"
+)
+
+// LoyaltyLedgerEntry est un mouvement de points ; le solde du client est la somme de ses mouvements
+type LoyaltyLedgerEntry struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	CustomerID  uint       `json:"customer_id" gorm:"index;not null"`
+	Kind        LedgerKind `json:"kind" gorm:"type:varchar(20);not null" enums:"earn,redeem,adjustment"`
+	Points      int        `json:"points"`
+	CommandeID  *uint      `json:"commande_id,omitempty" gorm:"index"`
+	RewardID    *uint      `json:"reward_id,omitempty"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Reward est un produit offert en échange de points
+type Reward struct {
+	ID         uint                  `json:"id" gorm:"primaryKey"`
+	Name       string                `json:"name" gorm:"not null" example:"Dessert offert"`
+	ProductID  uint                  `json:"product_id" gorm:"not null" example:"4"`
+	PointsCost int                   `json:"points_cost" gorm:"not null" example:"50"`
+	IsActive   bool                  `json:"is_active" gorm:"default:true"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	DeletedAt  soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+}
+
+// addPoints enregistre un mouvement et met à jour le solde dans la même transaction.
+// Un débit n'est appliqué que si le solde le permet.
+func addPoints(tx *gorm.DB, entry *LoyaltyLedgerEntry) error {
+	query := tx.Model(&Customer{}).Where("id = ?", entry.CustomerID)
+	if entry.Points < 0 {
+		query = query.Where("points_balance >= ?", -entry.Points)
+	}
+	res := query.UpdateColumn("points_balance", gorm.Expr("points_balance + ?", entry.Points))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotEnoughPoints
+	}
+	return tx.Create(entry).Error
+}
+
+// EarnLoyaltyPoints crédite les points d'une commande livrée rattachée à un client qui a
+// consenti au programme. L'opération est idempotente : une commande ne rapporte qu'une fois.
+func EarnLoyaltyPoints(tx *gorm.DB, commandeID uint) error {
+	var commande Commande
+	if err := tx.Select("id", "customer_id", "price", "ticket_number").First(&commande, commandeID).Error; err != nil {
+		return err
+	}
+	if commande.CustomerID == nil {
+		return nil
+	}
+
+	var customer Customer
+	if err := tx.First(&customer, *commande.CustomerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	if !customer.LoyaltyConsent {
+		return nil
+	}
+
+	var already int64
+	if err := tx.Model(&LoyaltyLedgerEntry{}).Where("commande_id = ? AND kind = ?", commande.ID, LedgerEarn).Count(&already).Error; err != nil {
+		return err
+	}
+	if already > 0 {
+		return nil
+	}
+
+	points := int(commande.Price.Mul(PointsPerEuro()).Floor().IntPart())
+	if points <= 0 {
+		return nil
+	}
+
+	return addPoints(tx, &LoyaltyLedgerEntry{
+		CustomerID:  customer.ID,
+		Kind:        LedgerEarn,
+		Points:      points,
+		CommandeID:  &commande.ID,
+		Description: fmt.Sprintf("Commande n° %d", commande.TicketNumber),
+	})
+}
+
+// RedeemReward débite les points du client et ajoute le produit offert à une commande en attente,
+// puis recalcule ses totaux. La ligne offerte porte RewardID : elle est conservée quand la commande est modifiée.
+func RedeemReward(db *gorm.DB, customer *Customer, reward *Reward, commande *Commande) (*LoyaltyLedgerEntry, error) {
+	if !reward.IsActive {
+		return nil, fmt.Errorf("Cette récompense n'est plus disponible")
+	}
+	if !customer.LoyaltyConsent {
+		return nil, fmt.Errorf("Le client n'a pas adhéré au programme de fidélité")
+	}
+	if commande.Status != StatusPending {
+		return nil, fmt.Errorf("La récompense ne peut être ajoutée qu'à une commande en attente")
+	}
+
+	var product Product
+	if err := db.First(&product, reward.ProductID).Error; err != nil {
+		return nil, fmt.Errorf("Le produit offert n'existe plus")
+	}
+	if !product.IsAvailable {
+		return nil, fmt.Errorf("Le produit %s n'est plus disponible", product.Name)
+	}
+
+	entry := &LoyaltyLedgerEntry{
+		CustomerID:  customer.ID,
+		Kind:        LedgerRedeem,
+		Points:      -reward.PointsCost,
+		CommandeID:  &commande.ID,
+		RewardID:    &reward.ID,
+		Description: reward.Name,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := addPoints(tx, entry); err != nil {
+			return err
+		}
+
+		// Le client est rattaché à la commande s'il ne l'était pas encore
+		if commande.CustomerID == nil {
+			if err := tx.Model(commande).UpdateColumn("customer_id", customer.ID).Error; err != nil {
+				return err
+			}
+			commande.CustomerID = &customer.ID
+		}
+
+		if err := tx.Create(&CommandeProduct{
+			CommandeID:  commande.ID,
+			ProductID:   product.ID,
+			Name:        product.Name,
+			Price:       decimal.Zero,
+			BasePrice:   product.Price,
+			Quantity:    1,
+			Modifiers:   []string{"Récompense fidélité : " + reward.Name},
+			RewardID:    &reward.ID,
+			ImageURL:    product.ImageURL,
+			Description: product.Description,
+			Type:        product.Type,
+			TaxCategory: product.TaxCategory,
+		}).Error; err != nil {
+			return err
+		}
+
+		// La ligne offerte entre dans la ventilation de TVA et les totaux de la commande
+		if err := tx.Preload("Menus").Preload("Products").First(commande, commande.ID).Error; err != nil {
+			return err
+		}
+		return ApplyTaxes(tx, commande)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	customer.PointsBalance -= reward.PointsCost
+	return entry, nil
+}
+
+// AdjustPoints applique une correction manuelle du solde
+func AdjustPoints(db *gorm.DB, customer *Customer, points int, description string) (*LoyaltyLedgerEntry, error) {
+	entry := &LoyaltyLedgerEntry{CustomerID: customer.ID, Kind: LedgerAdjustment, Points: points, Description: description}
+	if err := db.Transaction(func(tx *gorm.DB) error { return addPoints(tx, entry) }); err != nil {
+		return nil, err
+	}
+	customer.PointsBalance += points
+	return entry, nil
+}
+
+// GetLoyaltyLedger retourne les mouvements d'un client, du plus récent au plus ancien
+func GetLoyaltyLedger(db *gorm.DB, customerID uint) ([]LoyaltyLedgerEntry, error) {
+	var entries []LoyaltyLedgerEntry
+	err := db.Where("customer_id = ?", customerID).Order("created_at DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// CreateReward crée une récompense
+func CreateReward(db *gorm.DB, reward *Reward) error {
+	return db.Create(reward).Error
+}
+
+// GetAllRewards récupère les récompenses
+func GetAllRewards(db *gorm.DB) ([]Reward, error) {
+	var rewards []Reward
+	err := db.Order("points_cost").Find(&rewards).Error
+	return rewards, err
+}
+
+// GetRewardByID récupère une récompense par son ID
+func GetRewardByID(db *gorm.DB, id uint) (*Reward, error) {
+	var reward Reward
+	err := db.First(&reward, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("La récompense avec l'Id %d n'a pas été trouvée", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &reward, nil
+}
+
+// UpdateReward met à jour une récompense
+func UpdateReward(db *gorm.DB, reward *Reward) error {
+	return db.Save(reward).Error
+}
+
+// DeleteReward supprime une récompense
+func DeleteReward(db *gorm.DB, id uint) error {
+	return db.Delete(&Reward{}, id).Error
+}
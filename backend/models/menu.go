@@ -17,6 +17,7 @@ type Menu struct {
 	Price       decimal.Decimal       `json:"price" gorm:"not null"`
 	ImageURL    string                `json:"image_url"`
 	Description string                `json:"description" gorm:"type:text"`
+	TaxCategory TaxCategory           `json:"tax_category" gorm:"type:varchar(20);default:food" enums:"food,soft_drink,alcohol"`
 	MenuItems   []MenuItem            `json:"menu_items" gorm:"foreignKey:MenuID"`
 	CreatedAt   time.Time             `json:"created_at"`
 	UpdatedAt   time.Time             `json:"updated_at"`
@@ -55,5 +56,20 @@ func UpdateMenu(db *gorm.DB, menu *Menu) error {
 
 // DeleteMenu supprime un menu
 func DeleteMenu(db *gorm.DB, id uint) error {
-	return db.Delete(&Menu{}, id).Error
+	return db.Delete(&Menu{ID: id}).Error
+}
+
+// AfterCreate publie la création du menu
+func (m *Menu) AfterCreate(tx *gorm.DB) error {
+	return PublishEvent(tx, Event{Type: EventMenuCreated, Data: m})
+}
+
+// AfterUpdate publie la modification du menu
+func (m *Menu) AfterUpdate(tx *gorm.DB) error {
+	return PublishEvent(tx, Event{Type: EventMenuUpdated, Data: m})
+}
+
+// AfterDelete publie la suppression du menu
+func (m *Menu) AfterDelete(tx *gorm.DB) error {
+	return PublishEvent(tx, Event{Type: EventMenuDeleted, Data: m})
 }
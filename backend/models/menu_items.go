@@ -10,8 +10,10 @@ import (
 
 // MenuItem represents a menu item entity
 type MenuItem struct {
-	ID          uint            `json:"id" gorm:"primaryKey"`
-	MenuID      uint            `json:"menu_id"`
+	ID     uint `json:"id" gorm:"primaryKey"`
+	MenuID uint `json:"menu_id"`
+	// Produit d'origine, dont la disponibilité conditionne celle du menu (0 pour les anciens menus)
+	ProductID   uint            `json:"product_id" gorm:"index"`
 	Name        string          `json:"name"`
 	Price       decimal.Decimal `json:"price"`
 	ImageURL    string          `json:"image_url"`
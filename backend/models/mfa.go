@@ -0,0 +1,308 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"LearningCampusKabre/totp"
+
+	"gorm.io/gorm"
+)
+
+// Réglages de la double authentification
+const (
+	RecoveryCodeCount = 10
+	MFAChallengeTTL   = 5 * time.Minute
+	// Codes faux acceptés sur un même challenge avant de devoir se reconnecter
+	MaxMFAChallengeAttempts = 5
+)
+
+var (
+	// ErrInvalidMFACode est retournée pour un code TOTP ou de secours incorrect
+	ErrInvalidMFACode = errors.New("code de vérification incorrect")
+	// ErrMFAChallengeInvalid est retournée pour un challenge inconnu, expiré ou épuisé
+	ErrMFAChallengeInvalid = errors.New("challenge de double authentification invalide ou expiré, reconnectez-vous")
+	// ErrMFAAlreadyEnabled et ErrMFANotEnabled signalent un état incompatible avec l'opération
+	ErrMFAAlreadyEnabled = errors.New("la double authentification est déjà activée")
+	ErrMFANotEnabled     = errors.New("la double authentification n'est pas activée")
+	ErrMFANotStarted     = errors.New("aucun enrôlement en cours, demandez d'abord un nouveau secret")
+)
+
+// TOTPIssuer retourne le nom affiché dans l'application d'authentification, configurable via TOTP_ISSUER
+func TOTPIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Wacdo"
+}
+
+// RecoveryCode est un code de secours à usage unique, pour se connecter sans l'application
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAChallenge est la première étape d'une connexion avec double authentification : le mot de
+// passe est vérifié, il reste à fournir un code
+type MFAChallenge struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	Attempts  int        `json:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAEnabled indique si l'utilisateur a activé la double authentification
+func (u *User) MFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// NeedsMFA indique si les jetons de l'utilisateur doivent avoir été émis après double
+// authentification : toujours pour un administrateur, ou sur décision d'un administrateur
+func (u *User) NeedsMFA() bool {
+	return u.Role == RoleAdmin || u.MFARequired
+}
+
+// BeginTOTPEnrollment génère un nouveau secret, en attente de confirmation par un premier code
+func BeginTOTPEnrollment(db *gorm.DB, user *User) (string, string, error) {
+	if user.MFAEnabled() {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := db.Model(user).UpdateColumn("pending_totp_secret", secret).Error; err != nil {
+		return "", "", err
+	}
+	user.PendingTOTPSecret = secret
+	return secret, totp.ProvisioningURI(TOTPIssuer(), user.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment active la double authentification si le code correspond au secret en
+// attente, et retourne les codes de secours (affichés une seule fois)
+func ConfirmTOTPEnrollment(db *gorm.DB, user *User, code string, now time.Time) ([]string, error) {
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.PendingTOTPSecret == "" {
+		return nil, ErrMFANotStarted
+	}
+	step, ok := totp.Validate(user.PendingTOTPSecret, code, now, 1)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).UpdateColumns(map[string]interface{}{
+			"totp_secret":         user.PendingTOTPSecret,
+			"pending_totp_secret": "",
+			"totp_enabled_at":     now,
+			"last_totp_step":      step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret, user.PendingTOTPSecret, user.TOTPEnabledAt, user.LastTOTPStep = user.PendingTOTPSecret, "", &now, step
+	return codes, nil
+}
+
+// DisableTOTP désactive la double authentification après vérification du mot de passe et d'un code
+func DisableTOTP(db *gorm.DB, user *User, password string, code string, now time.Time) error {
+	if !user.MFAEnabled() {
+		return ErrMFANotEnabled
+	}
+	if !user.CheckPassword(password) {
+		return ErrWrongPassword
+	}
+	if !VerifyMFACode(db, user, code, now) {
+		return ErrInvalidMFACode
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).UpdateColumns(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"last_totp_step":  0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		user.TOTPSecret, user.TOTPEnabledAt = "", nil
+		return tx.Where("user_id = ?", user.ID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes remplace les codes de secours après vérification d'un code TOTP
+func RegenerateRecoveryCodes(db *gorm.DB, user *User, code string, now time.Time) ([]string, error) {
+	if !user.MFAEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+	if !verifyTOTPCode(db, user, code, now) {
+		return nil, ErrInvalidMFACode
+	}
+	return replaceRecoveryCodes(db, user.ID)
+}
+
+// SetMFARequired impose (ou non) la double authentification à un utilisateur
+func SetMFARequired(db *gorm.DB, id uint, required bool) (*User, error) {
+	user, err := GetUserByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Model(user).UpdateColumn("mfa_required", required).Error; err != nil {
+		return nil, err
+	}
+	user.MFARequired = required
+	return user, nil
+}
+
+// ResetTOTP supprime la double authentification d'un utilisateur qui a perdu son second facteur
+// et ferme ses sessions ; s'il y est soumis, il devra l'activer à nouveau
+func ResetTOTP(db *gorm.DB, id uint) (*User, error) {
+	user, err := GetUserByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).UpdateColumns(map[string]interface{}{
+			"totp_secret":         "",
+			"pending_totp_secret": "",
+			"totp_enabled_at":     nil,
+			"last_totp_step":      0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret, user.PendingTOTPSecret, user.TOTPEnabledAt, user.LastTOTPStep = "", "", nil, 0
+	return user, nil
+}
+
+// VerifyMFACode accepte un code TOTP ou, à défaut, un code de secours non utilisé
+func VerifyMFACode(db *gorm.DB, user *User, code string, now time.Time) bool {
+	return verifyTOTPCode(db, user, code, now) || useRecoveryCode(db, user.ID, code, now)
+}
+
+// verifyTOTPCode vérifie un code TOTP ; un code déjà utilisé (même période ou antérieure) est refusé
+func verifyTOTPCode(db *gorm.DB, user *User, code string, now time.Time) bool {
+	step, ok := totp.Validate(user.TOTPSecret, code, now, 1)
+	if !ok || step <= user.LastTOTPStep {
+		return false
+	}
+	// La condition garantit qu'un code n'est accepté qu'une fois, même en parallèle
+	result := db.Model(&User{}).Where("id = ? AND last_totp_step < ?", user.ID, step).UpdateColumn("last_totp_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	user.LastTOTPStep = step
+	return true
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func useRecoveryCode(db *gorm.DB, userID uint, code string, now time.Time) bool {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return false
+	}
+	result := db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashSecretToken(code)).
+		Update("used_at", now)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// replaceRecoveryCodes supprime les anciens codes de secours et en génère de nouveaux, au format XXXXX-XXXXX
+func replaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	if err := db.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, RecoveryCodeCount)
+	rows := make([]RecoveryCode, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		rows = append(rows, RecoveryCode{UserID: userID, CodeHash: hashSecretToken(code)})
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CreateMFAChallenge ouvre la seconde étape de connexion et retourne le jeton à présenter avec le code
+func CreateMFAChallenge(db *gorm.DB, userID uint, now time.Time) (string, error) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	challenge := MFAChallenge{UserID: userID, TokenHash: hash, ExpiresAt: now.Add(MFAChallengeTTL)}
+	if err := db.Create(&challenge).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteMFAChallenge vérifie le code fourni pour un challenge et retourne l'utilisateur authentifié.
+// Le challenge ne sert qu'une fois et n'accepte qu'un nombre limité de codes faux.
+func CompleteMFAChallenge(db *gorm.DB, token string, code string, now time.Time) (*User, error) {
+	var challenge MFAChallenge
+	if err := db.Where("token_hash = ?", hashSecretToken(token)).First(&challenge).Error; err != nil {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if challenge.UsedAt != nil || now.After(challenge.ExpiresAt) || challenge.Attempts >= MaxMFAChallengeAttempts {
+		return nil, ErrMFAChallengeInvalid
+	}
+
+	user, err := ActiveUser(db, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled() {
+		return nil, ErrMFAChallengeInvalid
+	}
+
+	if !VerifyMFACode(db, user, code, now) {
+		db.Model(&challenge).UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+		return nil, ErrInvalidMFACode
+	}
+
+	result := db.Model(&MFAChallenge{}).Where("id = ? AND used_at IS NULL", challenge.ID).Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrMFAChallengeInvalid
+	}
+	return user, nil
+}
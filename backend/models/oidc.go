@@ -0,0 +1,197 @@
+package models
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"LearningCampusKabre/oidc"
+
+	"gorm.io/gorm"
+)
+
+// OIDCLoginTTL est le temps laissé pour s'authentifier chez le fournisseur d'identité
+const OIDCLoginTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCStateInvalid est retournée pour un state inconnu, expiré ou déjà utilisé
+	ErrOIDCStateInvalid = errors.New("connexion SSO invalide ou expirée, recommencez")
+	// ErrOIDCNoRole est retournée quand aucun groupe de l'utilisateur n'est associé à un rôle
+	ErrOIDCNoRole = errors.New("aucun de vos groupes ne donne accès au back-office")
+	// ErrOIDCEmailConflict est retournée quand l'email appartient déjà à un compte que le fournisseur
+	// ne permet pas de rattacher (email non vérifié ou compte lié à une autre identité)
+	ErrOIDCEmailConflict = errors.New("un compte existe déjà avec cet email, contactez un administrateur")
+	// ErrOIDCEmailMissing est retournée quand le fournisseur ne transmet pas d'email
+	ErrOIDCEmailMissing = errors.New("le fournisseur d'identité n'a pas transmis d'email")
+	// ErrOIDCPrivilegedLink est retournée quand l'email correspond à un compte local à privilèges
+	// et que leur rattachement automatique n'est pas autorisé (OIDC_LINK_PRIVILEGED)
+	ErrOIDCPrivilegedLink = errors.New("ce compte a des droits d'administration : son rattachement au SSO doit être autorisé par un administrateur")
+)
+
+// privilegedPermissions sont les permissions qui donnent la main sur les comptes et les rôles :
+// un compte local qui en dispose n'est pas rattaché automatiquement à une identité SSO
+var privilegedPermissions = []Permission{PermUserWrite, PermUserDelete, PermRoleManage, PermInvitationManage}
+
+// OIDCLogin est une connexion SSO en cours : le state envoyé au fournisseur et la valeur du cookie
+// qui lie la connexion au navigateur (seules leurs empreintes sont conservées), le nonce attendu
+// dans l'ID token et le code_verifier PKCE
+type OIDCLogin struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	StateHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	BindingHash  string     `json:"-" gorm:"not null"`
+	Nonce        string     `json:"-" gorm:"not null"`
+	CodeVerifier string     `json:"-" gorm:"not null"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CreateOIDCLogin enregistre une connexion SSO et retourne son state et la valeur du cookie
+// à déposer dans le navigateur qui la démarre
+func CreateOIDCLogin(db *gorm.DB, nonce, codeVerifier string, now time.Time) (string, string, error) {
+	state, stateHash, err := newSecretToken()
+	if err != nil {
+		return "", "", err
+	}
+	binding, bindingHash, err := newSecretToken()
+	if err != nil {
+		return "", "", err
+	}
+	login := OIDCLogin{StateHash: stateHash, BindingHash: bindingHash, Nonce: nonce, CodeVerifier: codeVerifier, ExpiresAt: now.Add(OIDCLoginTTL)}
+	if err := db.Create(&login).Error; err != nil {
+		return "", "", err
+	}
+	return state, binding, nil
+}
+
+// ConsumeOIDCLogin retrouve la connexion du state et la marque utilisée : un state ne sert qu'une
+// fois, et seulement depuis le navigateur qui a démarré la connexion (cookie binding). Sans cela, un
+// code et un state interceptés ou fournis par un tiers ouvriraient une session.
+func ConsumeOIDCLogin(db *gorm.DB, state, binding string, now time.Time) (*OIDCLogin, error) {
+	var login OIDCLogin
+	err := db.Where("state_hash = ?", hashSecretToken(state)).First(&login).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if login.UsedAt != nil || now.After(login.ExpiresAt) {
+		return nil, ErrOIDCStateInvalid
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(hashSecretToken(binding)), []byte(login.BindingHash)) != 1 {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	result := db.Model(&OIDCLogin{}).Where("id = ? AND used_at IS NULL", login.ID).Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &login, nil
+}
+
+// ProvisionOIDCUser retrouve ou crée le compte d'une identité SSO (provisionnement à la volée).
+// Le rôle issu des groupes n'est appliqué qu'aux comptes créés par le SSO, le fournisseur faisant
+// foi ; un compte local rattaché garde le rôle attribué par les administrateurs. Il n'est rattaché
+// que si le fournisseur a vérifié l'email, et s'il a des droits d'administration seulement avec
+// linkPrivileged. Sans rôle, la connexion est refusée. La tentative est journalisée comme une
+// connexion par mot de passe.
+func ProvisionOIDCUser(db *gorm.DB, identity *oidc.Identity, role UserRole, linkPrivileged bool, ip string, now time.Time) (*User, error) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	attempt := LoginAttempt{Email: email, IP: ip, CreatedAt: now}
+
+	var user User
+	err := db.Where("oidc_subject = ?", identity.Subject).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	found := err == nil
+	if found {
+		attempt.UserID = &user.ID
+	}
+
+	if role == "" {
+		attempt.Reason = "oidc_no_role"
+		recordLoginAttempt(db, attempt)
+		return nil, ErrOIDCNoRole
+	}
+	if err := checkRoleExists(db, role); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		if email == "" {
+			return nil, ErrOIDCEmailMissing
+		}
+		err := db.Where("LOWER(email) = ?", email).First(&user).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			user = User{Email: email, Description: identity.Name, OIDCManaged: true}
+		case err != nil:
+			return nil, err
+		case !identity.EmailVerified || user.OIDCSubject != nil:
+			attempt.UserID, attempt.Reason = &user.ID, "oidc_email_conflict"
+			recordLoginAttempt(db, attempt)
+			return nil, ErrOIDCEmailConflict
+		case !linkPrivileged:
+			privileged, err := isPrivilegedRole(db, user.Role)
+			if err != nil {
+				return nil, err
+			}
+			if privileged {
+				attempt.UserID, attempt.Reason = &user.ID, "oidc_privileged_link"
+				recordLoginAttempt(db, attempt)
+				return nil, ErrOIDCPrivilegedLink
+			}
+		}
+		subject := identity.Subject
+		user.OIDCSubject = &subject
+	} else if email != "" && email != strings.ToLower(user.Email) {
+		// Email changé chez le fournisseur : repris s'il n'appartient pas à un autre compte
+		var count int64
+		if err := db.Model(&User{}).Where("LOWER(email) = ? AND id <> ?", email, user.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			user.Email = email
+		}
+	}
+
+	if user.OIDCManaged {
+		user.Role = role
+	}
+	if err := user.Validate(); err != nil {
+		return nil, err
+	}
+	if err := db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+
+	attempt.UserID = &user.ID
+	attempt.Success = user.DisabledAt == nil
+	if !attempt.Success {
+		attempt.Reason = "disabled"
+	}
+	recordLoginAttempt(db, attempt)
+	return &user, nil
+}
+
+// isPrivilegedRole indique si un rôle donne la main sur les comptes ou les rôles
+func isPrivilegedRole(db *gorm.DB, role UserRole) (bool, error) {
+	permissions, err := RolePermissions(db, role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		for _, privileged := range privilegedPermissions {
+			if p == privileged {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
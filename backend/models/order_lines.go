@@ -0,0 +1,412 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errOrderInvalid annule la modification d'une commande dont une ligne est refusée (voir EditCommande)
+var errOrderInvalid = errors.New("commande invalide")
+
+// OrderLine représente une ligne demandée (un menu ou un produit) avant la création d'une commande
+// @Description Ligne de panier : renseigner menu_id OU product_id
+type OrderLine struct {
+	MenuID    uint     `json:"menu_id,omitempty" example:"1"`
+	ProductID uint     `json:"product_id,omitempty" example:"0"`
+	Quantity  int      `json:"quantity" example:"2"`
+	Modifiers []string `json:"modifiers,omitempty" example:"sans oignons"`
+}
+
+// PricedLine est le résultat de la validation d'une ligne côté serveur
+type PricedLine struct {
+	OrderLine
+	Name      string          `json:"name"`
+	BasePrice decimal.Decimal `json:"base_price"`
+	UnitPrice decimal.Decimal `json:"unit_price"`
+	// Grille de prix ayant fixé le prix unitaire, le cas échéant
+	PriceListID   *uint           `json:"price_list_id,omitempty"`
+	PriceListName string          `json:"price_list_name,omitempty"`
+	Total         decimal.Decimal `json:"total"`
+	Discount      decimal.Decimal `json:"discount"`
+	Error         string          `json:"error,omitempty"`
+
+	menu    *Menu
+	product *Product
+}
+
+// PricedOrder regroupe les lignes validées et le total calculé par le serveur
+type PricedOrder struct {
+	Lines          []PricedLine       `json:"lines"`
+	Subtotal       decimal.Decimal    `json:"subtotal"`
+	Discount       decimal.Decimal    `json:"discount"`
+	Promotions     []AppliedPromotion `json:"promotions,omitempty"`
+	PromotionError string             `json:"promotion_error,omitempty"`
+	Total          decimal.Decimal    `json:"total"`
+	Valid          bool               `json:"valid"`
+}
+
+// PricingContext regroupe ce qui détermine le prix d'une commande en plus de ses lignes
+type PricingContext struct {
+	Channel ChannelType
+	At      time.Time
+	// Grille manuelle choisie à la commande (ex. tarif étudiant)
+	PriceListID *uint
+	CouponCodes []string
+}
+
+// PriceOrder chiffre les lignes au prix catalogue, applique les grilles de prix du moment
+// puis les promotions. C'est le calcul de référence de toute commande passée par un client.
+func PriceOrder(db *gorm.DB, lines []OrderLine, ctx PricingContext) (*PricedOrder, error) {
+	order, err := PriceOrderLines(db, lines)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPricing(db, order, ctx); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// applyPricing applique à des lignes déjà chiffrées au catalogue les grilles de prix du moment
+// puis les promotions
+func applyPricing(db *gorm.DB, order *PricedOrder, ctx PricingContext) error {
+	if ctx.At.IsZero() {
+		ctx.At = time.Now()
+	}
+
+	resolver, err := NewPriceResolver(db, ctx.Channel, ctx.At, ctx.PriceListID)
+	if err != nil {
+		return err
+	}
+	ApplyPriceLists(order, resolver)
+
+	return ApplyPromotions(db, order, ctx.CouponCodes, ctx.At)
+}
+
+// UnavailableMenuComponent retourne le nom du premier produit du menu qui n'est plus disponible
+// (retiré de la vente ou supprimé), ou une chaîne vide si le menu peut être commandé
+func UnavailableMenuComponent(db *gorm.DB, menuID uint) (string, error) {
+	var items []MenuItem
+	if err := db.Where("menu_id = ? AND product_id <> 0", menuID).Find(&items).Error; err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	var available []uint
+	if err := db.Model(&Product{}).Where("id IN ? AND is_available = ?", ids, true).Pluck("id", &available).Error; err != nil {
+		return "", err
+	}
+	ok := map[uint]bool{}
+	for _, id := range available {
+		ok[id] = true
+	}
+	for _, item := range items {
+		if !ok[item.ProductID] {
+			return item.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// GetOrderableMenus récupère, avec leurs items, les menus dont tous les produits sont disponibles :
+// ceux que UnavailableMenuComponent accepte, en une seule requête
+func GetOrderableMenus(db *gorm.DB) ([]Menu, error) {
+	available := db.Model(&Product{}).Select("id").Where("is_available = ?", true)
+	missing := db.Model(&MenuItem{}).Select("1").
+		Where("menu_items.menu_id = menus.id AND menu_items.product_id <> 0 AND menu_items.product_id NOT IN (?)", available)
+
+	var menus []Menu
+	err := db.Preload("MenuItems").Where("NOT EXISTS (?)", missing).Find(&menus).Error
+	return menus, err
+}
+
+// PriceOrderLines vérifie chaque ligne (existence, disponibilité, quantité) et calcule les prix
+// à partir du catalogue : le prix envoyé par le client n'est jamais utilisé.
+func PriceOrderLines(db *gorm.DB, lines []OrderLine) (*PricedOrder, error) {
+	order := &PricedOrder{Subtotal: decimal.Zero, Discount: decimal.Zero, Total: decimal.Zero, Valid: len(lines) > 0}
+
+	for _, line := range lines {
+		priced := PricedLine{OrderLine: line, UnitPrice: decimal.Zero, Total: decimal.Zero, Discount: decimal.Zero}
+
+		switch {
+		case line.Quantity <= 0:
+			priced.Error = "La quantité doit être supérieure à 0"
+		case (line.MenuID == 0) == (line.ProductID == 0):
+			priced.Error = "Une ligne doit référencer soit un menu, soit un produit"
+		case line.MenuID != 0:
+			var menu Menu
+			err := db.First(&menu, line.MenuID).Error
+			if err == gorm.ErrRecordNotFound {
+				priced.Error = fmt.Sprintf("Le menu avec l'Id %d n'a pas été trouvé", line.MenuID)
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+			}
+			priced.menu = &menu
+			priced.Name = menu.Name
+			priced.BasePrice = menu.Price
+			priced.UnitPrice = menu.Price
+			unavailable, err := UnavailableMenuComponent(db, menu.ID)
+			if err != nil {
+				return nil, err
+			}
+			if unavailable != "" {
+				priced.Error = fmt.Sprintf("Le menu %s n'est plus disponible (%s)", menu.Name, unavailable)
+			}
+		default:
+			var product Product
+			err := db.First(&product, line.ProductID).Error
+			if err == gorm.ErrRecordNotFound {
+				priced.Error = fmt.Sprintf("Le produit avec l'Id %d n'a pas été trouvé", line.ProductID)
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+			}
+			priced.product = &product
+			priced.Name = product.Name
+			priced.BasePrice = product.Price
+			priced.UnitPrice = product.Price
+			if !product.IsAvailable {
+				priced.Error = fmt.Sprintf("Le produit %s n'est plus disponible", product.Name)
+			}
+		}
+
+		if priced.Error != "" {
+			order.Valid = false
+		} else {
+			priced.Total = priced.UnitPrice.Mul(decimal.NewFromInt(int64(line.Quantity)))
+			order.Subtotal = order.Subtotal.Add(priced.Total)
+		}
+
+		order.Lines = append(order.Lines, priced)
+	}
+
+	order.Total = order.Subtotal
+	return order, nil
+}
+
+// CreateCommandeFromLines crée la commande et ses snapshots dans une seule transaction.
+// Le numéro de ticket et le prix total sont attribués ici.
+func CreateCommandeFromLines(db *gorm.DB, order *PricedOrder, commande *Commande) error {
+	if !order.Valid {
+		return fmt.Errorf("la commande contient des lignes invalides")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := ReservePickupSlot(tx, commande, now); err != nil {
+			return err
+		}
+
+		ticket, err := NextTicketNumber(tx, QueueDay(commande, now))
+		if err != nil {
+			return err
+		}
+
+		commande.TicketNumber = ticket
+		commande.Price = order.Total
+		commande.DiscountTotal = order.Discount
+
+		if err := tx.Create(commande).Error; err != nil {
+			return err
+		}
+
+		if err := createCommandeLines(tx, commande, order); err != nil {
+			return err
+		}
+
+		if err := recordPromotions(tx, commande, order.Promotions); err != nil {
+			return err
+		}
+
+		if err := tx.Preload("Menus").Preload("Products").Preload("Promotions").First(commande, commande.ID).Error; err != nil {
+			return err
+		}
+		if err := ApplyTaxes(tx, commande); err != nil {
+			return err
+		}
+		if err := SetEstimatedReadyAt(tx, commande); err != nil {
+			return err
+		}
+		return PublishEvent(tx, Event{Type: EventCommandeCreated, Data: commande})
+	})
+}
+
+// createCommandeLines enregistre les lignes chiffrées d'une commande
+func createCommandeLines(tx *gorm.DB, commande *Commande, order *PricedOrder) error {
+	for _, line := range order.Lines {
+		if line.menu != nil {
+			m := line.menu
+			if err := tx.Create(&CommandeMenu{
+				CommandeID:    commande.ID,
+				MenuID:        m.ID,
+				Name:          m.Name,
+				Price:         line.UnitPrice,
+				BasePrice:     line.BasePrice,
+				PriceListID:   line.PriceListID,
+				PriceListName: line.PriceListName,
+				Quantity:      line.Quantity,
+				Modifiers:     line.Modifiers,
+				Discount:      line.Discount,
+				Description:   m.Description,
+				ImageURL:      m.ImageURL,
+				TaxCategory:   m.TaxCategory,
+			}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		p := line.product
+		if err := tx.Create(&CommandeProduct{
+			CommandeID:    commande.ID,
+			ProductID:     p.ID,
+			Name:          p.Name,
+			Price:         line.UnitPrice,
+			BasePrice:     line.BasePrice,
+			PriceListID:   line.PriceListID,
+			PriceListName: line.PriceListName,
+			Quantity:      line.Quantity,
+			Modifiers:     line.Modifiers,
+			Discount:      line.Discount,
+			ImageURL:      p.ImageURL,
+			Description:   p.Description,
+			Type:          p.Type,
+			TaxCategory:   p.TaxCategory,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commandePricingContext retrouve le contexte de calcul d'une commande existante : son canal, sa date,
+// la grille manuelle choisie à la commande (portée par ses lignes) et les codes promo saisis
+func commandePricingContext(db *gorm.DB, commande *Commande) (PricingContext, error) {
+	ctx := PricingContext{Channel: commande.Channel, At: commande.CreatedAt}
+
+	var listIDs []uint
+	for _, table := range []string{"commande_menus", "commande_products"} {
+		var ids []uint
+		err := db.Table(table).Where("commande_id = ? AND price_list_id IS NOT NULL", commande.ID).Distinct().Pluck("price_list_id", &ids).Error
+		if err != nil {
+			return ctx, err
+		}
+		listIDs = append(listIDs, ids...)
+	}
+	if len(listIDs) > 0 {
+		var manual []uint
+		if err := db.Model(&PriceList{}).Where("id IN ? AND manual = ?", listIDs, true).Limit(1).Pluck("id", &manual).Error; err != nil {
+			return ctx, err
+		}
+		if len(manual) > 0 {
+			ctx.PriceListID = &manual[0]
+		}
+	}
+
+	var codes []string
+	if err := db.Model(&CommandePromotion{}).Where("commande_id = ? AND code <> ''", commande.ID).Pluck("code", &codes).Error; err != nil {
+		return ctx, err
+	}
+	ctx.CouponCodes = codes
+	return ctx, nil
+}
+
+// EditCommande change le statut d'une commande et, si lines n'est pas nil, remplace ses lignes,
+// le tout dans une transaction. Les nouvelles lignes sont chiffrées avec PriceOrder dans le contexte
+// de la commande (voir commandePricingContext) et ses promotions recalculées : leurs utilisations
+// précédentes sont libérées puis celles retenues enregistrées à nouveau. Les lignes offertes par une
+// récompense fidélité, payées en points, sont conservées. Si une ligne est refusée, la commande
+// n'est pas modifiée : le résultat retourné n'est pas Valid.
+func EditCommande(db *gorm.DB, commande *Commande, status StatusType, lines []OrderLine) (*PricedOrder, error) {
+	var order *PricedOrder
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Save déclenche les hooks d'historisation des statuts
+		commande.Status = status
+		if err := tx.Omit(clause.Associations).Save(commande).Error; err != nil {
+			return err
+		}
+		if lines == nil {
+			return nil
+		}
+
+		var err error
+		if order, err = replaceCommandeLines(tx, commande, lines); err != nil {
+			return err
+		}
+		if !order.Valid {
+			// Le statut et les promotions libérées sont restaurés avec le reste de la transaction
+			return errOrderInvalid
+		}
+		return nil
+	})
+	if err == errOrderInvalid {
+		err = nil
+	}
+	return order, err
+}
+
+// replaceCommandeLines remplace les lignes d'une commande et ses promotions ; à appeler dans une
+// transaction, annulée par l'appelant si le résultat n'est pas Valid
+func replaceCommandeLines(tx *gorm.DB, commande *Commande, lines []OrderLine) (*PricedOrder, error) {
+	ctx, err := commandePricingContext(tx, commande)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []CommandePromotion
+	if err := tx.Where("commande_id = ?", commande.ID).Find(&previous).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range previous {
+		err := tx.Model(&Promotion{}).Where("id = ? AND used_count > 0", p.PromotionID).
+			UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("commande_id = ?", commande.ID).Delete(&CommandePromotion{}).Error; err != nil {
+		return nil, err
+	}
+
+	order, err := PriceOrder(tx, lines, ctx)
+	if err != nil || !order.Valid {
+		return order, err
+	}
+
+	if err := tx.Where("commande_id = ?", commande.ID).Delete(&CommandeMenu{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("commande_id = ? AND reward_id IS NULL", commande.ID).Delete(&CommandeProduct{}).Error; err != nil {
+		return nil, err
+	}
+	if err := createCommandeLines(tx, commande, order); err != nil {
+		return nil, err
+	}
+	if err := recordPromotions(tx, commande, order.Promotions); err != nil {
+		return nil, err
+	}
+
+	commande.Price, commande.DiscountTotal = order.Total, order.Discount
+	err = tx.Model(commande).UpdateColumns(map[string]interface{}{"price": order.Total, "discount_total": order.Discount}).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Preload("Menus").Preload("Products").Preload("Promotions").First(commande, commande.ID).Error; err != nil {
+		return nil, err
+	}
+	return order, ApplyTaxes(tx, commande)
+}
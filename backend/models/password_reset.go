@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Réglages par défaut de la réinitialisation du mot de passe
+const (
+	DefaultPasswordResetTTL = 30 * time.Minute
+	// Demandes acceptées par heure, pour un même compte et pour une même adresse IP
+	MaxResetRequestsPerAccount = 3
+	MaxResetRequestsPerIP      = 10
+)
+
+// ErrTooManyResetRequests est retournée quand l'adresse IP a fait trop de demandes récentes
+var ErrTooManyResetRequests = errors.New("trop de demandes de réinitialisation, réessayez plus tard")
+
+// ErrResetTokenInvalid est retournée pour un lien de réinitialisation inconnu, expiré ou déjà utilisé
+var ErrResetTokenInvalid = errors.New("lien de réinitialisation invalide ou expiré")
+
+// PasswordResetTTL retourne la durée de validité d'un lien, configurable via PASSWORD_RESET_TTL_MINUTES
+func PasswordResetTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_TTL_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultPasswordResetTTL
+}
+
+// PasswordResetToken est un jeton de réinitialisation à usage unique ; seule son empreinte est conservée
+type PasswordResetToken struct {
+	ID     uint  `json:"id" gorm:"primaryKey"`
+	UserID *uint `json:"user_id" gorm:"index"`
+	// Vide pour une demande qui n'a pas produit de jeton (email inconnu, compte trop sollicité)
+	TokenHash *string    `json:"-" gorm:"uniqueIndex"`
+	IP        string     `json:"ip" gorm:"index"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+// RequestPasswordReset crée un jeton pour le compte de cet email. Pour ne pas révéler quels
+// emails ont un compte, un email inconnu (ou un compte désactivé, ou trop sollicité) ne produit
+// ni erreur ni jeton ; la demande est quand même comptée pour l'adresse IP.
+func RequestPasswordReset(db *gorm.DB, email string, ip string, now time.Time) (string, *User, error) {
+	since := now.Add(-time.Hour)
+
+	var ipCount int64
+	if err := db.Model(&PasswordResetToken{}).Where("ip = ? AND created_at > ?", ip, since).Count(&ipCount).Error; err != nil {
+		return "", nil, err
+	}
+	if ipCount >= MaxResetRequestsPerIP {
+		return "", nil, ErrTooManyResetRequests
+	}
+
+	request := PasswordResetToken{IP: ip, CreatedAt: now}
+	var user User
+	// Les comptes SSO sans mot de passe local n'en reçoivent pas : l'accès reste géré par le fournisseur
+	err := db.Where("LOWER(email) = ? AND disabled_at IS NULL AND password <> ''", strings.ToLower(email)).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return "", nil, err
+	}
+
+	var token string
+	if err == nil {
+		var accountCount int64
+		if err := db.Model(&PasswordResetToken{}).Where("user_id = ? AND created_at > ?", user.ID, since).Count(&accountCount).Error; err != nil {
+			return "", nil, err
+		}
+		if accountCount < MaxResetRequestsPerAccount {
+			var hash string
+			if token, hash, err = newSecretToken(); err != nil {
+				return "", nil, err
+			}
+			request.UserID = &user.ID
+			request.TokenHash = &hash
+			request.ExpiresAt = now.Add(PasswordResetTTL())
+		}
+	}
+
+	if err := db.Create(&request).Error; err != nil {
+		return "", nil, err
+	}
+
+	if token == "" {
+		return "", nil, nil
+	}
+	return token, &user, nil
+}
+
+// ResetPassword remplace le mot de passe du compte associé au jeton. Les autres jetons du compte
+// sont invalidés et toutes ses sessions fermées.
+func ResetPassword(db *gorm.DB, token string, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var reset PasswordResetToken
+		if err := tx.Where("token_hash = ?", hashSecretToken(token)).First(&reset).Error; err != nil {
+			return ErrResetTokenInvalid
+		}
+		now := time.Now()
+		if reset.UserID == nil || reset.UsedAt != nil || now.After(reset.ExpiresAt) {
+			return ErrResetTokenInvalid
+		}
+
+		user, err := ActiveUser(tx, *reset.UserID)
+		if err != nil {
+			return ErrResetTokenInvalid
+		}
+
+		// La condition sur used_at garantit l'usage unique face à deux requêtes simultanées
+		result := tx.Model(&PasswordResetToken{}).Where("id = ? AND used_at IS NULL", reset.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResetTokenInvalid
+		}
+		if err := tx.Model(&PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", user.ID).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		if err := setUserPassword(tx, user, password); err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, user.ID)
+	})
+}
@@ -0,0 +1,350 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Permission est un droit nommé "ressource:action", exigé par une route via RequirePermission
+type Permission string
+
+// Permissions existantes
+const (
+	PermProductRead   Permission = "product:read"
+	PermProductWrite  Permission = "product:write"
+	PermProductDelete Permission = "product:delete"
+
+	PermMenuRead   Permission = "menu:read"
+	PermMenuWrite  Permission = "menu:write"
+	PermMenuDelete Permission = "menu:delete"
+
+	PermOrderRead    Permission = "order:read"
+	PermOrderCreate  Permission = "order:create"
+	PermOrderEdit    Permission = "order:edit"
+	PermOrderPrepare Permission = "order:prepare"
+	PermOrderDeliver Permission = "order:deliver"
+	PermOrderPay     Permission = "order:pay"
+	PermOrderDelete  Permission = "order:delete"
+
+	PermCartWrite Permission = "cart:write"
+
+	PermCustomerRead   Permission = "customer:read"
+	PermCustomerWrite  Permission = "customer:write"
+	PermCustomerDelete Permission = "customer:delete"
+	PermLoyaltyAdjust  Permission = "loyalty:adjust"
+	PermRewardRead     Permission = "reward:read"
+	PermRewardWrite    Permission = "reward:write"
+
+	PermStationRead  Permission = "station:read"
+	PermStationWrite Permission = "station:write"
+	PermStationWork  Permission = "station:work"
+
+	PermPrinterManage   Permission = "printer:manage"
+	PermTaxManage       Permission = "tax:manage"
+	PermReportRead      Permission = "report:read"
+	PermPromotionManage Permission = "promotion:manage"
+	PermPriceListManage Permission = "price_list:manage"
+	PermDeviceManage    Permission = "device:manage"
+	PermDeliveryManage  Permission = "delivery:manage"
+	PermWebhookManage   Permission = "webhook:manage"
+
+	PermUserRead         Permission = "user:read"
+	PermUserWrite        Permission = "user:write"
+	PermUserDelete       Permission = "user:delete"
+	PermInvitationManage Permission = "invitation:manage"
+	PermAuditRead        Permission = "audit:read"
+	PermRoleManage       Permission = "role:manage"
+)
+
+// @Description Permission et sa description, pour l'écran d'édition des rôles
+type PermissionInfo struct {
+	Name        Permission `json:"name" example:"product:write"`
+	Description string     `json:"description" example:"Créer et modifier les produits"`
+}
+
+// AllPermissions est le catalogue des permissions, dans l'ordre d'affichage
+var AllPermissions = []PermissionInfo{
+	{PermProductRead, "Consulter les produits"},
+	{PermProductWrite, "Créer, modifier et archiver les produits"},
+	{PermProductDelete, "Supprimer définitivement les produits"},
+	{PermMenuRead, "Consulter les menus"},
+	{PermMenuWrite, "Créer, modifier et archiver les menus"},
+	{PermMenuDelete, "Supprimer définitivement les menus"},
+	{PermOrderRead, "Consulter les commandes, tickets et TVA"},
+	{PermOrderCreate, "Créer des commandes"},
+	{PermOrderEdit, "Modifier librement une commande (contenu, prix, statut)"},
+	{PermOrderPrepare, "Passer une commande au statut prête"},
+	{PermOrderDeliver, "Remettre une commande ou la renvoyer en attente"},
+	{PermOrderPay, "Encaisser une commande"},
+	{PermOrderDelete, "Supprimer une commande"},
+	{PermCartWrite, "Gérer les paniers de caisse"},
+	{PermCustomerRead, "Consulter les clients fidélité"},
+	{PermCustomerWrite, "Créer et modifier les clients, utiliser leurs récompenses"},
+	{PermCustomerDelete, "Supprimer les clients"},
+	{PermLoyaltyAdjust, "Ajuster manuellement les points de fidélité"},
+	{PermRewardRead, "Consulter les récompenses"},
+	{PermRewardWrite, "Gérer le catalogue des récompenses"},
+	{PermStationRead, "Consulter les postes de préparation"},
+	{PermStationWrite, "Gérer les postes de préparation"},
+	{PermStationWork, "Travailler sur un poste (tickets, fin de préparation)"},
+	{PermPrinterManage, "Gérer les imprimantes et les impressions"},
+	{PermTaxManage, "Gérer les taux de TVA"},
+	{PermReportRead, "Consulter les rapports"},
+	{PermPromotionManage, "Gérer les promotions"},
+	{PermPriceListManage, "Gérer les grilles tarifaires"},
+	{PermDeviceManage, "Enregistrer et révoquer les appareils"},
+	{PermDeliveryManage, "Gérer les plateformes de livraison"},
+	{PermWebhookManage, "Gérer les webhooks sortants"},
+	{PermUserRead, "Consulter les comptes du staff"},
+	{PermUserWrite, "Créer, modifier, désactiver et déverrouiller les comptes"},
+	{PermUserDelete, "Supprimer les comptes"},
+	{PermInvitationManage, "Inviter des membres du staff"},
+	{PermAuditRead, "Consulter le journal des connexions"},
+	{PermRoleManage, "Modifier les rôles et leurs permissions"},
+}
+
+// DefaultRolePermissions sont les permissions des rôles créés au premier démarrage. Le rôle admin
+// n'y figure pas : il a toujours toutes les permissions.
+var DefaultRolePermissions = map[UserRole][]Permission{
+	RolePreparer: {
+		PermProductRead, PermMenuRead,
+		PermOrderRead, PermOrderCreate, PermOrderPrepare, PermOrderDelete,
+		PermStationRead, PermStationWork,
+	},
+	RoleReceiver: {
+		PermProductRead, PermMenuRead,
+		PermOrderRead, PermOrderCreate, PermOrderDeliver, PermOrderPay, PermOrderDelete,
+		PermCartWrite, PermCustomerRead, PermCustomerWrite, PermRewardRead,
+		PermStationRead,
+	},
+}
+
+var (
+	// ErrRoleNotFound est retournée pour un rôle inconnu
+	ErrRoleNotFound = errors.New("Rôle introuvable")
+	// ErrRoleProtected est retournée quand on tente de modifier le rôle admin ou de supprimer un rôle prédéfini
+	ErrRoleProtected = errors.New("Ce rôle ne peut pas être modifié ou supprimé")
+	// ErrRoleInUse est retournée quand on supprime un rôle encore attribué
+	ErrRoleInUse = errors.New("Ce rôle est encore attribué à des utilisateurs")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,19}$`)
+
+// Role est un ensemble de permissions attribuable aux utilisateurs. Les rôles prédéfinis
+// (admin, preparer, receiver) ne peuvent pas être supprimés, et admin a toujours toutes les permissions.
+type Role struct {
+	ID          uint         `json:"id" gorm:"primaryKey" example:"2"`
+	Name        UserRole     `json:"name" gorm:"type:varchar(20);not null;uniqueIndex" example:"preparer"`
+	Description string       `json:"description" example:"Équipe cuisine"`
+	Permissions []Permission `json:"permissions" gorm:"serializer:json" example:"order:read,order:prepare"`
+	IsSystem    bool         `json:"is_system" gorm:"default:false"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// @Description Données d'un rôle
+type RoleInput struct {
+	Name        string       `json:"name" example:"manager"`
+	Description string       `json:"description" example:"Responsable de salle"`
+	Permissions []Permission `json:"permissions" example:"order:read,order:edit"`
+}
+
+// IsKnown indique si la permission fait partie du catalogue
+func (p Permission) IsKnown() bool {
+	for _, info := range AllPermissions {
+		if info.Name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// allPermissionNames retourne toutes les permissions du catalogue
+func allPermissionNames() []Permission {
+	names := make([]Permission, 0, len(AllPermissions))
+	for _, info := range AllPermissions {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// Validate vérifie le nom du rôle et ses permissions
+func (r *Role) Validate() error {
+	if !roleNamePattern.MatchString(string(r.Name)) {
+		return fmt.Errorf("Nom de rôle invalide : 2 à 20 caractères, minuscules, chiffres, - ou _")
+	}
+	for _, p := range r.Permissions {
+		if !p.IsKnown() {
+			return fmt.Errorf("Permission inconnue : %s", p)
+		}
+	}
+	return nil
+}
+
+// withEffectivePermissions complète le rôle admin, qui a toujours toutes les permissions
+func (r *Role) withEffectivePermissions() *Role {
+	if r.Name == RoleAdmin {
+		r.Permissions = allPermissionNames()
+	}
+	return r
+}
+
+// SeedRoles crée les rôles prédéfinis manquants avec leurs permissions par défaut. Les rôles
+// existants ne sont pas modifiés : les changements faits par les administrateurs sont conservés.
+func SeedRoles(db *gorm.DB) error {
+	roles := []Role{
+		{Name: RoleAdmin, Description: "Administrateur : toutes les permissions", IsSystem: true},
+		{Name: RolePreparer, Description: "Équipe de préparation", Permissions: DefaultRolePermissions[RolePreparer], IsSystem: true},
+		{Name: RoleReceiver, Description: "Accueil et caisse", Permissions: DefaultRolePermissions[RoleReceiver], IsSystem: true},
+	}
+	for _, role := range roles {
+		if err := db.Where("name = ?", role.Name).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roleExists indique si un rôle peut être attribué : rôle prédéfini ou rôle créé par un administrateur
+func roleExists(db *gorm.DB, name UserRole) (bool, error) {
+	if name.IsValid() {
+		return true, nil
+	}
+	var count int64
+	err := db.Model(&Role{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// checkRoleExists retourne une erreur si le rôle ne peut pas être attribué
+func checkRoleExists(db *gorm.DB, name UserRole) error {
+	exists, err := roleExists(db, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("Rôle utilisateur invalide")
+	}
+	return nil
+}
+
+// RolePermissions retourne les permissions effectives d'un rôle. Un rôle prédéfini absent de la
+// base (avant SeedRoles) garde ses permissions par défaut.
+func RolePermissions(db *gorm.DB, name UserRole) ([]Permission, error) {
+	if name == RoleAdmin {
+		return allPermissionNames(), nil
+	}
+	var roles []Role
+	if err := db.Where("name = ?", name).Limit(1).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return DefaultRolePermissions[name], nil
+	}
+	return roles[0].Permissions, nil
+}
+
+// GetAllRoles récupère les rôles avec leurs permissions effectives
+func GetAllRoles(db *gorm.DB) ([]Role, error) {
+	var roles []Role
+	if err := db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		roles[i].withEffectivePermissions()
+	}
+	return roles, nil
+}
+
+// GetRoleByID récupère un rôle
+func GetRoleByID(db *gorm.DB, id uint) (*Role, error) {
+	var role Role
+	if err := db.First(&role, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role.withEffectivePermissions(), nil
+}
+
+// CreateRole crée un rôle personnalisé
+func CreateRole(db *gorm.DB, role *Role) error {
+	role.IsSystem = false
+	if err := role.Validate(); err != nil {
+		return err
+	}
+	var count int64
+	if err := db.Model(&Role{}).Where("name = ?", role.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 || role.Name.IsValid() {
+		return fmt.Errorf("Un rôle porte déjà ce nom")
+	}
+	return db.Create(role).Error
+}
+
+// UpdateRole modifie la description et les permissions d'un rôle. Le nom, attribué aux
+// utilisateurs, ne change pas ; le rôle admin n'est pas modifiable.
+func UpdateRole(db *gorm.DB, id uint, description string, permissions []Permission) (*Role, error) {
+	role, err := GetRoleByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == RoleAdmin {
+		return nil, ErrRoleProtected
+	}
+	role.Description, role.Permissions = description, permissions
+	if err := role.Validate(); err != nil {
+		return nil, err
+	}
+	if err := db.Model(role).Select("description", "permissions").Updates(role).Error; err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole supprime un rôle personnalisé qui n'est plus attribué
+func DeleteRole(db *gorm.DB, id uint) error {
+	role, err := GetRoleByID(db, id)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem || role.Name.IsValid() {
+		return ErrRoleProtected
+	}
+	var count int64
+	if err := db.Model(&User{}).Where("role = ?", role.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+	return db.Delete(&Role{}, id).Error
+}
+
+// HasPermission indique si la liste contient la permission demandée
+func HasPermission(permissions []Permission, wanted Permission) bool {
+	for _, p := range permissions {
+		if p == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusPermission retourne la permission nécessaire pour passer une commande au statut donné :
+// la cuisine la déclare prête, l'accueil la remet ou la renvoie en attente ; tout autre statut
+// relève de la modification libre
+func StatusPermission(status StatusType) Permission {
+	switch status {
+	case StatusReady:
+		return PermOrderPrepare
+	case StatusPending, StatusDelivered:
+		return PermOrderDeliver
+	}
+	return PermOrderEdit
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Valeurs par défaut pour les retraits programmés
+const (
+	PickupSlotDuration        = 15 * time.Minute
+	DefaultPickupSlotCapacity = 10
+	DefaultPickupLeadTime     = 20 * time.Minute
+	// Une commande ne peut pas être programmée plus loin que cet horizon
+	MaxPickupAdvance = 7 * 24 * time.Hour
+)
+
+// ErrPickupSlotFull est retournée quand le créneau de retrait demandé est complet
+var ErrPickupSlotFull = errors.New("le créneau de retrait demandé est complet")
+
+// PickupSlotCapacity retourne le nombre maximal de commandes par créneau, configurable via PICKUP_SLOT_CAPACITY
+func PickupSlotCapacity() int {
+	if capacity, err := strconv.Atoi(os.Getenv("PICKUP_SLOT_CAPACITY")); err == nil && capacity > 0 {
+		return capacity
+	}
+	return DefaultPickupSlotCapacity
+}
+
+// PickupLeadTime retourne le délai avant le retrait auquel une commande programmée entre en préparation,
+// configurable via PICKUP_LEAD_SECONDS
+func PickupLeadTime() time.Duration {
+	return durationFromEnv("PICKUP_LEAD_SECONDS", DefaultPickupLeadTime)
+}
+
+// PickupSlotStart retourne le début du créneau de 15 minutes contenant t
+func PickupSlotStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()-t.Minute()%15, 0, 0, t.Location())
+}
+
+// ValidatePickupAt vérifie qu'une heure de retrait est dans le futur et dans l'horizon autorisé
+func ValidatePickupAt(pickupAt, now time.Time) error {
+	if !pickupAt.After(now) {
+		return fmt.Errorf("L'heure de retrait doit être dans le futur")
+	}
+	if pickupAt.Sub(now) > MaxPickupAdvance {
+		return fmt.Errorf("L'heure de retrait ne peut pas dépasser %d jours", int(MaxPickupAdvance.Hours()/24))
+	}
+	return nil
+}
+
+// countPickupSlot compte les commandes non remises dont le retrait tombe dans le créneau
+func countPickupSlot(db *gorm.DB, slot time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&Commande{}).
+		Where("pickup_at >= ? AND pickup_at < ? AND status <> ?", slot, slot.Add(PickupSlotDuration), StatusDelivered).
+		Count(&count).Error
+	return count, err
+}
+
+// PickupSlotLock est la ligne verrouillée pendant la réservation d'un créneau : les commandes
+// concurrentes sur un même créneau sont ainsi comptées l'une après l'autre
+type PickupSlotLock struct {
+	Slot     time.Time `json:"slot" gorm:"primaryKey"`
+	LockedAt time.Time `json:"locked_at"`
+}
+
+// lockPickupSlot verrouille le créneau jusqu'à la fin de la transaction en mettant à jour sa ligne
+func lockPickupSlot(tx *gorm.DB, slot, now time.Time) error {
+	slot = slot.UTC()
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&PickupSlotLock{Slot: slot, LockedAt: now}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&PickupSlotLock{}).Where("slot = ?", slot).Update("locked_at", now).Error
+}
+
+// ReservePickupSlot vérifie la capacité du créneau de la commande et fixe son statut initial :
+// "scheduled" tant que le retrait est plus loin que le délai de préparation, "pending" sinon.
+// À appeler dans la transaction qui crée la commande : le créneau reste verrouillé jusqu'à sa fin.
+func ReservePickupSlot(tx *gorm.DB, commande *Commande, now time.Time) error {
+	if commande.PickupAt == nil {
+		return nil
+	}
+
+	slot := PickupSlotStart(*commande.PickupAt)
+	if err := lockPickupSlot(tx, slot, now); err != nil {
+		return err
+	}
+	count, err := countPickupSlot(tx, slot)
+	if err != nil {
+		return err
+	}
+	if count >= int64(PickupSlotCapacity()) {
+		return ErrPickupSlotFull
+	}
+
+	if commande.PickupAt.Sub(now) > PickupLeadTime() {
+		commande.Status = StatusScheduled
+	} else {
+		commande.Status = StatusPending
+	}
+	return nil
+}
+
+// PickupSlot est un créneau de retrait et ses places restantes
+type PickupSlot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Remaining int       `json:"remaining"`
+}
+
+// GetPickupSlots liste les créneaux à venir d'une journée avec leurs places restantes
+func GetPickupSlots(db *gorm.DB, day, now time.Time) ([]PickupSlot, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	var pickups []time.Time
+	err := db.Model(&Commande{}).
+		Where("pickup_at >= ? AND pickup_at < ? AND status <> ?", start, end, StatusDelivered).
+		Pluck("pickup_at", &pickups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[int64]int{}
+	for _, p := range pickups {
+		used[PickupSlotStart(p.In(day.Location())).Unix()]++
+	}
+
+	capacity := PickupSlotCapacity()
+	slots := []PickupSlot{}
+	for slot := start; slot.Before(end); slot = slot.Add(PickupSlotDuration) {
+		// Un créneau commencé reste proposé tant qu'il n'est pas terminé
+		if !slot.Add(PickupSlotDuration).After(now) || slot.Sub(now) > MaxPickupAdvance {
+			continue
+		}
+		slots = append(slots, PickupSlot{
+			Start:     slot,
+			End:       slot.Add(PickupSlotDuration),
+			Remaining: max(capacity-used[slot.Unix()], 0),
+		})
+	}
+	return slots, nil
+}
+
+// ReleaseScheduledCommandes fait entrer en file de préparation ("pending") les commandes programmées
+// dont le retrait est à moins du délai de préparation, et retourne les commandes libérées.
+func ReleaseScheduledCommandes(db *gorm.DB, now time.Time) ([]Commande, error) {
+	var due []Commande
+	err := db.Preload("Menus").Preload("Products").
+		Where("status = ? AND pickup_at <= ?", StatusScheduled, now.Add(PickupLeadTime())).
+		Order("pickup_at, id").
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range due {
+		// Save déclenche l'historisation du changement de statut
+		due[i].Status = StatusPending
+		if err := db.Omit(clause.Associations).Save(&due[i]).Error; err != nil {
+			return due[:i], err
+		}
+		if err := SetEstimatedReadyAt(db, &due[i]); err != nil {
+			return due[:i+1], err
+		}
+	}
+	return due, nil
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Règles des codes PIN de connexion rapide
+const (
+	MinPINLength = 4
+	MaxPINLength = 6
+	// Échecs consécutifs avant que le code PIN soit effacé : il faut alors en choisir un nouveau
+	// après connexion par mot de passe
+	MaxFailedPINs = 5
+	// Inactivité tolérée par défaut sur une session ouverte par code PIN
+	DefaultPINIdleTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidPIN est retournée pour un utilisateur inconnu, sans code PIN ou un mauvais code
+	ErrInvalidPIN = errors.New("Code PIN incorrect")
+	// ErrPINFormat est retournée pour un code PIN qui n'est pas composé de 4 à 6 chiffres
+	ErrPINFormat = errors.New("Le code PIN doit contenir de 4 à 6 chiffres")
+	// ErrPINNotAllowed est retournée pour les comptes soumis à la double authentification
+	ErrPINNotAllowed = errors.New("La connexion par code PIN n'est pas disponible pour ce compte")
+)
+
+// PINIdleTimeout retourne l'inactivité tolérée sur une tablette, configurable via PIN_IDLE_TIMEOUT_MINUTES
+func PINIdleTimeout() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("PIN_IDLE_TIMEOUT_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultPINIdleTimeout
+}
+
+// @Description Membre du staff proposé sur l'écran de connexion d'une tablette
+type PINUserResponse struct {
+	ID          uint     `json:"id" example:"3"`
+	Email       string   `json:"email" example:"john@example.com"`
+	Role        UserRole `json:"role" example:"preparer" enums:"preparer,receiver"`
+	Description string   `json:"description" example:"Équipe du soir"`
+}
+
+// ValidatePIN vérifie qu'un code PIN ne contient que 4 à 6 chiffres
+func ValidatePIN(pin string) error {
+	if len(pin) < MinPINLength || len(pin) > MaxPINLength {
+		return ErrPINFormat
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return ErrPINFormat
+		}
+	}
+	return nil
+}
+
+// SetUserPIN enregistre le code PIN d'un utilisateur après vérification de son mot de passe.
+// Les comptes soumis à la double authentification (administrateurs) n'y ont pas droit.
+func SetUserPIN(db *gorm.DB, userID uint, password string, pin string) error {
+	user, err := GetUserByID(db, userID)
+	if err != nil {
+		return err
+	}
+	if user.NeedsMFA() {
+		return ErrPINNotAllowed
+	}
+	if !user.CheckPassword(password) {
+		return ErrWrongPassword
+	}
+	if err := ValidatePIN(pin); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return db.Model(user).UpdateColumns(map[string]interface{}{
+		"pin_hash":    string(hashed),
+		"pin_set_at":  time.Now(),
+		"failed_pins": 0,
+	}).Error
+}
+
+// ClearUserPIN efface le code PIN d'un utilisateur et ferme ses sessions de tablette
+func ClearUserPIN(db *gorm.DB, id uint) (*User, error) {
+	user, err := GetUserByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).UpdateColumns(map[string]interface{}{
+			"pin_hash":    "",
+			"pin_set_at":  nil,
+			"failed_pins": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&AuthSession{}).Where("user_id = ? AND device_id IS NOT NULL AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.PINHash, user.PINSetAt, user.FailedPINs = "", nil, 0
+	return user, nil
+}
+
+// GetPINUsers liste les comptes actifs qui peuvent se connecter par code PIN
+func GetPINUsers(db *gorm.DB) ([]PINUserResponse, error) {
+	var users []User
+	err := db.Where("pin_hash <> '' AND disabled_at IS NULL AND role IN ?", []UserRole{RolePreparer, RoleReceiver}).
+		Order("email").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]PINUserResponse, 0, len(users))
+	for _, u := range users {
+		if u.NeedsMFA() {
+			continue
+		}
+		responses = append(responses, PINUserResponse{ID: u.ID, Email: u.Email, Role: u.Role, Description: u.Description})
+	}
+	return responses, nil
+}
+
+// AuthenticatePIN vérifie le code PIN d'un utilisateur saisi sur une tablette. Chaque tentative
+// est journalisée ; après MaxFailedPINs échecs le code est effacé. Le verrouillage du compte
+// par les échecs de mot de passe s'applique aussi.
+func AuthenticatePIN(db *gorm.DB, userID uint, pin string, ip string, now time.Time) (*User, error) {
+	user, err := ActiveUser(db, userID)
+	if err == ErrUserDisabled {
+		recordLoginAttempt(db, LoginAttempt{IP: ip, Reason: "invalid_pin", CreatedAt: now})
+		return nil, ErrInvalidPIN
+	}
+	if err != nil {
+		return nil, err
+	}
+	attempt := LoginAttempt{Email: user.Email, UserID: &user.ID, IP: ip, CreatedAt: now}
+
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		attempt.Reason = "locked"
+		recordLoginAttempt(db, attempt)
+		return nil, &LoginBlockedError{Locked: true, RetryAfter: user.LockedUntil.Sub(now)}
+	}
+	if user.NeedsMFA() {
+		attempt.Reason = "invalid_pin"
+		recordLoginAttempt(db, attempt)
+		return nil, ErrPINNotAllowed
+	}
+	if user.PINHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PINHash), []byte(pin)) != nil {
+		attempt.Reason = "invalid_pin"
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Incrément conditionnel en base : des essais simultanés comptent tous, et l'effacement
+			// se décide sur la nouvelle valeur. Un code déjà changé ou effacé entre-temps n'est pas compté.
+			result := tx.Model(&User{}).Where("id = ? AND pin_hash = ?", user.ID, user.PINHash).
+				UpdateColumn("failed_pins", gorm.Expr("failed_pins + 1"))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 && user.PINHash != "" {
+				var failures int
+				if err := tx.Model(&User{}).Where("id = ?", user.ID).Select("failed_pins").Scan(&failures).Error; err != nil {
+					return err
+				}
+				if failures >= MaxFailedPINs {
+					attempt.Reason = "pin_disabled"
+					err := tx.Model(&User{}).Where("id = ?", user.ID).
+						UpdateColumns(map[string]interface{}{"pin_hash": "", "pin_set_at": nil, "failed_pins": 0}).Error
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return recordLoginAttempt(tx, attempt)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidPIN
+	}
+
+	attempt.Success = true
+	if err := db.Model(user).UpdateColumn("failed_pins", 0).Error; err != nil {
+		return nil, err
+	}
+	recordLoginAttempt(db, attempt)
+	return user, nil
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// ErrPriceListUnavailable est retournée quand la grille manuelle demandée n'existe pas ou est inactive
+var ErrPriceListUnavailable = errors.New("la grille de prix demandée n'est pas disponible")
+
+// PriceList est une grille de prix nommée qui remplace les prix de base des produits et menus
+// qu'elle contient, sur certains créneaux et/ou certains canaux. Une grille manuelle
+// (ex. « étudiant ») ne s'applique que si elle est choisie explicitement à la commande.
+type PriceList struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"not null" example:"Late night"`
+	Priority int    `json:"priority" example:"10"`
+	// Canaux concernés ; vide pour tous les canaux
+	Channels []ChannelType `json:"channels" gorm:"serializer:json" example:"kiosk"`
+	// Jours concernés (0 = dimanche ... 6 = samedi) ; vide pour tous les jours
+	Days []int `json:"days" gorm:"serializer:json" example:"5,6"`
+	// Créneau horaire au format HH:MM ; une fin antérieure au début déborde sur le lendemain
+	StartTime string                `json:"start_time" gorm:"type:varchar(5)" example:"22:00"`
+	EndTime   string                `json:"end_time" gorm:"type:varchar(5)" example:"02:00"`
+	Manual    bool                  `json:"manual"`
+	IsActive  bool                  `json:"is_active" gorm:"default:true"`
+	Items     []PriceListItem       `json:"items" gorm:"foreignKey:PriceListID"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+}
+
+// PriceListItem est le prix d'un produit ou d'un menu dans une grille
+type PriceListItem struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	PriceListID uint            `json:"price_list_id" gorm:"index"`
+	ProductID   *uint           `json:"product_id,omitempty" example:"1"`
+	MenuID      *uint           `json:"menu_id,omitempty"`
+	Price       decimal.Decimal `json:"price" gorm:"type:decimal(10,2);not null" example:"6.50"`
+}
+
+// parseClock convertit HH:MM en minutes depuis minuit
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("Heure invalide %q, format attendu : HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate vérifie la cohérence de la grille
+func (pl *PriceList) Validate() error {
+	if pl.Name == "" {
+		return fmt.Errorf("Le nom de la grille est obligatoire")
+	}
+	if (pl.StartTime == "") != (pl.EndTime == "") {
+		return fmt.Errorf("Le créneau doit avoir une heure de début et une heure de fin")
+	}
+	if pl.StartTime != "" {
+		if _, err := parseClock(pl.StartTime); err != nil {
+			return err
+		}
+		if _, err := parseClock(pl.EndTime); err != nil {
+			return err
+		}
+	}
+	for _, day := range pl.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("Jour invalide %d, attendu entre 0 (dimanche) et 6 (samedi)", day)
+		}
+	}
+	for _, channel := range pl.Channels {
+		if !channel.IsValid() {
+			return fmt.Errorf("Canal invalide %q", channel)
+		}
+	}
+	for _, item := range pl.Items {
+		if (item.ProductID == nil) == (item.MenuID == nil) {
+			return fmt.Errorf("Chaque prix doit référencer soit un produit, soit un menu")
+		}
+		if item.Price.IsNegative() {
+			return fmt.Errorf("Un prix ne peut pas être négatif")
+		}
+	}
+	return nil
+}
+
+// AppliesTo indique si la grille s'applique automatiquement à ce canal à cet instant
+func (pl *PriceList) AppliesTo(channel ChannelType, now time.Time) bool {
+	if !pl.IsActive {
+		return false
+	}
+	if len(pl.Channels) > 0 {
+		found := false
+		for _, c := range pl.Channels {
+			found = found || c == channel
+		}
+		if !found {
+			return false
+		}
+	}
+
+	day := now.Weekday()
+	if pl.StartTime != "" {
+		start, _ := parseClock(pl.StartTime)
+		end, _ := parseClock(pl.EndTime)
+		minutes := now.Hour()*60 + now.Minute()
+
+		switch {
+		case start <= end:
+			if minutes < start || minutes >= end {
+				return false
+			}
+		case minutes >= start:
+			// Partie du créneau avant minuit
+		case minutes < end:
+			// Après minuit, le créneau appartient au jour où il a commencé
+			day = now.AddDate(0, 0, -1).Weekday()
+		default:
+			return false
+		}
+	}
+
+	if len(pl.Days) > 0 {
+		for _, d := range pl.Days {
+			if time.Weekday(d) == day {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// CreatePriceList crée une grille et ses prix
+func CreatePriceList(db *gorm.DB, priceList *PriceList) error {
+	return db.Create(priceList).Error
+}
+
+// GetAllPriceLists récupère toutes les grilles avec leurs prix
+func GetAllPriceLists(db *gorm.DB) ([]PriceList, error) {
+	var priceLists []PriceList
+	err := db.Preload("Items").Order("priority DESC, id").Find(&priceLists).Error
+	return priceLists, err
+}
+
+// GetPriceListByID récupère une grille par son ID
+func GetPriceListByID(db *gorm.DB, id uint) (*PriceList, error) {
+	var priceList PriceList
+	err := db.Preload("Items").First(&priceList, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("La grille de prix avec l'Id %d n'a pas été trouvée", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &priceList, nil
+}
+
+// UpdatePriceList met à jour une grille en remplaçant tous ses prix
+func UpdatePriceList(db *gorm.DB, priceList *PriceList) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("price_list_id = ?", priceList.ID).Delete(&PriceListItem{}).Error; err != nil {
+			return err
+		}
+		for i := range priceList.Items {
+			priceList.Items[i].ID = 0
+			priceList.Items[i].PriceListID = priceList.ID
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(priceList).Error
+	})
+}
+
+// DeletePriceList supprime une grille
+func DeletePriceList(db *gorm.DB, id uint) error {
+	return db.Delete(&PriceList{}, id).Error
+}
+
+// PriceResolver donne le prix applicable à un produit ou un menu selon les grilles retenues
+type PriceResolver struct {
+	lists []PriceList
+}
+
+// ResolvedPrice est un prix issu d'une grille
+type ResolvedPrice struct {
+	Price         decimal.Decimal
+	PriceListID   uint
+	PriceListName string
+}
+
+// NewPriceResolver charge les grilles applicables à ce canal et à cet instant, plus la grille
+// manuelle demandée le cas échéant. Une grille manuelle inconnue ou inactive est une erreur.
+func NewPriceResolver(db *gorm.DB, channel ChannelType, now time.Time, manualID *uint) (*PriceResolver, error) {
+	var all []PriceList
+	if err := db.Preload("Items").Where("is_active = ?", true).Order("priority DESC, id").Find(&all).Error; err != nil {
+		return nil, err
+	}
+
+	resolver := &PriceResolver{}
+	manualFound := manualID == nil
+	for _, pl := range all {
+		if manualID != nil && pl.ID == *manualID {
+			manualFound = true
+			resolver.lists = append(resolver.lists, pl)
+			continue
+		}
+		if !pl.Manual && pl.AppliesTo(channel, now) {
+			resolver.lists = append(resolver.lists, pl)
+		}
+	}
+	if !manualFound {
+		return nil, ErrPriceListUnavailable
+	}
+	return resolver, nil
+}
+
+// lookup retourne le prix de la grille la plus prioritaire qui contient l'article
+func (r *PriceResolver) lookup(match func(PriceListItem) bool) (ResolvedPrice, bool) {
+	if r == nil {
+		return ResolvedPrice{}, false
+	}
+	for _, pl := range r.lists {
+		for _, item := range pl.Items {
+			if match(item) {
+				return ResolvedPrice{Price: item.Price, PriceListID: pl.ID, PriceListName: pl.Name}, true
+			}
+		}
+	}
+	return ResolvedPrice{}, false
+}
+
+// ProductPrice retourne le prix d'un produit issu d'une grille, s'il y en a un
+func (r *PriceResolver) ProductPrice(productID uint) (ResolvedPrice, bool) {
+	return r.lookup(func(item PriceListItem) bool { return item.ProductID != nil && *item.ProductID == productID })
+}
+
+// MenuPrice retourne le prix d'un menu issu d'une grille, s'il y en a un
+func (r *PriceResolver) MenuPrice(menuID uint) (ResolvedPrice, bool) {
+	return r.lookup(func(item PriceListItem) bool { return item.MenuID != nil && *item.MenuID == menuID })
+}
+
+// ApplyPriceLists remplace les prix de base des lignes par ceux des grilles applicables
+// et recalcule les totaux. À appeler après PriceOrderLines et avant ApplyPromotions.
+func ApplyPriceLists(order *PricedOrder, resolver *PriceResolver) {
+	order.Subtotal = decimal.Zero
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if line.Error != "" {
+			continue
+		}
+
+		var resolved ResolvedPrice
+		var ok bool
+		if line.menu != nil {
+			resolved, ok = resolver.MenuPrice(line.menu.ID)
+		} else {
+			resolved, ok = resolver.ProductPrice(line.product.ID)
+		}
+		if ok {
+			line.UnitPrice = resolved.Price
+			line.PriceListID = &resolved.PriceListID
+			line.PriceListName = resolved.PriceListName
+			line.Total = line.UnitPrice.Mul(decimal.NewFromInt(int64(line.Quantity)))
+		}
+		order.Subtotal = order.Subtotal.Add(line.Total)
+	}
+	order.Total = order.Subtotal.Sub(order.Discount)
+}
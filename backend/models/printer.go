@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// Port RAW utilisé par les imprimantes thermiques réseau
+const DefaultPrinterPort = "9100"
+
+// Printer représente une imprimante thermique réseau (RAW TCP).
+// Une imprimante rattachée à un poste reçoit uniquement les lignes de ce poste,
+// une imprimante sans poste reçoit le ticket complet de la commande.
+type Printer struct {
+	ID        uint                  `json:"id" gorm:"primaryKey" example:"1"`
+	Name      string                `json:"name" gorm:"not null" example:"Imprimante grill"`
+	Address   string                `json:"address" gorm:"not null" example:"192.168.1.50:9100"`
+	StationID *uint                 `json:"station_id" example:"2"`
+	IsActive  bool                  `json:"is_active" gorm:"default:true" example:"true"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli" json:"-"`
+}
+
+// NormalizePrinterAddress ajoute le port 9100 si l'adresse n'en précise pas
+func NormalizePrinterAddress(address string) (string, error) {
+	if address == "" {
+		return "", fmt.Errorf("Adresse d'imprimante invalide")
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address, nil
+	}
+	return net.JoinHostPort(address, DefaultPrinterPort), nil
+}
+
+// Définition du statut d'un travail d'impression
+type PrintJobStatus string
+
+const (
+	PrintJobPending PrintJobStatus = "pending"
+	PrintJobPrinted PrintJobStatus = "printed"
+	PrintJobDead    PrintJobStatus = "dead"
+)
+
+// PrintJob est un ticket en attente d'impression. Après trop d'échecs il passe en "dead" (liste des rejets).
+type PrintJob struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	PrinterID     uint           `json:"printer_id" gorm:"index"`
+	CommandeID    uint           `json:"commande_id" gorm:"index"`
+	StationID     *uint          `json:"station_id"`
+	Payload       []byte         `json:"-"`
+	Status        PrintJobStatus `json:"status" gorm:"type:varchar(20);index"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"index"`
+	PrintedAt     *time.Time     `json:"printed_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// CreatePrinter crée une imprimante
+func CreatePrinter(db *gorm.DB, printer *Printer) error {
+	return db.Create(printer).Error
+}
+
+// GetAllPrinters récupère toutes les imprimantes
+func GetAllPrinters(db *gorm.DB) ([]Printer, error) {
+	var printers []Printer
+	err := db.Order("id").Find(&printers).Error
+	return printers, err
+}
+
+// GetPrinterByID récupère une imprimante par son ID
+func GetPrinterByID(db *gorm.DB, id uint) (*Printer, error) {
+	var printer Printer
+	err := db.First(&printer, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("L'imprimante avec l'Id %d n'a pas été trouvée", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &printer, nil
+}
+
+// UpdatePrinter met à jour une imprimante
+func UpdatePrinter(db *gorm.DB, printer *Printer) error {
+	return db.Save(printer).Error
+}
+
+// DeletePrinter supprime une imprimante
+func DeletePrinter(db *gorm.DB, id uint) error {
+	return db.Delete(&Printer{}, id).Error
+}
+
+// GetPrintJobs récupère les travaux d'impression, éventuellement filtrés par statut
+func GetPrintJobs(db *gorm.DB, status PrintJobStatus) ([]PrintJob, error) {
+	var jobs []PrintJob
+	query := db.Order("id desc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&jobs).Error
+	return jobs, err
+}
+
+// GetDuePrintJobs récupère les travaux à envoyer maintenant
+func GetDuePrintJobs(db *gorm.DB, now time.Time, limit int) ([]PrintJob, error) {
+	var jobs []PrintJob
+	err := db.Where("status = ? AND next_attempt_at <= ?", PrintJobPending, now).
+		Order("id").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// ClaimPrintJob réserve un travail pour un worker jusqu'à now+lease. La mise à jour conditionnelle
+// garantit qu'un seul worker l'imprime ; si ce worker s'arrête avant d'enregistrer le résultat, le
+// travail redevient disponible à la fin du bail.
+func ClaimPrintJob(db *gorm.DB, job *PrintJob, now time.Time, lease time.Duration) (bool, error) {
+	leaseUntil := now.Add(lease)
+	result := db.Model(&PrintJob{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", job.ID, PrintJobPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	job.NextAttemptAt = leaseUntil
+	return true, nil
+}
+
+// RetryPrintJob remet un travail en file d'attente, par exemple depuis la liste des rejets
+func RetryPrintJob(db *gorm.DB, id uint) (*PrintJob, error) {
+	var job PrintJob
+	if err := db.First(&job, id).Error; err != nil {
+		return nil, fmt.Errorf("Le travail d'impression avec l'Id %d n'a pas été trouvé", id)
+	}
+
+	job.Status = PrintJobPending
+	job.Attempts = 0
+	job.LastError = ""
+	job.NextAttemptAt = time.Now()
+	return &job, db.Save(&job).Error
+}
@@ -25,7 +25,7 @@ const (
 // Méthode pour valider si un type de produit est valide
 func (r TypeProduct) IsValid() bool {
 	switch r {
-	case TypeEntree, TypePlat, TypeDessert:
+	case TypeEntree, TypePlat, TypeDessert, TypeBoisson:
 		return true
 	}
 	return false
@@ -39,6 +39,7 @@ type Product struct {
 	ImageURL    string                `json:"image_url" gorm:"type:text" example:"https://example.com/images/salade-cesar.jpg"`
 	Description string                `json:"description" gorm:"type:text" example:"Une délicieuse salade composée de laitue, poulet grillé, croûtons et parmesan."`
 	Type        TypeProduct           `json:"type" gorm:"not null" example:"entree"`
+	TaxCategory TaxCategory           `json:"tax_category" gorm:"type:varchar(20);default:food" example:"food" enums:"food,soft_drink,alcohol"`
 	CreatedAt   time.Time             `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time             `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt   soft_delete.DeletedAt `gorm:"softDelete:milli" json:"-"`
@@ -76,7 +77,22 @@ func UpdateProduct(db *gorm.DB, product *Product) error {
 
 // DeleteProduct supprime un produit
 func DeleteProduct(db *gorm.DB, id uint) error {
-	return db.Delete(&Product{}, id).Error
+	return db.Delete(&Product{ID: id}).Error
+}
+
+// AfterCreate publie la création du produit
+func (p *Product) AfterCreate(tx *gorm.DB) error {
+	return PublishEvent(tx, Event{Type: EventProductCreated, Data: p})
+}
+
+// AfterUpdate publie la modification du produit
+func (p *Product) AfterUpdate(tx *gorm.DB) error {
+	return PublishEvent(tx, Event{Type: EventProductUpdated, Data: p})
+}
+
+// AfterDelete publie la suppression du produit
+func (p *Product) AfterDelete(tx *gorm.DB) error {
+	return PublishEvent(tx, Event{Type: EventProductDeleted, Data: p})
 }
 
 func GetAvailableItemsByType(db *gorm.DB, Type string) ([]Product, error) {
@@ -0,0 +1,474 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// Définition du type de promotion
+type PromotionKind string
+
+const (
+	// Remise en pourcentage sur la commande ou sur un produit/menu
+	PromoPercentage PromotionKind = "percentage"
+	// Remise d'un montant fixe sur la commande ou sur un produit/menu
+	PromoFixed PromotionKind = "fixed"
+	// X achetés, Y offerts sur un produit ou un menu
+	PromoBuyXGetY PromotionKind = "buy_x_get_y"
+	// Menu supérieur facturé au prix du menu de base plus un supplément
+	PromoMenuUpgrade PromotionKind = "menu_upgrade"
+)
+
+// Méthode pour valider si un type de promotion est valide
+func (k PromotionKind) IsValid() bool {
+	switch k {
+	case PromoPercentage, PromoFixed, PromoBuyXGetY, PromoMenuUpgrade:
+		return true
+	}
+	return false
+}
+
+// ErrPromotionExhausted est retournée quand un code promo a atteint sa limite d'utilisation
+var ErrPromotionExhausted = errors.New("le code promo a atteint sa limite d'utilisation")
+
+// Promotion est une règle de remise, automatique ou déclenchée par un code promo
+type Promotion struct {
+	ID   uint          `json:"id" gorm:"primaryKey"`
+	Name string        `json:"name" gorm:"not null" example:"Menu maxi au prix du menu classique"`
+	Kind PromotionKind `json:"kind" gorm:"type:varchar(20);not null" example:"percentage" enums:"percentage,fixed,buy_x_get_y,menu_upgrade"`
+	// Pourcentage, montant fixe ou supplément du menu supérieur selon le type
+	Value decimal.Decimal `json:"value" gorm:"type:decimal(10,2)" example:"10"`
+	// Code promo en majuscules ; vide pour une promotion appliquée automatiquement.
+	// Unique parmi les promotions non supprimées : le code d'une promotion supprimée peut être repris.
+	Code *string `json:"code,omitempty" gorm:"type:varchar(50);uniqueIndex:idx_promotion_code,where:deleted_at = 0" example:"BIENVENUE"`
+	// Cible de la remise : sans cible, elle s'applique à toute la commande
+	ProductID    *uint `json:"product_id,omitempty"`
+	MenuID       *uint `json:"menu_id,omitempty"`
+	BaseMenuID   *uint `json:"base_menu_id,omitempty"`
+	BuyQuantity  int   `json:"buy_quantity,omitempty" example:"2"`
+	FreeQuantity int   `json:"free_quantity,omitempty" example:"1"`
+	// Montant minimal de la commande (avant remise)
+	MinOrderAmount decimal.Decimal `json:"min_order_amount" gorm:"type:decimal(10,2)"`
+	// Nombre maximal d'utilisations, 0 pour illimité
+	MaxUses   int        `json:"max_uses" example:"100"`
+	UsedCount int        `json:"used_count"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	// Une promotion non cumulable n'est jamais combinée avec une autre
+	Stackable bool                  `json:"stackable" example:"true"`
+	IsActive  bool                  `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+}
+
+// CommandePromotion enregistre une promotion appliquée à une commande et le montant remisé
+type CommandePromotion struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	CommandeID  uint            `json:"commande_id" gorm:"index"`
+	PromotionID uint            `json:"promotion_id" gorm:"index"`
+	Name        string          `json:"name"`
+	Code        string          `json:"code,omitempty"`
+	Discount    decimal.Decimal `json:"discount" gorm:"type:decimal(10,2)"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// AppliedPromotion est une promotion retenue lors du calcul d'une commande
+type AppliedPromotion struct {
+	PromotionID uint            `json:"promotion_id"`
+	Name        string          `json:"name"`
+	Code        string          `json:"code,omitempty"`
+	Discount    decimal.Decimal `json:"discount"`
+
+	maxUses int
+}
+
+// NormalizePromotionCode met un code promo au format enregistré
+func NormalizePromotionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate vérifie la cohérence des champs selon le type de promotion
+func (p *Promotion) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("Le nom de la promotion est obligatoire")
+	}
+	if !p.Kind.IsValid() {
+		return fmt.Errorf("Type de promotion invalide")
+	}
+	if p.ProductID != nil && p.MenuID != nil {
+		return fmt.Errorf("Une promotion cible soit un produit, soit un menu")
+	}
+	if p.Value.IsNegative() || p.MinOrderAmount.IsNegative() || p.MaxUses < 0 {
+		return fmt.Errorf("Les montants et limites ne peuvent pas être négatifs")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("La fin de validité doit être postérieure au début")
+	}
+
+	switch p.Kind {
+	case PromoPercentage:
+		if p.Value.IsZero() || p.Value.GreaterThan(decimal.NewFromInt(100)) {
+			return fmt.Errorf("Le pourcentage doit être compris entre 0 et 100")
+		}
+	case PromoFixed:
+		if p.Value.IsZero() {
+			return fmt.Errorf("Le montant de la remise est obligatoire")
+		}
+	case PromoBuyXGetY:
+		if p.ProductID == nil && p.MenuID == nil {
+			return fmt.Errorf("Une offre X achetés Y offerts doit cibler un produit ou un menu")
+		}
+		if p.BuyQuantity <= 0 || p.FreeQuantity <= 0 {
+			return fmt.Errorf("Les quantités achetées et offertes doivent être supérieures à 0")
+		}
+	case PromoMenuUpgrade:
+		if p.MenuID == nil || p.BaseMenuID == nil {
+			return fmt.Errorf("Un surclassement doit indiquer le menu supérieur et le menu de base")
+		}
+	}
+
+	if p.Code != nil {
+		code := NormalizePromotionCode(*p.Code)
+		if code == "" {
+			p.Code = nil
+		} else {
+			p.Code = &code
+		}
+	}
+	return nil
+}
+
+// IsUsableAt indique si la promotion est active, dans sa fenêtre de validité et pas épuisée
+func (p *Promotion) IsUsableAt(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return p.MaxUses == 0 || p.UsedCount < p.MaxUses
+}
+
+// DropLegacyPromotionCodeIndex supprime l'ancien index unique sur le code, qui portait aussi
+// sur les promotions supprimées ; AutoMigrate crée l'index partiel qui le remplace
+func DropLegacyPromotionCodeIndex(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&Promotion{}, "idx_promotions_code") {
+		return db.Migrator().DropIndex(&Promotion{}, "idx_promotions_code")
+	}
+	return nil
+}
+
+// CreatePromotion crée une nouvelle promotion
+func CreatePromotion(db *gorm.DB, promotion *Promotion) error {
+	return db.Create(promotion).Error
+}
+
+// GetAllPromotions récupère toutes les promotions
+func GetAllPromotions(db *gorm.DB) ([]Promotion, error) {
+	var promotions []Promotion
+	err := db.Order("id").Find(&promotions).Error
+	return promotions, err
+}
+
+// GetPromotionByID récupère une promotion par son ID
+func GetPromotionByID(db *gorm.DB, id uint) (*Promotion, error) {
+	var promotion Promotion
+	err := db.First(&promotion, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("La promotion avec l'Id %d n'a pas été trouvée", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &promotion, nil
+}
+
+// UpdatePromotion met à jour une promotion existante
+func UpdatePromotion(db *gorm.DB, promotion *Promotion) error {
+	return db.Save(promotion).Error
+}
+
+// DeletePromotion supprime une promotion ; les commandes passées gardent leur remise
+func DeletePromotion(db *gorm.DB, id uint) error {
+	return db.Delete(&Promotion{}, id).Error
+}
+
+// ApplyPromotions calcule les remises d'une commande déjà chiffrée : promotions automatiques
+// et codes promo saisis par le client. Les promotions cumulables s'additionnent ; une promotion
+// non cumulable n'est retenue que si elle est plus avantageuse que toutes les autres réunies.
+// Un code inconnu ou inutilisable rend la commande invalide.
+func ApplyPromotions(db *gorm.DB, order *PricedOrder, codes []string, now time.Time) error {
+	if !order.Valid {
+		return nil
+	}
+
+	var candidates []Promotion
+	if err := db.Where("code IS NULL AND is_active = ?", true).Order("id").Find(&candidates).Error; err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, raw := range codes {
+		code := NormalizePromotionCode(raw)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		var promotion Promotion
+		err := db.Where("code = ?", code).First(&promotion).Error
+		if err == gorm.ErrRecordNotFound {
+			order.reject(fmt.Sprintf("Le code promo %s n'existe pas", code))
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("erreur lors de la récupération: %w", err)
+		}
+		if !promotion.IsUsableAt(now) {
+			order.reject(fmt.Sprintf("Le code promo %s n'est plus valable", code))
+			return nil
+		}
+		candidates = append(candidates, promotion)
+	}
+
+	var stackable, exclusive []Promotion
+	for _, p := range candidates {
+		if !p.IsUsableAt(now) || order.Subtotal.LessThan(p.MinOrderAmount) {
+			continue
+		}
+		if p.Stackable {
+			stackable = append(stackable, p)
+		} else {
+			exclusive = append(exclusive, p)
+		}
+	}
+
+	// Meilleure combinaison : toutes les cumulables, ou une seule non cumulable
+	best, bestLines, err := evaluatePromotions(db, order.Lines, stackable)
+	if err != nil {
+		return err
+	}
+	for _, p := range exclusive {
+		applied, lines, err := evaluatePromotions(db, order.Lines, []Promotion{p})
+		if err != nil {
+			return err
+		}
+		if totalDiscount(applied).GreaterThan(totalDiscount(best)) {
+			best, bestLines = applied, lines
+		}
+	}
+
+	for i := range order.Lines {
+		order.Lines[i].Discount = bestLines[i]
+	}
+	order.Promotions = best
+	order.Discount = totalDiscount(best)
+	order.Total = order.Subtotal.Sub(order.Discount)
+	return nil
+}
+
+// reject marque la commande invalide à cause d'une promotion
+func (order *PricedOrder) reject(message string) {
+	order.Valid = false
+	order.PromotionError = message
+}
+
+func totalDiscount(applied []AppliedPromotion) decimal.Decimal {
+	total := decimal.Zero
+	for _, a := range applied {
+		total = total.Add(a.Discount)
+	}
+	return total
+}
+
+// evaluatePromotions applique les promotions l'une après l'autre, chacune portant sur
+// le montant restant des lignes : une ligne ne peut donc jamais devenir négative.
+func evaluatePromotions(db *gorm.DB, lines []PricedLine, promotions []Promotion) ([]AppliedPromotion, []decimal.Decimal, error) {
+	discounts := make([]decimal.Decimal, len(lines))
+	for i := range discounts {
+		discounts[i] = decimal.Zero
+	}
+
+	var applied []AppliedPromotion
+	for _, p := range promotions {
+		perLine, err := p.lineDiscounts(db, lines, discounts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		amount := decimal.Zero
+		for i, d := range perLine {
+			remaining := lines[i].Total.Sub(discounts[i])
+			if d.GreaterThan(remaining) {
+				d = remaining
+			}
+			discounts[i] = discounts[i].Add(d)
+			amount = amount.Add(d)
+		}
+		if amount.IsPositive() {
+			code := ""
+			if p.Code != nil {
+				code = *p.Code
+			}
+			applied = append(applied, AppliedPromotion{PromotionID: p.ID, Name: p.Name, Code: code, Discount: amount, maxUses: p.MaxUses})
+		}
+	}
+	return applied, discounts, nil
+}
+
+// targets indique si la ligne est concernée par la promotion
+func (p *Promotion) targets(line PricedLine) bool {
+	switch {
+	case p.ProductID != nil:
+		return line.ProductID == *p.ProductID
+	case p.MenuID != nil:
+		return line.MenuID == *p.MenuID
+	}
+	return true
+}
+
+// lineDiscounts calcule la remise de la promotion sur chaque ligne
+func (p *Promotion) lineDiscounts(db *gorm.DB, lines []PricedLine, already []decimal.Decimal) ([]decimal.Decimal, error) {
+	result := make([]decimal.Decimal, len(lines))
+	base := decimal.Zero
+	for i, line := range lines {
+		result[i] = decimal.Zero
+		if p.targets(line) {
+			base = base.Add(line.Total.Sub(already[i]))
+		}
+	}
+	if !base.IsPositive() {
+		return result, nil
+	}
+
+	switch p.Kind {
+	case PromoPercentage:
+		spread(result, lines, already, p, base.Mul(p.Value).Div(decimal.NewFromInt(100)).Round(2), base)
+	case PromoFixed:
+		spread(result, lines, already, p, decimal.Min(p.Value, base), base)
+	case PromoBuyXGetY:
+		quantity := 0
+		for _, line := range lines {
+			if p.targets(line) {
+				quantity += line.Quantity
+			}
+		}
+		free := quantity / (p.BuyQuantity + p.FreeQuantity) * p.FreeQuantity
+		for i, line := range lines {
+			if free == 0 {
+				break
+			}
+			if !p.targets(line) {
+				continue
+			}
+			units := min(free, line.Quantity)
+			result[i] = line.UnitPrice.Mul(decimal.NewFromInt(int64(units)))
+			free -= units
+		}
+	case PromoMenuUpgrade:
+		var baseMenu Menu
+		if err := db.First(&baseMenu, *p.BaseMenuID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return result, nil
+			}
+			return nil, err
+		}
+		upgraded := baseMenu.Price.Add(p.Value)
+		for i, line := range lines {
+			if p.targets(line) && line.UnitPrice.GreaterThan(upgraded) {
+				result[i] = line.UnitPrice.Sub(upgraded).Mul(decimal.NewFromInt(int64(line.Quantity)))
+			}
+		}
+	}
+	return result, nil
+}
+
+// spread répartit une remise globale sur les lignes ciblées au prorata de leur montant,
+// le reliquat d'arrondi allant sur la dernière ligne
+func spread(result []decimal.Decimal, lines []PricedLine, already []decimal.Decimal, p *Promotion, amount, base decimal.Decimal) {
+	last := -1
+	allocated := decimal.Zero
+	for i, line := range lines {
+		if !p.targets(line) || !line.Total.Sub(already[i]).IsPositive() {
+			continue
+		}
+		result[i] = amount.Mul(line.Total.Sub(already[i])).Div(base).Round(2)
+		allocated = allocated.Add(result[i])
+		last = i
+	}
+	if last >= 0 {
+		result[last] = result[last].Add(amount.Sub(allocated))
+	}
+}
+
+// recordPromotions enregistre les promotions appliquées et consomme leurs utilisations.
+// Le compteur est incrémenté sous condition pour qu'un code ne dépasse jamais sa limite.
+func recordPromotions(tx *gorm.DB, commande *Commande, applied []AppliedPromotion) error {
+	for _, a := range applied {
+		res := tx.Model(&Promotion{}).
+			Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", a.PromotionID).
+			UpdateColumn("used_count", gorm.Expr("used_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 && a.maxUses > 0 {
+			return ErrPromotionExhausted
+		}
+
+		if err := tx.Create(&CommandePromotion{
+			CommandeID:  commande.ID,
+			PromotionID: a.PromotionID,
+			Name:        a.Name,
+			Code:        a.Code,
+			Discount:    a.Discount,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiscountReportLine est le coût d'une promotion sur une période
+type DiscountReportLine struct {
+	PromotionID uint            `json:"promotion_id"`
+	Name        string          `json:"name"`
+	Code        string          `json:"code,omitempty"`
+	Uses        int64           `json:"uses"`
+	Discount    decimal.Decimal `json:"discount"`
+}
+
+// GetDiscountReport totalise les remises accordées par promotion entre deux dates
+func GetDiscountReport(db *gorm.DB, from, to time.Time) ([]DiscountReportLine, error) {
+	var applied []CommandePromotion
+	err := db.Joins("JOIN commandes ON commandes.id = commande_promotions.commande_id AND commandes.deleted_at = 0").
+		Where("commande_promotions.created_at >= ? AND commande_promotions.created_at < ?", from, to).
+		Order("commande_promotions.promotion_id").
+		Find(&applied).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var report []DiscountReportLine
+	index := map[uint]int{}
+	for _, a := range applied {
+		i, ok := index[a.PromotionID]
+		if !ok {
+			i = len(report)
+			index[a.PromotionID] = i
+			report = append(report, DiscountReportLine{PromotionID: a.PromotionID, Name: a.Name, Code: a.Code, Discount: decimal.Zero})
+		}
+		report[i].Uses++
+		report[i].Discount = report[i].Discount.Add(a.Discount)
+	}
+	return report, nil
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// GetCustomerCommandes retourne les commandes d'un client, de la plus récente à la plus ancienne
+func GetCustomerCommandes(db *gorm.DB, customerID uint, limit int) ([]Commande, error) {
+	var commandes []Commande
+	query := db.Preload("Menus").Preload("Products").Preload("Promotions").
+		Where("customer_id = ?", customerID).
+		Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&commandes).Error
+	return commandes, err
+}
+
+// ReorderLines reconstruit les lignes d'une commande passée. Les produits offerts par une
+// récompense fidélité ne sont pas repris : ils doivent être échangés à nouveau contre des points.
+func ReorderLines(commande *Commande) []OrderLine {
+	var lines []OrderLine
+	for _, m := range commande.Menus {
+		lines = append(lines, OrderLine{MenuID: m.MenuID, Quantity: max(m.Quantity, 1), Modifiers: m.Modifiers})
+	}
+	for _, p := range commande.Products {
+		if p.RewardID != nil {
+			continue
+		}
+		lines = append(lines, OrderLine{ProductID: p.ProductID, Quantity: max(p.Quantity, 1), Modifiers: p.Modifiers})
+	}
+	return lines
+}
+
+// ReorderResult est le résultat d'une nouvelle commande créée à partir d'une commande passée
+type ReorderResult struct {
+	Commande *Commande    `json:"commande,omitempty"`
+	Order    *PricedOrder `json:"order"`
+	// Lignes de la commande d'origine qui ne peuvent plus être servies
+	Unavailable []PricedLine `json:"unavailable"`
+}
+
+// Reorder recrée une commande passée aux prix et disponibilités du moment. Les lignes qui ne
+// peuvent plus être servies (article supprimé ou indisponible) sont écartées et signalées ;
+// si aucune ligne ne peut être servie, aucune commande n'est créée.
+func Reorder(db *gorm.DB, source *Commande, commande *Commande, ctx PricingContext) (*ReorderResult, error) {
+	lines := ReorderLines(source)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("La commande ne contient aucun article à recommander")
+	}
+
+	// Les lignes ne sont chiffrées qu'une fois : celles qui ne peuvent plus être servies (y compris
+	// un menu dont un produit est indisponible) sont écartées avant les grilles et les promotions
+	checked, err := PriceOrderLines(db, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReorderResult{Unavailable: []PricedLine{}}
+	order := &PricedOrder{Subtotal: decimal.Zero, Discount: decimal.Zero, Total: decimal.Zero}
+	for _, line := range checked.Lines {
+		if line.Error != "" {
+			result.Unavailable = append(result.Unavailable, line)
+			continue
+		}
+		order.Lines = append(order.Lines, line)
+		order.Subtotal = order.Subtotal.Add(line.Total)
+	}
+	if len(order.Lines) == 0 {
+		result.Order = checked
+		return result, nil
+	}
+	order.Total = order.Subtotal
+	order.Valid = true
+
+	if err := applyPricing(db, order, ctx); err != nil {
+		return nil, err
+	}
+	result.Order = order
+	if !order.Valid {
+		return result, nil
+	}
+
+	if commande.CustomerID == nil {
+		commande.CustomerID = source.CustomerID
+	}
+	if commande.ServiceMode == "" {
+		commande.ServiceMode = source.ServiceMode
+	}
+	if err := CreateCommandeFromLines(db, order, commande); err != nil {
+		return nil, err
+	}
+	result.Commande = commande
+	return result, nil
+}
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Durées de vie par défaut des jetons
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// AccessTokenTTL retourne la durée de vie d'un JWT d'accès, configurable via ACCESS_TOKEN_TTL_MINUTES
+func AccessTokenTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultAccessTokenTTL
+}
+
+// RefreshTokenTTL retourne la durée de vie d'un jeton de rafraîchissement, configurable via REFRESH_TOKEN_TTL_DAYS
+func RefreshTokenTTL() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_TTL_DAYS")); err == nil && days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return DefaultRefreshTokenTTL
+}
+
+var (
+	// ErrRefreshTokenInvalid est retournée pour un jeton inconnu, expiré ou d'une session fermée
+	ErrRefreshTokenInvalid = errors.New("jeton de rafraîchissement invalide ou expiré")
+	// ErrRefreshTokenReused est retournée quand un jeton déjà échangé est présenté à nouveau :
+	// il a probablement été volé, toute la session est fermée
+	ErrRefreshTokenReused = errors.New("jeton de rafraîchissement déjà utilisé, session fermée")
+	// ErrUserDisabled est retournée pour un compte supprimé ou désactivé
+	ErrUserDisabled = errors.New("compte supprimé ou désactivé")
+	// ErrSessionExpired est retournée pour une session fermée ou restée inactive trop longtemps
+	ErrSessionExpired = errors.New("Session expirée, veuillez vous reconnecter")
+	// ErrSessionDevice est retournée quand une session ouverte sur une tablette est utilisée sans
+	// les identifiants de cette tablette
+	ErrSessionDevice = errors.New("Session liée à une tablette : identifiants de l'appareil manquants ou invalides")
+)
+
+// Fréquence maximale d'enregistrement de l'activité d'une session, pour ne pas écrire à chaque requête
+const sessionTouchInterval = 30 * time.Second
+
+// AuthSession est une connexion d'un utilisateur : elle dure tant que ses jetons de
+// rafraîchissement sont échangés, et sa fermeture invalide aussi les JWT d'accès qui la citent.
+type AuthSession struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	UserID    uint   `json:"user_id" gorm:"index"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	// Vrai si la connexion a été confirmée par un second facteur
+	MFA bool `json:"mfa" gorm:"default:false"`
+	// Tablette partagée sur laquelle la session a été ouverte par code PIN
+	DeviceID *uint `json:"device_id" gorm:"index"`
+	// Inactivité tolérée en secondes avant fermeture automatique (0 : pas de limite)
+	IdleTimeoutSeconds int        `json:"idle_timeout_seconds" gorm:"default:0"`
+	LastSeenAt         *time.Time `json:"last_seen_at"`
+	RevokedAt          *time.Time `json:"revoked_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// RefreshToken est un jeton de rafraîchissement à usage unique : chaque échange le remplace
+// par un nouveau jeton de la même session. Seule son empreinte est conservée.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	SessionID uint       `json:"session_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ActiveUser récupère un utilisateur qui peut encore se connecter
+func ActiveUser(db *gorm.DB, id uint) (*User, error) {
+	var user User
+	if err := db.First(&user, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserDisabled
+		}
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrUserDisabled
+	}
+	return &user, nil
+}
+
+// idleExpired indique si la session est restée inactive au-delà de sa limite
+func (s *AuthSession) idleExpired(now time.Time) bool {
+	if s.IdleTimeoutSeconds <= 0 {
+		return false
+	}
+	lastSeen := s.CreatedAt
+	if s.LastSeenAt != nil {
+		lastSeen = *s.LastSeenAt
+	}
+	return now.Sub(lastSeen) > time.Duration(s.IdleTimeoutSeconds)*time.Second
+}
+
+// StartSession ouvre une session pour l'utilisateur et retourne son premier jeton de rafraîchissement
+func StartSession(db *gorm.DB, session *AuthSession) (string, error) {
+	var token string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		var err error
+		token, err = issueRefreshToken(tx, session.ID)
+		return err
+	})
+	return token, err
+}
+
+func issueRefreshToken(db *gorm.DB, sessionID uint) (string, error) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	refresh := RefreshToken{SessionID: sessionID, TokenHash: hash, ExpiresAt: time.Now().Add(RefreshTokenTTL())}
+	if err := db.Create(&refresh).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken échange un jeton de rafraîchissement contre un nouveau. Un jeton déjà
+// échangé ferme la session : le voleur comme l'utilisateur légitime doivent se reconnecter.
+func RotateRefreshToken(db *gorm.DB, token string) (*User, *AuthSession, string, error) {
+	var (
+		user     *User
+		session  AuthSession
+		newToken string
+		reused   bool
+	)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var refresh RefreshToken
+		if err := tx.Where("token_hash = ?", hashSecretToken(token)).First(&refresh).Error; err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		if err := tx.First(&session, refresh.SessionID).Error; err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		now := time.Now()
+		if session.RevokedAt != nil || session.idleExpired(now) {
+			return ErrRefreshTokenInvalid
+		}
+		if refresh.UsedAt != nil {
+			reused = true
+			return ErrRefreshTokenReused
+		}
+		if now.After(refresh.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		var err error
+		if user, err = ActiveUser(tx, session.UserID); err != nil {
+			return err
+		}
+
+		// La condition sur used_at garantit l'usage unique face à deux requêtes simultanées
+		result := tx.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", refresh.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return ErrRefreshTokenReused
+		}
+
+		newToken, err = issueRefreshToken(tx, session.ID)
+		return err
+	})
+
+	// La fermeture de la session est faite hors de la transaction annulée
+	if reused {
+		if revokeErr := RevokeSession(db, session.ID); revokeErr != nil {
+			return nil, nil, "", revokeErr
+		}
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return user, &session, newToken, nil
+}
+
+// SessionForRefreshToken retrouve la session d'un jeton de rafraîchissement, même déjà échangé
+func SessionForRefreshToken(db *gorm.DB, token string) (*AuthSession, error) {
+	var refresh RefreshToken
+	if err := db.Where("token_hash = ?", hashSecretToken(token)).First(&refresh).Error; err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	var session AuthSession
+	if err := db.First(&session, refresh.SessionID).Error; err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return &session, nil
+}
+
+// RevokeSession ferme une session
+func RevokeSession(db *gorm.DB, id uint) error {
+	return db.Model(&AuthSession{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions ferme toutes les sessions d'un utilisateur
+func RevokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&AuthSession{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeDeviceSessions ferme les sessions ouvertes sur une tablette, sauf exceptID
+func RevokeDeviceSessions(db *gorm.DB, deviceID uint, exceptID uint) error {
+	return db.Model(&AuthSession{}).Where("device_id = ? AND id <> ? AND revoked_at IS NULL", deviceID, exceptID).Update("revoked_at", time.Now()).Error
+}
+
+// TouchSession vérifie qu'une session est encore ouverte pour cet utilisateur et enregistre son
+// activité. Une session inactive au-delà de sa limite est fermée.
+func TouchSession(db *gorm.DB, id uint, userID uint, now time.Time) (*AuthSession, error) {
+	var session AuthSession
+	if err := db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session).Error; err != nil {
+		return nil, ErrSessionExpired
+	}
+	if session.idleExpired(now) {
+		if err := RevokeSession(db, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrSessionExpired
+	}
+	if session.IdleTimeoutSeconds > 0 && (session.LastSeenAt == nil || now.Sub(*session.LastSeenAt) >= sessionTouchInterval) {
+		db.Model(&session).UpdateColumn("last_seen_at", now)
+		session.LastSeenAt = &now
+	}
+	return &session, nil
+}
@@ -0,0 +1,337 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/soft_delete"
+)
+
+// Station représente un poste de préparation en cuisine (boissons, frites, burgers...)
+type Station struct {
+	ID           uint                  `json:"id" gorm:"primaryKey" example:"1"`
+	Name         string                `json:"name" gorm:"not null" example:"Boissons"`
+	ProductTypes []TypeProduct         `json:"product_types" gorm:"serializer:json" example:"boisson"`
+	IsDefault    bool                  `json:"is_default" example:"false"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	DeletedAt    soft_delete.DeletedAt `gorm:"softDelete:milli" json:"-"`
+}
+
+// CommandeStationTask enregistre la prise en charge d'une commande par un poste (première
+// apparition sur son écran) et la fin de sa préparation
+type CommandeStationTask struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CommandeID uint       `json:"commande_id" gorm:"uniqueIndex:idx_commande_station"`
+	StationID  uint       `json:"station_id" gorm:"uniqueIndex:idx_commande_station"`
+	StartedAt  *time.Time `json:"started_at"`
+	DoneBy     uint       `json:"done_by"`
+	DoneAt     *time.Time `json:"done_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// StationTicketLine est une ligne à préparer par un poste
+type StationTicketLine struct {
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	Type      TypeProduct `json:"type"`
+	Modifiers []string    `json:"modifiers,omitempty"`
+	MenuName  string      `json:"menu_name,omitempty"`
+}
+
+// StationTicket est la vue d'une commande pour un poste : seulement ses lignes
+type StationTicket struct {
+	CommandeID   uint                `json:"commande_id"`
+	TicketNumber uint                `json:"ticket_number"`
+	Status       StatusType          `json:"status"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Lines        []StationTicketLine `json:"lines"`
+	Done         bool                `json:"done"`
+}
+
+// CreateStation crée un poste
+func CreateStation(db *gorm.DB, station *Station) error {
+	return db.Create(station).Error
+}
+
+// GetAllStations récupère tous les postes
+func GetAllStations(db *gorm.DB) ([]Station, error) {
+	var stations []Station
+	err := db.Order("id").Find(&stations).Error
+	return stations, err
+}
+
+// GetStationByID récupère un poste par son ID
+func GetStationByID(db *gorm.DB, id uint) (*Station, error) {
+	var station Station
+	err := db.First(&station, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("Le poste avec l'Id %d n'a pas été trouvé", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &station, nil
+}
+
+// UpdateStation met à jour un poste existant
+func UpdateStation(db *gorm.DB, station *Station) error {
+	return db.Save(station).Error
+}
+
+// DeleteStation supprime un poste
+func DeleteStation(db *gorm.DB, id uint) error {
+	return db.Delete(&Station{}, id).Error
+}
+
+// stationForType retourne le poste qui prépare un type de produit, ou le poste par défaut
+func stationForType(stations []Station, t TypeProduct) *Station {
+	var fallback *Station
+	for i := range stations {
+		for _, st := range stations[i].ProductTypes {
+			if st == t {
+				return &stations[i]
+			}
+		}
+		if stations[i].IsDefault && fallback == nil {
+			fallback = &stations[i]
+		}
+	}
+	return fallback
+}
+
+// CheckStationsCoverage vérifie que chaque type de produit est préparé par un poste, directement
+// ou via le poste par défaut : sinon ses lignes n'apparaîtraient sur aucun écran de cuisine
+func CheckStationsCoverage(stations []Station) error {
+	if len(stations) == 0 {
+		return nil
+	}
+	covered := map[TypeProduct]bool{}
+	for _, station := range stations {
+		if station.IsDefault {
+			return nil
+		}
+		for _, t := range station.ProductTypes {
+			covered[t] = true
+		}
+	}
+	for _, t := range []TypeProduct{TypeEntree, TypePlat, TypeDessert, TypeBoisson} {
+		if !covered[t] {
+			return fmt.Errorf("Aucun poste ne prépare les produits de type %s : ajoutez-le à un poste ou désignez un poste par défaut", t)
+		}
+	}
+	return nil
+}
+
+// menuItemsByMenu charge en une requête les items des menus des commandes, par menu
+func menuItemsByMenu(db *gorm.DB, commandes []Commande) (map[uint][]MenuItem, error) {
+	items := map[uint][]MenuItem{}
+	var menuIDs []uint
+	for _, commande := range commandes {
+		for _, m := range commande.Menus {
+			menuIDs = append(menuIDs, m.MenuID)
+		}
+	}
+	if len(menuIDs) == 0 {
+		return items, nil
+	}
+
+	var all []MenuItem
+	if err := db.Where("menu_id IN ?", menuIDs).Find(&all).Error; err != nil {
+		return nil, err
+	}
+	for _, item := range all {
+		items[item.MenuID] = append(items[item.MenuID], item)
+	}
+	return items, nil
+}
+
+// RouteCommande répartit les lignes d'une commande entre les postes.
+// Les menus sont éclatés en leurs items, chacun étant envoyé au poste de son type.
+// Menus et Products doivent être préchargés.
+func RouteCommande(db *gorm.DB, commande *Commande, stations []Station) (map[uint][]StationTicketLine, error) {
+	menuItems, err := menuItemsByMenu(db, []Commande{*commande})
+	if err != nil {
+		return nil, err
+	}
+	return routeLines(commande, stations, menuItems), nil
+}
+
+// routeLines répartit les lignes d'une commande entre les postes, les items des menus étant déjà chargés
+func routeLines(commande *Commande, stations []Station, menuItems map[uint][]MenuItem) map[uint][]StationTicketLine {
+	routed := map[uint][]StationTicketLine{}
+
+	for _, p := range commande.Products {
+		if station := stationForType(stations, p.Type); station != nil {
+			routed[station.ID] = append(routed[station.ID], StationTicketLine{
+				Name:      p.Name,
+				Quantity:  p.Quantity,
+				Type:      p.Type,
+				Modifiers: p.Modifiers,
+			})
+		}
+	}
+
+	for _, m := range commande.Menus {
+		for _, item := range menuItems[m.MenuID] {
+			if station := stationForType(stations, item.Type); station != nil {
+				routed[station.ID] = append(routed[station.ID], StationTicketLine{
+					Name:      item.Name,
+					Quantity:  m.Quantity,
+					Type:      item.Type,
+					Modifiers: m.Modifiers,
+					MenuName:  m.Name,
+				})
+			}
+		}
+	}
+
+	return routed
+}
+
+// GetStationTickets retourne les commandes en cours qui ont des lignes pour ce poste. Une commande
+// affichée pour la première fois est prise en charge par le poste (voir startStationTask).
+func GetStationTickets(db *gorm.DB, station *Station) ([]StationTicket, error) {
+	stations, err := GetAllStations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var commandes []Commande
+	err = db.Preload("Menus").Preload("Products").
+		Where("status IN ?", []StatusType{StatusPending, StatusPreparing}).
+		Order("created_at").
+		Find(&commandes).Error
+	if err != nil {
+		return nil, err
+	}
+	menuItems, err := menuItemsByMenu(db, commandes)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []CommandeStationTask
+	if err := db.Where("station_id = ?", station.ID).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	started, done := map[uint]bool{}, map[uint]bool{}
+	for _, task := range tasks {
+		started[task.CommandeID] = task.StartedAt != nil
+		done[task.CommandeID] = task.DoneAt != nil
+	}
+
+	now := time.Now()
+	tickets := []StationTicket{}
+	for i := range commandes {
+		lines, ok := routeLines(&commandes[i], stations, menuItems)[station.ID]
+		if !ok {
+			continue
+		}
+		if !started[commandes[i].ID] {
+			if err := startStationTask(db, &commandes[i], station, now); err != nil {
+				return nil, err
+			}
+		}
+		tickets = append(tickets, StationTicket{
+			CommandeID:   commandes[i].ID,
+			TicketNumber: commandes[i].TicketNumber,
+			Status:       commandes[i].Status,
+			CreatedAt:    commandes[i].CreatedAt,
+			Lines:        lines,
+			Done:         done[commandes[i].ID],
+		})
+	}
+
+	return tickets, nil
+}
+
+// startStationTask enregistre la prise en charge d'une commande par un poste. La première prise en
+// charge fait passer la commande en "preparing" : le temps de préparation est ainsi mesuré de la même
+// façon pour les commandes à un seul poste et celles réparties entre plusieurs.
+func startStationTask(db *gorm.DB, commande *Commande, station *Station, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		task := CommandeStationTask{CommandeID: commande.ID, StationID: station.ID, StartedAt: &now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&task).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&CommandeStationTask{}).
+			Where("commande_id = ? AND station_id = ? AND started_at IS NULL", commande.ID, station.ID).
+			Update("started_at", now).Error
+		if err != nil || commande.Status != StatusPending {
+			return err
+		}
+
+		// Save déclenche les hooks d'historisation des statuts
+		commande.Status = StatusPreparing
+		return tx.Omit(clause.Associations).Save(commande).Error
+	})
+}
+
+// MarkStationDone enregistre qu'un poste a terminé ses lignes pour une commande.
+// La commande passe en "preparing" au premier poste terminé si aucun poste ne l'avait encore prise
+// en charge, et en "ready" quand tous les postes concernés ont fini.
+func MarkStationDone(db *gorm.DB, commandeID uint, station *Station, userID uint) (*Commande, error) {
+	var commande Commande
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Menus").Preload("Products").First(&commande, commandeID).Error; err != nil {
+			return fmt.Errorf("La commande avec l'Id %d n'a pas été trouvée", commandeID)
+		}
+		if commande.Status != StatusPending && commande.Status != StatusPreparing {
+			return fmt.Errorf("La commande n'est plus en préparation")
+		}
+
+		stations, err := GetAllStations(tx)
+		if err != nil {
+			return err
+		}
+		routed, err := RouteCommande(tx, &commande, stations)
+		if err != nil {
+			return err
+		}
+		if _, ok := routed[station.ID]; !ok {
+			return fmt.Errorf("Le poste %s n'a aucune ligne dans cette commande", station.Name)
+		}
+
+		now := time.Now()
+		task := CommandeStationTask{CommandeID: commande.ID, StationID: station.ID}
+		if err := tx.Where(task).FirstOrCreate(&task).Error; err != nil {
+			return err
+		}
+		if task.StartedAt == nil {
+			task.StartedAt = &now
+		}
+		task.DoneAt = &now
+		task.DoneBy = userID
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+
+		var doneCount int64
+		stationIDs := make([]uint, 0, len(routed))
+		for id := range routed {
+			stationIDs = append(stationIDs, id)
+		}
+		err = tx.Model(&CommandeStationTask{}).
+			Where("commande_id = ? AND station_id IN ? AND done_at IS NOT NULL", commande.ID, stationIDs).
+			Count(&doneCount).Error
+		if err != nil {
+			return err
+		}
+
+		// Save déclenche les hooks d'historisation des statuts
+		switch {
+		case int(doneCount) == len(routed):
+			commande.Status = StatusReady
+		case commande.Status == StatusPending:
+			commande.Status = StatusPreparing
+		default:
+			return nil
+		}
+		return tx.Omit("Menus", "Products").Save(&commande).Error
+	})
+
+	return &commande, err
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultBoardDeliveredDisplay est la durée pendant laquelle un ticket livré reste affiché
+const DefaultBoardDeliveredDisplay = 60 * time.Second
+
+// BoardDeliveredDisplay retourne la durée d'affichage après livraison, configurable via BOARD_DELIVERED_DISPLAY_SECONDS
+func BoardDeliveredDisplay() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("BOARD_DELIVERED_DISPLAY_SECONDS")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return DefaultBoardDeliveredDisplay
+}
+
+// StatusBoard est ce qu'affiche l'écran de la salle d'attente.
+// Il ne contient volontairement que des numéros de ticket : ni prix, ni contenu de commande.
+type StatusBoard struct {
+	Preparing []uint `json:"preparing"`
+	Ready     []uint `json:"ready"`
+	Delivered []uint `json:"delivered"`
+}
+
+// Equal compare deux états de l'écran (utilisé pour ne pousser que les changements)
+func (b StatusBoard) Equal(other StatusBoard) bool {
+	return equalTickets(b.Preparing, other.Preparing) &&
+		equalTickets(b.Ready, other.Ready) &&
+		equalTickets(b.Delivered, other.Delivered)
+}
+
+func equalTickets(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetStatusBoard construit l'état de l'écran à partir des commandes du jour, précommandes
+// comprises : elles comptent pour le jour de leur retrait
+func GetStatusBoard(db *gorm.DB, now time.Time, deliveredDisplay time.Duration) (StatusBoard, error) {
+	board := StatusBoard{Preparing: []uint{}, Ready: []uint{}, Delivered: []uint{}}
+
+	// On ne sélectionne que les colonnes nécessaires à l'affichage
+	var rows []struct {
+		ID           uint
+		TicketNumber uint
+		Status       StatusType
+	}
+	err := db.Model(&Commande{}).
+		Select("id", "ticket_number", "status").
+		Scopes(onQueueDay(now)).
+		Order("ticket_number").
+		Scan(&rows).Error
+	if err != nil {
+		return board, err
+	}
+
+	var delivered []uint
+	for _, row := range rows {
+		if row.Status == StatusDelivered {
+			delivered = append(delivered, row.ID)
+		}
+	}
+	deliveredAt, err := deliveredTimes(db, delivered)
+	if err != nil {
+		return board, err
+	}
+
+	for _, row := range rows {
+		switch row.Status {
+		case StatusPending, StatusPreparing:
+			board.Preparing = append(board.Preparing, row.TicketNumber)
+		case StatusReady:
+			board.Ready = append(board.Ready, row.TicketNumber)
+		case StatusDelivered:
+			// La remise est datée par l'historique des statuts : une mise à jour ultérieure
+			// de la commande (updated_at) ne la fait pas réapparaître
+			if at, ok := deliveredAt[row.ID]; ok && now.Sub(at) <= deliveredDisplay {
+				board.Delivered = append(board.Delivered, row.TicketNumber)
+			}
+		}
+	}
+
+	return board, nil
+}
+
+// deliveredTimes retourne, pour chaque commande, la date de son dernier passage au statut livré
+func deliveredTimes(db *gorm.DB, commandeIDs []uint) (map[uint]time.Time, error) {
+	deliveredAt := map[uint]time.Time{}
+	if len(commandeIDs) == 0 {
+		return deliveredAt, nil
+	}
+
+	var changes []CommandeStatusChange
+	err := db.Select("commande_id", "changed_at").
+		Where("commande_id IN ? AND to_status = ?", commandeIDs, StatusDelivered).
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if change.ChangedAt.After(deliveredAt[change.CommandeID]) {
+			deliveredAt[change.CommandeID] = change.ChangedAt
+		}
+	}
+	return deliveredAt, nil
+}
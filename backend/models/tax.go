@@ -0,0 +1,280 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Définition de la catégorie fiscale d'un produit ou d'un menu
+type TaxCategory string
+
+const (
+	TaxFood      TaxCategory = "food"
+	TaxSoftDrink TaxCategory = "soft_drink"
+	TaxAlcohol   TaxCategory = "alcohol"
+)
+
+// Méthode pour valider si une catégorie fiscale est valide
+func (t TaxCategory) IsValid() bool {
+	switch t {
+	case TaxFood, TaxSoftDrink, TaxAlcohol:
+		return true
+	}
+	return false
+}
+
+// Définition du mode de consommation, qui détermine le taux de TVA
+type ServiceMode string
+
+const (
+	ServiceOnSite   ServiceMode = "on_site"
+	ServiceTakeaway ServiceMode = "takeaway"
+)
+
+// Méthode pour valider si un mode de consommation est valide
+func (m ServiceMode) IsValid() bool {
+	switch m {
+	case ServiceOnSite, ServiceTakeaway:
+		return true
+	}
+	return false
+}
+
+// LegacyVATRate est le taux appliqué aux lignes enregistrées avant le calcul de la TVA par ligne
+var LegacyVATRate = decimal.NewFromInt(10)
+
+// TaxRate est le taux de TVA (en pourcentage) d'une catégorie pour un mode de consommation
+type TaxRate struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	Category    TaxCategory     `json:"category" gorm:"type:varchar(20);uniqueIndex:idx_tax_rate" example:"food" enums:"food,soft_drink,alcohol"`
+	ServiceMode ServiceMode     `json:"service_mode" gorm:"type:varchar(20);uniqueIndex:idx_tax_rate" example:"on_site" enums:"on_site,takeaway"`
+	Rate        decimal.Decimal `json:"rate" gorm:"type:decimal(5,2);not null" example:"10"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Taux par défaut, créés au démarrage s'ils n'existent pas encore
+var defaultTaxRates = []TaxRate{
+	{Category: TaxFood, ServiceMode: ServiceOnSite, Rate: decimal.NewFromInt(10)},
+	{Category: TaxFood, ServiceMode: ServiceTakeaway, Rate: decimal.RequireFromString("5.5")},
+	{Category: TaxSoftDrink, ServiceMode: ServiceOnSite, Rate: decimal.NewFromInt(10)},
+	{Category: TaxSoftDrink, ServiceMode: ServiceTakeaway, Rate: decimal.RequireFromString("5.5")},
+	{Category: TaxAlcohol, ServiceMode: ServiceOnSite, Rate: decimal.NewFromInt(20)},
+	{Category: TaxAlcohol, ServiceMode: ServiceTakeaway, Rate: decimal.NewFromInt(20)},
+}
+
+// SeedTaxRates crée les taux par défaut manquants sans écraser ceux configurés
+func SeedTaxRates(db *gorm.DB) error {
+	for _, rate := range defaultTaxRates {
+		r := rate
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&r).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllTaxRates récupère tous les taux
+func GetAllTaxRates(db *gorm.DB) ([]TaxRate, error) {
+	var rates []TaxRate
+	err := db.Order("category, service_mode").Find(&rates).Error
+	return rates, err
+}
+
+// UpsertTaxRate crée ou met à jour le taux d'une catégorie pour un mode de consommation
+func UpsertTaxRate(db *gorm.DB, rate *TaxRate) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "category"}, {Name: "service_mode"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate).Error
+}
+
+// TaxTable permet de retrouver rapidement un taux
+type TaxTable map[TaxCategory]map[ServiceMode]decimal.Decimal
+
+// LoadTaxTable charge tous les taux configurés
+func LoadTaxTable(db *gorm.DB) (TaxTable, error) {
+	rates, err := GetAllTaxRates(db)
+	if err != nil {
+		return nil, err
+	}
+	table := TaxTable{}
+	for _, r := range rates {
+		if table[r.Category] == nil {
+			table[r.Category] = map[ServiceMode]decimal.Decimal{}
+		}
+		table[r.Category][r.ServiceMode] = r.Rate
+	}
+	return table, nil
+}
+
+// Rate retourne le taux applicable, ou le taux historique si rien n'est configuré
+func (t TaxTable) Rate(category TaxCategory, mode ServiceMode) decimal.Decimal {
+	if category == "" {
+		category = TaxFood
+	}
+	if mode == "" {
+		mode = ServiceOnSite
+	}
+	if rate, ok := t[category][mode]; ok {
+		return rate
+	}
+	return LegacyVATRate
+}
+
+// TaxAmounts décompose un montant TTC
+type TaxAmounts struct {
+	Net   decimal.Decimal `json:"net"`
+	Tax   decimal.Decimal `json:"tax"`
+	Gross decimal.Decimal `json:"gross"`
+}
+
+// ComputeTax calcule le HT (arrondi au centime) et la TVA à partir d'un montant TTC.
+// La TVA est obtenue par différence pour que HT + TVA = TTC au centime près.
+func ComputeTax(gross, rate decimal.Decimal) TaxAmounts {
+	divisor := decimal.NewFromInt(1).Add(rate.Div(decimal.NewFromInt(100)))
+	net := gross.Div(divisor).Round(2)
+	return TaxAmounts{Net: net, Tax: gross.Sub(net), Gross: gross}
+}
+
+// lineTax retourne le taux et les montants d'une ligne, recalculés pour les lignes historiques
+func lineTax(price decimal.Decimal, quantity int, discount, rate, net, tax, gross decimal.Decimal) (decimal.Decimal, TaxAmounts) {
+	if !gross.IsZero() || !rate.IsZero() {
+		return rate, TaxAmounts{Net: net, Tax: tax, Gross: gross}
+	}
+	return LegacyVATRate, ComputeTax(lineGross(price, quantity, discount), LegacyVATRate)
+}
+
+// lineGross est le montant TTC payé pour une ligne, remise déduite
+func lineGross(price decimal.Decimal, quantity int, discount decimal.Decimal) decimal.Decimal {
+	if quantity <= 0 {
+		quantity = 1
+	}
+	return price.Mul(decimal.NewFromInt(int64(quantity))).Sub(discount)
+}
+
+// LinesTotal retourne le montant TTC des lignes de la commande, remises déduites.
+// Menus et Products doivent être préchargés.
+func (c *Commande) LinesTotal() decimal.Decimal {
+	total := decimal.Zero
+	for _, m := range c.Menus {
+		total = total.Add(lineGross(m.Price, m.Quantity, m.Discount))
+	}
+	for _, p := range c.Products {
+		total = total.Add(lineGross(p.Price, p.Quantity, p.Discount))
+	}
+	return total
+}
+
+// Tax retourne le taux et les montants de la ligne
+func (l CommandeMenu) Tax() (decimal.Decimal, TaxAmounts) {
+	return lineTax(l.Price, l.Quantity, l.Discount, l.TaxRate, l.NetAmount, l.TaxAmount, l.GrossAmount)
+}
+
+// Tax retourne le taux et les montants de la ligne
+func (l CommandeProduct) Tax() (decimal.Decimal, TaxAmounts) {
+	return lineTax(l.Price, l.Quantity, l.Discount, l.TaxRate, l.NetAmount, l.TaxAmount, l.GrossAmount)
+}
+
+// ApplyTaxes calcule la TVA de chaque ligne, remise déduite, selon sa catégorie et le mode
+// de consommation, puis les totaux HT et TVA de la commande. Menus et Products doivent être préchargés.
+func ApplyTaxes(db *gorm.DB, commande *Commande) error {
+	table, err := LoadTaxTable(db)
+	if err != nil {
+		return err
+	}
+
+	netTotal, taxTotal := decimal.Zero, decimal.Zero
+	applyLine := func(model interface{}, category TaxCategory, gross decimal.Decimal) (decimal.Decimal, TaxAmounts, error) {
+		rate := table.Rate(category, commande.ServiceMode)
+		amounts := ComputeTax(gross, rate)
+		netTotal, taxTotal = netTotal.Add(amounts.Net), taxTotal.Add(amounts.Tax)
+
+		err := db.Model(model).UpdateColumns(map[string]interface{}{
+			"tax_rate":     rate,
+			"net_amount":   amounts.Net,
+			"tax_amount":   amounts.Tax,
+			"gross_amount": amounts.Gross,
+		}).Error
+		return rate, amounts, err
+	}
+
+	for i := range commande.Menus {
+		m := &commande.Menus[i]
+		rate, amounts, err := applyLine(m, m.TaxCategory, lineGross(m.Price, m.Quantity, m.Discount))
+		if err != nil {
+			return err
+		}
+		m.TaxRate, m.NetAmount, m.TaxAmount, m.GrossAmount = rate, amounts.Net, amounts.Tax, amounts.Gross
+	}
+	for i := range commande.Products {
+		p := &commande.Products[i]
+		rate, amounts, err := applyLine(p, p.TaxCategory, lineGross(p.Price, p.Quantity, p.Discount))
+		if err != nil {
+			return err
+		}
+		p.TaxRate, p.NetAmount, p.TaxAmount, p.GrossAmount = rate, amounts.Net, amounts.Tax, amounts.Gross
+	}
+
+	commande.NetTotal = netTotal
+	commande.TaxTotal = taxTotal
+	return db.Model(commande).UpdateColumns(map[string]interface{}{
+		"net_total": netTotal,
+		"tax_total": taxTotal,
+	}).Error
+}
+
+// VATSummary est le détail de TVA pour un taux
+type VATSummary struct {
+	Rate  decimal.Decimal `json:"rate"`
+	Net   decimal.Decimal `json:"net"`
+	Tax   decimal.Decimal `json:"tax"`
+	Gross decimal.Decimal `json:"gross"`
+}
+
+// SummarizeVAT regroupe les lignes des commandes par taux. Menus et Products doivent être préchargés.
+func SummarizeVAT(commandes []Commande) []VATSummary {
+	byRate := map[string]*VATSummary{}
+	add := func(rate decimal.Decimal, amounts TaxAmounts) {
+		key := rate.String()
+		if byRate[key] == nil {
+			byRate[key] = &VATSummary{Rate: rate, Net: decimal.Zero, Tax: decimal.Zero, Gross: decimal.Zero}
+		}
+		s := byRate[key]
+		s.Net, s.Tax, s.Gross = s.Net.Add(amounts.Net), s.Tax.Add(amounts.Tax), s.Gross.Add(amounts.Gross)
+	}
+
+	for _, commande := range commandes {
+		for _, m := range commande.Menus {
+			add(m.Tax())
+		}
+		for _, p := range commande.Products {
+			add(p.Tax())
+		}
+	}
+
+	summaries := make([]VATSummary, 0, len(byRate))
+	for _, s := range byRate {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Rate.LessThan(summaries[j].Rate) })
+	return summaries
+}
+
+// GetDailyVATSummary retourne le détail de TVA des commandes d'une journée
+func GetDailyVATSummary(db *gorm.DB, day time.Time) ([]VATSummary, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+
+	var commandes []Commande
+	err := db.Preload("Menus").Preload("Products").
+		Where("created_at >= ? AND created_at < ?", start, start.AddDate(0, 0, 1)).
+		Find(&commandes).Error
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeVAT(commandes), nil
+}
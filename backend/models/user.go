@@ -13,14 +13,14 @@ import (
 // Définition du type pour le rôle
 type UserRole string
 
-// Constantes pour les valeurs possibles
+// Rôles prédéfinis ; les administrateurs peuvent en créer d'autres (voir Role)
 const (
 	RoleAdmin    UserRole = "admin"
 	RolePreparer UserRole = "preparer"
 	RoleReceiver UserRole = "receiver"
 )
 
-// Méthode pour valider si un rôle est valide
+// IsValid indique si le rôle est un rôle prédéfini
 func (r UserRole) IsValid() bool {
 	switch r {
 	case RoleAdmin, RolePreparer, RoleReceiver:
@@ -31,20 +31,37 @@ func (r UserRole) IsValid() bool {
 
 // Structure User avec le type UserRole
 type User struct {
-	ID          uint                  `json:"id" gorm:"primaryKey" example:"1"`
-	Email       string                `json:"email" gorm:"not null" example:"john@example.com"`
-	Role        UserRole              `json:"role" gorm:"type:varchar(20);not null" example:"admin" enums:"admin,preparer,receiver"`
-	Description string                `json:"description" gorm:"type:text" example:"Utilisateur administrateur"`
+	ID          uint     `json:"id" gorm:"primaryKey" example:"1"`
+	Email       string   `json:"email" gorm:"not null" example:"john@example.com"`
+	Role        UserRole `json:"role" gorm:"type:varchar(20);not null" example:"admin"`
+	Description string   `json:"description" gorm:"type:text" example:"Utilisateur administrateur"`
+	// Renseigné quand un administrateur désactive le compte : plus aucune connexion n'est acceptée
+	DisabledAt *time.Time `json:"disabled_at"`
+	// Échecs de connexion consécutifs et verrouillage temporaire (voir AuthenticateLogin)
+	FailedLogins      int        `json:"failed_logins" gorm:"default:0"`
+	LastFailedLoginAt *time.Time `json:"last_failed_login_at"`
+	LockedUntil       *time.Time `json:"locked_until"`
+	// Double authentification TOTP (voir mfa.go) : le secret n'est jamais sérialisé
+	TOTPSecret        string     `json:"-"`
+	PendingTOTPSecret string     `json:"-"`
+	TOTPEnabledAt     *time.Time `json:"totp_enabled_at"`
+	// Dernière période TOTP acceptée, pour refuser la réutilisation d'un code
+	LastTOTPStep int64 `json:"-" gorm:"default:0"`
+	// Imposé par un administrateur ; toujours vrai en pratique pour le rôle admin (voir NeedsMFA)
+	MFARequired bool `json:"mfa_required" gorm:"default:false"`
+	// Code PIN haché pour la connexion rapide sur les tablettes partagées (voir pin.go)
+	PINHash    string     `json:"-"`
+	PINSetAt   *time.Time `json:"pin_set_at"`
+	FailedPINs int        `json:"-" gorm:"column:failed_pins;default:0"`
+	// Identifiant (sub) chez le fournisseur OpenID Connect des comptes connectés par SSO (voir oidc.go) ;
+	// OIDCManaged marque les comptes créés par le SSO, dont le rôle suit les groupes du fournisseur
+	OIDCSubject *string               `json:"-" gorm:"column:oidc_subject;index"`
+	OIDCManaged bool                  `json:"-" gorm:"column:oidc_managed;default:false"`
 	CreatedAt   time.Time             `json:"created_at"`
 	UpdatedAt   time.Time             `json:"updated_at"`
 	DeletedAt   soft_delete.DeletedAt `gorm:"softDelete:milli" swaggertype:"integer"`
-	Password    string                `json:"password,omitempty" binding:"required,min=6" example:"motdepasse123"`
-}
-
-// Fonction pour créer un utilisateur en base de données
-func CreateUser(db *gorm.DB, user *User) error {
-	result := db.Create(user)
-	return result.Error
+	// Hachage bcrypt, jamais sérialisé : les réponses utilisent UserResponse
+	Password string `json:"-"`
 }
 
 // Récupérer un utilisateur par ID
@@ -61,26 +78,33 @@ func GetUserByID(db *gorm.DB, id uint) (*User, error) {
 	return &user, nil
 }
 
-// Mettre à jour un utilisateur
-func UpdateUser(db *gorm.DB, user *User) error {
-	return db.Save(user).Error
-}
-
-// Supprimer un utilisateur
+// Supprimer un utilisateur et fermer ses sessions
 func DeleteUser(db *gorm.DB, id uint) error {
-	return db.Delete(&User{}, id).Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&User{}, id).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, id)
+	})
 }
 
-// LoginUser vérifie les informations d'identification de l'utilisateur
-func LoginUser(db *gorm.DB, name string, password string) (*User, error) {
-	var user User
-	err := db.Where("name = ? AND password = ?", name, password).First(&user).Error
+// SetUserDisabled désactive ou réactive un compte ; la désactivation ferme ses sessions
+func SetUserDisabled(db *gorm.DB, id uint, disabled bool) (*User, error) {
+	user, err := GetUserByID(db, id)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("Nom d'utilisateur ou mot de passe incorrect")
-		}
-		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+		return nil, err
 	}
-
-	return &user, nil
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if !disabled {
+			user.DisabledAt = nil
+			return tx.Model(user).Update("disabled_at", nil).Error
+		}
+		now := time.Now()
+		user.DisabledAt = &now
+		if err := tx.Model(user).Update("disabled_at", now).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, id)
+	})
+	return user, err
 }
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Longueur minimale d'un mot de passe
+const MinPasswordLength = 6
+
+var (
+	// ErrInvalidCredentials est retournée pour un email inconnu ou un mauvais mot de passe
+	ErrInvalidCredentials = errors.New("Email ou mot de passe incorrect")
+	// ErrWrongPassword est retournée quand l'ancien mot de passe ne correspond pas
+	ErrWrongPassword = errors.New("Ancien mot de passe incorrect")
+)
+
+// @Description Utilisateur tel que retourné par l'API, sans mot de passe
+type UserResponse struct {
+	ID          uint       `json:"id" example:"1"`
+	Email       string     `json:"email" example:"john@example.com"`
+	Role        UserRole   `json:"role" example:"admin"`
+	Description string     `json:"description" example:"Utilisateur administrateur"`
+	DisabledAt  *time.Time `json:"disabled_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+	MFAEnabled  bool       `json:"mfa_enabled"`
+	MFARequired bool       `json:"mfa_required"`
+	HasPIN      bool       `json:"has_pin"`
+	SSO         bool       `json:"sso"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// @Description Données nécessaires pour créer un utilisateur
+type UserInput struct {
+	Email       string `json:"email" binding:"required" example:"john@example.com"`
+	Password    string `json:"password" binding:"required" example:"motdepasse123"`
+	Role        string `json:"role" binding:"required" example:"preparer"`
+	Description string `json:"description" example:"Équipe du soir"`
+}
+
+// @Description Données modifiables d'un utilisateur ; le mot de passe se change à part
+type UserUpdateInput struct {
+	Email       string `json:"email" binding:"required" example:"john@example.com"`
+	Role        string `json:"role" binding:"required" example:"preparer"`
+	Description string `json:"description" example:"Équipe du soir"`
+}
+
+// @Description Changement de son propre mot de passe
+type ChangePasswordInput struct {
+	OldPassword string `json:"old_password" binding:"required" example:"motdepasse123"`
+	NewPassword string `json:"new_password" binding:"required" example:"nouveau456"`
+}
+
+// Response retourne la représentation publique de l'utilisateur
+func (u User) Response() UserResponse {
+	return UserResponse{
+		ID:          u.ID,
+		Email:       u.Email,
+		Role:        u.Role,
+		Description: u.Description,
+		DisabledAt:  u.DisabledAt,
+		LockedUntil: u.LockedUntil,
+		MFAEnabled:  u.MFAEnabled(),
+		MFARequired: u.NeedsMFA(),
+		HasPIN:      u.PINHash != "",
+		SSO:         u.OIDCSubject != nil,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
+
+// UserResponses convertit une liste d'utilisateurs
+func UserResponses(users []User) []UserResponse {
+	responses := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		responses = append(responses, u.Response())
+	}
+	return responses
+}
+
+// ValidatePassword vérifie qu'un nouveau mot de passe est acceptable
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("Le mot de passe doit contenir au moins %d caractères", MinPasswordLength)
+	}
+	return nil
+}
+
+// HashPassword retourne le hachage bcrypt d'un mot de passe
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// isPasswordHash indique si la valeur stockée est déjà un hachage bcrypt
+func isPasswordHash(value string) bool {
+	_, err := bcrypt.Cost([]byte(value))
+	return err == nil
+}
+
+// SetPassword vérifie le nouveau mot de passe et le remplace par son hachage. Tout chemin qui
+// définit un mot de passe passe par ici : la valeur reçue est toujours hachée, même si elle
+// ressemble déjà à un hachage.
+func (u *User) SetPassword(password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.Password = hashed
+	return nil
+}
+
+// CheckPassword compare un mot de passe en clair au hachage de l'utilisateur. Un compte sans
+// mot de passe local (SSO) est refusé après le même calcul qu'un compte ordinaire.
+func (u *User) CheckPassword(password string) bool {
+	if u.Password == "" {
+		compareDummyPassword(password)
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
+
+var (
+	dummyPasswordOnce sync.Once
+	dummyPasswordHash []byte
+)
+
+// compareDummyPassword fait le travail d'une vérification bcrypt sans compte à vérifier
+func compareDummyPassword(password string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("mot de passe factice"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+// Validate vérifie les champs modifiables de l'utilisateur
+func (u *User) Validate() error {
+	if !strings.Contains(u.Email, "@") {
+		return fmt.Errorf("Email invalide")
+	}
+	if !roleNamePattern.MatchString(string(u.Role)) {
+		return fmt.Errorf("Rôle utilisateur invalide")
+	}
+	return nil
+}
+
+// CreateUser crée un utilisateur ; le mot de passe en clair est haché par SetPassword
+func CreateUser(db *gorm.DB, user *User, password string) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	if err := checkRoleExists(db, user.Role); err != nil {
+		return err
+	}
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	taken, err := emailTaken(db, user.Email)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+	return db.Create(user).Error
+}
+
+// UpdateUser met à jour le profil d'un utilisateur. Le mot de passe n'est jamais modifié
+// ici : il passe par ChangePassword.
+func UpdateUser(db *gorm.DB, user *User) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	if err := checkRoleExists(db, user.Role); err != nil {
+		return err
+	}
+	var count int64
+	err := db.Model(&User{}).Where("LOWER(email) = ? AND id <> ?", strings.ToLower(user.Email), user.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return db.Model(user).Select("email", "role", "description").Updates(user).Error
+}
+
+// LoginUser vérifie l'email et le mot de passe d'un utilisateur
+func LoginUser(db *gorm.DB, email string, password string) (*User, error) {
+	var user User
+	if err := db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	if !user.CheckPassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
+// setUserPassword remplace le mot de passe d'un utilisateur par le hachage du nouveau
+func setUserPassword(db *gorm.DB, user *User, password string) error {
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	return db.Model(user).UpdateColumn("password", user.Password).Error
+}
+
+// ChangePassword remplace le mot de passe après vérification de l'ancien. Les autres
+// sessions de l'utilisateur sont fermées ; keepSessionID (0 pour aucune) reste ouverte.
+func ChangePassword(db *gorm.DB, userID uint, oldPassword string, newPassword string, keepSessionID uint) error {
+	user, err := GetUserByID(db, userID)
+	if err != nil {
+		return err
+	}
+	if !user.CheckPassword(oldPassword) {
+		return ErrWrongPassword
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := setUserPassword(tx, user, newPassword); err != nil {
+			return err
+		}
+		return tx.Model(&AuthSession{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// RehashPlaintextPasswords hache les mots de passe encore stockés en clair (comptes créés
+// avant que chaque écriture passe par SetPassword). Retourne le nombre de comptes corrigés.
+func RehashPlaintextPasswords(db *gorm.DB) (int, error) {
+	var users []User
+	if err := db.Unscoped().Select("id", "password").Where("password <> ''").Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, user := range users {
+		if isPasswordHash(user.Password) {
+			continue
+		}
+		hashed, err := HashPassword(user.Password)
+		if err != nil {
+			return count, err
+		}
+		if err := db.Unscoped().Model(&User{}).Where("id = ?", user.ID).UpdateColumn("password", hashed).Error; err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,282 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// WebhookSubscription est un abonnement d'un système externe (comptabilité, BI, affichage) aux évènements
+type WebhookSubscription struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	URL         string `json:"url" gorm:"not null" example:"https://compta.example.com/hooks/kabre"`
+	Description string `json:"description" example:"Export comptable"`
+	// Évènements transmis ; vide pour tous les évènements
+	Events []EventType `json:"events" gorm:"serializer:json" example:"commande.created"`
+	// Secret de signature des envois, communiqué uniquement à la création
+	Secret    string                `json:"-" gorm:"not null"`
+	IsActive  bool                  `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli" swaggerignore:"true"`
+}
+
+// Définition de l'état d'un envoi de webhook
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookDelivered WebhookDeliveryStatus = "delivered"
+	// Abandonné après trop d'échecs ; peut être relancé manuellement
+	WebhookDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery est un évènement à envoyer à un abonné, conservé jusqu'à sa réception
+type WebhookDelivery struct {
+	ID             uint                  `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint                  `json:"subscription_id" gorm:"index"`
+	Event          EventType             `json:"event" gorm:"type:varchar(50)"`
+	Payload        []byte                `json:"-"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);index"`
+	Attempts       int                   `json:"attempts"`
+	LastError      string                `json:"last_error"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at" gorm:"index"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	Logs           []WebhookAttempt      `json:"logs,omitempty" gorm:"foreignKey:DeliveryID"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// WebhookAttempt trace une tentative d'envoi
+type WebhookAttempt struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DeliveryID uint      `json:"delivery_id" gorm:"index"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookPayload est le corps JSON envoyé aux abonnés
+type WebhookPayload struct {
+	Event      EventType   `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+	// Statut précédent, pour commande.status_changed
+	PreviousStatus StatusType `json:"previous_status,omitempty"`
+}
+
+// Validate vérifie la cohérence de l'abonnement
+func (s *WebhookSubscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL du webhook invalide")
+	}
+	// Le serveur ne doit pas servir de relais vers le réseau interne ; le worker vérifie aussi
+	// l'adresse résolue au moment de l'envoi
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("L'URL du webhook doit désigner une adresse publique")
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return fmt.Errorf("L'URL du webhook doit désigner une adresse publique")
+	}
+	for _, event := range s.Events {
+		if !event.IsValid() {
+			return fmt.Errorf("Évènement inconnu %q", event)
+		}
+	}
+	return nil
+}
+
+// IsPublicIP indique si une adresse peut recevoir des webhooks : les adresses de bouclage,
+// lien-local, privées et non spécifiées sont refusées
+func IsPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsUnspecified()
+}
+
+// Accepts indique si l'abonnement reçoit ce type d'évènement
+func (s *WebhookSubscription) Accepts(event EventType) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookSubscription crée un abonnement et lui attribue un secret de signature
+func CreateWebhookSubscription(db *gorm.DB, subscription *WebhookSubscription) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	subscription.Secret = hex.EncodeToString(raw)
+	return db.Create(subscription).Error
+}
+
+// GetAllWebhookSubscriptions récupère tous les abonnements
+func GetAllWebhookSubscriptions(db *gorm.DB) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	err := db.Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetWebhookSubscriptionByID récupère un abonnement par son ID
+func GetWebhookSubscriptionByID(db *gorm.DB, id uint) (*WebhookSubscription, error) {
+	var subscription WebhookSubscription
+	err := db.First(&subscription, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("L'abonnement avec l'Id %d n'a pas été trouvé", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &subscription, nil
+}
+
+// UpdateWebhookSubscription met à jour un abonnement
+func UpdateWebhookSubscription(db *gorm.DB, subscription *WebhookSubscription) error {
+	return db.Save(subscription).Error
+}
+
+// DeleteWebhookSubscription supprime un abonnement ; ses envois en attente sont abandonnés
+func DeleteWebhookSubscription(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", id, WebhookPending).
+			Updates(map[string]interface{}{"status": WebhookDead, "last_error": "abonnement supprimé"}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&WebhookSubscription{}, id).Error
+	})
+}
+
+// EnqueueWebhooks met l'évènement en file pour chaque abonnement actif concerné. Enregistré avec
+// OnEvent, il s'exécute dans la transaction qui produit l'évènement : rien n'est envoyé pour une
+// opération annulée, et rien n'est perdu pour une opération validée.
+func EnqueueWebhooks(tx *gorm.DB, event Event) error {
+	var subscriptions []WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	var payload []byte
+	now := time.Now()
+	deliveries := []WebhookDelivery{}
+	for _, s := range subscriptions {
+		if !s.Accepts(event.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			payload, err = json.Marshal(WebhookPayload{Event: event.Type, OccurredAt: now, Data: event.Data, PreviousStatus: event.FromStatus})
+			if err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			SubscriptionID: s.ID,
+			Event:          event.Type,
+			Payload:        payload,
+			Status:         WebhookPending,
+			NextAttemptAt:  now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// GetDueWebhookDeliveries récupère les envois en attente arrivés à échéance
+func GetDueWebhookDeliveries(db *gorm.DB, now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.Where("status = ? AND next_attempt_at <= ?", WebhookPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimWebhookDelivery réserve un envoi pour un worker jusqu'à now+lease. La mise à jour
+// conditionnelle garantit qu'un seul worker l'obtient ; si ce worker s'arrête avant d'enregistrer
+// le résultat, l'envoi redevient disponible à la fin du bail.
+func ClaimWebhookDelivery(db *gorm.DB, delivery *WebhookDelivery, now time.Time, lease time.Duration) (bool, error) {
+	leaseUntil := now.Add(lease)
+	result := db.Model(&WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, WebhookPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.NextAttemptAt = leaseUntil
+	return true, nil
+}
+
+// GetWebhookDeliveries récupère les envois d'un abonnement, éventuellement filtrés par état
+func GetWebhookDeliveries(db *gorm.DB, subscriptionID uint, status WebhookDeliveryStatus) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	query := db.Where("subscription_id = ?", subscriptionID).Order("created_at DESC, id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Limit(200).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetWebhookDeliveryByID récupère un envoi et l'historique de ses tentatives
+func GetWebhookDeliveryByID(db *gorm.DB, id uint) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := db.Preload("Logs", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).First(&delivery, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("L'envoi avec l'Id %d n'a pas été trouvé", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+	}
+	return &delivery, nil
+}
+
+// RecordWebhookAttempt enregistre le résultat d'une tentative et l'état de l'envoi
+func RecordWebhookAttempt(db *gorm.DB, delivery *WebhookDelivery, attempt *WebhookAttempt) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Logs").Save(delivery).Error
+	})
+}
+
+// RedeliverWebhook remet un envoi en file d'attente, qu'il ait été reçu ou abandonné
+func RedeliverWebhook(db *gorm.DB, id uint) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := db.First(&delivery, id).Error; err != nil {
+		return nil, fmt.Errorf("L'envoi avec l'Id %d n'a pas été trouvé", id)
+	}
+
+	delivery.Status = WebhookPending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.DeliveredAt = nil
+	delivery.NextAttemptAt = time.Now()
+	return &delivery, db.Save(&delivery).Error
+}
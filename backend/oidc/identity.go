@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Identity est l'utilisateur authentifié par le fournisseur, lu dans l'ID token
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	// Méthodes d'authentification (claim amr, RFC 8176), par exemple "pwd" et "mfa"
+	AMR []string
+}
+
+// MFA indique si le fournisseur atteste d'une authentification multifacteur
+func (i *Identity) MFA() bool {
+	for _, method := range i.AMR {
+		if method == "mfa" {
+			return true
+		}
+	}
+	return false
+}
+
+// identityFromClaims extrait l'identité des claims ; les groupes peuvent être une liste ou une
+// chaîne selon le fournisseur
+func identityFromClaims(claims jwt.MapClaims, groupsClaim string) *Identity {
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	identity.Groups = stringList(claims[groupsClaim])
+	identity.AMR = stringList(claims["amr"])
+	return identity
+}
+
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// GroupRole associe un groupe du fournisseur à un rôle de l'application
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// ParseGroupRoles lit une liste "groupe=rôle" séparée par des virgules, par ordre de priorité :
+// "wacdo-admins=admin,wacdo-cuisine=preparer,wacdo-accueil=receiver"
+func ParseGroupRoles(value string) ([]GroupRole, error) {
+	var mapping []GroupRole
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(entry, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("OIDC_GROUP_ROLES : entrée %q invalide, groupe=rôle attendu", entry)
+		}
+		mapping = append(mapping, GroupRole{Group: group, Role: role})
+	}
+	return mapping, nil
+}
+
+// RoleFor retourne le rôle du premier groupe de la correspondance dont l'utilisateur est membre ;
+// false si aucun de ses groupes n'ouvre l'accès
+func (c *Config) RoleFor(groups []string) (string, bool) {
+	member := map[string]bool{}
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, mapping := range c.GroupRoles {
+		if member[mapping.Group] {
+			return mapping.Role, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,326 @@
+// Package oidc implémente la connexion OpenID Connect auprès du fournisseur d'identité de la
+// franchise : flux authorization code avec PKCE (RFC 7636), vérification de l'ID token avec les
+// clés publiées par le fournisseur et correspondance des groupes avec les rôles.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"LearningCampusKabre/jwtkeys"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidIDToken est retournée pour un ID token mal signé, expiré, d'un autre émetteur ou client
+	ErrInvalidIDToken = errors.New("ID token invalide")
+	// ErrExchangeFailed est retournée quand le fournisseur refuse le code d'autorisation
+	ErrExchangeFailed = errors.New("code d'autorisation refusé par le fournisseur d'identité")
+)
+
+// DefaultScopes sont demandés si OIDC_SCOPES n'est pas défini
+var DefaultScopes = []string{"openid", "email", "profile", "groups"}
+
+// keysRefreshInterval limite le rechargement des clés du fournisseur sur un kid inconnu
+const keysRefreshInterval = time.Minute
+
+// Config décrit le client enregistré auprès du fournisseur d'identité
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// URL du front-end où le fournisseur renvoie le code, transmis ensuite à /auth/oidc/callback
+	RedirectURL string
+	Scopes      []string
+	// Claim de l'ID token qui liste les groupes de l'utilisateur
+	GroupsClaim string
+	GroupRoles  []GroupRole
+	// Rattache aussi les comptes locaux à droits d'administration (OIDC_LINK_PRIVILEGED=true)
+	LinkPrivileged bool
+	// Accepte le claim amr "mfa" comme second facteur (OIDC_TRUST_AMR=true) ; sinon la double
+	// authentification locale s'applique aussi aux connexions SSO
+	TrustAMR bool
+}
+
+// ConfigFromEnv lit la configuration OIDC_* ; nil sans OIDC_ISSUER (SSO désactivé)
+func ConfigFromEnv() (*Config, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	cfg := &Config{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       DefaultScopes,
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+
+		LinkPrivileged: os.Getenv("OIDC_LINK_PRIVILEGED") == "true",
+		TrustAMR:       os.Getenv("OIDC_TRUST_AMR") == "true",
+	}
+	if scopes := strings.Fields(os.Getenv("OIDC_SCOPES")); len(scopes) > 0 {
+		cfg.Scopes = scopes
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC_CLIENT_ID et OIDC_REDIRECT_URL sont obligatoires avec OIDC_ISSUER")
+	}
+
+	var err error
+	if cfg.GroupRoles, err = ParseGroupRoles(os.Getenv("OIDC_GROUP_ROLES")); err != nil {
+		return nil, err
+	}
+	if len(cfg.GroupRoles) == 0 {
+		return nil, errors.New("OIDC_GROUP_ROLES doit associer au moins un groupe à un rôle")
+	}
+	return cfg, nil
+}
+
+// discovery est le document /.well-known/openid-configuration du fournisseur
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider est le client du fournisseur d'identité. Le document de découverte et les clés
+// publiques sont chargés au premier usage puis gardés en mémoire.
+type Provider struct {
+	Config Config
+	HTTP   *http.Client
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]jwtkeys.JWK
+	keysFetchedAt time.Time
+}
+
+// NewProvider crée le client du fournisseur
+func NewProvider(cfg Config) *Provider {
+	return &Provider{Config: cfg, HTTP: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// NewFromEnv crée le client à partir des variables OIDC_* ; nil si le SSO n'est pas configuré
+func NewFromEnv() (*Provider, error) {
+	cfg, err := ConfigFromEnv()
+	if cfg == nil || err != nil {
+		return nil, err
+	}
+	return NewProvider(*cfg), nil
+}
+
+// getJSON décode la réponse JSON d'un GET
+func (p *Provider) getJSON(ctx context.Context, endpoint string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s : statut %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// discover charge le document de découverte et vérifie qu'il correspond à l'émetteur configuré
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc discovery
+	if err := p.getJSON(ctx, p.Config.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("découverte OIDC : %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.Config.Issuer {
+		return nil, fmt.Errorf("découverte OIDC : émetteur %q inattendu", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("découverte OIDC : document incomplet")
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// NewPKCE retourne un code_verifier aléatoire et son code_challenge S256
+func NewPKCE() (verifier string, challenge string, err error) {
+	if verifier, err = RandomString(); err != nil {
+		return "", "", err
+	}
+	return verifier, S256(verifier), nil
+}
+
+// S256 calcule le code_challenge d'un code_verifier
+func S256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString retourne 256 bits aléatoires encodés en base64url, pour le verifier et le nonce
+func RandomString() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// AuthCodeURL construit l'URL d'autorisation du fournisseur vers laquelle rediriger le navigateur
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	authURL, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.Config.ClientID)
+	query.Set("redirect_uri", p.Config.RedirectURL)
+	query.Set("scope", strings.Join(p.Config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange échange le code d'autorisation contre un ID token, puis le vérifie
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.Config.RedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.Config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.Config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrExchangeFailed
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil || tokens.IDToken == "" {
+		return nil, ErrExchangeFailed
+	}
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// publicKey retourne la clé du fournisseur pour ce kid, en rechargeant ses clés si le kid est
+// inconnu (rotation côté fournisseur)
+func (p *Provider) publicKey(ctx context.Context, kid string) (jwtkeys.JWK, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return jwtkeys.JWK{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return jwtkeys.JWK{}, jwtkeys.ErrUnknownKey
+	}
+
+	var set jwtkeys.JWKSet
+	if err := p.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return jwtkeys.JWK{}, fmt.Errorf("clés OIDC : %w", err)
+	}
+	p.keys = map[string]jwtkeys.JWK{}
+	for _, key := range set.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			p.keys[key.Kid] = key
+		}
+	}
+	p.keysFetchedAt = time.Now()
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return jwtkeys.JWK{}, jwtkeys.ErrUnknownKey
+	}
+	return key, nil
+}
+
+// VerifyIDToken vérifie la signature (RS256 ou EdDSA, algorithme de la clé désignée par le kid),
+// l'émetteur, l'audience, l'expiration et le nonce de la connexion en cours
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Identity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwtkeys.AlgRS256, jwtkeys.AlgEdDSA}),
+		jwt.WithIssuer(p.Config.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.publicKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm() {
+			return nil, jwtkeys.ErrAlgorithm
+		}
+		return key.PublicKey()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w : %v", ErrInvalidIDToken, err)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("%w : nonce incorrect", ErrInvalidIDToken)
+	}
+
+	identity := identityFromClaims(claims, p.Config.GroupsClaim)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w : sub manquant", ErrInvalidIDToken)
+	}
+	return identity, nil
+}
@@ -0,0 +1,69 @@
+package printing
+
+import (
+	"time"
+
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/receipts"
+
+	"gorm.io/gorm"
+)
+
+// EnqueueCommande prépare les tickets cuisine d'une commande pour chaque imprimante active :
+// les imprimantes de poste reçoivent leurs lignes, les autres le ticket complet.
+// Retourne les travaux créés ; le worker se charge de l'envoi.
+func EnqueueCommande(db *gorm.DB, commandeID uint) ([]models.PrintJob, error) {
+	var commande models.Commande
+	if err := db.Preload("Menus").Preload("Products").First(&commande, commandeID).Error; err != nil {
+		return nil, err
+	}
+
+	var printers []models.Printer
+	if err := db.Where("is_active = ?", true).Find(&printers).Error; err != nil {
+		return nil, err
+	}
+	if len(printers) == 0 {
+		return nil, nil
+	}
+
+	stations, err := models.GetAllStations(db)
+	if err != nil {
+		return nil, err
+	}
+	routed, err := models.RouteCommande(db, &commande, stations)
+	if err != nil {
+		return nil, err
+	}
+	stationNames := map[uint]string{}
+	for _, s := range stations {
+		stationNames[s.ID] = s.Name
+	}
+
+	var jobs []models.PrintJob
+	now := time.Now()
+	for _, printer := range printers {
+		ticket := receipts.KitchenTicketFromCommande(&commande)
+		if printer.StationID != nil {
+			lines, ok := routed[*printer.StationID]
+			if !ok {
+				continue
+			}
+			ticket.Station = stationNames[*printer.StationID]
+			ticket.Lines = lines
+		}
+
+		jobs = append(jobs, models.PrintJob{
+			PrinterID:     printer.ID,
+			CommandeID:    commande.ID,
+			StationID:     printer.StationID,
+			Payload:       receipts.RenderKitchenTicket(ticket),
+			Status:        models.PrintJobPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return jobs, db.Create(&jobs).Error
+}
@@ -0,0 +1,159 @@
+package printing
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"LearningCampusKabre/models"
+
+	"gorm.io/gorm"
+)
+
+// Worker envoie les travaux d'impression en attente aux imprimantes, avec nouvelles tentatives.
+type Worker struct {
+	DB          *gorm.DB
+	Interval    time.Duration
+	Timeout     time.Duration
+	MaxAttempts int
+	// Durée pendant laquelle un travail réservé n'est pas repris par un autre worker
+	Lease time.Duration
+	// Backoff retourne le délai avant la tentative suivante
+	Backoff func(attempts int) time.Duration
+}
+
+// NewWorker crée un worker avec les réglages par défaut
+func NewWorker(db *gorm.DB) *Worker {
+	return &Worker{
+		DB:          db,
+		Interval:    2 * time.Second,
+		Timeout:     3 * time.Second,
+		MaxAttempts: 5,
+		Lease:       time.Minute,
+		Backoff: func(attempts int) time.Duration {
+			return time.Duration(attempts*attempts) * 5 * time.Second
+		},
+	}
+}
+
+// Run traite la file jusqu'à l'annulation du contexte
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.ProcessDue(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue envoie les travaux arrivés à échéance et retourne le nombre de ceux traités.
+// Les imprimantes sont servies en parallèle, chacune dans l'ordre de ses travaux ; après un
+// échec, les travaux suivants de la même imprimante attendent le prochain passage pour qu'une
+// imprimante hors ligne ne retienne pas toute la file.
+func (w *Worker) ProcessDue(now time.Time) int {
+	jobs, err := models.GetDuePrintJobs(w.DB, now, 50)
+	if err != nil {
+		log.Println("❌ Erreur lors de la lecture de la file d'impression :", err)
+		return 0
+	}
+
+	byPrinter := map[uint][]int{}
+	var claimed []int
+	for i := range jobs {
+		ok, err := models.ClaimPrintJob(w.DB, &jobs[i], now, w.Lease)
+		if err != nil {
+			log.Println("❌ Erreur lors de la réservation d'un travail d'impression :", err)
+			continue
+		}
+		if ok {
+			byPrinter[jobs[i].PrinterID] = append(byPrinter[jobs[i].PrinterID], i)
+			claimed = append(claimed, i)
+		}
+	}
+
+	// Seuls les envois réseau sont parallèles : les accès à la base restent sur ce goroutine
+	attempted := make([]bool, len(jobs))
+	results := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for printerID, indexes := range byPrinter {
+		printer, err := models.GetPrinterByID(w.DB, printerID)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range indexes {
+				attempted[i] = true
+				if err == nil {
+					results[i] = Send(printer.Address, jobs[i].Payload, w.Timeout)
+				} else {
+					results[i] = err
+				}
+				if results[i] != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	processed := 0
+	for _, i := range claimed {
+		if !attempted[i] {
+			w.release(&jobs[i], now)
+			continue
+		}
+		w.record(&jobs[i], results[i], now)
+		processed++
+	}
+	return processed
+}
+
+// release rend immédiatement disponible un travail réservé mais pas envoyé
+func (w *Worker) release(job *models.PrintJob, now time.Time) {
+	err := w.DB.Model(job).UpdateColumn("next_attempt_at", now).Error
+	if err != nil {
+		log.Println("❌ Erreur lors de la mise à jour du travail d'impression :", err)
+	}
+}
+
+// record enregistre le résultat d'un envoi
+func (w *Worker) record(job *models.PrintJob, err error, now time.Time) {
+	job.Attempts++
+
+	switch {
+	case err == nil:
+		printedAt := now
+		job.Status = models.PrintJobPrinted
+		job.PrintedAt = &printedAt
+		job.LastError = ""
+	case job.Attempts >= w.MaxAttempts:
+		job.Status = models.PrintJobDead
+		job.LastError = err.Error()
+	default:
+		job.LastError = err.Error()
+		job.NextAttemptAt = now.Add(w.Backoff(job.Attempts))
+	}
+
+	if err := w.DB.Save(job).Error; err != nil {
+		log.Println("❌ Erreur lors de la mise à jour du travail d'impression :", err)
+	}
+}
+
+// Send envoie un flux brut à une imprimante RAW TCP (port 9100)
+func Send(address string, payload []byte, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err = conn.Write(payload)
+	return err
+}
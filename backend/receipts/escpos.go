@@ -0,0 +1,98 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"LearningCampusKabre/models"
+)
+
+// Commandes ESC/POS utilisées par les tickets cuisine
+var (
+	escInit        = []byte{0x1B, 0x40}       // ESC @ : réinitialisation
+	escCodePage    = []byte{0x1B, 0x74, 0x10} // ESC t 16 : page de code WPC1252
+	escAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escBoldOff     = []byte{0x1B, 0x45, 0x00}
+	gsSizeNormal   = []byte{0x1D, 0x21, 0x00}
+	gsSizeDouble   = []byte{0x1D, 0x21, 0x11} // double largeur et double hauteur
+	gsCut          = []byte{0x1D, 0x56, 0x42, 0x03}
+)
+
+// Largeur d'une ligne sur une imprimante 80 mm en police normale
+const ticketWidth = 42
+
+// KitchenTicket représente un ticket cuisine, pour toute la commande ou pour un seul poste
+type KitchenTicket struct {
+	TicketNumber uint
+	CreatedAt    time.Time
+	Channel      models.ChannelType
+	Station      string
+	Lines        []models.StationTicketLine
+}
+
+// KitchenTicketFromCommande construit le ticket cuisine d'une commande complète.
+// Menus et Products doivent être préchargés.
+func KitchenTicketFromCommande(commande *models.Commande) KitchenTicket {
+	ticket := KitchenTicket{
+		TicketNumber: commande.TicketNumber,
+		CreatedAt:    commande.CreatedAt,
+		Channel:      commande.Channel,
+	}
+	for _, m := range commande.Menus {
+		ticket.Lines = append(ticket.Lines, models.StationTicketLine{Name: m.Name, Quantity: m.Quantity, Modifiers: m.Modifiers})
+	}
+	for _, p := range commande.Products {
+		ticket.Lines = append(ticket.Lines, models.StationTicketLine{Name: p.Name, Quantity: p.Quantity, Type: p.Type, Modifiers: p.Modifiers})
+	}
+	return ticket
+}
+
+// RenderKitchenTicket produit le flux ESC/POS d'un ticket cuisine (sans prix)
+func RenderKitchenTicket(ticket KitchenTicket) []byte {
+	var out bytes.Buffer
+	write := func(parts ...[]byte) {
+		for _, p := range parts {
+			out.Write(p)
+		}
+	}
+	line := func(text string) {
+		out.Write(encodeWindows1252(text))
+		out.WriteByte('\n')
+	}
+
+	write(escInit, escCodePage, escAlignCenter, escBoldOn, gsSizeDouble)
+	line(fmt.Sprintf("TICKET %d", ticket.TicketNumber))
+	write(gsSizeNormal, escBoldOff)
+	if ticket.Station != "" {
+		line(strings.ToUpper(ticket.Station))
+	}
+	line(ticket.CreatedAt.Format("02/01/2006 15:04"))
+	if ticket.Channel != "" {
+		line(string(ticket.Channel))
+	}
+
+	write(escAlignLeft)
+	line(strings.Repeat("-", ticketWidth))
+	for _, l := range ticket.Lines {
+		write(escBoldOn)
+		line(fmt.Sprintf("%2d x %s", l.Quantity, l.Name))
+		write(escBoldOff)
+		if l.MenuName != "" {
+			line("     (" + l.MenuName + ")")
+		}
+		for _, modifier := range l.Modifiers {
+			line("     > " + modifier)
+		}
+	}
+	line(strings.Repeat("-", ticketWidth))
+
+	// Quelques sauts de ligne avant la coupe du papier
+	out.WriteString("\n\n\n")
+	write(gsCut)
+
+	return out.Bytes()
+}
@@ -0,0 +1,161 @@
+package receipts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"LearningCampusKabre/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Company regroupe les mentions légales imprimées sur les factures
+type Company struct {
+	Name      string
+	Address   string
+	SIRET     string
+	VATNumber string
+}
+
+// CompanyFromEnv lit les informations de l'entreprise dans les variables COMPANY_*
+func CompanyFromEnv() Company {
+	return Company{
+		Name:      os.Getenv("COMPANY_NAME"),
+		Address:   os.Getenv("COMPANY_ADDRESS"),
+		SIRET:     os.Getenv("COMPANY_SIRET"),
+		VATNumber: os.Getenv("COMPANY_VAT_NUMBER"),
+	}
+}
+
+// invoiceLine est une ligne de facture, les prix étant TTC
+type invoiceLine struct {
+	Name      string
+	Quantity  int
+	UnitPrice decimal.Decimal
+	Total     decimal.Decimal
+}
+
+func invoiceLines(commande *models.Commande) []invoiceLine {
+	var lines []invoiceLine
+	add := func(name string, quantity int, unit decimal.Decimal) {
+		if quantity <= 0 {
+			quantity = 1
+		}
+		lines = append(lines, invoiceLine{
+			Name:      name,
+			Quantity:  quantity,
+			UnitPrice: unit,
+			Total:     unit.Mul(decimal.NewFromInt(int64(quantity))),
+		})
+	}
+	for _, m := range commande.Menus {
+		add(m.Name, m.Quantity, m.Price)
+	}
+	for _, p := range commande.Products {
+		add(p.Name, p.Quantity, p.Price)
+	}
+	return lines
+}
+
+// formatEuros affiche un montant au format français : 12,50 €
+func formatEuros(d decimal.Decimal) string {
+	return strings.Replace(d.StringFixed(2), ".", ",", 1) + " €"
+}
+
+// RenderInvoicePDF produit la facture PDF d'une commande. Menus, Products et Promotions doivent être préchargés.
+func RenderInvoicePDF(commande *models.Commande, company Company) []byte {
+	doc := newPDFDocument()
+	const left, right = 50.0, 545.0
+	y := 790.0
+
+	nextLine := func(step float64) {
+		y -= step
+		if y < 60 {
+			doc.NewPage()
+			y = 790
+		}
+	}
+
+	// En-tête entreprise
+	doc.Text(left, y, 16, true, company.Name)
+	nextLine(18)
+	for _, part := range strings.Split(company.Address, "\n") {
+		if part != "" {
+			doc.Text(left, y, 10, false, part)
+			nextLine(13)
+		}
+	}
+	if company.SIRET != "" {
+		doc.Text(left, y, 10, false, "SIRET : "+company.SIRET)
+		nextLine(13)
+	}
+	if company.VATNumber != "" {
+		doc.Text(left, y, 10, false, "TVA intracommunautaire : "+company.VATNumber)
+		nextLine(13)
+	}
+
+	// Références de la commande
+	nextLine(20)
+	doc.Text(left, y, 14, true, fmt.Sprintf("Facture n° %06d", commande.ID))
+	doc.TextRight(right, y, 14, true, fmt.Sprintf("Ticket %d", commande.TicketNumber))
+	nextLine(16)
+	doc.Text(left, y, 10, false, "Date : "+commande.CreatedAt.Format("02/01/2006 15:04"))
+	nextLine(24)
+
+	// Lignes
+	doc.Text(left, y, 10, true, "Désignation")
+	doc.TextRight(360, y, 10, true, "Qté")
+	doc.TextRight(450, y, 10, true, "P.U. TTC")
+	doc.TextRight(right, y, 10, true, "Total TTC")
+	nextLine(6)
+	doc.Line(left, y, right, y)
+	nextLine(14)
+
+	lines := invoiceLines(commande)
+	for _, l := range lines {
+		doc.Text(left, y, 10, false, l.Name)
+		doc.TextRight(360, y, 10, false, fmt.Sprint(l.Quantity))
+		doc.TextRight(450, y, 10, false, formatEuros(l.UnitPrice))
+		doc.TextRight(right, y, 10, false, formatEuros(l.Total))
+		nextLine(14)
+	}
+	for _, promotion := range commande.Promotions {
+		doc.Text(left, y, 10, false, "Remise : "+promotion.Name)
+		doc.TextRight(right, y, 10, false, formatEuros(promotion.Discount.Neg()))
+		nextLine(14)
+	}
+	doc.Line(left, y+8, right, y+8)
+	nextLine(10)
+
+	// Détail de la TVA
+	doc.Text(left, y, 10, true, "Taux TVA")
+	doc.TextRight(360, y, 10, true, "Base HT")
+	doc.TextRight(450, y, 10, true, "TVA")
+	doc.TextRight(right, y, 10, true, "TTC")
+	nextLine(14)
+
+	totalNet, totalTax, totalGross := decimal.Zero, decimal.Zero, decimal.Zero
+	// Montants enregistrés sur chaque ligne lors de la prise de commande
+	for _, s := range models.SummarizeVAT([]models.Commande{*commande}) {
+		doc.Text(left, y, 10, false, strings.Replace(s.Rate.String(), ".", ",", 1)+" %")
+		doc.TextRight(360, y, 10, false, formatEuros(s.Net))
+		doc.TextRight(450, y, 10, false, formatEuros(s.Tax))
+		doc.TextRight(right, y, 10, false, formatEuros(s.Gross))
+		totalNet, totalTax, totalGross = totalNet.Add(s.Net), totalTax.Add(s.Tax), totalGross.Add(s.Gross)
+		nextLine(14)
+	}
+
+	// Totaux
+	nextLine(10)
+	doc.TextRight(450, y, 11, false, "Total HT")
+	doc.TextRight(right, y, 11, false, formatEuros(totalNet))
+	nextLine(15)
+	doc.TextRight(450, y, 11, false, "Total TVA")
+	doc.TextRight(right, y, 11, false, formatEuros(totalTax))
+	nextLine(15)
+	doc.TextRight(450, y, 12, true, "Total TTC")
+	doc.TextRight(right, y, 12, true, formatEuros(totalGross))
+
+	return doc.Bytes()
+}
@@ -0,0 +1,116 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Format A4 en points PDF
+const (
+	pageWidth  = 595
+	pageHeight = 842
+)
+
+// pdfDocument est un générateur PDF minimal : texte en Helvetica et traits, sur une ou plusieurs pages.
+// La sortie est déterministe (pas de date de création) pour pouvoir être comparée à des fichiers de référence.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{}
+	doc.NewPage()
+	return doc
+}
+
+// NewPage ajoute une page et la rend courante
+func (d *pdfDocument) NewPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) current() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Text écrit une ligne de texte à la position donnée (origine en bas à gauche)
+func (d *pdfDocument) Text(x, y float64, size int, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.current(), "BT /%s %d Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapePDFString(text))
+}
+
+// TextRight écrit une ligne de texte alignée à droite sur x (largeur estimée)
+func (d *pdfDocument) TextRight(x, y float64, size int, bold bool, text string) {
+	d.Text(x-textWidth(text, size), y, size, bold, text)
+}
+
+// Line trace un trait
+func (d *pdfDocument) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.current(), "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Bytes assemble le document PDF complet
+func (d *pdfDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objets 1 à 4 : catalogue, arbre des pages, polices ; puis une page + un contenu par page
+	kids := ""
+	for i := range d.pages {
+		kids += fmt.Sprintf("%d 0 R ", 5+2*i)
+	}
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(d.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents %d 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> >>",
+			pageWidth, pageHeight, 6+2*i,
+		))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// escapePDFString encode le texte en WinAnsi et échappe les caractères spéciaux PDF
+func escapePDFString(s string) string {
+	var out bytes.Buffer
+	for _, b := range encodeWindows1252(s) {
+		switch b {
+		case '(', ')', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(b)
+		default:
+			if b >= 0x80 {
+				fmt.Fprintf(&out, "\\%03o", b)
+			} else {
+				out.WriteByte(b)
+			}
+		}
+	}
+	return out.String()
+}
+
+// textWidth estime la largeur d'un texte en Helvetica (largeur moyenne d'un caractère ≈ 0,5 em)
+func textWidth(s string, size int) float64 {
+	return float64(len([]rune(s))) * float64(size) * 0.5
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupBoardRoutes(router *gin.Engine, db *gorm.DB) {
+	boardController := controllers.RefBoardController(db)
+
+	// Écran de la salle d'attente : lecture seule, authentifié par appareil
+	board := router.Group("/api/board")
+	board.Use(middlewares.DeviceAuthMiddleware(db, models.DeviceBoard))
+	{
+		board.GET("", boardController.GetBoard)
+		board.GET("/stream", boardController.StreamBoard)
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupCartRoutes(router *gin.Engine, db *gorm.DB) {
+	cartController := controllers.RefCartController(db)
+
+	carts := router.Group("/api/carts")
+	carts.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermCartWrite))
+	{
+		carts.POST("", cartController.CreateCart)
+		carts.GET("/:id", cartController.GetCart)
+		carts.DELETE("/:id", cartController.DeleteCart)
+		carts.POST("/:id/lines", cartController.AddCartLine)
+		carts.PUT("/:id/lines/:lineId", cartController.UpdateCartLine)
+		carts.DELETE("/:id/lines/:lineId", cartController.RemoveCartLine)
+		carts.POST("/:id/checkout", cartController.CheckoutCart)
+	}
+}
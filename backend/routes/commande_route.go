@@ -3,6 +3,7 @@ package routes
 import (
 	"LearningCampusKabre/controllers"
 	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,16 +11,23 @@ import (
 
 func SetupCommandesRoutes(router *gin.Engine, db *gorm.DB) {
 	commandeController := &controllers.CommandeController{DB: db}
+	kioskController := controllers.RefKioskController(db)
 
 	commandeRoutes := router.Group("/api/commandes")
 	{
-		commandeRoutes.POST("", middlewares.AuthMiddleware(), middlewares.RequireRole("admin", "receiver", "preparer"), commandeController.CreateCommande)
-		commandeRoutes.GET("", middlewares.AuthMiddleware(), commandeController.GetAllCommandes)
-		commandeRoutes.GET("/:id", middlewares.AuthMiddleware(), commandeController.GetCommandeByID)
-		commandeRoutes.PUT("/admin/:id", middlewares.AuthMiddleware(), middlewares.RequireRole("admin"), commandeController.AdminUpdateCommande)
-		commandeRoutes.PUT("/preparer/:id", middlewares.AuthMiddleware(), middlewares.RequireRole("preparer"), commandeController.PreparerUpdateCommande)
-		commandeRoutes.PUT("/receiver/:id", middlewares.AuthMiddleware(), middlewares.RequireRole("receiver"), commandeController.ReceiverUpdateCommande)
-		commandeRoutes.DELETE("/:id", middlewares.AuthMiddleware(), middlewares.RequireRole("admin", "receiver", "preparer"), commandeController.DeleteCommande)
+		commandeRoutes.POST("", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderCreate), commandeController.CreateCommande)
+		commandeRoutes.GET("", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderRead), commandeController.GetAllCommandes)
+		commandeRoutes.GET("/pickup-slots", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderCreate), kioskController.GetPickupSlots)
+		commandeRoutes.GET("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderRead), commandeController.GetCommandeByID)
+		commandeRoutes.PUT("/admin/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderEdit), commandeController.AdminUpdateCommande)
+		// Deux adresses historiques pour le même handler, qui vérifie la permission du statut demandé
+		staffUpdate := []gin.HandlerFunc{middlewares.AuthMiddleware(db), middlewares.RequireAnyPermission(models.PermOrderPrepare, models.PermOrderDeliver), commandeController.StaffUpdateCommande}
+		commandeRoutes.PUT("/preparer/:id", staffUpdate...)
+		commandeRoutes.PUT("/receiver/:id", staffUpdate...)
+		commandeRoutes.GET("/:id/receipt", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderRead), commandeController.GetCommandeReceipt)
+		commandeRoutes.GET("/:id/vat", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderRead), commandeController.GetCommandeVAT)
+		commandeRoutes.PUT("/:id/payment", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderPay), commandeController.MarkCommandePaid)
+		commandeRoutes.DELETE("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermOrderDelete), commandeController.DeleteCommande)
 	}
 }
 
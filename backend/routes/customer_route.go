@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupCustomerRoutes(router *gin.Engine, db *gorm.DB) {
+	customerController := controllers.RefCustomerController(db)
+	rewardController := controllers.RefRewardController(db)
+
+	customers := router.Group("/api/customers")
+	customers.Use(middlewares.AuthMiddleware(db))
+	{
+		customers.POST("", middlewares.RequirePermission(models.PermCustomerWrite), customerController.CreateCustomer)
+		customers.GET("/lookup", middlewares.RequirePermission(models.PermCustomerRead), customerController.LookupCustomer)
+		customers.GET("/:id", middlewares.RequirePermission(models.PermCustomerRead), customerController.GetCustomerByID)
+		customers.PUT("/:id", middlewares.RequirePermission(models.PermCustomerWrite), customerController.UpdateCustomer)
+		customers.DELETE("/:id", middlewares.RequirePermission(models.PermCustomerDelete), customerController.DeleteCustomer)
+		customers.GET("/:id/ledger", middlewares.RequirePermission(models.PermCustomerRead), customerController.GetLoyaltyLedger)
+		customers.GET("/:id/commandes", middlewares.RequirePermission(models.PermCustomerRead), customerController.GetCustomerCommandes)
+		customers.POST("/:id/commandes/:commandeId/reorder", middlewares.RequirePermission(models.PermOrderCreate), customerController.Reorder)
+		customers.POST("/:id/points", middlewares.RequirePermission(models.PermLoyaltyAdjust), customerController.AdjustPoints)
+		customers.POST("/:id/rewards/:rewardId/redeem", middlewares.RequirePermission(models.PermCustomerWrite), customerController.RedeemReward)
+	}
+
+	router.PUT("/api/commandes/:id/customer", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermCustomerWrite), customerController.AttachCustomer)
+
+	rewards := router.Group("/api/rewards")
+	rewards.Use(middlewares.AuthMiddleware(db))
+	{
+		rewards.GET("", middlewares.RequirePermission(models.PermRewardRead), rewardController.GetAllRewards)
+		rewards.POST("", middlewares.RequirePermission(models.PermRewardWrite), rewardController.CreateReward)
+		rewards.PUT("/:id", middlewares.RequirePermission(models.PermRewardWrite), rewardController.UpdateReward)
+		rewards.DELETE("/:id", middlewares.RequirePermission(models.PermRewardWrite), rewardController.DeleteReward)
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupDeliveryRoutes(router *gin.Engine, db *gorm.DB) {
+	deliveryController := controllers.RefDeliveryController(db)
+
+	// Webhooks des plateformes : authentifiés par signature HMAC, jamais par JWT staff
+	router.POST("/api/delivery/webhooks/:id", deliveryController.ReceiveWebhook)
+
+	platforms := router.Group("/api/delivery/platforms")
+	platforms.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermDeliveryManage))
+	{
+		platforms.POST("", deliveryController.CreateDeliveryPlatform)
+		platforms.GET("", deliveryController.GetAllDeliveryPlatforms)
+		platforms.PUT("/:id", deliveryController.UpdateDeliveryPlatform)
+		platforms.DELETE("/:id", deliveryController.DeleteDeliveryPlatform)
+		platforms.GET("/:id/mappings", deliveryController.GetDeliveryMappings)
+		platforms.PUT("/:id/mappings", deliveryController.SaveDeliveryMapping)
+		platforms.DELETE("/:id/mappings/:mappingId", deliveryController.DeleteDeliveryMapping)
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupKioskRoutes(router *gin.Engine, db *gorm.DB) {
+	kioskController := controllers.RefKioskController(db)
+	deviceController := controllers.RefDeviceController(db)
+
+	// Routes publiques de la borne : authentifiées par appareil, jamais par JWT staff
+	kiosk := router.Group("/api/kiosk")
+	kiosk.Use(middlewares.DeviceAuthMiddleware(db, models.DeviceKiosk))
+	{
+		kiosk.GET("/catalog", kioskController.GetCatalog)
+		kiosk.GET("/pickup-slots", kioskController.GetPickupSlots)
+		kiosk.POST("/cart/validate", kioskController.ValidateCart)
+		kiosk.POST("/commandes", kioskController.CreateKioskCommande)
+	}
+
+	devices := router.Group("/api/devices")
+	devices.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermDeviceManage))
+	{
+		devices.POST("", deviceController.CreateDevice)
+		devices.GET("", deviceController.GetAllDevices)
+		devices.DELETE("/:id", deviceController.DeleteDevice)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"LearningCampusKabre/controllers"
 	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,11 +14,12 @@ func SetupMenuRoutes(router *gin.Engine, db *gorm.DB) {
 
 	menuRoutes := router.Group("/api/menus")
 	{
-		menuRoutes.POST("", middlewares.AuthMiddleware(), middlewares.RequireRole("admin"), menuController.CreateMenu)
+		menuRoutes.POST("", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermMenuWrite), menuController.CreateMenu)
+		// Liste publique, comme la carte affichée en salle
 		menuRoutes.GET("", menuController.GetAllMenus)
-		menuRoutes.GET("/:id", middlewares.AuthMiddleware(), menuController.GetMenuByID)
-		menuRoutes.PUT("/:id", middlewares.AuthMiddleware(), menuController.UpdateMenu)
-		menuRoutes.DELETE("/:id", middlewares.AuthMiddleware(), middlewares.RequireRole("admin"), menuController.DeleteMenu)
-		menuRoutes.DELETE("/softdelete/:id", middlewares.AuthMiddleware(), menuController.SoftDeleteMenu)
+		menuRoutes.GET("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermMenuRead), menuController.GetMenuByID)
+		menuRoutes.PUT("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermMenuWrite), menuController.UpdateMenu)
+		menuRoutes.DELETE("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermMenuDelete), menuController.DeleteMenu)
+		menuRoutes.DELETE("/softdelete/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermMenuWrite), menuController.SoftDeleteMenu)
 	}
 }
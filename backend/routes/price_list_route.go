@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupPriceListRoutes(router *gin.Engine, db *gorm.DB) {
+	priceListController := controllers.RefPriceListController(db)
+
+	priceLists := router.Group("/api/price-lists")
+	priceLists.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermPriceListManage))
+	{
+		priceLists.POST("", priceListController.CreatePriceList)
+		priceLists.GET("", priceListController.GetAllPriceLists)
+		priceLists.GET("/:id", priceListController.GetPriceListByID)
+		priceLists.PUT("/:id", priceListController.UpdatePriceList)
+		priceLists.DELETE("/:id", priceListController.DeletePriceList)
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupPrinterRoutes(router *gin.Engine, db *gorm.DB) {
+	printerController := controllers.RefPrinterController(db)
+
+	printers := router.Group("/api/printers")
+	printers.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermPrinterManage))
+	{
+		printers.POST("", printerController.CreatePrinter)
+		printers.GET("", printerController.GetAllPrinters)
+		printers.PUT("/:id", printerController.UpdatePrinter)
+		printers.DELETE("/:id", printerController.DeletePrinter)
+	}
+
+	printJobs := router.Group("/api/print-jobs")
+	printJobs.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermPrinterManage))
+	{
+		printJobs.GET("", printerController.GetPrintJobs)
+		printJobs.POST("/:id/retry", printerController.RetryPrintJob)
+		printJobs.POST("/commandes/:id", printerController.ReprintCommande)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"LearningCampusKabre/controllers"
 	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,11 +14,11 @@ func SetupProductRoutes(router *gin.Engine, db *gorm.DB) {
 
 	productRoutes := router.Group("/api/products")
 	{
-		productRoutes.POST("", middlewares.AuthMiddleware(), middlewares.RequireRole("admin"), productController.CreateProduct)
-		productRoutes.GET("", middlewares.AuthMiddleware(), productController.GetAllProducts)
-		productRoutes.GET("/:id", middlewares.AuthMiddleware(), productController.GetProduct)
-		productRoutes.PUT("/:id", middlewares.AuthMiddleware(), productController.UpdateProduct)
-		productRoutes.DELETE("/:id", middlewares.AuthMiddleware(), middlewares.RequireRole("admin"), productController.DeleteProduct)
-		productRoutes.DELETE("/softdelete/:id", middlewares.AuthMiddleware(), productController.SoftDeleteProduct)
+		productRoutes.POST("", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermProductWrite), productController.CreateProduct)
+		productRoutes.GET("", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermProductRead), productController.GetAllProducts)
+		productRoutes.GET("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermProductRead), productController.GetProduct)
+		productRoutes.PUT("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermProductWrite), productController.UpdateProduct)
+		productRoutes.DELETE("/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermProductDelete), productController.DeleteProduct)
+		productRoutes.DELETE("/softdelete/:id", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermProductWrite), productController.SoftDeleteProduct)
 	}
 }
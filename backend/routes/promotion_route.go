@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupPromotionRoutes(router *gin.Engine, db *gorm.DB) {
+	promotionController := controllers.RefPromotionController(db)
+
+	promotions := router.Group("/api/promotions")
+	promotions.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermPromotionManage))
+	{
+		promotions.POST("", promotionController.CreatePromotion)
+		promotions.GET("", promotionController.GetAllPromotions)
+		promotions.GET("/:id", promotionController.GetPromotionByID)
+		promotions.PUT("/:id", promotionController.UpdatePromotion)
+		promotions.DELETE("/:id", promotionController.DeletePromotion)
+	}
+
+	reports := router.Group("/api/reports")
+	reports.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermReportRead))
+	{
+		reports.GET("/discounts", promotionController.GetDiscountReport)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupRoleRoutes(router *gin.Engine, db *gorm.DB) {
+	roleController := controllers.RefRoleController(db)
+
+	router.GET("/api/permissions", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermRoleManage), roleController.GetPermissions)
+
+	roles := router.Group("/api/roles")
+	roles.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermRoleManage))
+	{
+		roles.GET("", roleController.GetAllRoles)
+		roles.POST("", roleController.CreateRole)
+		roles.PUT("/:id", roleController.UpdateRole)
+		roles.DELETE("/:id", roleController.DeleteRole)
+	}
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SetupRoutes enregistre toutes les routes de l'API ; une erreur signale une configuration
+// invalide qui doit empêcher le démarrage
+func SetupRoutes(router *gin.Engine, db *gorm.DB) error {
+	SetupProductRoutes(router, db)
+	if err := SetupUserRoutes(router, db); err != nil {
+		return err
+	}
+	SetupMenuRoutes(router, db)
+	SetupCommandesRoutes(router, db)
+	SetupKioskRoutes(router, db)
+	SetupCartRoutes(router, db)
+	SetupBoardRoutes(router, db)
+	SetupStationRoutes(router, db)
+	SetupPrinterRoutes(router, db)
+	SetupTaxRoutes(router, db)
+	SetupPromotionRoutes(router, db)
+	SetupPriceListRoutes(router, db)
+	SetupCustomerRoutes(router, db)
+	SetupDeliveryRoutes(router, db)
+	SetupWebhookRoutes(router, db)
+	SetupRoleRoutes(router, db)
+	return nil
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupStationRoutes(router *gin.Engine, db *gorm.DB) {
+	stationController := controllers.RefStationController(db)
+
+	stations := router.Group("/api/stations")
+	stations.Use(middlewares.AuthMiddleware(db))
+	{
+		stations.POST("", middlewares.RequirePermission(models.PermStationWrite), stationController.CreateStation)
+		stations.GET("", middlewares.RequirePermission(models.PermStationRead), stationController.GetAllStations)
+		stations.PUT("/:id", middlewares.RequirePermission(models.PermStationWrite), stationController.UpdateStation)
+		stations.DELETE("/:id", middlewares.RequirePermission(models.PermStationWrite), stationController.DeleteStation)
+		stations.GET("/:id/tickets", middlewares.RequirePermission(models.PermStationWork), stationController.GetStationTickets)
+		stations.PUT("/:id/commandes/:commandeId/done", middlewares.RequirePermission(models.PermStationWork), stationController.MarkStationDone)
+	}
+}
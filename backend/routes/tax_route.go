@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupTaxRoutes(router *gin.Engine, db *gorm.DB) {
+	taxController := controllers.RefTaxController(db)
+
+	taxRates := router.Group("/api/tax-rates")
+	taxRates.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermTaxManage))
+	{
+		taxRates.GET("", taxController.GetTaxRates)
+		taxRates.PUT("", taxController.UpdateTaxRate)
+	}
+
+	reports := router.Group("/api/reports")
+	reports.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermReportRead))
+	{
+		reports.GET("/vat", taxController.GetDailyVAT)
+	}
+}
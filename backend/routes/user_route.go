@@ -1,30 +1,97 @@
 package routes
 
 import (
+	"time"
+
 	"LearningCampusKabre/controllers"
 	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupUserRoutes(router *gin.Engine, db *gorm.DB) {
+// SetupUserRoutes enregistre les routes des comptes et de l'authentification ; échoue si la
+// configuration du SSO est invalide
+func SetupUserRoutes(router *gin.Engine, db *gorm.DB) error {
 	userController := controllers.RefUserController(db)
 	authController := controllers.RefAuthController(db)
+	mfaController := controllers.RefMFAController(db)
+	oidcController, err := controllers.RefOIDCController(db)
+	if err != nil {
+		return err
+	}
+
+	// Limites par adresse IP, en plus des compteurs par compte de la connexion
+	loginLimit := middlewares.RateLimit(middlewares.NewRateLimiter(10, time.Minute), middlewares.ByIP)
+	tokenLimit := middlewares.RateLimit(middlewares.NewRateLimiter(30, time.Minute), middlewares.ByIP)
+	resetLimit := middlewares.RateLimit(middlewares.NewRateLimiter(5, time.Minute), middlewares.ByIP)
+
+	// Clés publiques des JWT, à l'adresse standard attendue par les autres services
+	router.GET("/.well-known/jwks.json", authController.JWKS)
 
 	auth := router.Group("/api/auth")
 	{
-		auth.POST("/register", authController.Register)
-		auth.POST("/login", authController.Login)
+		auth.POST("/register", tokenLimit, authController.Register)
+		auth.POST("/login", loginLimit, authController.Login)
+		auth.POST("/refresh", tokenLimit, authController.Refresh)
+		auth.POST("/forgot", resetLimit, authController.ForgotPassword)
+		auth.POST("/reset", resetLimit, authController.ResetPassword)
+		auth.POST("/logout", middlewares.AuthMiddleware(db), authController.Logout)
+		auth.POST("/change-password", middlewares.AuthMiddleware(db), authController.ChangePassword)
+		auth.POST("/pin/set", middlewares.AuthMiddleware(db), authController.SetPIN)
+	}
+
+	// Connexion rapide par code PIN, uniquement depuis une tablette enregistrée
+	pin := router.Group("/api/auth/pin")
+	pin.Use(middlewares.DeviceAuthMiddleware(db, models.DeviceTablet))
+	{
+		pin.GET("/users", authController.GetPINUsers)
+		pin.POST("", loginLimit, authController.PINLogin)
+	}
+
+	// Connexion SSO auprès du fournisseur d'identité de la franchise (OpenID Connect)
+	sso := router.Group("/api/auth/oidc")
+	{
+		sso.GET("/login", tokenLimit, oidcController.Login)
+		sso.POST("/callback", loginLimit, oidcController.Callback)
+	}
+
+	// Double authentification : l'activation reste accessible sans second facteur,
+	// pour que les comptes qui y sont soumis puissent l'activer
+	mfa := router.Group("/api/auth/2fa")
+	{
+		mfa.POST("/verify", loginLimit, mfaController.Verify)
+		mfa.POST("/setup", middlewares.AuthMiddleware(db), mfaController.Setup)
+		mfa.POST("/enable", middlewares.AuthMiddleware(db), mfaController.Enable)
+		mfa.POST("/disable", middlewares.AuthMiddleware(db), mfaController.Disable)
+		mfa.POST("/recovery-codes", middlewares.AuthMiddleware(db), mfaController.RegenerateRecoveryCodes)
+	}
+
+	invitations := router.Group("/api/invitations")
+	invitations.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermInvitationManage))
+	{
+		invitations.POST("", authController.CreateInvitation)
+		invitations.GET("", authController.GetAllInvitations)
+		invitations.DELETE("/:id", authController.RevokeInvitation)
 	}
 
 	users := router.Group("/api/users")
-	users.Use(middlewares.AuthMiddleware())
+	users.Use(middlewares.AuthMiddleware(db))
 	{
-		users.POST("", userController.CreateUser)
-		users.GET("", userController.GetAllUsers)
-		users.GET("/:id", userController.GetUserByID)
-		users.PUT("/:id", userController.UpdateUser)
-		users.DELETE("/:id", middlewares.RequireRole("admin"), userController.DeleteUser)
+		users.POST("", middlewares.RequirePermission(models.PermUserWrite), userController.CreateUser)
+		users.GET("", middlewares.RequirePermission(models.PermUserRead), userController.GetAllUsers)
+		users.GET("/:id", middlewares.RequirePermission(models.PermUserRead), userController.GetUserByID)
+		users.PUT("/:id", middlewares.RequirePermission(models.PermUserWrite), userController.UpdateUser)
+		users.POST("/:id/disable", middlewares.RequirePermission(models.PermUserWrite), userController.DisableUser)
+		users.POST("/:id/enable", middlewares.RequirePermission(models.PermUserWrite), userController.EnableUser)
+		users.POST("/:id/unlock", middlewares.RequirePermission(models.PermUserWrite), userController.UnlockUser)
+		users.POST("/:id/require-2fa", middlewares.RequirePermission(models.PermUserWrite), mfaController.SetRequired)
+		users.DELETE("/:id/2fa", middlewares.RequirePermission(models.PermUserWrite), mfaController.Reset)
+		users.DELETE("/:id/pin", middlewares.RequirePermission(models.PermUserWrite), userController.ClearPIN)
+		users.DELETE("/:id", middlewares.RequirePermission(models.PermUserDelete), userController.DeleteUser)
 	}
+
+	router.GET("/api/login-attempts", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermAuditRead), userController.GetLoginAttempts)
+	return nil
 }
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupWebhookRoutes(router *gin.Engine, db *gorm.DB) {
+	webhookController := controllers.RefWebhookController(db)
+
+	subscriptions := router.Group("/api/webhooks")
+	subscriptions.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermWebhookManage))
+	{
+		subscriptions.POST("", webhookController.CreateWebhookSubscription)
+		subscriptions.GET("", webhookController.GetAllWebhookSubscriptions)
+		subscriptions.PUT("/:id", webhookController.UpdateWebhookSubscription)
+		subscriptions.DELETE("/:id", webhookController.DeleteWebhookSubscription)
+		subscriptions.GET("/:id/deliveries", webhookController.GetWebhookDeliveries)
+	}
+
+	deliveries := router.Group("/api/webhook-deliveries")
+	deliveries.Use(middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermWebhookManage))
+	{
+		deliveries.GET("/:id", webhookController.GetWebhookDelivery)
+		deliveries.POST("/:id/redeliver", webhookController.RedeliverWebhook)
+	}
+}
@@ -0,0 +1,55 @@
+package scheduling
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/printing"
+
+	"gorm.io/gorm"
+)
+
+// Scheduler libère les commandes programmées dans la file de préparation à l'approche de leur retrait.
+type Scheduler struct {
+	DB       *gorm.DB
+	Interval time.Duration
+}
+
+// NewScheduler crée un scheduler avec les réglages par défaut
+func NewScheduler(db *gorm.DB) *Scheduler {
+	return &Scheduler{DB: db, Interval: 30 * time.Second}
+}
+
+// Run libère les commandes arrivées à échéance jusqu'à l'annulation du contexte
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.ReleaseDue(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReleaseDue passe en "pending" les commandes programmées arrivées à échéance, envoie leurs
+// tickets en cuisine et retourne leur nombre
+func (s *Scheduler) ReleaseDue(now time.Time) int {
+	released, err := models.ReleaseScheduledCommandes(s.DB, now)
+	if err != nil {
+		log.Println("❌ Erreur lors de la libération des commandes programmées :", err)
+	}
+
+	// Les tickets cuisine ne sont imprimés qu'à l'entrée en préparation
+	for _, commande := range released {
+		if _, err := printing.EnqueueCommande(s.DB, commande.ID); err != nil {
+			log.Println("❌ Erreur lors de la mise en file d'impression :", err)
+		}
+	}
+	return len(released)
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+/////////////////////////////////////
+// STATUS BOARD
+/////////////////////////////////////
+
+func TestStatusBoardShowsOnlyTicketNumbers(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Commande{}, &models.TicketCounter{}, &models.CommandeStatusChange{})
+
+	now := time.Now()
+	commandes := []models.Commande{
+		{TicketNumber: 1, Status: models.StatusPending, Price: decimal.NewFromInt(12)},
+		{TicketNumber: 2, Status: models.StatusReady, Price: decimal.NewFromInt(8)},
+		{TicketNumber: 3, Status: models.StatusDelivered, Price: decimal.NewFromInt(5)},
+		{TicketNumber: 4, Status: models.StatusDelivered, Price: decimal.NewFromInt(5)},
+	}
+	for i := range commandes {
+		db.Create(&commandes[i])
+	}
+	// Le ticket 4 a été livré il y a longtemps : il ne doit plus être affiché, même modifié depuis
+	db.Model(&models.CommandeStatusChange{}).Where("commande_id = ?", commandes[3].ID).UpdateColumn("changed_at", now.Add(-time.Hour))
+	db.Model(&commandes[3]).UpdateColumn("updated_at", now)
+	// Une commande d'hier n'apparaît jamais
+	db.Create(&models.Commande{TicketNumber: 99, Status: models.StatusReady, Price: decimal.NewFromInt(5), CreatedAt: now.AddDate(0, 0, -1)})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/board", controllers.RefBoardController(db).GetBoard)
+
+	req, _ := http.NewRequest("GET", "/board", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, strings.Contains(w.Body.String(), "price"))
+
+	var board models.StatusBoard
+	json.Unmarshal(w.Body.Bytes(), &board)
+
+	assert.Equal(t, []uint{1}, board.Preparing)
+	assert.Equal(t, []uint{2}, board.Ready)
+	assert.Equal(t, []uint{3}, board.Delivered)
+}
+
+func TestStatusBoardStreamPushesChanges(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file:board_stream?mode=memory&cache=shared"), &gorm.Config{})
+	db.AutoMigrate(&models.Commande{}, &models.TicketCounter{}, &models.CommandeStatusChange{})
+
+	commande := models.Commande{TicketNumber: 7, Status: models.StatusPreparing, Price: decimal.NewFromInt(9)}
+	db.Create(&commande)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	boardController := controllers.RefBoardController(db)
+	boardController.PollInterval = 10 * time.Millisecond
+	r.GET("/board/stream", boardController.StreamBoard)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/board/stream")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	// Un événement est envoyé à la connexion, puis à chaque changement uniquement
+	events := make(chan models.StatusBoard, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+				var board models.StatusBoard
+				json.Unmarshal([]byte(data), &board)
+				events <- board
+			}
+		}
+		close(events)
+	}()
+
+	next := func() models.StatusBoard {
+		select {
+		case board := <-events:
+			return board
+		case <-time.After(2 * time.Second):
+			t.Fatal("aucun événement reçu")
+			return models.StatusBoard{}
+		}
+	}
+
+	board := next()
+	assert.Equal(t, []uint{7}, board.Preparing)
+	assert.Empty(t, board.Ready)
+
+	commande.Status = models.StatusReady
+	db.Save(&commande)
+
+	board = next()
+	assert.Empty(t, board.Preparing)
+	assert.Equal(t, []uint{7}, board.Ready)
+
+	// Sans changement, aucun nouvel événement
+	select {
+	case board, ok := <-events:
+		assert.False(t, ok, "événement inattendu : %v", board)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données en mémoire pour les paniers
+func setupCartTestDB() (*gorm.DB, models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Cart{}, &models.CartLine{}, &models.Printer{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{})
+
+	product := models.Product{Name: "Frites", Price: decimal.NewFromFloat(2.50), IsAvailable: true, Type: models.TypePlat}
+	db.Create(&product)
+
+	return db, product
+}
+
+// Router pour les tests
+func setupCartRouter(cc *controllers.CartController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/carts", cc.CreateCart)
+	r.GET("/carts/:id", cc.GetCart)
+	r.POST("/carts/:id/lines", cc.AddCartLine)
+	r.PUT("/carts/:id/lines/:lineId", cc.UpdateCartLine)
+	r.POST("/carts/:id/checkout", cc.CheckoutCart)
+	return r
+}
+
+func cartRequest(router *gin.Engine, method, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+/////////////////////////////////////
+// EDIT AND CHECKOUT CART
+/////////////////////////////////////
+
+func TestCartEditAndCheckout(t *testing.T) {
+	db, product := setupCartTestDB()
+	router := setupCartRouter(controllers.RefCartController(db))
+
+	w, cart := cartRequest(router, "POST", "/carts", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	cartPath := fmt.Sprintf("/carts/%v", cart["id"])
+
+	// Ajout d'une ligne valide et d'une ligne vers un produit inexistant
+	_, cart = cartRequest(router, "POST", cartPath+"/lines", map[string]interface{}{"product_id": product.ID, "quantity": 1, "modifiers": []string{"sans sel"}})
+	w, cart = cartRequest(router, "POST", cartPath+"/lines", map[string]interface{}{"product_id": 999, "quantity": 1})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, false, cart["valid"])
+
+	lines := cart["lines"].([]interface{})
+	assert.Len(t, lines, 2)
+	assert.NotEmpty(t, lines[1].(map[string]interface{})["error"])
+
+	// Une ligne invalide empêche la conversion en commande
+	w, _ = cartRequest(router, "POST", cartPath+"/checkout", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// On retire la ligne invalide en base et on modifie la quantité de la première
+	db.Where("product_id = ?", 999).Delete(&models.CartLine{})
+	firstLine := lines[0].(map[string]interface{})
+	w, cart = cartRequest(router, "PUT", fmt.Sprintf("%s/lines/%v", cartPath, firstLine["id"]), map[string]interface{}{"quantity": 3, "modifiers": []string{"sans sel"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, cart["valid"])
+	assert.Equal(t, "7.5", cart["total"])
+
+	w, response := cartRequest(router, "POST", cartPath+"/checkout", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	commande := response["commande"].(map[string]interface{})
+	assert.Equal(t, "7.5", commande["price"])
+	assert.Equal(t, "pending", commande["status"])
+
+	// Le panier n'existe plus après la conversion
+	var count int64
+	db.Model(&models.Cart{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+/////////////////////////////////////
+// EXPIRED CART
+/////////////////////////////////////
+
+func TestExpiredCartIsGone(t *testing.T) {
+	db, _ := setupCartTestDB()
+	router := setupCartRouter(controllers.RefCartController(db))
+
+	cart := models.Cart{}
+	models.CreateCart(db, &cart)
+	db.Model(&cart).Update("expires_at", time.Now().Add(-time.Minute))
+
+	w, _ := cartRequest(router, "GET", fmt.Sprintf("/carts/%d", cart.ID), nil)
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+/////////////////////////////////////
+// OWNERSHIP AND DOUBLE CHECKOUT
+/////////////////////////////////////
+
+func TestCartIsPrivateToItsOwner(t *testing.T) {
+	db, product := setupCartTestDB()
+	cc := controllers.RefCartController(db)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// L'utilisateur connecté est simulé par un en-tête
+	router.Use(func(c *gin.Context) {
+		var id uint
+		fmt.Sscan(c.GetHeader("X-Test-User"), &id)
+		c.Set("user_id", id)
+	})
+	router.GET("/carts/:id", cc.GetCart)
+	router.POST("/carts/:id/lines", cc.AddCartLine)
+	router.DELETE("/carts/:id", cc.DeleteCart)
+	router.POST("/carts/:id/checkout", cc.CheckoutCart)
+
+	cart := models.Cart{UserID: 1}
+	models.CreateCart(db, &cart)
+	path := fmt.Sprintf("/carts/%d", cart.ID)
+	request := func(method, path string, user string, body interface{}) int {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(body)
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// L'utilisateur B ne peut ni lire, ni modifier, ni encaisser, ni supprimer le panier de A
+	line := map[string]interface{}{"product_id": product.ID, "quantity": 1}
+	assert.Equal(t, http.StatusNotFound, request("GET", path, "2", nil))
+	assert.Equal(t, http.StatusNotFound, request("POST", path+"/lines", "2", line))
+	assert.Equal(t, http.StatusNotFound, request("POST", path+"/checkout", "2", nil))
+	assert.Equal(t, http.StatusNotFound, request("DELETE", path, "2", nil))
+
+	assert.Equal(t, http.StatusOK, request("POST", path+"/lines", "1", line))
+	assert.Equal(t, http.StatusOK, request("GET", path, "1", nil))
+}
+
+func TestCartCannotBeCheckedOutTwice(t *testing.T) {
+	db, product := setupCartTestDB()
+	cart := models.Cart{}
+	models.CreateCart(db, &cart)
+	db.Create(&models.CartLine{CartID: cart.ID, ProductID: product.ID, Quantity: 1})
+	loaded, _ := models.GetCartByID(db, cart.ID)
+
+	// Deux encaissements du même panier chargé : seul le premier crée une commande
+	_, err := models.CheckoutCart(db, loaded, &models.Commande{Status: models.StatusPending}, models.PricingContext{Channel: models.ChannelCounter})
+	assert.NoError(t, err)
+	_, err = models.CheckoutCart(db, loaded, &models.Commande{Status: models.StatusPending}, models.PricingContext{Channel: models.ChannelCounter})
+	assert.Equal(t, models.ErrCartCheckedOut, err)
+
+	var count int64
+	db.Model(&models.Commande{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+/////////////////////////////////////
+// STAFF ORDERS
+/////////////////////////////////////
+
+// Base de données des tests de TVA complétée des grilles de prix
+func setupCommandeTestDB() (*gorm.DB, models.Product, models.Product) {
+	db, burger, beer := setupTaxTestDB()
+	db.AutoMigrate(&models.PriceList{}, &models.PriceListItem{})
+	return db, burger, beer
+}
+
+func setupCommandeRouter(cc *controllers.CommandeController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/commandes", cc.CreateCommande)
+	r.PUT("/commandes/admin/:id", cc.AdminUpdateCommande)
+	return r
+}
+
+func TestStaffCommandePriceComesFromTheCatalog(t *testing.T) {
+	db, burger, beer := setupCommandeTestDB()
+	router := setupCommandeRouter(&controllers.CommandeController{DB: db})
+
+	// Le prix envoyé est ignoré
+	body := fmt.Sprintf(`{"products":[%d,%d],"price":0.01}`, burger.ID, beer.ID)
+	req, _ := http.NewRequest("POST", "/commandes", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Commande models.Commande `json:"commande"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "13.40", created.Commande.Price.StringFixed(2))
+
+	body = fmt.Sprintf(`{"products":[%d],"price":999,"status":"pending"}`, burger.ID)
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/commandes/admin/%d", created.Commande.ID), strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Commande
+	db.First(&stored, created.Commande.ID)
+	assert.Equal(t, "8.50", stored.Price.StringFixed(2))
+	assert.True(t, stored.NetTotal.Add(stored.TaxTotal).Equal(stored.Price))
+}
+
+func TestStaffCommandeUpdateFailsWhenTaxesCannotBeComputed(t *testing.T) {
+	db, burger, _ := setupCommandeTestDB()
+	router := setupCommandeRouter(&controllers.CommandeController{DB: db})
+
+	commande := models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(1)}
+	db.Create(&commande)
+	db.Migrator().DropTable(&models.TaxRate{})
+
+	body := fmt.Sprintf(`{"products":[%d],"status":"pending"}`, burger.ID)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/commandes/admin/%d", commande.ID), strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// La mise à jour est annulée
+	var lines int64
+	db.Model(&models.CommandeProduct{}).Where("commande_id = ?", commande.ID).Count(&lines)
+	assert.Equal(t, int64(0), lines)
+}
+
+func TestStaffCommandeUsesPriceListsAndPromotions(t *testing.T) {
+	db, burger, beer := setupCommandeTestDB()
+	router := setupCommandeRouter(&controllers.CommandeController{DB: db})
+
+	student := models.PriceList{Name: "Étudiant", Manual: true, IsActive: true,
+		Items: []models.PriceListItem{{ProductID: &burger.ID, Price: decimal.NewFromInt(6)}}}
+	db.Create(&student)
+	db.Create(&models.Promotion{Name: "Bienvenue", Kind: models.PromoFixed, Value: decimal.NewFromInt(1), Code: code("BIENVENUE"), IsActive: true})
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/commandes", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Tarif étudiant sur le burger (6 €) + bière (4,90 €) - 1 € de code promo
+	w := create(fmt.Sprintf(`{"products":[%d,%d],"price_list_id":%d,"coupon_codes":["bienvenue"]}`, burger.ID, beer.ID, student.ID))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Commande models.Commande `json:"commande"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "9.90", created.Commande.Price.StringFixed(2))
+	assert.Equal(t, "1.00", created.Commande.DiscountTotal.StringFixed(2))
+	assert.Equal(t, models.ChannelCounter, created.Commande.Channel)
+	assert.NotZero(t, created.Commande.TicketNumber)
+
+	// Un produit retiré de la vente est refusé avec le détail des lignes
+	db.Model(&beer).Update("is_available", false)
+	w = create(fmt.Sprintf(`{"products":[%d]}`, beer.ID))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = create(fmt.Sprintf(`{"products":[%d],"coupon_codes":["INCONNU"]}`, burger.ID))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestStaffCommandeEditIsPricedLikeCreation(t *testing.T) {
+	db, burger, beer := setupCommandeTestDB()
+	router := setupCommandeRouter(&controllers.CommandeController{DB: db})
+
+	student := models.PriceList{Name: "Étudiant", Manual: true, IsActive: true,
+		Items: []models.PriceListItem{{ProductID: &burger.ID, Price: decimal.NewFromInt(6)}}}
+	db.Create(&student)
+	promo := models.Promotion{Name: "Bienvenue", Kind: models.PromoFixed, Value: decimal.NewFromInt(1), Code: code("BIENVENUE"), IsActive: true, MaxUses: 1}
+	db.Create(&promo)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := send("POST", "/commandes", fmt.Sprintf(`{"products":[%d],"price_list_id":%d,"coupon_codes":["bienvenue"]}`, burger.ID, student.ID))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Commande models.Commande `json:"commande"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	path := fmt.Sprintf("/commandes/admin/%d", created.Commande.ID)
+
+	// Deux burgers sans oignons, un burger et une bière : tarif étudiant et code promo de la commande
+	w = send("PUT", path, fmt.Sprintf(`{"lines":[{"product_id":%d,"quantity":2,"modifiers":["sans oignons"]}],"products":[%d,%d],"status":"pending"}`, burger.ID, burger.ID, beer.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stored := func() models.Commande {
+		var commande models.Commande
+		db.Preload("Products").Preload("Promotions").First(&commande, created.Commande.ID)
+		return commande
+	}
+	commande := stored()
+	assert.Len(t, commande.Products, 3)
+	assert.Equal(t, 2, commande.Products[0].Quantity)
+	assert.Equal(t, []string{"sans oignons"}, commande.Products[0].Modifiers)
+	assert.Equal(t, "6.00", commande.Products[0].Price.StringFixed(2))
+	assert.Equal(t, student.ID, *commande.Products[1].PriceListID)
+	// 3 × 6 € + 4,90 € - 1 €
+	assert.Equal(t, "21.90", commande.Price.StringFixed(2))
+	assert.Equal(t, "1.00", commande.DiscountTotal.StringFixed(2))
+	assert.Len(t, commande.Promotions, 1)
+	assert.True(t, commande.NetTotal.Add(commande.TaxTotal).Equal(commande.Price))
+	db.First(&promo, promo.ID)
+	assert.Equal(t, 1, promo.UsedCount, "l'utilisation du code est reprise, pas comptée deux fois")
+
+	// Un changement de statut seul ne touche pas aux lignes
+	w = send("PUT", path, `{"status":"preparing"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	commande = stored()
+	assert.Equal(t, models.StatusPreparing, commande.Status)
+	assert.Len(t, commande.Products, 3)
+	assert.Equal(t, "21.90", commande.Price.StringFixed(2))
+
+	// Ligne refusée : rien n'est modifié, ni le statut ni les promotions
+	db.Model(&beer).Update("is_available", false)
+	w = send("PUT", path, fmt.Sprintf(`{"products":[%d],"status":"ready"}`, beer.ID))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	commande = stored()
+	assert.Equal(t, models.StatusPreparing, commande.Status)
+	assert.Len(t, commande.Products, 3)
+	assert.Len(t, commande.Promotions, 1)
+	db.First(&promo, promo.ID)
+	assert.Equal(t, 1, promo.UsedCount)
+}
+
+func TestStaffStatusChangesFollowTheirRole(t *testing.T) {
+	db, burger, _ := setupCommandeTestDB()
+	commande := models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(1)}
+	db.Create(&commande)
+
+	cc := &controllers.CommandeController{DB: db}
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/commandes/preparer/:id", withPermissions(models.DefaultRolePermissions[models.RolePreparer]), cc.StaffUpdateCommande)
+	r.PUT("/commandes/receiver/:id", withPermissions(models.DefaultRolePermissions[models.RoleReceiver]), cc.StaffUpdateCommande)
+
+	send := func(path, body string) int {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/%d", path, commande.ID), strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	update := func(path, status string) int {
+		return send(path, fmt.Sprintf(`{"status":"%s"}`, status))
+	}
+
+	// Les lignes ne se modifient qu'avec la permission order:edit
+	assert.Equal(t, http.StatusForbidden, send("/commandes/preparer", fmt.Sprintf(`{"products":[%d],"status":"ready"}`, burger.ID)))
+
+	// La cuisine déclare seulement la commande prête ; l'accueil la remet ou la renvoie en attente
+	assert.Equal(t, http.StatusForbidden, update("/commandes/preparer", "preparing"))
+	assert.Equal(t, http.StatusForbidden, update("/commandes/preparer", "delivered"))
+	assert.Equal(t, http.StatusForbidden, update("/commandes/receiver", "ready"))
+	assert.Equal(t, http.StatusBadRequest, update("/commandes/preparer", "annulee"))
+	assert.Equal(t, http.StatusBadRequest, update("/commandes/receiver", ""))
+
+	assert.Equal(t, http.StatusOK, update("/commandes/preparer", "ready"))
+	assert.Equal(t, http.StatusOK, update("/commandes/receiver", "delivered"))
+	db.First(&commande, commande.ID)
+	assert.Equal(t, models.StatusDelivered, commande.Status)
+}
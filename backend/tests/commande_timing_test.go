@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"LearningCampusKabre/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+/////////////////////////////////////
+// STATUS HISTORY AND SLA
+/////////////////////////////////////
+
+func TestCommandeStatusHistoryAndSLA(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.CommandePromotion{})
+
+	commande := models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(10)}
+	db.Create(&commande)
+
+	commande.Status = models.StatusPreparing
+	db.Save(&commande)
+	commande.Status = models.StatusReady
+	db.Save(&commande)
+
+	var changes []models.CommandeStatusChange
+	db.Order("id").Find(&changes)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, models.StatusPreparing, changes[1].ToStatus)
+	assert.Equal(t, models.StatusPending, changes[1].FromStatus)
+
+	// Une sauvegarde sans changement de statut n'ajoute rien à l'historique
+	db.Save(&commande)
+	var count int64
+	db.Model(&models.CommandeStatusChange{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	// Commande en attente depuis 20 minutes : le SLA par défaut (10 minutes) est dépassé
+	late := models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(10), CreatedAt: time.Now().Add(-20 * time.Minute)}
+	db.Create(&late)
+
+	commandes, err := models.GetAllComm(db)
+	assert.NoError(t, err)
+	assert.Len(t, commandes, 2)
+
+	assert.False(t, commandes[0].SLABreached)
+	assert.NotNil(t, commandes[0].Timings.WaitingSeconds)
+	assert.NotNil(t, commandes[0].Timings.PreparationSeconds)
+	assert.Nil(t, commandes[0].Timings.HandoverSeconds)
+
+	assert.True(t, commandes[1].SLABreached)
+	assert.Nil(t, commandes[1].Timings.PreparationSeconds)
+}
+
+func TestPrepTimeOfScheduledCommandeStartsAtRelease(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.CommandePromotion{})
+
+	// Commande programmée trois heures à l'avance, libérée puis mise à disposition sans passer
+	// en préparation : l'attente avant la libération n'est pas du temps de préparation
+	commande := models.Commande{Status: models.StatusScheduled, Price: decimal.NewFromInt(10), CreatedAt: time.Now().Add(-3 * time.Hour)}
+	db.Create(&commande)
+	commande.Status = models.StatusPending
+	db.Save(&commande)
+	commande.Status = models.StatusReady
+	db.Save(&commande)
+
+	var stored models.Commande
+	db.First(&stored, commande.ID)
+	assert.NotNil(t, stored.PrepSeconds)
+	assert.Less(t, *stored.PrepSeconds, 60.0)
+}
+
+/////////////////////////////////////
+// READY TIME ESTIMATE
+/////////////////////////////////////
+
+func TestEstimateReadyAtAveragesRecentPrepTimes(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.CommandePromotion{})
+
+	// Le temps de préparation est enregistré à la mise à disposition et survit aux sauvegardes suivantes
+	commande := models.Commande{Status: models.StatusPreparing, Price: decimal.NewFromInt(10)}
+	db.Create(&commande)
+	commande.Status = models.StatusReady
+	db.Save(&commande)
+	commande.Status = models.StatusDelivered
+	db.Save(&commande)
+	var stored models.Commande
+	db.First(&stored, commande.ID)
+	assert.NotNil(t, stored.PrepSeconds)
+
+	now := time.Now()
+	history := func(prepSeconds float64, createdAt time.Time, productID, menuID uint) {
+		c := models.Commande{Status: models.StatusDelivered, Price: decimal.NewFromInt(10), CreatedAt: createdAt}
+		if productID != 0 {
+			c.Products = []models.CommandeProduct{{ProductID: productID, Price: decimal.NewFromInt(5)}}
+		}
+		if menuID != 0 {
+			c.Menus = []models.CommandeMenu{{MenuID: menuID, Price: decimal.NewFromInt(5)}}
+		}
+		db.Create(&c)
+		db.Model(&c).UpdateColumn("prep_seconds", prepSeconds)
+	}
+	history(600, now.Add(-time.Hour), 1, 0)
+	history(300, now.Add(-2*time.Hour), 1, 0)
+	history(900, now.Add(-time.Hour), 0, 3)
+	// Hors de la fenêtre d'historique : ignorée
+	history(10000, now.AddDate(0, 0, -40), 1, 0)
+
+	// Produit 1 : moyenne de 450 s ; produit 2 sans historique : durée par défaut (300 s)
+	order := models.Commande{Products: []models.CommandeProduct{{ProductID: 1}, {ProductID: 2}}}
+	estimate, err := models.EstimateReadyAt(db, &order, now)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, now.Add(450*time.Second), estimate, time.Second)
+
+	// Les lignes sont préparées en parallèle : le menu 3 (900 s) l'emporte
+	order.Menus = []models.CommandeMenu{{MenuID: 3}}
+	estimate, err = models.EstimateReadyAt(db, &order, now)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, now.Add(900*time.Second), estimate, time.Second)
+
+	// Chaque commande en attente ajoute le délai de file (60 s par défaut)
+	db.Create(&models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(10)})
+	estimate, err = models.EstimateReadyAt(db, &order, now)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, now.Add(960*time.Second), estimate, time.Second)
+}
@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/delivery"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// statusCall est une mise à jour de statut reçue par la fausse plateforme
+type statusCall struct {
+	Path      string
+	Signature string
+	Body      map[string]string
+}
+
+// fakePlatform simule l'API de statut d'une plateforme de livraison
+func fakePlatform(t *testing.T, code int) (*httptest.Server, chan statusCall) {
+	calls := make(chan statusCall, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		call := statusCall{Path: r.URL.Path, Signature: r.Header.Get("X-Signature")}
+		json.Unmarshal(raw, &call.Body)
+		calls <- call
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func waitStatusCall(t *testing.T, calls chan statusCall) statusCall {
+	select {
+	case call := <-calls:
+		return call
+	case <-time.After(2 * time.Second):
+		t.Fatal("aucune mise à jour de statut reçue par la plateforme")
+		return statusCall{}
+	}
+}
+
+func setupDeliveryTestDB(statusURL string) (*gorm.DB, models.DeliveryPlatform, models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Station{}, &models.Printer{}, &models.PrintJob{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{}, &models.DeliveryPlatform{}, &models.DeliveryProductMapping{}, &models.DeliveryStatusPush{})
+
+	product := models.Product{Name: "Burger", Price: decimal.NewFromInt(8), IsAvailable: true, Type: models.TypePlat}
+	db.Create(&product)
+
+	platform := models.DeliveryPlatform{Name: "Uber Eats", Adapter: "ubereats", WebhookSecret: "s3cr3t", StatusURL: statusURL + "/orders/{id}/status", IsActive: true}
+	models.CreateDeliveryPlatform(db, &platform)
+	models.SaveDeliveryMapping(db, &models.DeliveryProductMapping{PlatformID: platform.ID, ExternalID: "burger-classic", ProductID: &product.ID})
+
+	return db, platform, product
+}
+
+func uberEatsPayload(orderID string, items ...string) []byte {
+	var cartItems []map[string]interface{}
+	for _, item := range items {
+		cartItems = append(cartItems, map[string]interface{}{"external_data": item, "quantity": 2, "special_instructions": "sans oignons"})
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":    orderID,
+		"eater": map[string]string{"first_name": "Awa"},
+		"cart":  map[string]interface{}{"items": cartItems},
+	})
+	return body
+}
+
+func postWebhook(r *gin.Engine, platformID uint, body []byte, signature string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/delivery/webhooks/%d", platformID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Uber-Signature", signature)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func setupDeliveryRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	dc := controllers.RefDeliveryController(db)
+	r.POST("/delivery/webhooks/:id", dc.ReceiveWebhook)
+	return r
+}
+
+func TestDeliveryWebhookCreatesCommande(t *testing.T) {
+	server, calls := fakePlatform(t, http.StatusOK)
+	db, platform, product := setupDeliveryTestDB(server.URL)
+	r := setupDeliveryRouter(db)
+	t.Cleanup(models.OnEvent(delivery.Listen))
+
+	body := uberEatsPayload("UE-1001", "burger-classic")
+	signature := hex.EncodeToString(delivery.Sign(body, "s3cr3t"))
+
+	w := postWebhook(r, platform.ID, body, signature)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var commande models.Commande
+	db.Preload("Products").First(&commande)
+	assert.Equal(t, models.ChannelDelivery, commande.Channel)
+	assert.Equal(t, models.ServiceTakeaway, commande.ServiceMode)
+	assert.Equal(t, models.PaymentPaid, commande.PaymentStatus)
+	assert.Equal(t, "UE-1001", *commande.ExternalOrderID)
+	assert.Equal(t, product.ID, commande.Products[0].ProductID)
+	assert.Equal(t, 2, commande.Products[0].Quantity)
+	assert.Equal(t, []string{"sans oignons"}, commande.Products[0].Modifiers)
+	assert.True(t, decimal.NewFromInt(16).Equal(commande.Price))
+
+	// La plateforme est prévenue que la commande est acceptée, avec une requête signée
+	assert.Equal(t, 1, delivery.NewWorker(db).ProcessDue(time.Now()))
+	call := waitStatusCall(t, calls)
+	assert.Equal(t, "/orders/UE-1001/status", call.Path)
+	assert.Equal(t, "accepted", call.Body["status"])
+	raw, _ := json.Marshal(call.Body)
+	assert.Equal(t, hex.EncodeToString(delivery.Sign(raw, "s3cr3t")), call.Signature)
+
+	// Le webhook rejoué ne crée pas de doublon
+	w = postWebhook(r, platform.ID, body, signature)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.Commande{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDeliveryWebhookRejectsBadSignatureAndUnmappedItems(t *testing.T) {
+	server, _ := fakePlatform(t, http.StatusOK)
+	db, platform, _ := setupDeliveryTestDB(server.URL)
+	r := setupDeliveryRouter(db)
+
+	body := uberEatsPayload("UE-1002", "burger-classic")
+	w := postWebhook(r, platform.ID, body, hex.EncodeToString(delivery.Sign(body, "mauvais secret")))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	body = uberEatsPayload("UE-1003", "burger-classic", "milkshake")
+	w = postWebhook(r, platform.ID, body, hex.EncodeToString(delivery.Sign(body, "s3cr3t")))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []interface{}{"milkshake"}, response["unmapped"])
+
+	var count int64
+	db.Model(&models.Commande{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestDeliverooAdapterParsesOrder(t *testing.T) {
+	adapter, ok := delivery.GetAdapter("deliveroo")
+	assert.True(t, ok)
+
+	body := []byte(`{"order":{"id":"DR-42","customer":{"first_name":"Jean"},"items":[{"pos_item_id":"menu-maxi","quantity":1,"modifiers":[{"name":"Coca"}]}]}}`)
+	header := http.Header{}
+	header.Set("X-Deliveroo-Hmac-Sha256", base64.StdEncoding.EncodeToString(delivery.Sign(body, "secret")))
+	assert.True(t, adapter.VerifySignature(body, header, "secret"))
+	assert.False(t, adapter.VerifySignature(body, header, "autre"))
+
+	order, err := adapter.ParseOrder(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "DR-42", order.ID)
+	assert.Equal(t, "menu-maxi", order.Lines[0].ExternalID)
+	assert.Equal(t, []string{"Coca"}, order.Lines[0].Modifiers)
+}
+
+func TestDeliveryStatusPushedOnChange(t *testing.T) {
+	server, calls := fakePlatform(t, http.StatusOK)
+	db, platform, _ := setupDeliveryTestDB(server.URL)
+
+	externalID := "UE-2001"
+	commande := models.Commande{Status: models.StatusReady, Channel: models.ChannelDelivery, DeliveryPlatformID: &platform.ID, ExternalOrderID: &externalID}
+	db.Create(&commande)
+	event := models.Event{Type: models.EventCommandeStatusChanged, Data: &commande, FromStatus: models.StatusPreparing}
+
+	// Un changement annulé n'est jamais transmis
+	db.Transaction(func(tx *gorm.DB) error {
+		assert.NoError(t, delivery.Listen(tx, event))
+		return fmt.Errorf("annulé")
+	})
+	assert.Equal(t, 0, delivery.NewWorker(db).ProcessDue(time.Now()))
+
+	assert.NoError(t, delivery.Listen(db, event))
+	assert.Equal(t, 1, delivery.NewWorker(db).ProcessDue(time.Now()))
+	call := waitStatusCall(t, calls)
+	assert.Equal(t, "ready_for_pickup", call.Body["status"])
+
+	var push models.DeliveryStatusPush
+	db.First(&push)
+	assert.Equal(t, models.DeliveryPushSent, push.Status)
+
+	// Une commande qui ne vient pas d'une plateforme n'est pas transmise
+	assert.NoError(t, delivery.Listen(db, models.Event{Type: models.EventCommandeStatusChanged, Data: &models.Commande{Status: models.StatusReady}, FromStatus: models.StatusPreparing}))
+	assert.Equal(t, 0, delivery.NewWorker(db).ProcessDue(time.Now()))
+	select {
+	case <-calls:
+		t.Fatal("statut envoyé pour une commande sans plateforme")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDeliveryStatusPushRetriedAfterFailure(t *testing.T) {
+	server, calls := fakePlatform(t, http.StatusServiceUnavailable)
+	db, platform, _ := setupDeliveryTestDB(server.URL)
+	assert.NoError(t, models.EnqueueDeliveryStatusPush(db, platform.ID, "UE-3001", models.StatusReady))
+
+	now := time.Now()
+	worker := delivery.NewWorker(db)
+	assert.Equal(t, 1, worker.ProcessDue(now))
+	waitStatusCall(t, calls)
+
+	var push models.DeliveryStatusPush
+	db.First(&push)
+	assert.Equal(t, models.DeliveryPushPending, push.Status)
+	assert.Equal(t, 1, push.Attempts)
+	assert.Contains(t, push.LastError, "503")
+
+	// La mise à jour attend son délai, puis est renvoyée
+	assert.Equal(t, 0, worker.ProcessDue(now))
+	assert.Equal(t, 1, worker.ProcessDue(now.Add(worker.Backoff(1))))
+	waitStatusCall(t, calls)
+}
+
+func TestDeliveryStatusPushClaimedOnce(t *testing.T) {
+	db, platform, _ := setupDeliveryTestDB("http://127.0.0.1:1")
+	assert.NoError(t, models.EnqueueDeliveryStatusPush(db, platform.ID, "UE-4001", models.StatusReady))
+
+	now := time.Now()
+	pushes, _ := models.GetDueDeliveryStatusPushes(db, now, 10)
+	second := pushes[0]
+
+	// Deux workers lisent la même mise à jour : seul le premier la réserve
+	claimed, err := models.ClaimDeliveryStatusPush(db, &pushes[0], now, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = models.ClaimDeliveryStatusPush(db, &second, now, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	// Le bail expiré, la mise à jour redevient disponible
+	due, _ := models.GetDueDeliveryStatusPushes(db, now.Add(2*time.Minute), 10)
+	assert.Len(t, due, 1)
+}
+
+func TestDeliveryClientReportsPlatformErrors(t *testing.T) {
+	server, _ := fakePlatform(t, http.StatusInternalServerError)
+	platform := models.DeliveryPlatform{Name: "Deliveroo", Adapter: "deliveroo", WebhookSecret: "secret", StatusURL: server.URL + "/status"}
+
+	err := delivery.NewClient().PushStatus(&platform, "DR-1", models.StatusDelivered)
+	assert.Error(t, err)
+}
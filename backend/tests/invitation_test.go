@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupInvitationTestDB() *gorm.DB {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.User{}, &models.Invitation{}, &models.Role{})
+	return db
+}
+
+func postRegister(r *gin.Engine, body map[string]interface{}) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func setupAuthRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	ac := controllers.RefAuthController(db)
+	r.POST("/auth/register", ac.Register)
+	r.POST("/invitations", withPermissions(adminPermissions()), ac.CreateInvitation)
+	return r
+}
+
+func TestRegisterRequiresInvitation(t *testing.T) {
+	db := setupInvitationTestDB()
+	r := setupAuthRouter(db)
+
+	// L'ancien format (email et rôle libres) ne crée plus de compte
+	w := postRegister(r, map[string]interface{}{"email": "pirate@example.com", "password": "secret123", "role": "admin"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postRegister(r, map[string]interface{}{"token": "inconnu", "password": "secret123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestInvitationRedeemedOnce(t *testing.T) {
+	db := setupInvitationTestDB()
+	r := setupAuthRouter(db)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"email": "awa@example.com", "role": "preparer"})
+	req, _ := http.NewRequest("POST", "/invitations", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Token      string            `json:"token"`
+		Invitation models.Invitation `json:"invitation"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Token)
+
+	// Le jeton n'est pas stocké en clair
+	var stored models.Invitation
+	db.First(&stored)
+	assert.NotEqual(t, response.Token, stored.TokenHash)
+
+	w = postRegister(r, map[string]interface{}{"token": response.Token, "password": "court"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postRegister(r, map[string]interface{}{"token": response.Token, "password": "motdepasse123"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var user models.User
+	db.First(&user)
+	assert.Equal(t, "awa@example.com", user.Email)
+	assert.Equal(t, models.RolePreparer, user.Role)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("motdepasse123")))
+
+	// Usage unique
+	w = postRegister(r, map[string]interface{}{"token": response.Token, "password": "motdepasse123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestInvitationExpiredOrRevoked(t *testing.T) {
+	db := setupInvitationTestDB()
+
+	_, err := models.CreateInvitation(db, &models.Invitation{Email: "x@example.com", Role: "chef"}, time.Hour)
+	assert.Error(t, err)
+
+	expired := models.Invitation{Email: "old@example.com", Role: models.RoleReceiver}
+	token, _ := models.CreateInvitation(db, &expired, -time.Minute)
+	_, err = models.RedeemInvitation(db, token, "motdepasse123")
+	assert.ErrorIs(t, err, models.ErrInvitationInvalid)
+
+	revoked := models.Invitation{Email: "new@example.com", Role: models.RoleReceiver}
+	token, _ = models.CreateInvitation(db, &revoked, time.Hour)
+	assert.NoError(t, models.RevokeInvitation(db, revoked.ID))
+	_, err = models.RedeemInvitation(db, token, "motdepasse123")
+	assert.ErrorIs(t, err, models.ErrInvitationInvalid)
+}
+
+func TestBootstrapAdminOnlyOnce(t *testing.T) {
+	db := setupInvitationTestDB()
+
+	created, err := models.BootstrapAdmin(db, "admin@example.com", "motdepasse123")
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	created, err = models.BootstrapAdmin(db, "autre@example.com", "motdepasse123")
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	var admins []models.User
+	db.Where("role = ?", models.RoleAdmin).Find(&admins)
+	assert.Len(t, admins, 1)
+	assert.Equal(t, "admin@example.com", admins[0].Email)
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/jwtkeys"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTestSigningKey installe une clé EdDSA le temps du test
+func useTestSigningKey(t *testing.T) *jwtkeys.KeySet {
+	key, err := jwtkeys.GenerateKey("test", jwtkeys.AlgEdDSA, time.Time{})
+	assert.NoError(t, err)
+	keys, err := jwtkeys.NewKeySet(key)
+	assert.NoError(t, err)
+	jwtkeys.SetDefault(keys)
+	t.Cleanup(func() { jwtkeys.SetDefault(nil) })
+	return keys
+}
+
+// tokenKid lit le kid de l'en-tête d'un jeton sans le vérifier
+func tokenKid(t *testing.T, tokenString string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	assert.NoError(t, err)
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+func TestJWKSAndScheduledRotation(t *testing.T) {
+	db, r, _ := setupSessionTest(t)
+	r.GET("/.well-known/jwks.json", controllers.RefAuthController(db).JWKS)
+
+	// Clé RS256 en service, clé EdDSA programmée pour dans une heure
+	current, err := jwtkeys.GenerateKey("2026-10", jwtkeys.AlgRS256, time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	next, err := jwtkeys.GenerateKey("2026-11", jwtkeys.AlgEdDSA, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	keys, err := jwtkeys.NewKeySet(current, next)
+	assert.NoError(t, err)
+	jwtkeys.SetDefault(keys)
+
+	oldToken := login(t, r).Token
+	assert.Equal(t, "2026-10", tokenKid(t, oldToken))
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", oldToken, nil).Code)
+
+	// La clé à venir est déjà publiée, avec les paramètres attendus par les vérificateurs
+	w := authRequest(r, "GET", "/.well-known/jwks.json", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var set jwtkeys.JWKSet
+	json.Unmarshal(w.Body.Bytes(), &set)
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, jwtkeys.JWK{Kty: "RSA", Kid: "2026-10", Use: "sig", Alg: "RS256", N: set.Keys[0].N, E: "AQAB"}, set.Keys[0])
+	assert.Equal(t, "OKP", set.Keys[1].Kty)
+	assert.Equal(t, "Ed25519", set.Keys[1].Crv)
+	assert.NotEmpty(t, set.Keys[1].X)
+
+	// Heure de bascule passée : la nouvelle clé signe, les jetons de l'ancienne restent valides
+	signing, _ := keys.SigningKey(time.Now().Add(2 * time.Hour))
+	assert.Equal(t, "2026-11", signing.ID)
+	next.NotBefore = time.Now().Add(-time.Minute)
+	assert.NoError(t, keys.Replace([]*jwtkeys.Key{current, next}))
+	newToken := login(t, r).Token
+	assert.Equal(t, "2026-11", tokenKid(t, newToken))
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", oldToken, nil).Code)
+
+	// Ancienne clé retirée : ses jetons sont refusés
+	assert.NoError(t, keys.Replace([]*jwtkeys.Key{next}))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", oldToken, nil).Code)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", newToken, nil).Code)
+}
+
+func TestJWTAlgorithmIsChecked(t *testing.T) {
+	_, r, _ := setupSessionTest(t)
+	keys := jwtkeys.Default()
+	key, _ := keys.SigningKey(time.Now())
+
+	valid := login(t, r).Token
+	claims := jwt.MapClaims{}
+	jwt.NewParser().ParseUnverified(valid, claims)
+
+	// Jeton non signé
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	none.Header["kid"] = key.ID
+	unsigned, _ := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	// HMAC avec la clé publique comme secret (confusion d'algorithme)
+	public, _ := x509.MarshalPKIXPublicKey(key.Public())
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hmac.Header["kid"] = key.ID
+	hmacToken, _ := hmac.SignedString(public)
+
+	// Algorithme accepté mais différent de celui de la clé désignée, et kid inconnu
+	other, _ := jwtkeys.GenerateKey("", jwtkeys.AlgRS256, time.Time{})
+	mismatch := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	mismatch.Header["kid"] = key.ID
+	mismatchToken, _ := mismatch.SignedString(other.Private)
+	unknown, _ := jwtkeys.NewKeySet(other)
+	unknownToken, _ := unknown.Sign(claims)
+
+	// Jeton sans expiration
+	delete(claims, "exp")
+	noExp, _ := keys.Sign(claims)
+
+	for name, token := range map[string]string{"none": unsigned, "HS256": hmacToken, "algorithme": mismatchToken, "kid": unknownToken, "exp": noExp} {
+		assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", token, nil).Code, name)
+	}
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", valid, nil).Code)
+}
+
+func TestSigningKeysFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_PRIVATE_KEYS", "")
+
+	// Aucune clé : le serveur refuse de démarrer
+	_, err := jwtkeys.FromEnv()
+	assert.Equal(t, jwtkeys.ErrNoKey, err)
+	t.Setenv("JWT_KEYS_DIR", dir)
+	_, err = jwtkeys.FromEnv()
+	assert.Equal(t, jwtkeys.ErrNoKey, err)
+
+	// Une clé seulement programmée ne suffit pas
+	notBefore := time.Now().Add(time.Hour).Truncate(time.Second)
+	future, _ := jwtkeys.GenerateKey("", jwtkeys.AlgEdDSA, notBefore)
+	data, _ := jwtkeys.EncodePEM(future)
+	os.WriteFile(filepath.Join(dir, "future.pem"), data, 0o600)
+	_, err = jwtkeys.FromEnv()
+	assert.Equal(t, jwtkeys.ErrNoActiveKey, err)
+
+	// Clé active en variable d'environnement, en plus du répertoire ; kid et date relus du PEM
+	active, _ := jwtkeys.GenerateKey("actuelle", jwtkeys.AlgRS256, time.Time{})
+	data, _ = jwtkeys.EncodePEM(active)
+	t.Setenv("JWT_PRIVATE_KEYS", string(data))
+	keys, err := jwtkeys.FromEnv()
+	assert.NoError(t, err)
+	loaded := keys.Keys()
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, "actuelle", loaded[0].ID)
+	assert.Equal(t, future.ID, loaded[1].ID)
+	assert.True(t, notBefore.Equal(loaded[1].NotBefore))
+
+	// Clé RSA trop courte refusée
+	weak, _ := rsa.GenerateKey(rand.Reader, 1024)
+	weakPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)})
+	os.WriteFile(filepath.Join(dir, "weak.pem"), weakPEM, 0o600)
+	_, err = jwtkeys.FromEnv()
+	assert.Error(t, err)
+}
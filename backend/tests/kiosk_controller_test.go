@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données en mémoire avec une borne et deux produits
+func setupKioskTestDB() (*gorm.DB, models.Device, string, []models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Device{}, &models.Printer{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{})
+
+	products := []models.Product{
+		{Name: "Burger", Price: decimal.NewFromFloat(8.50), IsAvailable: true, Type: models.TypePlat},
+		{Name: "Tiramisu", Price: decimal.NewFromFloat(4.00), IsAvailable: true, Type: models.TypeDessert},
+	}
+	for i := range products {
+		db.Create(&products[i])
+	}
+	// Rupture de stock : le booléen false doit être forcé après la création (default:true)
+	db.Model(&products[1]).Update("is_available", false)
+
+	device := models.Device{Name: "Borne 1", Kind: models.DeviceKiosk, IsActive: true}
+	key, _ := models.CreateDevice(db, &device)
+
+	return db, device, key, products
+}
+
+// Router pour les tests
+func setupKioskRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	kc := controllers.RefKioskController(db)
+	kiosk := r.Group("/kiosk", middlewares.DeviceAuthMiddleware(db, models.DeviceKiosk))
+	kiosk.GET("/catalog", kc.GetCatalog)
+	kiosk.POST("/cart/validate", kc.ValidateCart)
+	kiosk.POST("/commandes", kc.CreateKioskCommande)
+	return r
+}
+
+func kioskRequest(router *gin.Engine, path string, device models.Device, key string, body interface{}) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Device-ID", fmt.Sprint(device.ID))
+	req.Header.Set("X-Device-Key", key)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+/////////////////////////////////////
+// DEVICE AUTH
+/////////////////////////////////////
+
+func TestKioskRejectsWrongKey(t *testing.T) {
+	db, device, _, products := setupKioskTestDB()
+	router := setupKioskRouter(db)
+
+	body := map[string]interface{}{
+		"lines": []map[string]interface{}{{"product_id": products[0].ID, "quantity": 1}},
+	}
+	w := kioskRequest(router, "/kiosk/commandes", device, "mauvaise-cle", body)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+/////////////////////////////////////
+// VALIDATE CART
+/////////////////////////////////////
+
+func TestKioskValidateCartReportsUnavailableLine(t *testing.T) {
+	db, device, key, products := setupKioskTestDB()
+	router := setupKioskRouter(db)
+
+	body := map[string]interface{}{
+		"lines": []map[string]interface{}{
+			{"product_id": products[0].ID, "quantity": 2},
+			{"product_id": products[1].ID, "quantity": 1},
+		},
+	}
+	w := kioskRequest(router, "/kiosk/cart/validate", device, key, body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order models.PricedOrder
+	json.Unmarshal(w.Body.Bytes(), &order)
+
+	assert.False(t, order.Valid)
+	assert.Len(t, order.Lines, 2)
+	assert.Empty(t, order.Lines[0].Error)
+	assert.Equal(t, "17", order.Lines[0].Total.String())
+	assert.NotEmpty(t, order.Lines[1].Error)
+}
+
+/////////////////////////////////////
+// CREATE KIOSK COMMANDE
+/////////////////////////////////////
+
+func TestKioskCreateCommande(t *testing.T) {
+	db, device, key, products := setupKioskTestDB()
+	router := setupKioskRouter(db)
+
+	body := map[string]interface{}{
+		"lines": []map[string]interface{}{{"product_id": products[0].ID, "quantity": 2}},
+	}
+
+	w := kioskRequest(router, "/kiosk/commandes", device, key, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, float64(1), response["ticket_number"])
+	assert.Equal(t, "required", response["payment_status"])
+
+	// Le deuxième ticket de la journée suit le premier
+	w = kioskRequest(router, "/kiosk/commandes", device, key, body)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, float64(2), response["ticket_number"])
+
+	var commande models.Commande
+	db.Preload("Products").First(&commande)
+	assert.Equal(t, models.StatusPending, commande.Status)
+	assert.Equal(t, models.ChannelKiosk, commande.Channel)
+	assert.Equal(t, "17", commande.Price.String())
+	assert.Len(t, commande.Products, 1)
+	assert.Equal(t, 2, commande.Products[0].Quantity)
+}
+
+func TestKioskValidateCartRejectsMenuWithUnavailableProduct(t *testing.T) {
+	db, device, key, products := setupKioskTestDB()
+	router := setupKioskRouter(db)
+
+	menu := models.Menu{Name: "Menu Burger", Price: decimal.NewFromFloat(11)}
+	db.Create(&menu)
+	for _, p := range products {
+		db.Create(&models.MenuItem{MenuID: menu.ID, ProductID: p.ID, Name: p.Name, Price: p.Price, Type: p.Type})
+	}
+
+	body := map[string]interface{}{"lines": []map[string]interface{}{{"menu_id": menu.ID, "quantity": 1}}}
+	w := kioskRequest(router, "/kiosk/cart/validate", device, key, body)
+	var order models.PricedOrder
+	json.Unmarshal(w.Body.Bytes(), &order)
+	assert.False(t, order.Valid)
+	assert.Contains(t, order.Lines[0].Error, "Tiramisu")
+
+	// Le produit revient en vente : le menu aussi
+	db.Model(&products[1]).Update("is_available", true)
+	w = kioskRequest(router, "/kiosk/cart/validate", device, key, body)
+	json.Unmarshal(w.Body.Bytes(), &order)
+	assert.True(t, order.Valid)
+}
+
+func TestKioskCatalogHidesMenusWithUnavailableProduct(t *testing.T) {
+	db, device, key, products := setupKioskTestDB()
+	router := setupKioskRouter(db)
+
+	// Menu burger seul, toujours disponible ; menu dessert, dont le tiramisu est en rupture
+	burger := models.Menu{Name: "Menu Burger", Price: decimal.NewFromFloat(11)}
+	dessert := models.Menu{Name: "Menu Dessert", Price: decimal.NewFromFloat(13)}
+	db.Create(&burger)
+	db.Create(&dessert)
+	db.Create(&models.MenuItem{MenuID: burger.ID, ProductID: products[0].ID, Name: products[0].Name})
+	for _, p := range products {
+		db.Create(&models.MenuItem{MenuID: dessert.ID, ProductID: p.ID, Name: p.Name})
+	}
+
+	catalog := func() []models.Menu {
+		req, _ := http.NewRequest("GET", "/kiosk/catalog", nil)
+		req.Header.Set("X-Device-ID", fmt.Sprint(device.ID))
+		req.Header.Set("X-Device-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Menus []models.Menu `json:"menus"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Menus
+	}
+
+	menus := catalog()
+	assert.Len(t, menus, 1)
+	assert.Equal(t, burger.ID, menus[0].ID)
+	assert.Len(t, menus[0].MenuItems, 1)
+
+	// Le produit revient en vente : le menu aussi
+	db.Model(&products[1]).Update("is_available", true)
+	assert.Len(t, catalog(), 2)
+
+	// Un produit supprimé rend aussi le menu indisponible
+	db.Delete(&products[0])
+	assert.Empty(t, catalog())
+}
+
+func TestTicketNumbersNeverRepeat(t *testing.T) {
+	db, _, _, _ := setupKioskTestDB()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+
+	// Une commande du jour existe déjà (avant l'installation du compteur), puis est supprimée
+	old := models.Commande{TicketNumber: 7, Status: models.StatusPending, CreatedAt: now.Add(-time.Hour)}
+	db.Create(&old)
+	db.Delete(&old)
+
+	first, err := models.NextTicketNumber(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(8), first)
+	second, _ := models.NextTicketNumber(db, now)
+	assert.Equal(t, uint(9), second)
+
+	// Le compteur repart à 1 le lendemain
+	next, _ := models.NextTicketNumber(db, now.Add(24*time.Hour))
+	assert.Equal(t, uint(1), next)
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginProgressiveDelayAndLockout(t *testing.T) {
+	db, _, user := setupSessionTest(t)
+	now := time.Now()
+
+	// Deux échecs sans délai, puis un délai qui double
+	for i := 0; i < 2; i++ {
+		_, err := models.AuthenticateLogin(db, "awa@example.com", "mauvais", "10.0.0.1", now)
+		assert.Equal(t, models.ErrInvalidCredentials, err)
+	}
+	_, err := models.AuthenticateLogin(db, "awa@example.com", "motdepasse123", "10.0.0.1", now)
+	blocked, ok := err.(*models.LoginBlockedError)
+	assert.True(t, ok)
+	assert.False(t, blocked.Locked)
+	assert.Equal(t, time.Second, blocked.RetryAfter)
+
+	now = now.Add(time.Second)
+	models.AuthenticateLogin(db, "awa@example.com", "mauvais", "10.0.0.1", now)
+	now = now.Add(2 * time.Second)
+	models.AuthenticateLogin(db, "awa@example.com", "mauvais", "10.0.0.1", now)
+	now = now.Add(4 * time.Second)
+	_, err = models.AuthenticateLogin(db, "awa@example.com", "mauvais", "10.0.0.1", now)
+	assert.Equal(t, models.ErrInvalidCredentials, err)
+
+	// Cinquième échec : compte verrouillé, même avec le bon mot de passe
+	_, err = models.AuthenticateLogin(db, "awa@example.com", "motdepasse123", "10.0.0.1", now.Add(time.Minute))
+	blocked, ok = err.(*models.LoginBlockedError)
+	assert.True(t, ok)
+	assert.True(t, blocked.Locked)
+
+	// Déverrouillage par un administrateur
+	_, err = models.UnlockUser(db, user.ID)
+	assert.NoError(t, err)
+	logged, err := models.AuthenticateLogin(db, "awa@example.com", "motdepasse123", "10.0.0.1", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, logged.ID)
+
+	attempts, _ := models.GetLoginAttempts(db, models.LoginAttemptFilter{Email: "AWA@example.com"})
+	assert.Len(t, attempts, 8)
+	assert.True(t, attempts[0].Success)
+	assert.Equal(t, "locked", attempts[1].Reason)
+	failed, _ := models.GetLoginAttempts(db, models.LoginAttemptFilter{UserID: user.ID, FailedOnly: true})
+	assert.Len(t, failed, 7)
+}
+
+func TestLoginBlockedPerIP(t *testing.T) {
+	db, r, _ := setupSessionTest(t)
+	now := time.Now()
+
+	// Des échecs sur des comptes différents depuis la même adresse
+	for i := 0; i < models.MaxFailedLoginsPerIP; i++ {
+		models.AuthenticateLogin(db, fmt.Sprintf("inconnu%d@example.com", i), "x", "", now)
+	}
+
+	w := authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "awa@example.com", "password": "motdepasse123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := middlewares.NewRateLimiter(2, time.Minute)
+	now := time.Now()
+	limiter.Now = func() time.Time { return now }
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/sensible", middlewares.RateLimit(limiter, middlewares.ByIP), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	call := func(ip string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/sensible", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, call("10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, call("10.0.0.1").Code)
+	w := call("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "61", w.Header().Get("Retry-After"))
+
+	// Les autres adresses ne sont pas touchées, et la fenêtre suivante repart de zéro
+	assert.Equal(t, http.StatusOK, call("10.0.0.2").Code)
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, call("10.0.0.1").Code)
+}
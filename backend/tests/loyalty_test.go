@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données avec un client fidèle, un dessert et une récompense à 20 points
+func setupLoyaltyTestDB() (*gorm.DB, models.Customer, models.Reward) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Customer{}, &models.LoyaltyLedgerEntry{}, &models.Reward{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{})
+	models.SeedTaxRates(db)
+
+	customer := models.Customer{FirstName: "Awa"}
+	customer.SetContact("06 12 34 56 78", "")
+	customer.SetConsents(true, false, time.Now())
+	models.CreateCustomer(db, &customer)
+
+	dessert := models.Product{Name: "Tiramisu", Price: decimal.NewFromInt(4), IsAvailable: true, Type: models.TypeDessert}
+	db.Create(&dessert)
+
+	reward := models.Reward{Name: "Dessert offert", ProductID: dessert.ID, PointsCost: 20, IsActive: true}
+	db.Create(&reward)
+
+	return db, customer, reward
+}
+
+// Crée une commande du client et la fait passer à "delivered"
+func deliverCommande(db *gorm.DB, customerID *uint, price string) models.Commande {
+	commande := models.Commande{Status: models.StatusReady, Price: decimal.RequireFromString(price), CustomerID: customerID}
+	db.Create(&commande)
+	commande.Status = models.StatusDelivered
+	db.Save(&commande)
+	return commande
+}
+
+func balance(db *gorm.DB, id uint) int {
+	customer, _ := models.GetCustomerByID(db, id)
+	return customer.PointsBalance
+}
+
+/////////////////////////////////////
+// EARNING POINTS
+/////////////////////////////////////
+
+func TestPointsEarnedOnDelivery(t *testing.T) {
+	db, customer, _ := setupLoyaltyTestDB()
+
+	commande := deliverCommande(db, &customer.ID, "12.90")
+	assert.Equal(t, 12, balance(db, customer.ID))
+
+	// Une nouvelle sauvegarde de la commande livrée ne crédite pas deux fois
+	assert.NoError(t, models.EarnLoyaltyPoints(db, commande.ID))
+	assert.Equal(t, 12, balance(db, customer.ID))
+
+	ledger, _ := models.GetLoyaltyLedger(db, customer.ID)
+	assert.Len(t, ledger, 1)
+	assert.Equal(t, models.LedgerEarn, ledger[0].Kind)
+	assert.Equal(t, commande.ID, *ledger[0].CommandeID)
+}
+
+func TestNoPointsWithoutConsent(t *testing.T) {
+	db, customer, _ := setupLoyaltyTestDB()
+	customer.SetConsents(false, false, time.Now())
+	models.UpdateCustomer(db, &customer)
+
+	deliverCommande(db, &customer.ID, "30")
+	assert.Equal(t, 0, balance(db, customer.ID))
+
+	// Une commande anonyme ne rapporte rien à personne
+	deliverCommande(db, nil, "30")
+	var entries int64
+	db.Model(&models.LoyaltyLedgerEntry{}).Count(&entries)
+	assert.Equal(t, int64(0), entries)
+}
+
+/////////////////////////////////////
+// REWARDS
+/////////////////////////////////////
+
+func TestRedeemRewardAddsFreeProduct(t *testing.T) {
+	db, customer, reward := setupLoyaltyTestDB()
+	deliverCommande(db, &customer.ID, "25")
+
+	commande := models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(9)}
+	db.Create(&commande)
+
+	_, err := models.RedeemReward(db, &customer, &reward, &commande)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, balance(db, customer.ID))
+	assert.Equal(t, customer.ID, *commande.CustomerID)
+
+	var free models.CommandeProduct
+	db.Where("commande_id = ?", commande.ID).First(&free)
+	assert.Equal(t, "Tiramisu", free.Name)
+	assert.True(t, free.Price.IsZero())
+
+	// Solde insuffisant pour une seconde récompense
+	_, err = models.RedeemReward(db, &customer, &reward, &commande)
+	assert.Equal(t, models.ErrNotEnoughPoints, err)
+	assert.Equal(t, 5, balance(db, customer.ID))
+}
+
+func TestRewardLineSurvivesCommandeUpdate(t *testing.T) {
+	db, customer, reward := setupLoyaltyTestDB()
+	deliverCommande(db, &customer.ID, "25")
+
+	burger := models.Product{Name: "Burger", Price: decimal.NewFromInt(9), IsAvailable: true, Type: models.TypePlat}
+	db.Create(&burger)
+	commande := models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(9)}
+	db.Create(&commande)
+	db.Create(&models.CommandeProduct{CommandeID: commande.ID, ProductID: burger.ID, Name: "Burger", Price: burger.Price, Quantity: 1})
+
+	_, err := models.RedeemReward(db, &customer, &reward, &commande)
+	assert.NoError(t, err)
+	// Les totaux incluent la ligne offerte
+	assert.Len(t, commande.Products, 2)
+	assert.True(t, commande.NetTotal.Add(commande.TaxTotal).Equal(decimal.NewFromInt(9)))
+
+	// Le comptoir modifie la commande : la récompense déjà payée en points reste
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/commandes/admin/:id", (&controllers.CommandeController{DB: db}).AdminUpdateCommande)
+	body := fmt.Sprintf(`{"products":[%d],"status":"pending"}`, burger.ID)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/commandes/admin/%d", commande.ID), strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Commande
+	db.Preload("Products").First(&stored, commande.ID)
+	var rewarded []models.CommandeProduct
+	for _, p := range stored.Products {
+		if p.RewardID != nil {
+			rewarded = append(rewarded, p)
+		}
+	}
+	assert.Len(t, rewarded, 1)
+	assert.Equal(t, "Tiramisu", rewarded[0].Name)
+	assert.Equal(t, "9.00", stored.Price.StringFixed(2))
+	assert.True(t, stored.NetTotal.Add(stored.TaxTotal).Equal(stored.Price))
+	assert.Equal(t, 5, balance(db, customer.ID))
+}
+
+/////////////////////////////////////
+// LOOKUP
+/////////////////////////////////////
+
+func TestCustomerLookupByPhoneOrCode(t *testing.T) {
+	db, customer, _ := setupLoyaltyTestDB()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	cc := controllers.RefCustomerController(db)
+	r.POST("/customers", cc.CreateCustomer)
+	r.GET("/customers/lookup", cc.LookupCustomer)
+
+	lookup := func(query string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/customers/lookup?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	// Le numéro est normalisé quelle que soit sa saisie
+	code, body := lookup("phone=%2B33%206%2012%2034%2056%2078")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(customer.ID), body["id"])
+
+	code, _ = lookup(fmt.Sprintf("code=%s", customer.LoyaltyCode))
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = lookup("phone=0700000000")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Un même numéro ne peut pas être inscrit deux fois
+	payload, _ := json.Marshal(map[string]interface{}{"phone": "0612345678", "loyalty_consent": true})
+	req, _ := http.NewRequest("POST", "/customers", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
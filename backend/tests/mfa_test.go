@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/totp"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// Vecteur de test de la RFC 6238 (secret "12345678901234567890", T = 59 s), sur 6 chiffres
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	code, err := totp.CodeAt(secret, totp.Step(time.Unix(59, 0)))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	step, ok := totp.Validate(secret, "287082", time.Unix(89, 0), 1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), step)
+	_, ok = totp.Validate(secret, "287082", time.Unix(150, 0), 1)
+	assert.False(t, ok)
+}
+
+func setupMFATest(t *testing.T) (*gin.Engine, models.User) {
+	db, r, user := setupSessionTest(t)
+	db.Model(&user).Update("role", models.RoleAdmin)
+
+	mc := controllers.RefMFAController(db)
+	r.POST("/auth/2fa/verify", mc.Verify)
+	r.POST("/auth/2fa/setup", middlewares.AuthMiddleware(db), mc.Setup)
+	r.POST("/auth/2fa/enable", middlewares.AuthMiddleware(db), mc.Enable)
+	r.POST("/auth/2fa/recovery-codes", middlewares.AuthMiddleware(db), mc.RegenerateRecoveryCodes)
+	r.GET("/admin", middlewares.AuthMiddleware(db), middlewares.RequirePermission(models.PermUserWrite), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r, user
+}
+
+// enableTOTP active la double authentification et retourne le secret et les codes de secours
+func enableTOTP(t *testing.T, r *gin.Engine, token string) (string, []string) {
+	w := authRequest(r, "POST", "/auth/2fa/setup", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var setup controllers.MFASetupResponse
+	json.Unmarshal(w.Body.Bytes(), &setup)
+	assert.Contains(t, setup.ProvisioningURI, "otpauth://totp/")
+
+	code, _ := totp.CodeAt(setup.Secret, totp.Step(time.Now()))
+	w = authRequest(r, "POST", "/auth/2fa/enable", token, map[string]string{"code": code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var recovery controllers.RecoveryCodesResponse
+	json.Unmarshal(w.Body.Bytes(), &recovery)
+	return setup.Secret, recovery.RecoveryCodes
+}
+
+func loginChallenge(t *testing.T, r *gin.Engine) string {
+	w := authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "awa@example.com", "password": "motdepasse123"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var challenge controllers.MFAChallengeResponse
+	json.Unmarshal(w.Body.Bytes(), &challenge)
+	assert.True(t, challenge.MFARequired)
+	return challenge.ChallengeToken
+}
+
+func TestAdminRoutesRequireMFA(t *testing.T) {
+	r, _ := setupMFATest(t)
+
+	// Sans second facteur, un administrateur peut seulement activer la double authentification
+	tokens := login(t, r)
+	assert.True(t, tokens.MFAEnrollmentRequired)
+	assert.Equal(t, http.StatusForbidden, authRequest(r, "GET", "/admin", tokens.Token, nil).Code)
+
+	secret, recovery := enableTOTP(t, r, tokens.Token)
+	assert.Len(t, recovery, models.RecoveryCodeCount)
+	assert.Equal(t, http.StatusForbidden, authRequest(r, "GET", "/admin", tokens.Token, nil).Code)
+
+	// Connexion en deux étapes : le code de la période suivante est accepté une seule fois
+	challenge := loginChallenge(t, r)
+	w := authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": challenge, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	code, _ := totp.CodeAt(secret, totp.Step(time.Now())+1)
+	w = authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": challenge, "code": code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var mfaTokens controllers.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &mfaTokens)
+	assert.False(t, mfaTokens.MFAEnrollmentRequired)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/admin", mfaTokens.Token, nil).Code)
+
+	// Le challenge et le code ne servent qu'une fois
+	w = authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": challenge, "code": code})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": loginChallenge(t, r), "code": code})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Le rafraîchissement conserve le second facteur
+	w = authRequest(r, "POST", "/auth/refresh", "", map[string]string{"refresh_token": mfaTokens.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var refreshed controllers.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/admin", refreshed.Token, nil).Code)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	r, _ := setupMFATest(t)
+	_, recovery := enableTOTP(t, r, login(t, r).Token)
+
+	w := authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": loginChallenge(t, r), "code": recovery[0]})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": loginChallenge(t, r), "code": recovery[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Trop de codes faux épuisent le challenge, même si le suivant est bon
+	challenge := loginChallenge(t, r)
+	for i := 0; i < models.MaxMFAChallengeAttempts; i++ {
+		authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": challenge, "code": "AAAAA-AAAAA"})
+	}
+	w = authRequest(r, "POST", "/auth/2fa/verify", "", map[string]string{"challenge_token": challenge, "code": recovery[1]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
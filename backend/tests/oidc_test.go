@@ -0,0 +1,296 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/jwtkeys"
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/oidc"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const (
+	ssoClientID    = "wacdo-backoffice"
+	ssoSecret      = "secret-client"
+	ssoRedirectURL = "https://backoffice.example.com/sso"
+)
+
+// mockOIDCProvider est un fournisseur d'identité minimal : découverte, autorisation (qui accepte
+// l'utilisateur défini par le test), échange de code avec vérification PKCE et clés publiques
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *jwtkeys.Key
+	// Claims de l'utilisateur qui s'authentifie au prochain passage sur /authorize
+	user jwt.MapClaims
+	// Modifie l'ID token avant signature, pour simuler un fournisseur malveillant
+	tamper func(claims jwt.MapClaims)
+	codes  map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := jwtkeys.GenerateKey("idp-1", jwtkeys.AlgRS256, time.Time{})
+	assert.NoError(t, err)
+	mock := &mockOIDCProvider{key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mock.server.URL,
+			"authorization_endpoint": mock.server.URL + "/authorize",
+			"token_endpoint":         mock.server.URL + "/token",
+			"jwks_uri":               mock.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwtkeys.JWKSet{Keys: []jwtkeys.JWK{key.JWK()}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("client_id") != ssoClientID || query.Get("redirect_uri") != ssoRedirectURL ||
+			query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+		code, _ := oidc.RandomString()
+		mock.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: mock.user}
+		http.Redirect(w, r, ssoRedirectURL+"?"+url.Values{"code": {code}, "state": {query.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		auth, ok := mock.codes[r.PostForm.Get("code")]
+		delete(mock.codes, r.PostForm.Get("code"))
+		if id != ssoClientID || secret != ssoSecret || !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("redirect_uri") != ssoRedirectURL || oidc.S256(r.PostForm.Get("code_verifier")) != auth.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := jwt.MapClaims{"iss": mock.server.URL, "aud": ssoClientID, "nonce": auth.nonce, "iat": time.Now().Unix(), "exp": time.Now().Add(5 * time.Minute).Unix()}
+		for name, value := range auth.claims {
+			claims[name] = value
+		}
+		if mock.tamper != nil {
+			mock.tamper(claims)
+		}
+		keys, _ := jwtkeys.NewKeySet(mock.key)
+		idToken, _ := keys.Sign(claims)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": idToken})
+	})
+
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+	return mock
+}
+
+func setupOIDCTest(t *testing.T) (*gorm.DB, *gin.Engine, *mockOIDCProvider, *oidc.Provider) {
+	db, r, _ := setupSessionTest(t)
+	db.AutoMigrate(&models.OIDCLogin{})
+	models.SeedRoles(db)
+
+	mock := newMockOIDCProvider(t)
+	groupRoles, err := oidc.ParseGroupRoles("wacdo-admins=admin, wacdo-cuisine=preparer, wacdo-accueil=receiver")
+	assert.NoError(t, err)
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       mock.server.URL,
+		ClientID:     ssoClientID,
+		ClientSecret: ssoSecret,
+		RedirectURL:  ssoRedirectURL,
+		Scopes:       oidc.DefaultScopes,
+		GroupsClaim:  "groups",
+		GroupRoles:   groupRoles,
+	})
+	oc := &controllers.OIDCController{DB: db, Provider: provider}
+	r.GET("/auth/oidc/login", oc.Login)
+	r.POST("/auth/oidc/callback", oc.Callback)
+	return db, r, mock, provider
+}
+
+// ssoRedirect suit le parcours navigateur jusqu'au retour sur le front-end et retourne code, state
+// et le cookie qui lie la connexion au navigateur
+func ssoRedirect(t *testing.T, r *gin.Engine) (string, string, string) {
+	w := authRequest(r, "GET", "/auth/oidc/login", "", nil)
+	assert.Equal(t, http.StatusFound, w.Code)
+	var binding string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oidc_binding" {
+			binding = cookie.Value
+			assert.True(t, cookie.HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+			assert.Equal(t, "/auth/oidc", cookie.Path)
+		}
+	}
+	assert.NotEmpty(t, binding)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	back, _ := url.Parse(resp.Header.Get("Location"))
+	return back.Query().Get("code"), back.Query().Get("state"), binding
+}
+
+// ssoCallback transmet code et state à l'API depuis le navigateur qui porte le cookie binding
+func ssoCallback(r *gin.Engine, code, state, binding string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"code": code, "state": state})
+	req, _ := http.NewRequest("POST", "/auth/oidc/callback", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if binding != "" {
+		req.AddCookie(&http.Cookie{Name: "oidc_binding", Value: binding})
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// ssoLogin authentifie user chez le fournisseur puis termine la connexion auprès de l'API
+func ssoLogin(t *testing.T, r *gin.Engine, mock *mockOIDCProvider, user jwt.MapClaims) *httptest.ResponseRecorder {
+	mock.user = user
+	code, state, binding := ssoRedirect(t, r)
+	return ssoCallback(r, code, state, binding)
+}
+
+func TestOIDCLoginProvisionsUsers(t *testing.T) {
+	db, r, mock, provider := setupOIDCTest(t)
+	chef := jwt.MapClaims{"sub": "idp|42", "email": "Chef@Example.com", "email_verified": true, "name": "Chef de cuisine", "groups": []string{"franchise", "wacdo-cuisine"}}
+
+	// Première connexion : compte créé à la volée avec le rôle du groupe, sans mot de passe local
+	w := ssoLogin(t, r, mock, chef)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens controllers.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", tokens.Token, nil).Code)
+
+	var user models.User
+	assert.NoError(t, db.Where("oidc_subject = ?", "idp|42").First(&user).Error)
+	assert.Equal(t, "chef@example.com", user.Email)
+	assert.Equal(t, models.RolePreparer, user.Role)
+	assert.Empty(t, user.Password)
+	assert.True(t, user.Response().SSO)
+	w = authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "chef@example.com", "password": ""})
+	assert.NotEqual(t, http.StatusOK, w.Code)
+
+	// Le fournisseur fait foi : le rôle suit les groupes, dans l'ordre de priorité de la correspondance
+	chef["groups"] = []string{"wacdo-accueil", "wacdo-admins"}
+	chef["amr"] = []string{"pwd", "mfa"}
+	assert.Equal(t, http.StatusOK, ssoLogin(t, r, mock, chef).Code)
+	var count int64
+	db.Model(&models.User{}).Where("oidc_subject = ?", "idp|42").Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.First(&user, user.ID)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+	var session models.AuthSession
+	db.Where("user_id = ?", user.ID).Order("id desc").First(&session)
+	assert.False(t, session.MFA, "claim amr ignoré sans OIDC_TRUST_AMR")
+
+	provider.Config.TrustAMR = true
+	assert.Equal(t, http.StatusOK, ssoLogin(t, r, mock, chef).Code)
+	session = models.AuthSession{}
+	db.Where("user_id = ?", user.ID).Order("id desc").First(&session)
+	assert.True(t, session.MFA, "second facteur attesté par le fournisseur")
+
+	// Aucun groupe associé à un rôle : accès refusé, rien n'est créé
+	w = ssoLogin(t, r, mock, jwt.MapClaims{"sub": "idp|43", "email": "stagiaire@example.com", "email_verified": true, "groups": []string{"franchise"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	db.Model(&models.User{}).Where("email = ?", "stagiaire@example.com").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Compte désactivé par un administrateur : le SSO ne le rouvre pas
+	models.SetUserDisabled(db, user.ID, true)
+	assert.Equal(t, http.StatusForbidden, ssoLogin(t, r, mock, chef).Code)
+}
+
+func TestOIDCLinksExistingAccountOnlyWithVerifiedEmail(t *testing.T) {
+	db, r, mock, provider := setupOIDCTest(t)
+	awa := jwt.MapClaims{"sub": "idp|7", "email": "awa@example.com", "email_verified": false, "groups": []string{"wacdo-accueil"}}
+
+	// Email non vérifié par le fournisseur : le compte local n'est pas rattaché
+	assert.Equal(t, http.StatusConflict, ssoLogin(t, r, mock, awa).Code)
+
+	awa["email_verified"] = true
+	assert.Equal(t, http.StatusOK, ssoLogin(t, r, mock, awa).Code)
+	var user models.User
+	db.Where("email = ?", "awa@example.com").First(&user)
+	assert.Equal(t, "idp|7", *user.OIDCSubject)
+
+	// Le compte local garde le rôle attribué par les administrateurs, quels que soient les groupes
+	awa["groups"] = []string{"wacdo-admins"}
+	assert.Equal(t, http.StatusOK, ssoLogin(t, r, mock, awa).Code)
+	db.First(&user, user.ID)
+	assert.Equal(t, models.RoleReceiver, user.Role)
+
+	// Le mot de passe local reste utilisable, une autre identité ne peut plus revendiquer l'email
+	login(t, r)
+	assert.Equal(t, http.StatusConflict, ssoLogin(t, r, mock, jwt.MapClaims{"sub": "idp|8", "email": "awa@example.com", "email_verified": true, "groups": []string{"wacdo-accueil"}}).Code)
+
+	// Compte local administrateur : rattachement refusé sauf si OIDC_LINK_PRIVILEGED l'autorise
+	assert.NoError(t, models.CreateUser(db, &models.User{Email: "direction@example.com", Role: models.RoleAdmin}, "motdepasse123"))
+	direction := jwt.MapClaims{"sub": "idp|9", "email": "direction@example.com", "email_verified": true, "groups": []string{"wacdo-cuisine"}}
+	assert.Equal(t, http.StatusForbidden, ssoLogin(t, r, mock, direction).Code)
+	var admin models.User
+	db.Where("email = ?", "direction@example.com").First(&admin)
+	assert.Nil(t, admin.OIDCSubject)
+
+	provider.Config.LinkPrivileged = true
+	assert.Equal(t, http.StatusOK, ssoLogin(t, r, mock, direction).Code)
+	db.First(&admin, admin.ID)
+	assert.Equal(t, "idp|9", *admin.OIDCSubject)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+}
+
+func TestOIDCCallbackRejectsTampering(t *testing.T) {
+	_, r, mock, _ := setupOIDCTest(t)
+	chef := jwt.MapClaims{"sub": "idp|42", "email": "chef@example.com", "email_verified": true, "groups": []string{"wacdo-cuisine"}}
+
+	// Code et state transmis depuis un autre navigateur (CSRF de connexion) : sans le cookie de la
+	// connexion ou avec celui d'une autre, refusés
+	mock.user = chef
+	code, state, binding := ssoRedirect(t, r)
+	_, _, otherBinding := ssoRedirect(t, r)
+	assert.Equal(t, http.StatusUnauthorized, ssoCallback(r, code, state, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, ssoCallback(r, code, state, otherBinding).Code)
+
+	// Le state ne sert qu'une fois
+	assert.Equal(t, http.StatusOK, ssoCallback(r, code, state, binding).Code)
+	assert.Equal(t, http.StatusUnauthorized, ssoCallback(r, code, state, binding).Code)
+
+	// Code intercepté rejoué avec le state d'une autre connexion : le code_verifier ne correspond pas
+	stolenCode, _, _ := ssoRedirect(t, r)
+	_, otherState, otherBinding := ssoRedirect(t, r)
+	assert.Equal(t, http.StatusUnauthorized, ssoCallback(r, stolenCode, otherState, otherBinding).Code)
+
+	// ID token invalide : nonce, audience, émetteur, expiration, clé inconnue
+	other, _ := jwtkeys.GenerateKey("idp-1", jwtkeys.AlgRS256, time.Time{})
+	tampers := map[string]func(jwt.MapClaims){
+		"nonce":    func(c jwt.MapClaims) { c["nonce"] = "autre" },
+		"audience": func(c jwt.MapClaims) { c["aud"] = "autre-client" },
+		"émetteur": func(c jwt.MapClaims) { c["iss"] = "https://idp.example.com" },
+		"exp":      func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+	}
+	for name, tamper := range tampers {
+		mock.tamper = tamper
+		assert.Equal(t, http.StatusUnauthorized, ssoLogin(t, r, mock, chef).Code, name)
+	}
+	mock.tamper = nil
+	mock.key = other
+	assert.Equal(t, http.StatusUnauthorized, ssoLogin(t, r, mock, chef).Code, "signature")
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/mail"
+	"LearningCampusKabre/models"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var resetLinkPattern = regexp.MustCompile(`https://app\.example\.com/reset\?token=([0-9a-f]+)`)
+
+func setupResetTest(t *testing.T) (*gorm.DB, *gin.Engine, string) {
+	db, r, _ := setupSessionTest(t)
+	db.AutoMigrate(&models.PasswordResetToken{})
+	t.Setenv("PASSWORD_RESET_URL", "https://app.example.com/reset?token={token}")
+
+	dir := t.TempDir()
+	ac := controllers.RefAuthController(db)
+	ac.Mailer = &mail.LogMailer{Dir: dir}
+	r.POST("/auth/forgot", ac.ForgotPassword)
+	r.POST("/auth/reset", ac.ResetPassword)
+	return db, r, dir
+}
+
+// waitSentTokens attend que count emails aient été écrits : l'envoi se fait en tâche de fond
+func waitSentTokens(t *testing.T, dir string, count int) []string {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		tokens := sentTokens(t, dir)
+		if len(tokens) >= count || time.Now().After(deadline) {
+			return tokens
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sentTokens retourne les jetons des emails écrits par le LogMailer
+func sentTokens(t *testing.T, dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	var tokens []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "To: awa@example.com")
+		if match := resetLinkPattern.FindStringSubmatch(string(content)); match != nil {
+			tokens = append(tokens, match[1])
+		}
+	}
+	return tokens
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	_, r, dir := setupResetTest(t)
+	session := login(t, r)
+
+	// Un email inconnu reçoit la même réponse, sans email envoyé
+	w := authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "inconnu@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, sentTokens(t, dir))
+
+	w = authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "AWA@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	tokens := waitSentTokens(t, dir, 1)
+	assert.Len(t, tokens, 1)
+
+	w = authRequest(r, "POST", "/auth/reset", "", map[string]string{"token": tokens[0], "password": "nouveau456"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Usage unique, sessions fermées, nouveau mot de passe actif
+	w = authRequest(r, "POST", "/auth/reset", "", map[string]string{"token": tokens[0], "password": "encore789"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", session.Token, nil).Code)
+	w = authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "awa@example.com", "password": "nouveau456"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPasswordResetTokenStoredHashed(t *testing.T) {
+	db, r, dir := setupResetTest(t)
+
+	authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "awa@example.com"})
+	token := waitSentTokens(t, dir, 1)[0]
+
+	var stored models.PasswordResetToken
+	db.Where("user_id IS NOT NULL").First(&stored)
+	assert.NotNil(t, stored.TokenHash)
+	assert.NotEqual(t, token, *stored.TokenHash)
+}
+
+func TestPasswordResetRateLimited(t *testing.T) {
+	_, r, dir := setupResetTest(t)
+
+	// Au-delà de 3 demandes par heure, le compte ne reçoit plus d'email
+	for i := 0; i < 5; i++ {
+		w := authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "awa@example.com"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Len(t, waitSentTokens(t, dir, models.MaxResetRequestsPerAccount), models.MaxResetRequestsPerAccount)
+
+	// Au-delà de 10 demandes par heure, l'adresse IP est refusée
+	for i := 0; i < 5; i++ {
+		authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "inconnu@example.com"})
+	}
+	w := authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "autre@example.com"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestResetEmailSubjectIsEncoded(t *testing.T) {
+	_, r, dir := setupResetTest(t)
+	authRequest(r, "POST", "/auth/forgot", "", map[string]string{"email": "awa@example.com"})
+	waitSentTokens(t, dir, 1)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	content, _ := os.ReadFile(files[0])
+	assert.Contains(t, string(content), "Subject: =?UTF-8?q?R=C3=A9initialisation_de_votre_mot_de_passe?=\r\n")
+}
+
+func TestMailDriverMustBeExplicit(t *testing.T) {
+	t.Setenv("MAIL_DRIVER", "")
+	t.Setenv("SMTP_HOST", "")
+	_, err := mail.NewFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("MAIL_DRIVER", "log")
+	mailer, err := mail.NewFromEnv()
+	assert.NoError(t, err)
+	assert.IsType(t, &mail.LogMailer{}, mailer)
+
+	t.Setenv("MAIL_DRIVER", "")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	mailer, err = mail.NewFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:587", mailer.(*mail.SMTPMailer).Addr)
+
+	t.Setenv("MAIL_DRIVER", "pigeon")
+	_, err = mail.NewFromEnv()
+	assert.Error(t, err)
+}
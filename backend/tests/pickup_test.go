@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/scheduling"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPickupTestDB() (*gorm.DB, models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.PickupSlotLock{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Station{}, &models.Printer{}, &models.PrintJob{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{})
+
+	product := models.Product{Name: "Burger", Price: decimal.NewFromInt(8), IsAvailable: true, Type: models.TypePlat}
+	db.Create(&product)
+	return db, product
+}
+
+// Envoie une commande de borne avec l'heure de retrait donnée
+func postPickupCommande(r *gin.Engine, productID uint, pickupAt time.Time) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"lines":     []models.OrderLine{{ProductID: productID, Quantity: 1}},
+		"pickup_at": pickupAt,
+	})
+	req, _ := http.NewRequest("POST", "/kiosk/commandes", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func setupPickupRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	kc := controllers.RefKioskController(db)
+	r.POST("/kiosk/commandes", kc.CreateKioskCommande)
+	r.GET("/kiosk/pickup-slots", kc.GetPickupSlots)
+	return r
+}
+
+func TestScheduledCommandeHeldOutOfQueue(t *testing.T) {
+	db, product := setupPickupTestDB()
+	db.Create(&models.Printer{Name: "Cuisine", Address: "127.0.0.1:1", IsActive: true})
+	r := setupPickupRouter(db)
+
+	pickupAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	w := postPickupCommande(r, product.ID, pickupAt)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var commande models.Commande
+	db.First(&commande)
+	assert.Equal(t, models.StatusScheduled, commande.Status)
+	assert.True(t, pickupAt.Equal(*commande.EstimatedReadyAt))
+
+	// Ni ticket cuisine ni place dans la file tant que la commande n'est pas libérée
+	var jobs int64
+	db.Model(&models.PrintJob{}).Count(&jobs)
+	assert.Equal(t, int64(0), jobs)
+
+	// Trop tôt : rien n'est libéré
+	scheduler := scheduling.NewScheduler(db)
+	assert.Equal(t, 0, scheduler.ReleaseDue(time.Now()))
+
+	// À l'approche du retrait, la commande entre en préparation et son ticket est imprimé
+	assert.Equal(t, 1, scheduler.ReleaseDue(pickupAt.Add(-models.PickupLeadTime())))
+	db.Preload("StatusChanges").First(&commande, commande.ID)
+	assert.Equal(t, models.StatusPending, commande.Status)
+	last := commande.StatusChanges[len(commande.StatusChanges)-1]
+	assert.Equal(t, models.StatusScheduled, last.FromStatus)
+	assert.Equal(t, models.StatusPending, last.ToStatus)
+
+	db.Model(&models.PrintJob{}).Count(&jobs)
+	assert.Equal(t, int64(1), jobs)
+}
+
+func TestPickupWithinLeadTimeGoesStraightToQueue(t *testing.T) {
+	db, product := setupPickupTestDB()
+	r := setupPickupRouter(db)
+
+	w := postPickupCommande(r, product.ID, time.Now().Add(models.PickupLeadTime()/2))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var commande models.Commande
+	db.First(&commande)
+	assert.Equal(t, models.StatusPending, commande.Status)
+
+	// Une heure passée est refusée
+	w = postPickupCommande(r, product.ID, time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPickupSlotCapacity(t *testing.T) {
+	t.Setenv("PICKUP_SLOT_CAPACITY", "2")
+	db, product := setupPickupTestDB()
+	r := setupPickupRouter(db)
+
+	slot := models.PickupSlotStart(time.Now().Add(3 * time.Hour))
+	assert.Equal(t, http.StatusCreated, postPickupCommande(r, product.ID, slot.Add(time.Minute)).Code)
+	assert.Equal(t, http.StatusCreated, postPickupCommande(r, product.ID, slot.Add(14*time.Minute)).Code)
+	assert.Equal(t, http.StatusConflict, postPickupCommande(r, product.ID, slot.Add(5*time.Minute)).Code)
+	// Le créneau suivant est libre
+	assert.Equal(t, http.StatusCreated, postPickupCommande(r, product.ID, slot.Add(15*time.Minute)).Code)
+
+	slots, err := models.GetPickupSlots(db, slot, time.Now())
+	assert.NoError(t, err)
+	for _, s := range slots {
+		switch {
+		case s.Start.Equal(slot):
+			assert.Equal(t, 0, s.Remaining)
+		case s.Start.Equal(slot.Add(models.PickupSlotDuration)):
+			assert.Equal(t, 1, s.Remaining)
+		}
+	}
+}
+
+func TestPreOrderTakesTicketAndBoardOfPickupDay(t *testing.T) {
+	db, product := setupPickupTestDB()
+	r := setupPickupRouter(db)
+
+	// Précommande passée la veille pour un retrait le lendemain midi
+	now := time.Now()
+	pickupAt := time.Date(now.Year(), now.Month(), now.Day()+1, 12, 0, 0, 0, now.Location())
+	assert.Equal(t, http.StatusCreated, postPickupCommande(r, product.ID, pickupAt).Code)
+
+	var preOrder models.Commande
+	db.First(&preOrder)
+	assert.Equal(t, uint(1), preOrder.TicketNumber)
+
+	// Le compteur du jour de la commande n'est pas touché, celui du retrait continue après elle
+	today, _ := models.NextTicketNumber(db, now)
+	assert.Equal(t, uint(1), today)
+	walkIn, _ := models.NextTicketNumber(db, pickupAt)
+	assert.Equal(t, uint(2), walkIn)
+
+	// Le jour du retrait, une fois libérée, elle apparaît sur l'écran de ce jour-là
+	releaseAt := pickupAt.Add(-models.PickupLeadTime())
+	assert.Equal(t, 1, scheduling.NewScheduler(db).ReleaseDue(releaseAt))
+	board, err := models.GetStatusBoard(db, releaseAt, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1}, board.Preparing)
+
+	// Mais pas sur celui du jour où elle a été passée
+	board, _ = models.GetStatusBoard(db, now, time.Minute)
+	assert.Empty(t, board.Preparing)
+}
+
+func TestScheduledCommandeCannotBePulledIntoQueueEarly(t *testing.T) {
+	db, product := setupPickupTestDB()
+	r := setupPickupRouter(db)
+	assert.Equal(t, http.StatusCreated, postPickupCommande(r, product.ID, time.Now().Add(2*time.Hour)).Code)
+	var commande models.Commande
+	db.First(&commande)
+
+	// Équipe de cuisine et accueil : permissions par défaut de leur rôle
+	cc := &controllers.CommandeController{DB: db}
+	as := func(role models.UserRole, handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("permissions", models.DefaultRolePermissions[role])
+			handler(c)
+		}
+	}
+	r.PUT("/commandes/preparer/:id", as(models.RolePreparer, cc.StaffUpdateCommande))
+	r.PUT("/commandes/receiver/:id", as(models.RoleReceiver, cc.StaffUpdateCommande))
+
+	update := func(path, status string) int {
+		body := fmt.Sprintf(`{"status":"%s"}`, status)
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/%d", path, commande.ID), strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusConflict, update("/commandes/preparer", "ready"))
+	assert.Equal(t, http.StatusConflict, update("/commandes/receiver", "pending"))
+	db.First(&commande, commande.ID)
+	assert.Equal(t, models.StatusScheduled, commande.Status)
+}
+
+func TestScheduledCommandeNotCountedForSLA(t *testing.T) {
+	pickupAt := time.Now().Add(time.Hour)
+	commande := models.Commande{Status: models.StatusScheduled, PickupAt: &pickupAt, CreatedAt: time.Now().Add(-time.Hour)}
+	commande.ComputeTimings(time.Now(), 10*time.Minute)
+	assert.False(t, commande.SLABreached)
+
+	// Libérée il y a 2 minutes : le SLA court depuis la libération
+	released := time.Now().Add(-2 * time.Minute)
+	commande.Status = models.StatusPending
+	commande.StatusChanges = []models.CommandeStatusChange{
+		{ToStatus: models.StatusScheduled, ChangedAt: commande.CreatedAt},
+		{FromStatus: models.StatusScheduled, ToStatus: models.StatusPending, ChangedAt: released},
+	}
+	commande.ComputeTimings(time.Now(), 10*time.Minute)
+	assert.False(t, commande.SLABreached)
+	assert.InDelta(t, 120, commande.Timings.ElapsedSeconds, 1)
+}
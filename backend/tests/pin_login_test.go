@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// Tablette enregistrée et route de connexion par code PIN, en plus des routes de session
+func setupPINTest(t *testing.T) (*gorm.DB, *gin.Engine, models.User, models.Device, string) {
+	db, r, user := setupSessionTest(t)
+	db.AutoMigrate(&models.Device{})
+
+	tablet := models.Device{Name: "Tablette cuisine", Kind: models.DeviceTablet, IsActive: true}
+	key, _ := models.CreateDevice(db, &tablet)
+
+	ac := controllers.RefAuthController(db)
+	r.POST("/auth/pin", middlewares.DeviceAuthMiddleware(db, models.DeviceTablet), ac.PINLogin)
+	return db, r, user, tablet, key
+}
+
+func pinLogin(r *gin.Engine, device models.Device, key string, userID uint, pin string) (int, controllers.TokenResponse) {
+	w := kioskRequest(r, "/auth/pin", device, key, map[string]interface{}{"user_id": userID, "pin": pin})
+	var tokens controllers.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	return w.Code, tokens
+}
+
+// tabletRequest envoie une requête authentifiée depuis une tablette, avec ses identifiants
+func tabletRequest(r *gin.Engine, method, path, token string, device models.Device, key string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("X-Device-ID", fmt.Sprint(device.ID))
+	req.Header.Set("X-Device-Key", key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPINLoginOnlyOnTablets(t *testing.T) {
+	db, r, user, tablet, key := setupPINTest(t)
+
+	assert.Equal(t, models.ErrWrongPassword, models.SetUserPIN(db, user.ID, "mauvais", "4821"))
+	assert.Equal(t, models.ErrPINFormat, models.SetUserPIN(db, user.ID, "motdepasse123", "12a4"))
+	assert.NoError(t, models.SetUserPIN(db, user.ID, "motdepasse123", "4821"))
+
+	code, tokens := pinLogin(r, tablet, key, user.ID, "4821")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, http.StatusOK, tabletRequest(r, "GET", "/me", tokens.Token, tablet, key, nil).Code)
+
+	// Une borne n'est pas une tablette, et une clé inconnue est refusée
+	kiosk := models.Device{Name: "Borne", Kind: models.DeviceKiosk, IsActive: true}
+	kioskKey, _ := models.CreateDevice(db, &kiosk)
+	code, _ = pinLogin(r, kiosk, kioskKey, user.ID, "4821")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = pinLogin(r, tablet, "mauvaise-cle", user.ID, "4821")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Les administrateurs, soumis à la double authentification, n'ont pas de code PIN
+	db.Model(&user).Update("role", models.RoleAdmin)
+	assert.Equal(t, models.ErrPINNotAllowed, models.SetUserPIN(db, user.ID, "motdepasse123", "4821"))
+	code, _ = pinLogin(r, tablet, key, user.ID, "4821")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestPINQuickSwitchAndIdleTimeout(t *testing.T) {
+	db, r, user, tablet, key := setupPINTest(t)
+	colleague := models.User{Email: "koffi@example.com", Role: models.RolePreparer}
+	models.CreateUser(db, &colleague, "motdepasse123")
+	models.SetUserPIN(db, user.ID, "motdepasse123", "4821")
+	models.SetUserPIN(db, colleague.ID, "motdepasse123", "1357")
+
+	_, first := pinLogin(r, tablet, key, user.ID, "4821")
+	code, second := pinLogin(r, tablet, key, colleague.ID, "1357")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Le changement d'utilisateur ferme la session précédente de la tablette
+	assert.Equal(t, http.StatusUnauthorized, tabletRequest(r, "GET", "/me", first.Token, tablet, key, nil).Code)
+	assert.Equal(t, http.StatusOK, tabletRequest(r, "GET", "/me", second.Token, tablet, key, nil).Code)
+
+	// Après l'inactivité tolérée, la session est fermée et ne se rafraîchit plus
+	var session models.AuthSession
+	db.Where("user_id = ? AND revoked_at IS NULL", colleague.ID).First(&session)
+	assert.Equal(t, int(models.DefaultPINIdleTimeout.Seconds()), session.IdleTimeoutSeconds)
+	_, err := models.TouchSession(db, session.ID, colleague.ID, time.Now().Add(models.DefaultPINIdleTimeout+time.Minute))
+	assert.Equal(t, models.ErrSessionExpired, err)
+	assert.Equal(t, http.StatusUnauthorized, tabletRequest(r, "GET", "/me", second.Token, tablet, key, nil).Code)
+	w := tabletRequest(r, "POST", "/auth/refresh", "", tablet, key, map[string]string{"refresh_token": second.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Les sessions par mot de passe n'ont pas de limite d'inactivité
+	tokens := login(t, r)
+	var passwordSession models.AuthSession
+	db.Where("user_id = ? AND device_id IS NULL", user.ID).First(&passwordSession)
+	_, err = models.TouchSession(db, passwordSession.ID, user.ID, time.Now().Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", tokens.Token, nil).Code)
+}
+
+func TestWrongPINsClearThePIN(t *testing.T) {
+	db, r, user, tablet, key := setupPINTest(t)
+	models.SetUserPIN(db, user.ID, "motdepasse123", "4821")
+
+	for i := 0; i < models.MaxFailedPINs; i++ {
+		code, _ := pinLogin(r, tablet, key, user.ID, "0000")
+		assert.Equal(t, http.StatusUnauthorized, code)
+	}
+
+	// Le bon code ne suffit plus : il faut en choisir un nouveau
+	code, _ := pinLogin(r, tablet, key, user.ID, "4821")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	attempts, _ := models.GetLoginAttempts(db, models.LoginAttemptFilter{UserID: user.ID, FailedOnly: true})
+	assert.Len(t, attempts, models.MaxFailedPINs+1)
+	assert.Equal(t, "invalid_pin", attempts[0].Reason)
+	assert.Equal(t, "pin_disabled", attempts[1].Reason)
+
+	models.SetUserPIN(db, user.ID, "motdepasse123", "4821")
+	code, _ = pinLogin(r, tablet, key, user.ID, "4821")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestTabletSessionRequiresDeviceCredentials(t *testing.T) {
+	db, r, user, tablet, key := setupPINTest(t)
+	models.SetUserPIN(db, user.ID, "motdepasse123", "4821")
+	_, tokens := pinLogin(r, tablet, key, user.ID, "4821")
+
+	// Le jeton copié hors de la tablette n'est pas accepté
+	other := models.Device{Name: "Tablette salle", Kind: models.DeviceTablet, IsActive: true}
+	otherKey, _ := models.CreateDevice(db, &other)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", tokens.Token, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, tabletRequest(r, "GET", "/me", tokens.Token, tablet, "mauvaise-cle", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, tabletRequest(r, "GET", "/me", tokens.Token, other, otherKey, nil).Code)
+	assert.Equal(t, http.StatusOK, tabletRequest(r, "GET", "/me", tokens.Token, tablet, key, nil).Code)
+
+	// Le rafraîchissement refusé ne consomme pas le jeton de la tablette
+	body := map[string]string{"refresh_token": tokens.RefreshToken}
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "POST", "/auth/refresh", "", body).Code)
+	assert.Equal(t, http.StatusUnauthorized, tabletRequest(r, "POST", "/auth/refresh", "", other, otherKey, body).Code)
+	assert.Equal(t, http.StatusOK, tabletRequest(r, "POST", "/auth/refresh", "", tablet, key, body).Code)
+}
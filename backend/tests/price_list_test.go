@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"LearningCampusKabre/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données avec un burger à 8 € et les tables nécessaires à une commande
+func setupPriceListTestDB() (*gorm.DB, models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{})
+
+	burger := models.Product{Name: "Burger", Price: decimal.NewFromInt(8), IsAvailable: true, Type: models.TypePlat}
+	db.Create(&burger)
+	return db, burger
+}
+
+func TestPriceListTimeWindow(t *testing.T) {
+	// Vendredi et samedi soir, de 22 h à 2 h du matin
+	lateNight := models.PriceList{Name: "Late night", Days: []int{5, 6}, StartTime: "22:00", EndTime: "02:00", IsActive: true}
+
+	friday := time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local)
+	assert.True(t, lateNight.AppliesTo(models.ChannelCounter, friday.Add(23*time.Hour)))
+	assert.False(t, lateNight.AppliesTo(models.ChannelCounter, friday.Add(21*time.Hour)))
+
+	// Samedi 1 h : suite du créneau de vendredi
+	assert.True(t, lateNight.AppliesTo(models.ChannelCounter, friday.Add(25*time.Hour)))
+	// Vendredi 1 h : suite du créneau de jeudi, hors jours autorisés
+	assert.False(t, lateNight.AppliesTo(models.ChannelCounter, friday.Add(time.Hour)))
+
+	// Restriction de canal
+	kioskOnly := models.PriceList{Name: "Borne", Channels: []models.ChannelType{models.ChannelKiosk}, IsActive: true}
+	assert.True(t, kioskOnly.AppliesTo(models.ChannelKiosk, friday))
+	assert.False(t, kioskOnly.AppliesTo(models.ChannelCounter, friday))
+}
+
+func TestPriceListResolvedAtOrderTimeAndRecorded(t *testing.T) {
+	db, burger := setupPriceListTestDB()
+
+	db.Create(&models.PriceList{Name: "Borne", Priority: 1, Channels: []models.ChannelType{models.ChannelKiosk}, IsActive: true,
+		Items: []models.PriceListItem{{ProductID: &burger.ID, Price: decimal.NewFromInt(7)}}})
+	student := models.PriceList{Name: "Étudiant", Priority: 5, Manual: true, IsActive: true,
+		Items: []models.PriceListItem{{ProductID: &burger.ID, Price: decimal.NewFromInt(6)}}}
+	db.Create(&student)
+
+	lines := []models.OrderLine{{ProductID: burger.ID, Quantity: 2}}
+
+	// Au comptoir, sans grille choisie : prix de base
+	order, err := models.PriceOrder(db, lines, models.PricingContext{Channel: models.ChannelCounter})
+	assert.NoError(t, err)
+	assert.Equal(t, "16", order.Total.String())
+	assert.Nil(t, order.Lines[0].PriceListID)
+
+	// Sur la borne : grille automatique du canal
+	order, _ = models.PriceOrder(db, lines, models.PricingContext{Channel: models.ChannelKiosk})
+	assert.Equal(t, "14", order.Total.String())
+	assert.Equal(t, "Borne", order.Lines[0].PriceListName)
+
+	// La grille manuelle, plus prioritaire, l'emporte quand elle est demandée
+	order, _ = models.PriceOrder(db, lines, models.PricingContext{Channel: models.ChannelKiosk, PriceListID: &student.ID})
+	assert.Equal(t, "12", order.Total.String())
+
+	commande := models.Commande{Status: models.StatusPending, Channel: models.ChannelKiosk}
+	assert.NoError(t, models.CreateCommandeFromLines(db, order, &commande))
+
+	line := commande.Products[0]
+	assert.Equal(t, "6", line.Price.String())
+	assert.Equal(t, "8", line.BasePrice.String())
+	assert.Equal(t, "Étudiant", line.PriceListName)
+	assert.Equal(t, student.ID, *line.PriceListID)
+
+	// Une grille manuelle inconnue est refusée
+	unknown := uint(999)
+	_, err = models.PriceOrder(db, lines, models.PricingContext{Channel: models.ChannelKiosk, PriceListID: &unknown})
+	assert.Equal(t, models.ErrPriceListUnavailable, err)
+}
+
+func TestUpdatePriceListReplacesItems(t *testing.T) {
+	db, burger := setupPriceListTestDB()
+
+	priceList := models.PriceList{Name: "Midi", IsActive: true, Items: []models.PriceListItem{{ProductID: &burger.ID, Price: decimal.NewFromInt(7)}}}
+	models.CreatePriceList(db, &priceList)
+
+	priceList.Items = []models.PriceListItem{{ProductID: &burger.ID, Price: decimal.RequireFromString("7.50")}}
+	assert.NoError(t, models.UpdatePriceList(db, &priceList))
+
+	stored, _ := models.GetPriceListByID(db, priceList.ID)
+	assert.Len(t, stored.Items, 1)
+	assert.Equal(t, "7.5", stored.Items[0].Price.String())
+}
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/printing"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakePrinter simule une imprimante RAW TCP : chaque connexion reçue est un ticket
+type fakePrinter struct {
+	listener net.Listener
+	received chan []byte
+}
+
+func startFakePrinter(t *testing.T) *fakePrinter {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	printer := &fakePrinter{listener: listener, received: make(chan []byte, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			data, _ := io.ReadAll(conn)
+			conn.Close()
+			printer.received <- data
+		}
+	}()
+
+	return printer
+}
+
+func (p *fakePrinter) Address() string {
+	return p.listener.Addr().String()
+}
+
+// Attend un ticket pendant au plus une seconde
+func (p *fakePrinter) next(t *testing.T) []byte {
+	select {
+	case data := <-p.received:
+		return data
+	case <-time.After(time.Second):
+		t.Fatal("aucun ticket reçu par l'imprimante")
+		return nil
+	}
+}
+
+// Base de données avec un poste boissons et une commande burger + boisson
+func setupPrinterTestDB() (*gorm.DB, models.Station, models.Commande) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Station{}, &models.Printer{}, &models.PrintJob{})
+
+	station := models.Station{Name: "Boissons", ProductTypes: []models.TypeProduct{models.TypeBoisson}}
+	db.Create(&station)
+
+	commande := models.Commande{TicketNumber: 12, Status: models.StatusPending, Price: decimal.NewFromInt(12)}
+	db.Create(&commande)
+	db.Create(&models.CommandeProduct{CommandeID: commande.ID, Name: "Burger", Quantity: 1, Type: models.TypePlat})
+	db.Create(&models.CommandeProduct{CommandeID: commande.ID, Name: "Cola", Quantity: 1, Type: models.TypeBoisson})
+
+	return db, station, commande
+}
+
+/////////////////////////////////////
+// PRINT QUEUE
+/////////////////////////////////////
+
+func TestPrintJobsAreSentToStationPrinters(t *testing.T) {
+	db, station, commande := setupPrinterTestDB()
+	fake := startFakePrinter(t)
+
+	db.Create(&models.Printer{Name: "Bar", Address: fake.Address(), StationID: &station.ID, IsActive: true})
+
+	jobs, err := printing.EnqueueCommande(db, commande.ID)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+
+	worker := printing.NewWorker(db)
+	assert.Equal(t, 1, worker.ProcessDue(time.Now()))
+
+	// Le poste boissons ne reçoit que sa ligne
+	ticket := fake.next(t)
+	assert.True(t, bytes.Contains(ticket, []byte("TICKET 12")))
+	assert.True(t, bytes.Contains(ticket, []byte("Cola")))
+	assert.False(t, bytes.Contains(ticket, []byte("Burger")))
+
+	var job models.PrintJob
+	db.First(&job, jobs[0].ID)
+	assert.Equal(t, models.PrintJobPrinted, job.Status)
+	assert.NotNil(t, job.PrintedAt)
+}
+
+func TestPrintJobGoesToDeadLetterAfterRetries(t *testing.T) {
+	db, _, commande := setupPrinterTestDB()
+
+	// Port fermé : on ouvre puis referme immédiatement un listener
+	fake := startFakePrinter(t)
+	address := fake.Address()
+	fake.listener.Close()
+
+	db.Create(&models.Printer{Name: "Comptoir", Address: address, IsActive: true})
+	jobs, _ := printing.EnqueueCommande(db, commande.ID)
+	assert.Len(t, jobs, 1)
+
+	worker := printing.NewWorker(db)
+	worker.MaxAttempts = 2
+	worker.Backoff = func(int) time.Duration { return 0 }
+
+	worker.ProcessDue(time.Now())
+	worker.ProcessDue(time.Now())
+
+	dead, _ := models.GetPrintJobs(db, models.PrintJobDead)
+	assert.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.NotEmpty(t, dead[0].LastError)
+
+	// Relance manuelle depuis la liste des rejets
+	job, err := models.RetryPrintJob(db, dead[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PrintJobPending, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+}
+
+func TestOfflinePrinterDoesNotHoldUpOthers(t *testing.T) {
+	db, _, commande := setupPrinterTestDB()
+	fake := startFakePrinter(t)
+	closed := startFakePrinter(t)
+	closed.listener.Close()
+
+	online := models.Printer{Name: "Bar", Address: fake.Address(), IsActive: true}
+	offline := models.Printer{Name: "Comptoir", Address: closed.Address(), IsActive: true}
+	db.Create(&online)
+	db.Create(&offline)
+	now := time.Now()
+	jobs := []models.PrintJob{
+		{PrinterID: offline.ID, CommandeID: commande.ID, Payload: []byte("1"), Status: models.PrintJobPending, NextAttemptAt: now},
+		{PrinterID: offline.ID, CommandeID: commande.ID, Payload: []byte("2"), Status: models.PrintJobPending, NextAttemptAt: now},
+		{PrinterID: online.ID, CommandeID: commande.ID, Payload: []byte("3"), Status: models.PrintJobPending, NextAttemptAt: now},
+	}
+	for i := range jobs {
+		db.Create(&jobs[i])
+	}
+
+	// L'imprimante hors ligne échoue une fois ; son deuxième travail attend le prochain passage
+	worker := printing.NewWorker(db)
+	assert.Equal(t, 2, worker.ProcessDue(now))
+	assert.Equal(t, []byte("3"), fake.next(t))
+	for i, expected := range []struct {
+		status   models.PrintJobStatus
+		attempts int
+	}{{models.PrintJobPending, 1}, {models.PrintJobPending, 0}, {models.PrintJobPrinted, 1}} {
+		var job models.PrintJob
+		db.First(&job, jobs[i].ID)
+		assert.Equal(t, expected.status, job.Status)
+		assert.Equal(t, expected.attempts, job.Attempts)
+	}
+	var skipped models.PrintJob
+	db.First(&skipped, jobs[1].ID)
+	assert.False(t, skipped.NextAttemptAt.After(now), "travail non envoyé rendu à la file")
+}
+
+func TestPrintJobClaimedOnce(t *testing.T) {
+	db, _, commande := setupPrinterTestDB()
+	fake := startFakePrinter(t)
+	printer := models.Printer{Name: "Bar", Address: fake.Address(), IsActive: true}
+	db.Create(&printer)
+	now := time.Now()
+	job := models.PrintJob{PrinterID: printer.ID, CommandeID: commande.ID, Payload: []byte("ticket"), Status: models.PrintJobPending, NextAttemptAt: now}
+	db.Create(&job)
+
+	// Une autre instance a réservé le travail : il n'est pas imprimé deux fois
+	claimed, err := models.ClaimPrintJob(db, &job, now, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = models.ClaimPrintJob(db, &job, now, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, 0, printing.NewWorker(db).ProcessDue(now))
+
+	// Bail expiré sans résultat : le travail est repris
+	assert.Equal(t, 1, printing.NewWorker(db).ProcessDue(now.Add(2*time.Minute)))
+	assert.Equal(t, []byte("ticket"), fake.next(t))
+}
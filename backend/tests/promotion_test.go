@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"LearningCampusKabre/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Catalogue : burger à 8 €, cola à 2 €, menu classique à 10 € et menu maxi à 13 €
+type promotionCatalog struct {
+	burger, cola  models.Product
+	classic, maxi models.Menu
+}
+
+func setupPromotionTestDB() (*gorm.DB, promotionCatalog) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{})
+	models.SeedTaxRates(db)
+
+	catalog := promotionCatalog{
+		burger:  models.Product{Name: "Burger", Price: decimal.NewFromInt(8), IsAvailable: true, Type: models.TypePlat},
+		cola:    models.Product{Name: "Cola", Price: decimal.NewFromInt(2), IsAvailable: true, Type: models.TypeBoisson},
+		classic: models.Menu{Name: "Menu classique", Price: decimal.NewFromInt(10)},
+		maxi:    models.Menu{Name: "Menu maxi", Price: decimal.NewFromInt(13)},
+	}
+	db.Create(&catalog.burger)
+	db.Create(&catalog.cola)
+	db.Create(&catalog.classic)
+	db.Create(&catalog.maxi)
+
+	return db, catalog
+}
+
+func priceWithPromotions(t *testing.T, db *gorm.DB, lines []models.OrderLine, codes ...string) *models.PricedOrder {
+	order, err := models.PriceOrderLines(db, lines)
+	assert.NoError(t, err)
+	assert.NoError(t, models.ApplyPromotions(db, order, codes, time.Now()))
+	return order
+}
+
+func code(value string) *string {
+	return &value
+}
+
+/////////////////////////////////////
+// DISCOUNT RULES
+/////////////////////////////////////
+
+func TestStackablePromotionsAndCoupon(t *testing.T) {
+	db, catalog := setupPromotionTestDB()
+
+	db.Create(&models.Promotion{Name: "10 % sur tout", Kind: models.PromoPercentage, Value: decimal.NewFromInt(10), Stackable: true, IsActive: true})
+	db.Create(&models.Promotion{Name: "Bienvenue", Kind: models.PromoFixed, Value: decimal.NewFromInt(1), Code: code("BIENVENUE"), MaxUses: 1, Stackable: true, IsActive: true})
+
+	lines := []models.OrderLine{{ProductID: catalog.burger.ID, Quantity: 1}, {ProductID: catalog.cola.ID, Quantity: 1}}
+
+	// Sans code : seule la promotion automatique s'applique
+	order := priceWithPromotions(t, db, lines)
+	assert.Equal(t, "1", order.Discount.String())
+	assert.Equal(t, "9", order.Total.String())
+
+	// Le code est insensible à la casse et se cumule : 10 - 10 % - 1
+	order = priceWithPromotions(t, db, lines, "bienvenue")
+	assert.True(t, order.Valid)
+	assert.Len(t, order.Promotions, 2)
+	assert.Equal(t, "8", order.Total.String())
+
+	// La remise est répartie sur les lignes et réduit la base de TVA
+	commande := models.Commande{Status: models.StatusPending, ServiceMode: models.ServiceOnSite}
+	assert.NoError(t, models.CreateCommandeFromLines(db, order, &commande))
+	assert.Equal(t, "8", commande.Price.String())
+	assert.Equal(t, "2", commande.DiscountTotal.String())
+	assert.Len(t, commande.Promotions, 2)
+	assert.True(t, commande.NetTotal.Add(commande.TaxTotal).Equal(commande.Price))
+
+	// Le code a atteint sa limite
+	order = priceWithPromotions(t, db, lines, "BIENVENUE")
+	assert.False(t, order.Valid)
+	assert.NotEmpty(t, order.PromotionError)
+}
+
+func TestBuyXGetY(t *testing.T) {
+	db, catalog := setupPromotionTestDB()
+	db.Create(&models.Promotion{Name: "2 colas achetés, 1 offert", Kind: models.PromoBuyXGetY, ProductID: &catalog.cola.ID, BuyQuantity: 2, FreeQuantity: 1, Stackable: true, IsActive: true})
+
+	order := priceWithPromotions(t, db, []models.OrderLine{{ProductID: catalog.cola.ID, Quantity: 7}, {ProductID: catalog.burger.ID, Quantity: 1}})
+
+	// 7 colas : deux lots de trois, donc deux offerts
+	assert.Equal(t, "4", order.Discount.String())
+	assert.Equal(t, "4", order.Lines[0].Discount.String())
+	assert.True(t, order.Lines[1].Discount.IsZero())
+}
+
+func TestMenuUpgrade(t *testing.T) {
+	db, catalog := setupPromotionTestDB()
+	db.Create(&models.Promotion{Name: "Maxi pour 1 € de plus", Kind: models.PromoMenuUpgrade, MenuID: &catalog.maxi.ID, BaseMenuID: &catalog.classic.ID, Value: decimal.NewFromInt(1), Stackable: true, IsActive: true})
+
+	order := priceWithPromotions(t, db, []models.OrderLine{{MenuID: catalog.maxi.ID, Quantity: 2}})
+	assert.Equal(t, "22", order.Total.String())
+}
+
+func TestNonStackablePromotionOnlyWhenBetter(t *testing.T) {
+	db, catalog := setupPromotionTestDB()
+	db.Create(&models.Promotion{Name: "1 € sur le burger", Kind: models.PromoFixed, Value: decimal.NewFromInt(1), ProductID: &catalog.burger.ID, Stackable: true, IsActive: true})
+	db.Create(&models.Promotion{Name: "Étudiant -30 %", Kind: models.PromoPercentage, Value: decimal.NewFromInt(30), Code: code("ETUDIANT"), Stackable: false, IsActive: true})
+
+	lines := []models.OrderLine{{ProductID: catalog.burger.ID, Quantity: 1}, {ProductID: catalog.cola.ID, Quantity: 1}}
+
+	order := priceWithPromotions(t, db, lines, "ETUDIANT")
+	assert.Len(t, order.Promotions, 1)
+	assert.Equal(t, "Étudiant -30 %", order.Promotions[0].Name)
+	assert.Equal(t, "3", order.Discount.String())
+}
+
+func TestCouponValidityWindowAndMinimum(t *testing.T) {
+	db, catalog := setupPromotionTestDB()
+	yesterday := time.Now().Add(-24 * time.Hour)
+	db.Create(&models.Promotion{Name: "Expiré", Kind: models.PromoFixed, Value: decimal.NewFromInt(2), Code: code("HIER"), EndsAt: &yesterday, IsActive: true})
+	db.Create(&models.Promotion{Name: "Dès 20 €", Kind: models.PromoFixed, Value: decimal.NewFromInt(5), Code: code("VINGT"), MinOrderAmount: decimal.NewFromInt(20), IsActive: true})
+
+	lines := []models.OrderLine{{ProductID: catalog.burger.ID, Quantity: 1}}
+
+	order := priceWithPromotions(t, db, lines, "HIER")
+	assert.False(t, order.Valid)
+
+	// Un minimum non atteint n'invalide pas la commande : la remise est simplement ignorée
+	order = priceWithPromotions(t, db, lines, "VINGT")
+	assert.True(t, order.Valid)
+	assert.True(t, order.Discount.IsZero())
+
+	order = priceWithPromotions(t, db, lines, "INCONNU")
+	assert.False(t, order.Valid)
+}
+
+/////////////////////////////////////
+// DISCOUNT REPORT
+/////////////////////////////////////
+
+func TestDiscountReport(t *testing.T) {
+	db, catalog := setupPromotionTestDB()
+	db.Create(&models.Promotion{Name: "1 € sur le burger", Kind: models.PromoFixed, Value: decimal.NewFromInt(1), ProductID: &catalog.burger.ID, Stackable: true, IsActive: true})
+
+	for i := 0; i < 3; i++ {
+		order := priceWithPromotions(t, db, []models.OrderLine{{ProductID: catalog.burger.ID, Quantity: 1}})
+		commande := models.Commande{Status: models.StatusPending}
+		assert.NoError(t, models.CreateCommandeFromLines(db, order, &commande))
+	}
+
+	now := time.Now()
+	report, err := models.GetDiscountReport(db, now.Add(-time.Hour), now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, report, 1)
+	assert.Equal(t, int64(3), report[0].Uses)
+	assert.Equal(t, "3", report[0].Discount.String())
+}
+
+func TestDeletedPromotionCodeCanBeReused(t *testing.T) {
+	db, _ := setupPromotionTestDB()
+
+	first := models.Promotion{Name: "Bienvenue", Kind: models.PromoPercentage, Value: decimal.NewFromInt(10), Code: code("BIENVENUE")}
+	assert.NoError(t, models.CreatePromotion(db, &first))
+
+	// Le code est unique parmi les promotions actives
+	duplicate := models.Promotion{Name: "Doublon", Kind: models.PromoPercentage, Value: decimal.NewFromInt(5), Code: code("BIENVENUE")}
+	assert.Error(t, models.CreatePromotion(db, &duplicate))
+
+	// Une fois la promotion supprimée, son code peut servir à une nouvelle
+	assert.NoError(t, models.DeletePromotion(db, first.ID))
+	again := models.Promotion{Name: "Bienvenue 2", Kind: models.PromoPercentage, Value: decimal.NewFromInt(15), Code: code("BIENVENUE")}
+	assert.NoError(t, models.CreatePromotion(db, &again))
+}
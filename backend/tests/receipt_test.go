@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/receipts"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// go test ./tests -run Receipt -update régénère les fichiers de référence
+var updateGolden = flag.Bool("update", false, "met à jour les fichiers de référence dans testdata")
+
+// Compare la sortie d'un rendu avec son fichier de référence
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fichier de référence manquant %s (lancer avec -update) : %v", path, err)
+	}
+	assert.True(t, bytes.Equal(want, got), "le rendu diffère de %s", path)
+}
+
+// Commande de référence, entièrement déterministe
+func receiptCommande() *models.Commande {
+	return &models.Commande{
+		ID:           42,
+		TicketNumber: 17,
+		Status:       models.StatusReady,
+		Channel:      models.ChannelCounter,
+		Price:        decimal.RequireFromString("23.40"),
+		CreatedAt:    time.Date(2026, 3, 14, 12, 30, 0, 0, time.UTC),
+		Menus: []models.CommandeMenu{
+			{MenuID: 1, Name: "Menu Best-Of", Price: decimal.RequireFromString("9.90"), Quantity: 2, Modifiers: []string{"sans oignons"}},
+		},
+		Products: []models.CommandeProduct{
+			{ProductID: 3, Name: "Crème brûlée", Price: decimal.RequireFromString("3.60"), Quantity: 1, Type: models.TypeDessert},
+		},
+	}
+}
+
+/////////////////////////////////////
+// RECEIPT RENDERERS
+/////////////////////////////////////
+
+func TestReceiptInvoicePDF(t *testing.T) {
+	company := receipts.Company{
+		Name:      "Wacdo Paris",
+		Address:   "12 rue de la Paix\n75002 Paris",
+		SIRET:     "123 456 789 00012",
+		VATNumber: "FR12123456789",
+	}
+
+	pdf := receipts.RenderInvoicePDF(receiptCommande(), company)
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4")))
+	assertGolden(t, "invoice.pdf.golden", pdf)
+}
+
+func TestReceiptKitchenTicketESCPOS(t *testing.T) {
+	ticket := receipts.RenderKitchenTicket(receipts.KitchenTicketFromCommande(receiptCommande()))
+
+	// Réinitialisation au début, coupe du papier à la fin
+	assert.True(t, bytes.HasPrefix(ticket, []byte{0x1B, 0x40}))
+	assert.True(t, bytes.HasSuffix(ticket, []byte{0x1D, 0x56, 0x42, 0x03}))
+	// Aucun prix sur un ticket cuisine
+	assert.False(t, bytes.Contains(ticket, []byte("9,90")))
+	assertGolden(t, "kitchen_ticket.escpos.golden", ticket)
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données avec un client et une commande passée : un burger, un dessert et un café offert
+func setupReorderTestDB() (*gorm.DB, models.Customer, models.Commande, []models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Printer{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{}, &models.PriceList{}, &models.PriceListItem{}, &models.Customer{}, &models.LoyaltyLedgerEntry{}, &models.Reward{})
+
+	customer := models.Customer{FirstName: "Awa"}
+	customer.SetContact("06 12 34 56 78", "awa@example.com")
+	customer.SetConsents(true, false, time.Now())
+	models.CreateCustomer(db, &customer)
+
+	products := []models.Product{
+		{Name: "Burger", Price: decimal.NewFromInt(8), IsAvailable: true, Type: models.TypePlat},
+		{Name: "Tiramisu", Price: decimal.NewFromInt(4), IsAvailable: true, Type: models.TypeDessert},
+		{Name: "Café", Price: decimal.RequireFromString("1.5"), IsAvailable: true, Type: models.TypeBoisson},
+	}
+	for i := range products {
+		db.Create(&products[i])
+	}
+
+	order, _ := models.PriceOrder(db, []models.OrderLine{
+		{ProductID: products[0].ID, Quantity: 2, Modifiers: []string{"sans oignons"}},
+		{ProductID: products[1].ID, Quantity: 1},
+	}, models.PricingContext{Channel: models.ChannelCounter})
+	source := models.Commande{Status: models.StatusPending, Channel: models.ChannelCounter, ServiceMode: models.ServiceTakeaway, CustomerID: &customer.ID}
+	models.CreateCommandeFromLines(db, order, &source)
+
+	rewardID := uint(1)
+	db.Create(&models.CommandeProduct{CommandeID: source.ID, ProductID: products[2].ID, Name: "Café", Price: decimal.Zero, Quantity: 1, RewardID: &rewardID})
+
+	return db, customer, source, products
+}
+
+func setupReorderRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	cc := controllers.RefCustomerController(db)
+	r.GET("/customers/:id/commandes", cc.GetCustomerCommandes)
+	r.POST("/customers/:id/commandes/:commandeId/reorder", cc.Reorder)
+	return r
+}
+
+func TestCustomerCommandeHistory(t *testing.T) {
+	db, customer, source, _ := setupReorderTestDB()
+	db.Create(&models.Commande{Status: models.StatusPending, Price: decimal.NewFromInt(3)})
+	r := setupReorderRouter(db)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/customers/%d/commandes", customer.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var commandes []models.Commande
+	json.Unmarshal(w.Body.Bytes(), &commandes)
+	assert.Len(t, commandes, 1)
+	assert.Equal(t, source.ID, commandes[0].ID)
+	assert.Len(t, commandes[0].Products, 3)
+}
+
+func TestReorderUsesCurrentPrices(t *testing.T) {
+	db, customer, source, products := setupReorderTestDB()
+	db.Model(&products[0]).Update("price", decimal.NewFromInt(9))
+	r := setupReorderRouter(db)
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/customers/%d/commandes/%d/reorder", customer.ID, source.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result models.ReorderResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Empty(t, result.Unavailable)
+	assert.NotEqual(t, source.ID, result.Commande.ID)
+	assert.Equal(t, customer.ID, *result.Commande.CustomerID)
+	assert.Equal(t, models.ServiceTakeaway, result.Commande.ServiceMode)
+	// 2 burgers au nouveau prix + le dessert ; le café offert n'est pas repris
+	assert.True(t, decimal.NewFromInt(22).Equal(result.Commande.Price))
+	assert.Len(t, result.Commande.Products, 2)
+	assert.Equal(t, []string{"sans oignons"}, result.Commande.Products[0].Modifiers)
+}
+
+func TestReorderReportsUnavailableLines(t *testing.T) {
+	db, customer, source, products := setupReorderTestDB()
+	db.Model(&products[1]).Update("is_available", false)
+	r := setupReorderRouter(db)
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/customers/%d/commandes/%d/reorder", customer.ID, source.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result models.ReorderResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Len(t, result.Unavailable, 1)
+	assert.Equal(t, products[1].ID, result.Unavailable[0].ProductID)
+	assert.True(t, decimal.NewFromInt(16).Equal(result.Commande.Price))
+
+	// Plus rien à servir : aucune commande n'est créée
+	db.Delete(&products[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestReorderSkipsMenusWithUnavailableComponents(t *testing.T) {
+	db, customer, _, products := setupReorderTestDB()
+
+	menu := models.Menu{Name: "Menu gourmand", Price: decimal.NewFromInt(11)}
+	db.Create(&menu)
+	db.Create(&models.MenuItem{MenuID: menu.ID, ProductID: products[1].ID, Name: "Tiramisu", Type: models.TypeDessert})
+	source := models.Commande{
+		CustomerID: &customer.ID,
+		Menus:      []models.CommandeMenu{{MenuID: menu.ID, Quantity: 1}},
+		Products:   []models.CommandeProduct{{ProductID: products[0].ID, Quantity: 1}},
+	}
+
+	// Le dessert du menu n'est plus servi : le menu est écarté, le burger est recommandé
+	db.Model(&products[1]).Update("is_available", false)
+	commande := models.Commande{Status: models.StatusPending, Channel: models.ChannelCounter}
+	result, err := models.Reorder(db, &source, &commande, models.PricingContext{Channel: models.ChannelCounter})
+	assert.NoError(t, err)
+	assert.Len(t, result.Unavailable, 1)
+	assert.Equal(t, menu.ID, result.Unavailable[0].MenuID)
+	assert.True(t, result.Order.Valid)
+	assert.True(t, decimal.NewFromInt(8).Equal(result.Commande.Price))
+	assert.Empty(t, result.Commande.Menus)
+}
+
+func TestReorderRejectsOtherCustomersCommande(t *testing.T) {
+	db, _, source, _ := setupReorderTestDB()
+	other := models.Customer{FirstName: "Jean"}
+	other.SetContact("0700000000", "")
+	models.CreateCustomer(db, &other)
+	r := setupReorderRouter(db)
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/customers/%d/commandes/%d/reorder", other.ID, source.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestKioskCommandeIdentifiesCustomer(t *testing.T) {
+	db, customer, _, products := setupReorderTestDB()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	kc := controllers.RefKioskController(db)
+	r.POST("/kiosk/commandes", kc.CreateKioskCommande)
+
+	send := func(ref models.CustomerRef) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"lines":    []models.OrderLine{{ProductID: products[1].ID, Quantity: 1}},
+			"customer": ref,
+		})
+		req, _ := http.NewRequest("POST", "/kiosk/commandes", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(models.CustomerRef{Email: "AWA@example.com"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	history, _ := models.GetCustomerCommandes(db, customer.ID, 0)
+	assert.Len(t, history, 2)
+
+	w = send(models.CustomerRef{Phone: "0799999999"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/routes"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Routes sans JWT staff : authentifiées par identifiants, jeton à usage unique, appareil ou signature
+var publicRoutes = map[string]string{
+	"POST /api/auth/register":         "jeton d'invitation",
+	"POST /api/auth/login":            "identifiants",
+	"POST /api/auth/refresh":          "jeton de rafraîchissement",
+	"POST /api/auth/forgot":           "demande de réinitialisation",
+	"POST /api/auth/reset":            "jeton de réinitialisation",
+	"POST /api/auth/2fa/verify":       "challenge de double authentification",
+	"GET /api/auth/oidc/login":        "fournisseur d'identité",
+	"POST /api/auth/oidc/callback":    "fournisseur d'identité",
+	"GET /api/auth/pin/users":         "tablette",
+	"POST /api/auth/pin":              "tablette",
+	"GET /api/kiosk/catalog":          "borne",
+	"GET /api/kiosk/pickup-slots":     "borne",
+	"POST /api/kiosk/cart/validate":   "borne",
+	"POST /api/kiosk/commandes":       "borne",
+	"GET /api/board":                  "écran de salle",
+	"GET /api/board/stream":           "écran de salle",
+	"POST /api/delivery/webhooks/:id": "signature HMAC de la plateforme",
+	"GET /api/menus":                  "carte publique",
+	"GET /.well-known/jwks.json":      "clés publiques",
+}
+
+// Routes de son propre compte, ouvertes à tout utilisateur connecté quel que soit son rôle
+var selfServiceRoutes = map[string]bool{
+	"POST /api/auth/logout":             true,
+	"POST /api/auth/change-password":    true,
+	"POST /api/auth/pin/set":            true,
+	"POST /api/auth/2fa/setup":          true,
+	"POST /api/auth/2fa/enable":         true,
+	"POST /api/auth/2fa/disable":        true,
+	"POST /api/auth/2fa/recovery-codes": true,
+}
+
+var routeParam = regexp.MustCompile(`[:*][A-Za-z]+`)
+
+func TestEveryRouteDeclaresAPermission(t *testing.T) {
+	useTestSigningKey(t)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.User{}, &models.AuthSession{}, &models.RefreshToken{}, &models.LoginAttempt{}, &models.Role{})
+	models.SeedRoles(db)
+
+	// Un utilisateur connecté dont le rôle n'a aucune permission
+	assert.NoError(t, models.CreateRole(db, &models.Role{Name: "stagiaire"}))
+	assert.NoError(t, models.CreateUser(db, &models.User{Email: "stagiaire@example.com", Role: "stagiaire"}, "motdepasse123"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	assert.NoError(t, routes.SetupRoutes(r, db))
+
+	w := authRequest(r, "POST", "/api/auth/login", "", map[string]string{"email": "stagiaire@example.com", "password": "motdepasse123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, public := publicRoutes[key]; public {
+			continue
+		}
+
+		path := routeParam.ReplaceAllString(route.Path, "1")
+		assert.Equal(t, http.StatusUnauthorized, authRequest(r, route.Method, path, "", nil).Code, "%s accessible sans JWT", key)
+		if selfServiceRoutes[key] {
+			continue
+		}
+		assert.Equal(t, http.StatusForbidden, authRequest(r, route.Method, path, tokens.Token, nil).Code, "%s ne déclare pas de permission", key)
+	}
+
+	// Les listes d'exception ne doivent pas garder de routes disparues
+	for key := range publicRoutes {
+		assert.True(t, registered[key], "route publique inconnue : %s", key)
+	}
+	for key := range selfServiceRoutes {
+		assert.True(t, registered[key], "route de compte inconnue : %s", key)
+	}
+}
+
+func TestRolePermissionsAreEditable(t *testing.T) {
+	db, _, user := setupSessionTest(t)
+	models.SeedRoles(db)
+
+	perms, _ := models.RolePermissions(db, user.Role)
+	assert.True(t, models.HasPermission(perms, models.PermOrderDeliver))
+	assert.False(t, models.HasPermission(perms, models.PermProductWrite))
+
+	// L'administrateur retire la remise des commandes au rôle receiver
+	var receiver models.Role
+	db.Where("name = ?", models.RoleReceiver).First(&receiver)
+	updated, err := models.UpdateRole(db, receiver.ID, receiver.Description, []models.Permission{models.PermOrderRead})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Permission{models.PermOrderRead}, updated.Permissions)
+	perms, _ = models.RolePermissions(db, user.Role)
+	assert.False(t, models.HasPermission(perms, models.PermOrderDeliver))
+
+	// Permissions inconnues refusées, admin intouchable, rôles prédéfinis non supprimables
+	_, err = models.UpdateRole(db, receiver.ID, "", []models.Permission{"commande:tout"})
+	assert.Error(t, err)
+	var admin models.Role
+	db.Where("name = ?", models.RoleAdmin).First(&admin)
+	_, err = models.UpdateRole(db, admin.ID, "", nil)
+	assert.Equal(t, models.ErrRoleProtected, err)
+	assert.Equal(t, models.ErrRoleProtected, models.DeleteRole(db, receiver.ID))
+
+	// Un rôle personnalisé s'attribue, et ne se supprime plus tant qu'il est attribué
+	manager := models.Role{Name: "manager", Permissions: []models.Permission{models.PermOrderEdit}}
+	assert.NoError(t, models.CreateRole(db, &manager))
+	user.Role = "manager"
+	assert.NoError(t, models.UpdateUser(db, &user))
+	assert.Equal(t, models.ErrRoleInUse, models.DeleteRole(db, manager.ID))
+	user.Role = "inconnu"
+	assert.Error(t, models.UpdateUser(db, &user))
+}
+
+func TestRoleManagerCannotGrantPermissionsTheyLack(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Role{})
+	models.SeedRoles(db)
+	managerPerms := []models.Permission{models.PermRoleManage, models.PermOrderRead}
+	manager := models.Role{Name: "manager", Permissions: managerPerms}
+	assert.NoError(t, models.CreateRole(db, &manager))
+	stagiaire := models.Role{Name: "stagiaire", Permissions: []models.Permission{models.PermOrderRead}}
+	assert.NoError(t, models.CreateRole(db, &stagiaire))
+	var receiver models.Role
+	db.Where("name = ?", models.RoleReceiver).First(&receiver)
+
+	gin.SetMode(gin.TestMode)
+	rc := controllers.RefRoleController(db)
+	request := func(role models.UserRole, permissions []models.Permission, method, url string, body interface{}) int {
+		r := gin.Default()
+		r.Use(func(c *gin.Context) {
+			c.Set("role", string(role))
+			c.Set("permissions", permissions)
+		})
+		r.POST("/roles", rc.CreateRole)
+		r.PUT("/roles/:id", rc.UpdateRole)
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	input := func(name string, permissions ...models.Permission) models.RoleInput {
+		return models.RoleInput{Name: name, Permissions: permissions}
+	}
+
+	// Création : uniquement avec des permissions que l'on a soi-même
+	assert.Equal(t, http.StatusForbidden, request("manager", managerPerms, "POST", "/roles", input("chef", models.PermUserWrite)))
+	assert.Equal(t, http.StatusCreated, request("manager", managerPerms, "POST", "/roles", input("lecteur", models.PermOrderRead)))
+
+	// Son propre rôle n'est pas modifiable, même sans ajout
+	assert.Equal(t, http.StatusForbidden, request("manager", managerPerms, "PUT", fmt.Sprintf("/roles/%d", manager.ID), input("", managerPerms...)))
+	// Ni un rôle plus puissant que le sien, ni une permission que l'on n'a pas
+	assert.Equal(t, http.StatusForbidden, request("manager", managerPerms, "PUT", fmt.Sprintf("/roles/%d", receiver.ID), input("", models.PermOrderRead)))
+	assert.Equal(t, http.StatusForbidden, request("manager", managerPerms, "PUT", fmt.Sprintf("/roles/%d", stagiaire.ID), input("", models.PermOrderRead, models.PermUserWrite)))
+	assert.Equal(t, http.StatusOK, request("manager", managerPerms, "PUT", fmt.Sprintf("/roles/%d", stagiaire.ID), input("", models.PermOrderRead, models.PermRoleManage)))
+
+	// L'administrateur peut tout donner
+	assert.Equal(t, http.StatusOK, request(models.RoleAdmin, adminPermissions(), "PUT", fmt.Sprintf("/roles/%d", manager.ID), input("", models.PermRoleManage, models.PermUserWrite)))
+	perms, _ := models.RolePermissions(db, "manager")
+	assert.Contains(t, perms, models.PermUserWrite)
+}
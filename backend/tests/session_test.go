@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSessionTest(t *testing.T) (*gorm.DB, *gin.Engine, models.User) {
+	useTestSigningKey(t)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.User{}, &models.AuthSession{}, &models.RefreshToken{}, &models.LoginAttempt{}, &models.RecoveryCode{}, &models.MFAChallenge{}, &models.Role{})
+
+	hashed, _ := models.HashPassword("motdepasse123")
+	user := models.User{Email: "awa@example.com", Role: models.RoleReceiver, Password: hashed}
+	db.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	ac := controllers.RefAuthController(db)
+	r.POST("/auth/login", ac.Login)
+	r.POST("/auth/refresh", ac.Refresh)
+	r.POST("/auth/logout", middlewares.AuthMiddleware(db), ac.Logout)
+	r.GET("/me", middlewares.AuthMiddleware(db), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": middlewares.CurrentUserID(c)})
+	})
+	return db, r, user
+}
+
+func authRequest(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func login(t *testing.T, r *gin.Engine) controllers.TokenResponse {
+	w := authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "awa@example.com", "password": "motdepasse123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens controllers.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	return tokens
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	_, r, _ := setupSessionTest(t)
+
+	tokens := login(t, r)
+	assert.NotEmpty(t, tokens.Token)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, 900, tokens.ExpiresIn)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", tokens.Token, nil).Code)
+
+	w := authRequest(r, "POST", "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rotated controllers.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", rotated.Token, nil).Code)
+
+	// L'ancien jeton rejoué ferme toute la session, y compris les jetons émis ensuite
+	w = authRequest(r, "POST", "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, "POST", "/auth/refresh", "", map[string]string{"refresh_token": rotated.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", rotated.Token, nil).Code)
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	_, r, _ := setupSessionTest(t)
+
+	first := login(t, r)
+	second := login(t, r)
+
+	assert.Equal(t, http.StatusOK, authRequest(r, "POST", "/auth/logout", first.Token, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", first.Token, nil).Code)
+	w := authRequest(r, "POST", "/auth/refresh", "", map[string]string{"refresh_token": first.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// L'autre appareil reste connecté jusqu'à la déconnexion globale
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", second.Token, nil).Code)
+	assert.Equal(t, http.StatusOK, authRequest(r, "POST", "/auth/logout", second.Token, map[string]bool{"all": true}).Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", second.Token, nil).Code)
+}
+
+func TestDisabledOrDeletedUserRejected(t *testing.T) {
+	db, r, user := setupSessionTest(t)
+
+	tokens := login(t, r)
+	_, err := models.SetUserDisabled(db, user.ID, true)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", tokens.Token, nil).Code)
+	w := authRequest(r, "POST", "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "awa@example.com", "password": "motdepasse123"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	models.SetUserDisabled(db, user.ID, false)
+	tokens = login(t, r)
+	assert.NoError(t, models.DeleteUser(db, user.ID))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", tokens.Token, nil).Code)
+}
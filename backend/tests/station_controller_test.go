@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données avec deux postes et une commande burger + boisson
+func setupStationTestDB() (*gorm.DB, []models.Station, models.Commande) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.Station{}, &models.CommandeStationTask{})
+
+	stations := []models.Station{
+		{Name: "Boissons", ProductTypes: []models.TypeProduct{models.TypeBoisson}},
+		{Name: "Grill", ProductTypes: []models.TypeProduct{models.TypePlat}, IsDefault: true},
+	}
+	for i := range stations {
+		db.Create(&stations[i])
+	}
+
+	commande := models.Commande{TicketNumber: 7, Status: models.StatusPending, Price: decimal.NewFromInt(12)}
+	db.Create(&commande)
+	db.Create(&models.CommandeProduct{CommandeID: commande.ID, Name: "Burger", Quantity: 1, Type: models.TypePlat})
+	db.Create(&models.CommandeProduct{CommandeID: commande.ID, Name: "Cola", Quantity: 2, Type: models.TypeBoisson})
+
+	return db, stations, commande
+}
+
+// Router pour les tests
+func setupStationRouter(sc *controllers.StationController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/stations/:id/tickets", sc.GetStationTickets)
+	r.PUT("/stations/:id/commandes/:commandeId/done", sc.MarkStationDone)
+	return r
+}
+
+/////////////////////////////////////
+// STATION TICKETS
+/////////////////////////////////////
+
+func TestStationTicketsOnlyShowOwnLines(t *testing.T) {
+	db, stations, _ := setupStationTestDB()
+	router := setupStationRouter(controllers.RefStationController(db))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/stations/%d/tickets", stations[0].ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tickets []models.StationTicket
+	json.Unmarshal(w.Body.Bytes(), &tickets)
+
+	assert.Len(t, tickets, 1)
+	assert.Equal(t, uint(7), tickets[0].TicketNumber)
+	assert.Len(t, tickets[0].Lines, 1)
+	assert.Equal(t, "Cola", tickets[0].Lines[0].Name)
+	assert.Equal(t, 2, tickets[0].Lines[0].Quantity)
+}
+
+/////////////////////////////////////
+// AUTOMATIC READY STATUS
+/////////////////////////////////////
+
+func TestCommandeReadyWhenAllStationsDone(t *testing.T) {
+	db, stations, commande := setupStationTestDB()
+	router := setupStationRouter(controllers.RefStationController(db))
+
+	done := func(station models.Station) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/stations/%d/commandes/%d/done", station.ID, commande.ID), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := done(stations[0])
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&commande, commande.ID)
+	assert.Equal(t, models.StatusPreparing, commande.Status)
+
+	w = done(stations[1])
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&commande, commande.ID)
+	assert.Equal(t, models.StatusReady, commande.Status)
+
+	// La commande est prête : un poste ne peut plus la modifier
+	w = done(stations[1])
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStationStartsPreparationWhenTicketIsShown(t *testing.T) {
+	db, stations, commande := setupStationTestDB()
+
+	// Commande qui ne concerne que le poste boissons
+	single := models.Commande{TicketNumber: 8, Status: models.StatusPending, Price: decimal.NewFromInt(4)}
+	db.Create(&single)
+	db.Create(&models.CommandeProduct{CommandeID: single.ID, Name: "Cola", Quantity: 1, Type: models.TypeBoisson})
+
+	// Le premier affichage sur l'écran du poste vaut prise en charge : les deux commandes passent
+	// en préparation, qu'elles concernent un ou plusieurs postes
+	tickets, err := models.GetStationTickets(db, &stations[0])
+	assert.NoError(t, err)
+	assert.Len(t, tickets, 2)
+	assert.Equal(t, models.StatusPreparing, tickets[0].Status)
+	for _, id := range []uint{commande.ID, single.ID} {
+		var stored models.Commande
+		db.Preload("StatusChanges").First(&stored, id)
+		assert.Equal(t, models.StatusPreparing, stored.Status)
+		assert.Len(t, stored.StatusChanges, 2)
+	}
+	var task models.CommandeStationTask
+	db.Where("commande_id = ? AND station_id = ?", single.ID, stations[0].ID).First(&task)
+	assert.NotNil(t, task.StartedAt)
+	assert.Nil(t, task.DoneAt)
+
+	// Un nouvel affichage ne change rien ; l'autre poste enregistre sa propre prise en charge
+	_, err = models.GetStationTickets(db, &stations[0])
+	assert.NoError(t, err)
+	_, err = models.GetStationTickets(db, &stations[1])
+	assert.NoError(t, err)
+	var changes, tasks int64
+	db.Model(&models.CommandeStationTask{}).Where("commande_id = ? AND started_at IS NOT NULL", commande.ID).Count(&tasks)
+	assert.Equal(t, int64(2), tasks)
+	db.Model(&models.CommandeStatusChange{}).Where("commande_id = ?", commande.ID).Count(&changes)
+	assert.Equal(t, int64(2), changes)
+
+	// La commande à un seul poste passe par la préparation avant d'être prête
+	_, err = models.MarkStationDone(db, single.ID, &stations[0], 1)
+	assert.NoError(t, err)
+	var stored models.Commande
+	db.Preload("StatusChanges").First(&stored, single.ID)
+	assert.Equal(t, models.StatusReady, stored.Status)
+	assert.Equal(t, models.StatusPreparing, stored.StatusChanges[1].ToStatus)
+}
+
+/////////////////////////////////////
+// STATION COVERAGE
+/////////////////////////////////////
+
+func TestStationConfigurationMustCoverEveryProductType(t *testing.T) {
+	db, stations, _ := setupStationTestDB()
+	sc := controllers.RefStationController(db)
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/stations", sc.CreateStation)
+	router.PUT("/stations/:id", sc.UpdateStation)
+	router.DELETE("/stations/:id", sc.DeleteStation)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Sans le poste par défaut, entrées et desserts ne seraient préparés nulle part
+	w := send("DELETE", fmt.Sprintf("/stations/%d", stations[1].ID), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "entree")
+
+	w = send("PUT", fmt.Sprintf("/stations/%d", stations[1].ID), `{"name":"Grill","product_types":["plat"],"is_default":false}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Un poste qui couvre les types restants permet de retirer le poste par défaut
+	w = send("POST", "/stations", `{"name":"Froid","product_types":["entree","dessert"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = send("PUT", fmt.Sprintf("/stations/%d", stations[1].ID), `{"name":"Grill","product_types":["plat"],"is_default":false}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestStationTicketsSplitMenusOfEveryCommande(t *testing.T) {
+	db, stations, commande := setupStationTestDB()
+
+	// Les items des menus de toutes les commandes sont chargés en une requête
+	db.Create(&models.MenuItem{MenuID: 5, Name: "Frites", Type: models.TypeEntree})
+	db.Create(&models.MenuItem{MenuID: 5, Name: "Soda", Type: models.TypeBoisson})
+	db.Create(&models.CommandeMenu{CommandeID: commande.ID, MenuID: 5, Name: "Menu Kids", Quantity: 2})
+	other := models.Commande{TicketNumber: 8, Status: models.StatusPending, Price: decimal.NewFromInt(9)}
+	db.Create(&other)
+	db.Create(&models.CommandeMenu{CommandeID: other.ID, MenuID: 5, Name: "Menu Kids", Quantity: 1})
+
+	tickets, err := models.GetStationTickets(db, &stations[0])
+	assert.NoError(t, err)
+	assert.Len(t, tickets, 2)
+	assert.Len(t, tickets[0].Lines, 2)
+	assert.Equal(t, "Soda", tickets[0].Lines[1].Name)
+	assert.Equal(t, 2, tickets[0].Lines[1].Quantity)
+	assert.Len(t, tickets[1].Lines, 1)
+	assert.Equal(t, "Menu Kids", tickets[1].Lines[0].MenuName)
+
+	// Les frites, sans poste dédié, vont au poste par défaut
+	tickets, err = models.GetStationTickets(db, &stations[1])
+	assert.NoError(t, err)
+	assert.Len(t, tickets, 2)
+	assert.Equal(t, "Frites", tickets[1].Lines[0].Name)
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"LearningCampusKabre/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Base de données avec les taux par défaut, un burger et une bière
+func setupTaxTestDB() (*gorm.DB, models.Product, models.Product) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.TaxRate{}, &models.Promotion{}, &models.CommandePromotion{})
+	models.SeedTaxRates(db)
+
+	burger := models.Product{Name: "Burger", Price: decimal.RequireFromString("8.50"), IsAvailable: true, Type: models.TypePlat}
+	beer := models.Product{Name: "Bière", Price: decimal.RequireFromString("4.90"), IsAvailable: true, Type: models.TypeBoisson, TaxCategory: models.TaxAlcohol}
+	db.Create(&burger)
+	db.Create(&beer)
+
+	return db, burger, beer
+}
+
+func createTaxedCommande(t *testing.T, db *gorm.DB, mode models.ServiceMode, lines []models.OrderLine) models.Commande {
+	order, err := models.PriceOrderLines(db, lines)
+	assert.NoError(t, err)
+
+	commande := models.Commande{Status: models.StatusPending, ServiceMode: mode}
+	assert.NoError(t, models.CreateCommandeFromLines(db, order, &commande))
+	return commande
+}
+
+func TestComputeTaxRoundsToTheCent(t *testing.T) {
+	amounts := models.ComputeTax(decimal.RequireFromString("8.50"), decimal.RequireFromString("5.5"))
+
+	assert.Equal(t, "8.06", amounts.Net.StringFixed(2))
+	assert.Equal(t, "0.44", amounts.Tax.StringFixed(2))
+	assert.True(t, amounts.Net.Add(amounts.Tax).Equal(amounts.Gross))
+}
+
+func TestCommandeLinesStoreTaxPerCategoryAndServiceMode(t *testing.T) {
+	db, burger, beer := setupTaxTestDB()
+	lines := []models.OrderLine{{ProductID: burger.ID, Quantity: 2}, {ProductID: beer.ID, Quantity: 1}}
+
+	onSite := createTaxedCommande(t, db, models.ServiceOnSite, lines)
+	takeaway := createTaxedCommande(t, db, models.ServiceTakeaway, lines)
+
+	// Sur place : 10 % sur le burger, 20 % sur l'alcool
+	assert.Equal(t, "10", onSite.Products[0].TaxRate.String())
+	assert.Equal(t, "15.45", onSite.Products[0].NetAmount.StringFixed(2))
+	assert.Equal(t, "20", onSite.Products[1].TaxRate.String())
+	assert.Equal(t, "4.08", onSite.Products[1].NetAmount.StringFixed(2))
+
+	// À emporter : seul le taux de la nourriture change
+	assert.Equal(t, "5.5", takeaway.Products[0].TaxRate.String())
+	assert.Equal(t, "20", takeaway.Products[1].TaxRate.String())
+
+	// Les totaux HT + TVA retombent exactement sur le TTC
+	for _, c := range []models.Commande{onSite, takeaway} {
+		assert.True(t, c.NetTotal.Add(c.TaxTotal).Equal(c.Price), "commande %d", c.ID)
+	}
+
+	// Les montants sont bien persistés
+	var stored models.CommandeProduct
+	db.First(&stored, onSite.Products[1].ID)
+	assert.Equal(t, "0.82", stored.TaxAmount.StringFixed(2))
+}
+
+func TestDailyVATSummaryGroupsByRate(t *testing.T) {
+	db, burger, beer := setupTaxTestDB()
+	createTaxedCommande(t, db, models.ServiceOnSite, []models.OrderLine{{ProductID: burger.ID, Quantity: 1}, {ProductID: beer.ID, Quantity: 1}})
+	createTaxedCommande(t, db, models.ServiceOnSite, []models.OrderLine{{ProductID: burger.ID, Quantity: 1}})
+
+	summaries, err := models.GetDailyVATSummary(db, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 2)
+
+	assert.Equal(t, "10", summaries[0].Rate.String())
+	assert.Equal(t, "17.00", summaries[0].Gross.StringFixed(2))
+	assert.Equal(t, "15.46", summaries[0].Net.StringFixed(2))
+	assert.Equal(t, "20", summaries[1].Rate.String())
+	assert.Equal(t, "4.90", summaries[1].Gross.StringFixed(2))
+
+	// Rien la veille
+	yesterday, _ := models.GetDailyVATSummary(db, time.Now().AddDate(0, 0, -1))
+	assert.Empty(t, yesterday)
+}
@@ -20,14 +20,32 @@ import (
 // Création d'une base de données en mémoire pour les tests
 func setupTestDB() *gorm.DB {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&models.User{})
+	db.AutoMigrate(&models.User{}, &models.Role{})
 	return db
 }
 
+// withPermissions simule un utilisateur authentifié avec ces permissions
+func withPermissions(permissions []models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("permissions", permissions)
+		c.Next()
+	}
+}
+
+// adminPermissions retourne toutes les permissions, comme pour un administrateur
+func adminPermissions() []models.Permission {
+	permissions := make([]models.Permission, 0, len(models.AllPermissions))
+	for _, info := range models.AllPermissions {
+		permissions = append(permissions, info.Name)
+	}
+	return permissions
+}
+
 // Configuration du routeur pour les tests
 func setupUserRouter(uc *controllers.UserController) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(withPermissions(adminPermissions()))
 	r.POST("/users", uc.CreateUser)
 	r.GET("/users", uc.GetAllUsers)
 	return r
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/middlewares"
+	"LearningCampusKabre/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUserWritesHashPasswordAndHideIt(t *testing.T) {
+	db := setupTestDB()
+	uc := controllers.RefUserController(db)
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(withPermissions(adminPermissions()))
+	r.POST("/users", uc.CreateUser)
+	r.GET("/users", uc.GetAllUsers)
+	r.PUT("/users/:id", uc.UpdateUser)
+
+	body, _ := json.Marshal(map[string]interface{}{"email": "awa@example.com", "password": "motdepasse123", "role": "receiver"})
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	var user models.User
+	db.First(&user)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("motdepasse123")))
+	hash := user.Password
+
+	// Une mise à jour du profil, même avec un mot de passe dans le corps, ne le modifie pas
+	body, _ = json.Marshal(map[string]interface{}{"email": "awa@example.com", "password": "pirate", "role": "preparer"})
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/users/%d", user.ID), bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&user)
+	assert.Equal(t, models.RolePreparer, user.Role)
+	assert.Equal(t, hash, user.Password)
+
+	// Un mot de passe qui ressemble à un hachage bcrypt est haché comme les autres : il ne
+	// remplace pas le hachage stocké
+	lookalike, _ := models.HashPassword("autre-secret")
+	body, _ = json.Marshal(map[string]interface{}{"email": "direct@example.com", "password": lookalike, "role": "receiver"})
+	req, _ = http.NewRequest("POST", "/users", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var direct models.User
+	db.Where("email = ?", "direct@example.com").First(&direct)
+	assert.NotEqual(t, lookalike, direct.Password)
+	assert.True(t, strings.HasPrefix(direct.Password, "$2"))
+	assert.True(t, direct.CheckPassword(lookalike))
+	assert.False(t, direct.CheckPassword("autre-secret"))
+
+	req, _ = http.NewRequest("GET", "/users", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.NotContains(t, w.Body.String(), "$2")
+}
+
+func TestChangePassword(t *testing.T) {
+	db, r, _ := setupSessionTest(t)
+	r.POST("/auth/change-password", middlewares.AuthMiddleware(db), controllers.RefAuthController(db).ChangePassword)
+
+	current := login(t, r)
+	other := login(t, r)
+
+	w := authRequest(r, "POST", "/auth/change-password", current.Token, map[string]string{"old_password": "mauvais", "new_password": "nouveau456"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = authRequest(r, "POST", "/auth/change-password", current.Token, map[string]string{"old_password": "motdepasse123", "new_password": "nouveau456"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// La session courante reste ouverte, les autres sont fermées
+	assert.Equal(t, http.StatusOK, authRequest(r, "GET", "/me", current.Token, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(r, "GET", "/me", other.Token, nil).Code)
+
+	w = authRequest(r, "POST", "/auth/login", "", map[string]string{"email": "awa@example.com", "password": "nouveau456"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRehashPlaintextPasswords(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.User{})
+
+	// Comptes enregistrés en clair par l'ancienne version, y compris un compte supprimé
+	db.Exec("INSERT INTO users (email, role, password, deleted_at) VALUES ('a@example.com', 'admin', 'secret', 0), ('b@example.com', 'preparer', 'old', 1)")
+	hashed, _ := models.HashPassword("motdepasse123")
+	db.Exec("INSERT INTO users (email, role, password, deleted_at) VALUES ('c@example.com', 'receiver', ?, 0)", hashed)
+
+	count, err := models.RehashPlaintextPasswords(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	user, err := models.LoginUser(db, "a@example.com", "secret")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "secret", user.Password)
+	_, err = models.LoginUser(db, "c@example.com", "motdepasse123")
+	assert.NoError(t, err)
+
+	count, _ = models.RehashPlaintextPasswords(db)
+	assert.Equal(t, 0, count)
+}
+
+func TestUserManagerCannotGrantMorePermissionsThanTheirOwn(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Invitation{})
+	models.SeedRoles(db)
+	admin := models.User{Email: "admin@example.com", Role: models.RoleAdmin}
+	receiver := models.User{Email: "accueil@example.com", Role: models.RoleReceiver}
+	models.CreateUser(db, &admin, "motdepasse123")
+	models.CreateUser(db, &receiver, "motdepasse123")
+
+	// Responsable d'accueil : permissions de l'accueil, plus la gestion des comptes et des invitations
+	manager := append(append([]models.Permission{}, models.DefaultRolePermissions[models.RoleReceiver]...), models.PermUserWrite, models.PermInvitationManage)
+	uc := controllers.RefUserController(db)
+	ac := controllers.RefAuthController(db)
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(withPermissions(manager))
+	r.POST("/users", uc.CreateUser)
+	r.PUT("/users/:id", uc.UpdateUser)
+	r.POST("/invitations", ac.CreateInvitation)
+
+	send := func(method, path string, body map[string]interface{}) int {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(raw))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, send("POST", "/users", map[string]interface{}{"email": "caisse@example.com", "password": "motdepasse123", "role": "receiver"}))
+	assert.Equal(t, http.StatusForbidden, send("POST", "/users", map[string]interface{}{"email": "pirate@example.com", "password": "motdepasse123", "role": "admin"}))
+	// La cuisine a des permissions (préparation, postes) que le responsable d'accueil n'a pas
+	assert.Equal(t, http.StatusForbidden, send("POST", "/users", map[string]interface{}{"email": "cuisine@example.com", "password": "motdepasse123", "role": "preparer"}))
+
+	assert.Equal(t, http.StatusForbidden, send("PUT", fmt.Sprintf("/users/%d", receiver.ID), map[string]interface{}{"email": receiver.Email, "role": "admin"}))
+	// Un compte plus privilégié ne peut pas être modifié, par exemple pour détourner son e-mail
+	assert.Equal(t, http.StatusForbidden, send("PUT", fmt.Sprintf("/users/%d", admin.ID), map[string]interface{}{"email": "pirate@example.com", "role": "admin"}))
+	db.First(&admin, admin.ID)
+	assert.Equal(t, "admin@example.com", admin.Email)
+
+	assert.Equal(t, http.StatusForbidden, send("POST", "/invitations", map[string]interface{}{"email": "invite@example.com", "role": "admin"}))
+	assert.Equal(t, http.StatusCreated, send("POST", "/invitations", map[string]interface{}{"email": "invite@example.com", "role": "receiver"}))
+}
@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"LearningCampusKabre/controllers"
+	"LearningCampusKabre/models"
+	"LearningCampusKabre/webhooks"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// webhookCall est un envoi reçu par le faux abonné
+type webhookCall struct {
+	Event     string
+	Timestamp string
+	Signature string
+	Body      []byte
+}
+
+// fakeSubscriber simule un système abonné ; code donne la réponse à renvoyer
+type fakeSubscriber struct {
+	mu    sync.Mutex
+	code  int
+	calls []webhookCall
+}
+
+func newFakeSubscriber(t *testing.T, code int) (*httptest.Server, *fakeSubscriber) {
+	subscriber := &fakeSubscriber{code: code}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		subscriber.mu.Lock()
+		defer subscriber.mu.Unlock()
+		subscriber.calls = append(subscriber.calls, webhookCall{
+			Event:     r.Header.Get("X-Webhook-Event"),
+			Timestamp: r.Header.Get("X-Webhook-Timestamp"),
+			Signature: r.Header.Get("X-Webhook-Signature"),
+			Body:      body,
+		})
+		w.WriteHeader(subscriber.code)
+	}))
+	t.Cleanup(server.Close)
+	return server, subscriber
+}
+
+func setupWebhookTestDB(t *testing.T) *gorm.DB {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.Product{}, &models.Menu{}, &models.MenuItem{}, &models.Commande{}, &models.TicketCounter{}, &models.CommandeMenu{}, &models.CommandeProduct{}, &models.CommandeStatusChange{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookAttempt{})
+	t.Cleanup(models.OnEvent(models.EnqueueWebhooks))
+	return db
+}
+
+// localWebhookWorker lève la restriction aux adresses publiques pour joindre le faux abonné local ;
+// les redirections restent refusées
+func localWebhookWorker(db *gorm.DB) *webhooks.Worker {
+	worker := webhooks.NewWorker(db)
+	worker.HTTP.Transport = http.DefaultTransport
+	return worker
+}
+
+func TestWebhookDeliveredWithSignature(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	server, subscriber := newFakeSubscriber(t, http.StatusOK)
+
+	subscription := models.WebhookSubscription{URL: server.URL, IsActive: true, Events: []models.EventType{models.EventCommandeStatusChanged}}
+	assert.NoError(t, models.CreateWebhookSubscription(db, &subscription))
+	assert.NotEmpty(t, subscription.Secret)
+
+	// Le produit créé n'est pas dans le filtre de l'abonnement
+	db.Create(&models.Product{Name: "Burger", Price: decimal.NewFromInt(8), IsAvailable: true, Type: models.TypePlat})
+	commande := models.Commande{Status: models.StatusPending}
+	db.Create(&commande)
+	commande.Status = models.StatusPreparing
+	db.Save(&commande)
+
+	now := time.Now()
+	assert.Equal(t, 1, localWebhookWorker(db).ProcessDue(now))
+	assert.Len(t, subscriber.calls, 1)
+
+	call := subscriber.calls[0]
+	assert.Equal(t, "commande.status_changed", call.Event)
+	expected := "sha256=" + hex.EncodeToString(webhooks.Sign(subscription.Secret, call.Timestamp, call.Body))
+	assert.Equal(t, expected, call.Signature)
+
+	var payload map[string]interface{}
+	json.Unmarshal(call.Body, &payload)
+	assert.Equal(t, "commande.status_changed", payload["event"])
+	assert.Equal(t, "pending", payload["previous_status"])
+	assert.Equal(t, "preparing", payload["data"].(map[string]interface{})["status"])
+
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	assert.Equal(t, models.WebhookDelivered, delivery.Status)
+	assert.NotNil(t, delivery.DeliveredAt)
+}
+
+func TestWebhookRetriedWithBackoffThenDead(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	server, subscriber := newFakeSubscriber(t, http.StatusInternalServerError)
+
+	subscription := models.WebhookSubscription{URL: server.URL, IsActive: true}
+	models.CreateWebhookSubscription(db, &subscription)
+	db.Create(&models.Product{Name: "Frites", Price: decimal.NewFromInt(3), IsAvailable: true, Type: models.TypePlat})
+
+	worker := localWebhookWorker(db)
+	worker.MaxAttempts = 3
+	now := time.Now()
+
+	assert.Equal(t, 1, worker.ProcessDue(now))
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	assert.Equal(t, models.WebhookPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, "l'abonné a répondu 500", delivery.LastError)
+	assert.WithinDuration(t, now.Add(30*time.Second), delivery.NextAttemptAt, time.Second)
+
+	// Pas de nouvelle tentative avant l'échéance, puis délai doublé
+	assert.Equal(t, 0, worker.ProcessDue(now.Add(10*time.Second)))
+	now = now.Add(30 * time.Second)
+	worker.ProcessDue(now)
+	db.First(&delivery)
+	assert.WithinDuration(t, now.Add(time.Minute), delivery.NextAttemptAt, time.Second)
+
+	worker.ProcessDue(now.Add(time.Minute))
+	full, _ := models.GetWebhookDeliveryByID(db, delivery.ID)
+	assert.Equal(t, models.WebhookDead, full.Status)
+	assert.Len(t, full.Logs, 3)
+	assert.Equal(t, 500, full.Logs[0].StatusCode)
+	assert.Len(t, subscriber.calls, 3)
+
+	// Le délai est plafonné à une heure
+	assert.Equal(t, time.Hour, webhooks.NewWorker(db).Backoff(10))
+}
+
+func TestWebhookRedeliver(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	server, subscriber := newFakeSubscriber(t, http.StatusOK)
+
+	subscription := models.WebhookSubscription{URL: server.URL, IsActive: true, Events: []models.EventType{models.EventProductCreated}}
+	models.CreateWebhookSubscription(db, &subscription)
+	db.Create(&models.Product{Name: "Soda", Price: decimal.NewFromInt(2), IsAvailable: true, Type: models.TypeBoisson})
+	localWebhookWorker(db).ProcessDue(time.Now())
+
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	assert.Equal(t, models.WebhookDelivered, delivery.Status)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	wc := controllers.RefWebhookController(db)
+	r.POST("/webhook-deliveries/:id/redeliver", wc.RedeliverWebhook)
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/webhook-deliveries/%d/redeliver", delivery.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.First(&delivery)
+	assert.Equal(t, models.WebhookPending, delivery.Status)
+	assert.Equal(t, 1, localWebhookWorker(db).ProcessDue(time.Now()))
+	assert.Len(t, subscriber.calls, 2)
+	assert.Equal(t, subscriber.calls[0].Body, subscriber.calls[1].Body)
+}
+
+func TestWebhookSubscriptionValidation(t *testing.T) {
+	assert.Error(t, (&models.WebhookSubscription{URL: "ftp://example.com"}).Validate())
+	assert.Error(t, (&models.WebhookSubscription{URL: "https://example.com", Events: []models.EventType{"commande.deleted"}}).Validate())
+	assert.NoError(t, (&models.WebhookSubscription{URL: "https://example.com", Events: []models.EventType{models.EventMenuUpdated}}).Validate())
+
+	// Les adresses internes sont refusées
+	for _, url := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://169.254.169.254/latest/meta-data", "http://10.0.0.5/hook", "http://192.168.1.10/hook", "http://0.0.0.0/hook"} {
+		assert.Error(t, (&models.WebhookSubscription{URL: url}).Validate(), url)
+	}
+}
+
+func TestWebhookWorkerRefusesInternalTargets(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	server, subscriber := newFakeSubscriber(t, http.StatusOK)
+
+	// Une adresse interne, par exemple un nom qui se résout vers le réseau local, est bloquée à la connexion
+	subscription := models.WebhookSubscription{URL: server.URL, IsActive: true}
+	models.CreateWebhookSubscription(db, &subscription)
+	db.Create(&models.Product{Name: "Soda", Price: decimal.NewFromInt(2), IsAvailable: true, Type: models.TypeBoisson})
+
+	assert.Equal(t, 1, webhooks.NewWorker(db).ProcessDue(time.Now()))
+	assert.Len(t, subscriber.calls, 0)
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	assert.Equal(t, models.WebhookPending, delivery.Status)
+	assert.Contains(t, delivery.LastError, "adresses publiques")
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	target, subscriber := newFakeSubscriber(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	t.Cleanup(redirect.Close)
+
+	subscription := models.WebhookSubscription{URL: redirect.URL, IsActive: true}
+	models.CreateWebhookSubscription(db, &subscription)
+	db.Create(&models.Product{Name: "Soda", Price: decimal.NewFromInt(2), IsAvailable: true, Type: models.TypeBoisson})
+
+	assert.Equal(t, 1, localWebhookWorker(db).ProcessDue(time.Now()))
+	assert.Len(t, subscriber.calls, 0)
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	assert.Equal(t, "l'abonné a répondu 302", delivery.LastError)
+}
+
+func TestWebhookDeliveryClaimedOnce(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	subscription := models.WebhookSubscription{URL: "https://example.com/hook", IsActive: true}
+	models.CreateWebhookSubscription(db, &subscription)
+	db.Create(&models.Product{Name: "Soda", Price: decimal.NewFromInt(2), IsAvailable: true, Type: models.TypeBoisson})
+
+	now := time.Now()
+	deliveries, _ := models.GetDueWebhookDeliveries(db, now, 10)
+	second := deliveries[0]
+
+	// Deux workers lisent le même envoi : seul le premier le réserve
+	claimed, err := models.ClaimWebhookDelivery(db, &deliveries[0], now, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = models.ClaimWebhookDelivery(db, &second, now, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	// Le bail expiré, l'envoi redevient disponible
+	due, _ := models.GetDueWebhookDeliveries(db, now.Add(2*time.Minute), 10)
+	assert.Len(t, due, 1)
+}
@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"LearningCampusKabre/models"
+
+	"gorm.io/gorm"
+)
+
+// Worker envoie les webhooks en attente aux abonnés, avec nouvelles tentatives.
+type Worker struct {
+	DB          *gorm.DB
+	HTTP        *http.Client
+	Interval    time.Duration
+	MaxAttempts int
+	// Durée pendant laquelle un envoi réservé n'est pas repris par un autre worker
+	Lease time.Duration
+	// Backoff retourne le délai avant la tentative suivante
+	Backoff func(attempts int) time.Duration
+}
+
+// NewWorker crée un worker avec les réglages par défaut : 8 tentatives espacées de 30 s, 1 min,
+// 2 min... jusqu'à 1 h entre deux tentatives
+func NewWorker(db *gorm.DB) *Worker {
+	return &Worker{
+		DB:          db,
+		HTTP:        NewHTTPClient(10 * time.Second),
+		Interval:    5 * time.Second,
+		MaxAttempts: 8,
+		Lease:       2 * time.Minute,
+		Backoff: func(attempts int) time.Duration {
+			delay := 30 * time.Second << (attempts - 1)
+			return min(delay, time.Hour)
+		},
+	}
+}
+
+// NewHTTPClient crée le client des envois : il ne se connecte qu'à des adresses publiques, vérifiées
+// après la résolution DNS, et ne suit pas les redirections, qui pourraient mener ailleurs
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: checkPublicAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkPublicAddress refuse la connexion à une adresse interne
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !models.IsPublicIP(ip) {
+		return fmt.Errorf("adresse %s refusée : les webhooks ne sont envoyés qu'à des adresses publiques", host)
+	}
+	return nil
+}
+
+// Run traite la file jusqu'à l'annulation du contexte
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.ProcessDue(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue envoie les webhooks arrivés à échéance et retourne le nombre de ceux traités
+func (w *Worker) ProcessDue(now time.Time) int {
+	deliveries, err := models.GetDueWebhookDeliveries(w.DB, now, 50)
+	if err != nil {
+		log.Println("❌ Erreur lors de la lecture de la file des webhooks :", err)
+		return 0
+	}
+
+	processed := 0
+	for i := range deliveries {
+		claimed, err := models.ClaimWebhookDelivery(w.DB, &deliveries[i], now, w.Lease)
+		if err != nil {
+			log.Println("❌ Erreur lors de la réservation d'un webhook :", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		w.process(&deliveries[i], now)
+		processed++
+	}
+	return processed
+}
+
+func (w *Worker) process(delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+	attempt := models.WebhookAttempt{}
+
+	subscription, err := models.GetWebhookSubscriptionByID(w.DB, delivery.SubscriptionID)
+	if err == nil {
+		start := time.Now()
+		attempt.StatusCode, err = w.send(subscription, delivery, now)
+		attempt.DurationMs = time.Since(start).Milliseconds()
+	}
+
+	switch {
+	case err == nil:
+		deliveredAt := now
+		delivery.Status = models.WebhookDelivered
+		delivery.DeliveredAt = &deliveredAt
+		delivery.LastError = ""
+	case delivery.Attempts >= w.MaxAttempts:
+		delivery.Status = models.WebhookDead
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(w.Backoff(delivery.Attempts))
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	if err := models.RecordWebhookAttempt(w.DB, delivery, &attempt); err != nil {
+		log.Println("❌ Erreur lors de la mise à jour de l'envoi du webhook :", err)
+	}
+}
+
+// send poste le payload à l'abonné ; toute réponse hors 2xx est un échec
+func (w *Worker) send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(Sign(subscription.Secret, timestamp, delivery.Payload)))
+
+	resp, err := w.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("l'abonné a répondu %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign calcule la signature HMAC-SHA256 de "timestamp.body". L'horodatage signé permet à
+// l'abonné de rejeter les envois rejoués.
+func Sign(secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}